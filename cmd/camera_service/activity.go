@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ActivityConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// parseClock parses a time of day like 07:30 into minutes since midnight, 24:00 is the end of the day
+func parseClock(clock string) (int, error) {
+	hoursStr, minutesStr, ok := strings.Cut(clock, ":")
+	if !ok || len(hoursStr) != 2 || len(minutesStr) != 2 {
+		return 0, fmt.Errorf("invalid time of day %q, expected a time like 07:30", clock)
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected a time like 07:30", clock)
+	}
+	minutes, err := strconv.Atoi(minutesStr)
+	if err != nil || minutes > 59 || hours > 24 || hours == 24 && minutes != 0 {
+		return 0, fmt.Errorf("invalid time of day %q, expected a time like 07:30", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// activePeriodStart returns when the current active period of rule started, or false when the rule is not active
+// at now. A period spanning midnight belongs to the weekday it starts on.
+func activePeriodStart(rule dbschema.ActivityRule, now time.Time) (time.Time, bool, error) {
+	location, err := time.LoadLocation(rule.Timezone)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid time zone %q: %w", rule.Timezone, err)
+	}
+	from, err := parseClock(rule.ActiveFrom)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	to, err := parseClock(rule.ActiveTo)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	now = now.In(location)
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, location)
+	minute := now.Hour()*60 + now.Minute()
+
+	activeOn := func(date time.Time) bool {
+		for _, weekday := range rule.Weekdays {
+			if time.Weekday(weekday) == date.Weekday() {
+				return true
+			}
+		}
+		return false
+	}
+	startOf := func(date time.Time) time.Time {
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, from, 0, 0, location)
+	}
+
+	switch {
+	case from < to:
+		if minute >= from && minute < to && activeOn(today) {
+			return startOf(today), true, nil
+		}
+	case minute >= from:
+		if activeOn(today) {
+			return startOf(today), true, nil
+		}
+	case minute < to:
+		yesterday := today.AddDate(0, 0, -1)
+		if activeOn(yesterday) {
+			return startOf(yesterday), true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// validateActivityRule checks what the table constraints can not, so mistakes are reported before the rule is used
+func validateActivityRule(rule dbschema.ActivityRule) error {
+	_, _, err := activePeriodStart(rule, time.Now())
+	return err
+}
+
+type activityJob struct {
+	config   ActivityConfig
+	queries  store.Store
+	notifier notify.Notifier
+	logger   *zap.SugaredLogger
+}
+
+func newActivityJob(config ActivityConfig, queries store.Store, notifier notify.Notifier, logger *zap.SugaredLogger) *activityJob {
+	return &activityJob{config: config, queries: queries, notifier: notifier, logger: logger.Named("activity")}
+}
+
+// run evaluates every activity rule every configured interval until ctx is done
+func (j *activityJob) run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.evaluateAll(ctx, time.Now()); err != nil {
+			j.logger.Errorf("error evaluating activity rules: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *activityJob) evaluateAll(ctx context.Context, now time.Time) error {
+	rules, err := j.queries.GetActivityRules(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting activity rules: %w", err)
+	}
+
+	cameras, err := j.queries.GetCameras(ctx, false)
+	if err != nil {
+		return fmt.Errorf("error getting cameras: %w", err)
+	}
+	byId := make(map[int64]dbschema.Camera, len(cameras))
+	for _, camera := range cameras {
+		byId[camera.ID] = camera
+	}
+
+	// deleted cameras, cameras that are not active and disabled rules are expected to be silent, their open alerts
+	// are resolved
+	for _, rule := range rules {
+		camera, found := byId[rule.CameraID]
+		switch {
+		case !found:
+			err = j.resolve(ctx, rule, "was deleted", now)
+		case camera.State != cameraStateActive:
+			err = j.resolve(ctx, rule, fmt.Sprintf("is in the %s state", camera.State), now)
+		case !rule.Enabled:
+			err = j.resolve(ctx, rule, fmt.Sprintf("is no longer watched by the disabled activity rule %d", rule.ID), now)
+		default:
+			err = j.evaluate(ctx, rule, camera, now)
+		}
+		if err != nil {
+			j.logger.Errorf("error evaluating activity rule %d: %s", rule.ID, err)
+		}
+	}
+	return nil
+}
+
+// evaluate opens an alert when camera, the camera of rule, was silent for the last window, and resolves it once
+// the camera detects enough people again. Rules are only checked once they were active for a whole window. Alerts
+// are also resolved when the active period of the rule ends or the camera enters a maintenance window, as the
+// camera is expected to be silent then.
+func (j *activityJob) evaluate(ctx context.Context, rule dbschema.ActivityRule, camera dbschema.Camera, now time.Time) error {
+	start, active, err := activePeriodStart(rule, now)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return j.resolve(ctx, rule, "left the active period of its activity rule", now)
+	}
+	window := time.Duration(rule.WindowMinutes) * time.Minute
+	windowStart := now.Add(-window)
+	if start.After(windowStart) {
+		return nil
+	}
+
+	count, err := j.queries.CountPersonDetectionsForCamera(ctx, dbschema.CountPersonDetectionsForCameraParams{
+		CameraID: rule.CameraID,
+		FromDate: pgtype.Timestamptz{Time: windowStart, Valid: true},
+		ToDate:   pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error counting detections: %w", err)
+	}
+	silent := count < int64(rule.MinDetections)
+
+	ruleId := pgtype.Int8{Int64: rule.ID, Valid: true}
+	alert, err := j.queries.GetUnresolvedActivityRuleAlert(ctx, ruleId)
+	open := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("error getting alert: %w", err)
+	}
+
+	if !silent {
+		if !open {
+			return nil
+		}
+		return j.resolveAlert(ctx, alert, fmt.Sprintf("Camera %s recovered", camera.Name),
+			fmt.Sprintf("camera %q detected %d people in the last %d minutes", camera.Name, count, rule.WindowMinutes), now)
+	}
+
+	inMaintenance, err := j.queries.GetCamerasInMaintenance(ctx, dbschema.GetCamerasInMaintenanceParams{
+		ToDate:   pgtype.Timestamptz{Time: now, Valid: true},
+		FromDate: pgtype.Timestamptz{Time: windowStart, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error getting cameras in maintenance: %w", err)
+	}
+	for _, cameraId := range inMaintenance {
+		if cameraId == rule.CameraID {
+			if !open {
+				return nil
+			}
+			return j.resolveAlert(ctx, alert, fmt.Sprintf("Camera %s is in maintenance", camera.Name),
+				fmt.Sprintf("camera %q is in a maintenance window, it is no longer expected to detect people", camera.Name), now)
+		}
+	}
+	if open {
+		return nil
+	}
+
+	message := fmt.Sprintf("camera %q detected %d people in the last %d minutes, at least %d were expected",
+		camera.Name, count, rule.WindowMinutes, rule.MinDetections)
+	alert, err = j.queries.CreateAlert(ctx, dbschema.CreateAlertParams{
+		Kind:           alertKindCameraSilent,
+		Severity:       "warning",
+		CameraID:       pgtype.Int8{Int64: camera.ID, Valid: true},
+		ActivityRuleID: ruleId,
+		Message:        message,
+	})
+	if err != nil {
+		return fmt.Errorf("error opening alert: %w", err)
+	}
+
+	notifyAlert(ctx, j.notifier, alert, fmt.Sprintf("Camera %s went silent", camera.Name), message, now, j.logger)
+	return nil
+}
+
+// resolve resolves the open alert of rule, if there is one, because its camera is no longer expected to detect
+// people for reason
+func (j *activityJob) resolve(ctx context.Context, rule dbschema.ActivityRule, reason string, now time.Time) error {
+	alert, err := j.queries.GetUnresolvedActivityRuleAlert(ctx, pgtype.Int8{Int64: rule.ID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting alert: %w", err)
+	}
+
+	// deleted cameras are still found
+	camera, err := j.queries.GetCamera(ctx, rule.CameraID)
+	if err != nil {
+		return fmt.Errorf("error getting camera: %w", err)
+	}
+
+	return j.resolveAlert(ctx, alert, fmt.Sprintf("Camera %s is no longer watched", camera.Name),
+		fmt.Sprintf("camera %q %s, it is no longer expected to detect people", camera.Name, reason), now)
+}
+
+func (j *activityJob) resolveAlert(ctx context.Context, alert dbschema.Alert, title string, message string, now time.Time) error {
+	alert, err := j.queries.ResolveAlert(ctx, alert.ID)
+	if err != nil {
+		return fmt.Errorf("error resolving alert: %w", err)
+	}
+	notifyAlert(ctx, j.notifier, alert, title, message, now, j.logger)
+	return nil
+}
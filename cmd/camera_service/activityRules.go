@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+func activityRuleCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	logger = logger.Named("activityRuleCtx")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			camera := ctx.Value("camera").(dbschema.Camera)
+
+			ruleId, err := strconv.ParseInt(chi.URLParam(r, "activityRuleId"), 10, 64)
+			if err != nil {
+				err := fmt.Errorf("error parsing activity rule id: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			rule, err := queries.GetActivityRule(ctx, ruleId)
+
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				HandlePqError(w, r, pgErr, logger)
+			} else if errors.Is(err, pgx.ErrNoRows) || err == nil && rule.CameraID != camera.ID {
+				http.Error(w, "activity rule not found", http.StatusNotFound)
+			} else if err != nil {
+				err := fmt.Errorf("error getting activity rule: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, "activityRule", rule)))
+			}
+		})
+	}
+}
+
+func getCameraActivityRules(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraActivityRules")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		rules, err := queries.GetActivityRulesForCamera(ctx, camera.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting activity rules: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(rules)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func postCameraActivityRule(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("postCameraActivityRule")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		// only window_minutes is required, the rest of the fields default to a rule active all day, every day
+		params := dbschema.CreateActivityRuleParams{
+			MinDetections: 1,
+			Weekdays:      []int32{0, 1, 2, 3, 4, 5, 6},
+			ActiveFrom:    "00:00",
+			ActiveTo:      "24:00",
+			Timezone:      "UTC",
+			Enabled:       true,
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err := fmt.Errorf("error decoding request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.CameraID = camera.ID
+
+		if err := validateActivityRule(dbschema.ActivityRule{
+			Weekdays:   params.Weekdays,
+			ActiveFrom: params.ActiveFrom,
+			ActiveTo:   params.ActiveTo,
+			Timezone:   params.Timezone,
+		}); err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rule, err := queries.CreateActivityRule(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error creating activity rule: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(rule)
+		if err != nil {
+			err = fmt.Errorf("error marshaling body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Location", path.Join(r.URL.String(), fmt.Sprintf("/%d", rule.ID)))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getActivityRule(logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getActivityRule")
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule := r.Context().Value("activityRule")
+
+		body, err := json.Marshal(rule)
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func patchActivityRule(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("patchActivityRule")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		rule := ctx.Value("activityRule").(dbschema.ActivityRule)
+
+		var params dbschema.UpdateActivityRuleParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err = fmt.Errorf("invalid body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.ID = rule.ID
+
+		// the schedule is validated as it will be once the changes are applied
+		if params.Weekdays != nil {
+			rule.Weekdays = params.Weekdays
+		}
+		if params.ActiveFrom.Valid {
+			rule.ActiveFrom = params.ActiveFrom.String
+		}
+		if params.ActiveTo.Valid {
+			rule.ActiveTo = params.ActiveTo.String
+		}
+		if params.Timezone.Valid {
+			rule.Timezone = params.Timezone.String
+		}
+		if err := validateActivityRule(rule); err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rule, err := queries.UpdateActivityRule(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error updating activity rule: %s", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(rule)
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %s", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func deleteActivityRule(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("deleteActivityRule")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		rule := ctx.Value("activityRule").(dbschema.ActivityRule)
+
+		err := queries.DeleteActivityRule(ctx, rule.ID)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+		} else if err != nil {
+			err := fmt.Errorf("error deleting activity rule: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
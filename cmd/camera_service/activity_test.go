@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestActivePeriodStart(t *testing.T) {
+	// a wednesday
+	wednesday := func(hour int, minute int) time.Time {
+		return time.Date(2023, 5, 3, hour, minute, 0, 0, time.UTC)
+	}
+	allWeek := []int32{0, 1, 2, 3, 4, 5, 6}
+	mexicoCity, err := time.LoadLocation("America/Mexico_City")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		rule     dbschema.ActivityRule
+		now      time.Time
+		expected time.Time
+		active   bool
+	}{
+		{"inside", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "UTC"},
+			wednesday(12, 0), wednesday(8, 0), true},
+		{"starts inclusive", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "UTC"},
+			wednesday(8, 0), wednesday(8, 0), true},
+		{"before", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "UTC"},
+			wednesday(7, 59), time.Time{}, false},
+		{"ends exclusive", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "UTC"},
+			wednesday(17, 0), time.Time{}, false},
+		{"other weekday", dbschema.ActivityRule{Weekdays: []int32{1, 2}, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "UTC"},
+			wednesday(12, 0), time.Time{}, false},
+		{"whole day", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "00:00", ActiveTo: "24:00", Timezone: "UTC"},
+			wednesday(23, 59), wednesday(0, 0), true},
+		{"spanning midnight before it", dbschema.ActivityRule{Weekdays: []int32{3}, ActiveFrom: "22:00", ActiveTo: "06:00", Timezone: "UTC"},
+			wednesday(23, 0), wednesday(22, 0), true},
+		{"spanning midnight after it", dbschema.ActivityRule{Weekdays: []int32{3}, ActiveFrom: "22:00", ActiveTo: "06:00", Timezone: "UTC"},
+			wednesday(29, 0), wednesday(22, 0), true},
+		{"spanning midnight from an inactive day", dbschema.ActivityRule{Weekdays: []int32{4}, ActiveFrom: "22:00", ActiveTo: "06:00", Timezone: "UTC"},
+			wednesday(29, 0), time.Time{}, false},
+		{"time zone before", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "America/Mexico_City"},
+			wednesday(13, 0), time.Time{}, false},
+		{"time zone inside", dbschema.ActivityRule{Weekdays: allWeek, ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "America/Mexico_City"},
+			wednesday(14, 30), time.Date(2023, 5, 3, 8, 0, 0, 0, mexicoCity), true},
+		{"time zone weekday", dbschema.ActivityRule{Weekdays: []int32{3}, ActiveFrom: "20:00", ActiveTo: "23:00", Timezone: "America/Mexico_City"},
+			wednesday(26, 30), time.Date(2023, 5, 3, 20, 0, 0, 0, mexicoCity), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, active, err := activePeriodStart(test.rule, test.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if active != test.active || !start.Equal(test.expected) {
+				t.Fatalf("expected %s active %t, got %s active %t", test.expected, test.active, start, active)
+			}
+		})
+	}
+}
+
+func TestActivePeriodStartRejectsInvalidRules(t *testing.T) {
+	for name, rule := range map[string]dbschema.ActivityRule{
+		"time zone":      {ActiveFrom: "08:00", ActiveTo: "17:00", Timezone: "Mars/Olympus_Mons"},
+		"short clock":    {ActiveFrom: "8:00", ActiveTo: "17:00", Timezone: "UTC"},
+		"minutes":        {ActiveFrom: "08:60", ActiveTo: "17:00", Timezone: "UTC"},
+		"after midnight": {ActiveFrom: "08:00", ActiveTo: "24:30", Timezone: "UTC"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := validateActivityRule(rule); err == nil {
+				t.Fatal("expected the rule to be rejected")
+			}
+		})
+	}
+}
+
+// notificationRecorder keeps every notification it is sent
+type notificationRecorder struct {
+	mutex         sync.Mutex
+	notifications []notify.Notification
+}
+
+func (r *notificationRecorder) Notify(ctx context.Context, notification notify.Notification) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+// take returns the notifications received since it was last called
+func (r *notificationRecorder) take() []notify.Notification {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	notifications := r.notifications
+	r.notifications = nil
+	return notifications
+}
+
+// newActivityTest returns a job watching a camera with a rule expecting a detection every hour between 08:00 and
+// 17:00 utc
+func newActivityTest(t *testing.T) (*activityJob, *store.Memory, *notificationRecorder, dbschema.ActivityRule) {
+	t.Helper()
+	ctx := context.Background()
+	queries := store.NewMemory()
+
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+	camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{
+		Name:             "entrance",
+		ConnectionString: "rtsp://entrance",
+		LocationID:       int32(location.ID),
+		Orientation:      dbenums.CameraOrientationHorizontal,
+		Tags:             map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("error creating camera: %s", err)
+	}
+	rule, err := queries.CreateActivityRule(ctx, dbschema.CreateActivityRuleParams{
+		CameraID:      camera.ID,
+		MinDetections: 1,
+		WindowMinutes: 60,
+		Weekdays:      []int32{0, 1, 2, 3, 4, 5, 6},
+		ActiveFrom:    "08:00",
+		ActiveTo:      "17:00",
+		Timezone:      "UTC",
+		Enabled:       true,
+	})
+	if err != nil {
+		t.Fatalf("error creating activity rule: %s", err)
+	}
+
+	notifications := &notificationRecorder{}
+	return newActivityJob(ActivityConfig{}, queries, notifications, zap.NewNop().Sugar()), queries, notifications, rule
+}
+
+// expectNotification fails the test unless exactly one notification was received since the last call, in state and
+// with a title containing title
+func expectNotification(t *testing.T, notifications *notificationRecorder, state string, title string) {
+	t.Helper()
+	received := notifications.take()
+	if len(received) != 1 {
+		t.Fatalf("expected a notification %q, got %+v", title, received)
+	}
+	if received[0].State != state || !strings.Contains(received[0].Title, title) {
+		t.Fatalf("expected a notification %q in state %s, got %q in state %s", title, state, received[0].Title, received[0].State)
+	}
+}
+
+func expectNoNotification(t *testing.T, notifications *notificationRecorder) {
+	t.Helper()
+	if received := notifications.take(); len(received) != 0 {
+		t.Fatalf("expected no notification, got %+v", received)
+	}
+}
+
+func evaluateActivity(t *testing.T, job *activityJob, now time.Time) {
+	t.Helper()
+	if err := job.evaluateAll(context.Background(), now); err != nil {
+		t.Fatalf("error evaluating activity rules: %s", err)
+	}
+}
+
+func TestActivityAlertLifecycle(t *testing.T) {
+	job, queries, notifications, rule := newActivityTest(t)
+	ctx := context.Background()
+	noon := time.Date(2023, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	// rules are only checked once they were active for a whole window
+	evaluateActivity(t, job, noon.Add(-3*time.Hour-30*time.Minute))
+	expectNoNotification(t, notifications)
+
+	evaluateActivity(t, job, noon)
+	expectNotification(t, notifications, "open", "went silent")
+	evaluateActivity(t, job, noon.Add(time.Minute))
+	expectNoNotification(t, notifications)
+
+	_, err := queries.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+		CameraID:        rule.CameraID,
+		DetectionDate:   pgtype.Timestamptz{Time: noon.Add(time.Minute), Valid: true},
+		TargetDirection: dbenums.DirectionLeft,
+	})
+	if err != nil {
+		t.Fatalf("error creating person detection: %s", err)
+	}
+	evaluateActivity(t, job, noon.Add(2*time.Minute))
+	expectNotification(t, notifications, "resolved", "recovered")
+
+	evaluateActivity(t, job, noon.Add(2*time.Hour))
+	expectNotification(t, notifications, "open", "went silent")
+
+	// the camera is expected to be silent during a maintenance window
+	_, err = queries.CreateMaintenanceWindow(ctx, dbschema.CreateMaintenanceWindowParams{
+		CameraID: rule.CameraID,
+		StartsAt: pgtype.Timestamptz{Time: noon.Add(2*time.Hour + 30*time.Minute), Valid: true},
+		EndsAt:   pgtype.Timestamptz{Time: noon.Add(3 * time.Hour), Valid: true},
+		Ingest:   "reject",
+	})
+	if err != nil {
+		t.Fatalf("error creating maintenance window: %s", err)
+	}
+	evaluateActivity(t, job, noon.Add(2*time.Hour+45*time.Minute))
+	expectNotification(t, notifications, "resolved", "in maintenance")
+	evaluateActivity(t, job, noon.Add(3*time.Hour+30*time.Minute))
+	expectNoNotification(t, notifications)
+
+	evaluateActivity(t, job, noon.Add(4*time.Hour))
+	expectNotification(t, notifications, "open", "went silent")
+
+	// and once the active period of the rule ends
+	evaluateActivity(t, job, noon.Add(6*time.Hour))
+	expectNotification(t, notifications, "resolved", "no longer watched")
+	evaluateActivity(t, job, noon.Add(7*time.Hour))
+	expectNoNotification(t, notifications)
+
+	alerts, err := queries.GetAlerts(ctx, dbschema.GetAlertsParams{Count: 10})
+	if err != nil {
+		t.Fatalf("error getting alerts: %s", err)
+	}
+	if len(alerts) != 3 {
+		t.Fatalf("expected 3 alerts, got %d", len(alerts))
+	}
+	for _, alert := range alerts {
+		if alert.State != "resolved" {
+			t.Errorf("alert %d is %s", alert.ID, alert.State)
+		}
+	}
+}
+
+func TestActivityAlertResolvedWhenCameraIsNotWatched(t *testing.T) {
+	noon := time.Date(2023, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		change func(ctx context.Context, queries *store.Memory, rule dbschema.ActivityRule) error
+		reason string
+	}{
+		{"deleted camera", func(ctx context.Context, queries *store.Memory, rule dbschema.ActivityRule) error {
+			_, err := queries.SoftDeleteCamera(ctx, rule.CameraID)
+			return err
+		}, "was deleted"},
+		{"camera in maintenance", func(ctx context.Context, queries *store.Memory, rule dbschema.ActivityRule) error {
+			_, err := queries.SetCameraState(ctx, dbschema.SetCameraStateParams{ID: rule.CameraID, State: cameraStateMaintenance})
+			return err
+		}, "maintenance state"},
+		{"disabled rule", func(ctx context.Context, queries *store.Memory, rule dbschema.ActivityRule) error {
+			_, err := queries.UpdateActivityRule(ctx, dbschema.UpdateActivityRuleParams{
+				ID:      rule.ID,
+				Enabled: pgtype.Bool{Bool: false, Valid: true},
+			})
+			return err
+		}, "disabled activity rule"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			job, queries, notifications, rule := newActivityTest(t)
+			ctx := context.Background()
+
+			evaluateActivity(t, job, noon)
+			expectNotification(t, notifications, "open", "went silent")
+
+			if err := test.change(ctx, queries, rule); err != nil {
+				t.Fatal(err)
+			}
+			evaluateActivity(t, job, noon.Add(time.Minute))
+			received := notifications.take()
+			if len(received) != 1 || received[0].State != "resolved" || !strings.Contains(received[0].Message, test.reason) {
+				t.Fatalf("expected the alert to be resolved because the camera %s, got %+v", test.reason, received)
+			}
+
+			_, err := queries.GetUnresolvedActivityRuleAlert(ctx, pgtype.Int8{Int64: rule.ID, Valid: true})
+			if err == nil {
+				t.Fatal("the alert is still open")
+			}
+			evaluateActivity(t, job, noon.Add(2*time.Minute))
+			expectNoNotification(t, notifications)
+		})
+	}
+}
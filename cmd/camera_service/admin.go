@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/client"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// adminBackend contains the operations the admin commands need, it is implemented by storeBackend for direct
+// database access and by *client.Client for access through the http api
+type adminBackend interface {
+	GetCameras(ctx context.Context, includeDeleted bool) ([]dbschema.Camera, error)
+	CreateCamera(ctx context.Context, arg dbschema.CreateCameraParams) (dbschema.Camera, error)
+	UpdateCamera(ctx context.Context, arg dbschema.UpdateCameraParams) (dbschema.Camera, error)
+	SoftDeleteCamera(ctx context.Context, id int64) (dbschema.Camera, error)
+	HardDeleteCamera(ctx context.Context, id int64, deleteDetections bool, reassignTo int64) error
+
+	GetLocations(ctx context.Context, includeDeleted bool) ([]dbschema.Location, error)
+	CreateLocation(ctx context.Context, arg dbschema.CreateLocationParams) (dbschema.Location, error)
+	UpdateLocation(ctx context.Context, arg dbschema.UpdateLocationParams) (dbschema.Location, error)
+	SoftDeleteLocation(ctx context.Context, id int64) (dbschema.Location, error)
+	DeleteLocation(ctx context.Context, id int64) error
+
+	StreamPersonDetectionsExport(ctx context.Context, arg dbschema.ExportPersonDetectionsParams, fn func(row dbschema.ExportPersonDetectionsRow) error) error
+}
+
+// storeBackend is the adminBackend working on the database directly, it deletes cameras the way the http api does
+type storeBackend struct {
+	store.Store
+}
+
+func (b storeBackend) HardDeleteCamera(ctx context.Context, id int64, deleteDetections bool, reassignTo int64) error {
+	return hardDeleteCamera(ctx, b.Store, id, deleteDetections, reassignTo)
+}
+
+// adminFlags are the flags shared by every admin command
+type adminFlags struct {
+	*flag.FlagSet
+	remote string
+	json   bool
+}
+
+func newAdminFlags(name string) *adminFlags {
+	flags := &adminFlags{FlagSet: flag.NewFlagSet(name, flag.ExitOnError)}
+	flags.StringVar(&flags.remote, "remote", "", "base url of a running camera_service, the database is used directly if empty")
+	flags.BoolVar(&flags.json, "json", false, "print results as json")
+	return flags
+}
+
+// backend returns the backend selected by the parsed flags
+func (f *adminFlags) backend(logger *zap.SugaredLogger) adminBackend {
+	if f.remote != "" {
+		return client.New(f.remote)
+	}
+
+	config := loadConfig(logger)
+	checkDatabaseSchema(config.Db, logger)
+	return storeBackend{store.NewPostgres(connectToDb(config.Db, logger))}
+}
+
+// isSet returns whether the named flag was given on the command line
+func (f *adminFlags) isSet(name string) bool {
+	set := false
+	f.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// optionalFloat returns value when the named flag was set, nil otherwise
+func (f *adminFlags) optionalFloat(name string, value float64) *float64 {
+	if !f.isSet(name) {
+		return nil
+	}
+	return &value
+}
+
+// tagFlags collects the key=value pairs of a repeatable --tag flag
+type tagFlags map[string]string
+
+func (t tagFlags) String() string {
+	pairs := make([]string, 0, len(t))
+	for key, value := range t {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (t tagFlags) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok {
+		return fmt.Errorf("invalid tag %q, expected key=value", pair)
+	}
+	if err := validateTag(key); err != nil {
+		return err
+	}
+	t[key] = value
+	return nil
+}
+
+// parseIdArg parses the leading id argument of commands like update and delete, returning the remaining arguments
+func parseIdArg(args []string, logger *zap.SugaredLogger) (int64, []string) {
+	if len(args) == 0 {
+		logger.Fatal("missing id argument")
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		logger.Fatalf("error parsing id: %s", err)
+	}
+
+	return id, args[1:]
+}
+
+func printJson(v any, logger *zap.SugaredLogger) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Fatalf("error encoding json: %s", err)
+	}
+}
+
+func printCameras(cameras []dbschema.Camera, asJson bool, logger *zap.SugaredLogger) {
+	if asJson {
+		printJson(cameras, logger)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tLOCATION ID\tSTATE\tMOUNT\tFLOOR X\tFLOOR Y\tHEADING\tHEIGHT\tORIENTATION\tCONNECTION STRING")
+	for _, camera := range cameras {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", camera.ID, camera.Name, camera.LocationID,
+			camera.State, camera.MountDescription, formatOptionalFloat(camera.FloorX), formatOptionalFloat(camera.FloorY),
+			formatOptionalFloat(camera.Heading), formatOptionalFloat(camera.MountingHeight), camera.Orientation,
+			camera.ConnectionString)
+	}
+	if err := w.Flush(); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// formatOptionalFloat formats f for a table, with a dash when it is not set
+func formatOptionalFloat(f *float64) string {
+	if f == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+// formatOptionalInt formats i for a table, with a dash when it is not set
+func formatOptionalInt(i pgtype.Int4) string {
+	if !i.Valid {
+		return "-"
+	}
+	return strconv.Itoa(int(i.Int32))
+}
+
+func printLocations(locations []dbschema.Location, asJson bool, logger *zap.SugaredLogger) {
+	if asJson {
+		printJson(locations, logger)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPARENT\tCAPACITY\tWARNING THRESHOLD\tDESCRIPTION")
+	for _, location := range locations {
+		parent := "-"
+		if location.ParentID.Valid {
+			parent = strconv.FormatInt(location.ParentID.Int64, 10)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", location.ID, location.Name, parent, formatOptionalInt(location.Capacity),
+			formatOptionalInt(location.WarningThreshold), location.Description)
+	}
+	if err := w.Flush(); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+func adminCameras(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("cameras")
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatal("missing cameras command")
+	}
+
+	action, args := args[0], args[1:]
+	flags := newAdminFlags("cameras " + action)
+
+	switch action {
+	case "list":
+		var includeDeleted bool
+		flags.BoolVar(&includeDeleted, "include-deleted", false, "also list deleted cameras")
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+
+		cameras, err := flags.backend(logger).GetCameras(ctx, includeDeleted)
+		if err != nil {
+			logger.Fatalf("error getting cameras: %s", err)
+		}
+		printCameras(cameras, flags.json, logger)
+
+	case "create":
+		var params dbschema.CreateCameraParams
+		var locationId int64
+		var orientation, entryDirection string
+		var floorX, floorY, heading, mountingHeight float64
+		tags := tagFlags{}
+		flags.StringVar(&params.Name, "name", "", "name of the camera")
+		flags.StringVar(&params.ConnectionString, "connection-string", "", "stream url of the camera")
+		flags.Int64Var(&locationId, "location-id", 0, "id of the location the camera belongs to")
+		flags.StringVar(&orientation, "orientation", string(dbenums.CameraOrientationHorizontal), "orientation of the camera")
+		flags.StringVar(&entryDirection, "entry-direction", string(dbenums.DirectionNone), "direction of the people entering the location as seen by an upright camera, none if the camera does not count occupancy")
+		flags.StringVar(&params.MountDescription, "mount-description", "", "description of where the camera is mounted")
+		flags.Float64Var(&floorX, "floor-x", 0, "x coordinate of the camera on the floor plan of its location")
+		flags.Float64Var(&floorY, "floor-y", 0, "y coordinate of the camera on the floor plan of its location")
+		flags.Float64Var(&heading, "heading", 0, "degrees clockwise from the top of the floor plan the camera looks at")
+		flags.Float64Var(&mountingHeight, "mounting-height", 0, "meters above the floor the camera is mounted at")
+		flags.Var(tags, "tag", "key=value tag of the camera, can be repeated")
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+		params.LocationID = int32(locationId)
+		params.Tags = tags
+		params.FloorX = flags.optionalFloat("floor-x", floorX)
+		params.FloorY = flags.optionalFloat("floor-y", floorY)
+		params.Heading = flags.optionalFloat("heading", heading)
+		params.MountingHeight = flags.optionalFloat("mounting-height", mountingHeight)
+		params.Orientation = dbenums.Orientation(orientation)
+		params.EntryDirection = dbenums.NullDirection{Direction: dbenums.Direction(entryDirection), Valid: true}
+
+		camera, err := flags.backend(logger).CreateCamera(ctx, params)
+		if err != nil {
+			logger.Fatalf("error creating camera: %s", err)
+		}
+		printCameras([]dbschema.Camera{camera}, flags.json, logger)
+
+	case "update":
+		var params dbschema.UpdateCameraParams
+		params.ID, args = parseIdArg(args, logger)
+
+		var name, connectionString, orientation, entryDirection, mountDescription string
+		var locationId int64
+		var floorX, floorY, heading, mountingHeight float64
+		tags := tagFlags{}
+		flags.StringVar(&name, "name", "", "name of the camera")
+		flags.StringVar(&connectionString, "connection-string", "", "stream url of the camera")
+		flags.Int64Var(&locationId, "location-id", 0, "id of the location the camera belongs to")
+		flags.StringVar(&orientation, "orientation", "", "orientation of the camera")
+		flags.StringVar(&entryDirection, "entry-direction", "", "direction of the people entering the location as seen by an upright camera, none if the camera does not count occupancy")
+		flags.StringVar(&mountDescription, "mount-description", "", "description of where the camera is mounted")
+		flags.Float64Var(&floorX, "floor-x", 0, "x coordinate of the camera on the floor plan of its location")
+		flags.Float64Var(&floorY, "floor-y", 0, "y coordinate of the camera on the floor plan of its location")
+		flags.Float64Var(&heading, "heading", 0, "degrees clockwise from the top of the floor plan the camera looks at")
+		flags.Float64Var(&mountingHeight, "mounting-height", 0, "meters above the floor the camera is mounted at")
+		flags.Var(tags, "tag", "key=value tag of the camera, can be repeated, replaces all of its tags")
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+
+		if flags.isSet("tag") {
+			params.Tags = tags
+		}
+		params.Name = pgtype.Text{String: name, Valid: flags.isSet("name")}
+		params.ConnectionString = pgtype.Text{String: connectionString, Valid: flags.isSet("connection-string")}
+		params.LocationID = pgtype.Int4{Int32: int32(locationId), Valid: flags.isSet("location-id")}
+		params.Orientation = dbenums.NullOrientation{Orientation: dbenums.Orientation(orientation), Valid: flags.isSet("orientation")}
+		params.EntryDirection = dbenums.NullDirection{Direction: dbenums.Direction(entryDirection), Valid: flags.isSet("entry-direction")}
+		params.MountDescription = pgtype.Text{String: mountDescription, Valid: flags.isSet("mount-description")}
+		params.FloorX = flags.optionalFloat("floor-x", floorX)
+		params.FloorY = flags.optionalFloat("floor-y", floorY)
+		params.Heading = flags.optionalFloat("heading", heading)
+		params.MountingHeight = flags.optionalFloat("mounting-height", mountingHeight)
+
+		camera, err := flags.backend(logger).UpdateCamera(ctx, params)
+		if err != nil {
+			logger.Fatalf("error updating camera: %s", err)
+		}
+		printCameras([]dbschema.Camera{camera}, flags.json, logger)
+
+	case "delete":
+		var hard bool
+		var detections string
+		var reassignTo int64
+		flags.BoolVar(&hard, "hard", false, "remove the camera for good instead of marking it as deleted")
+		flags.StringVar(&detections, "detections", "", "delete to delete the detections of the camera with it, requires -hard")
+		flags.Int64Var(&reassignTo, "reassign-to", 0, "id of the camera receiving the detections of the camera, requires -hard")
+		id, args := parseIdArg(args, logger)
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+		if detections != "" && detections != "delete" {
+			logger.Fatalf("invalid -detections %q, must be delete", detections)
+		}
+		if (detections != "" || reassignTo != 0) && !hard {
+			logger.Fatal("-detections and -reassign-to are only allowed with -hard")
+		}
+
+		backend := flags.backend(logger)
+		var err error
+		if hard {
+			err = backend.HardDeleteCamera(ctx, id, detections == "delete", reassignTo)
+		} else {
+			_, err = backend.SoftDeleteCamera(ctx, id)
+		}
+		if err != nil {
+			logger.Fatalf("error deleting camera: %s", err)
+		}
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatalf("unknown cameras command %q", action)
+	}
+}
+
+func adminLocations(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("locations")
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatal("missing locations command")
+	}
+
+	action, args := args[0], args[1:]
+	flags := newAdminFlags("locations " + action)
+
+	switch action {
+	case "list":
+		var includeDeleted bool
+		flags.BoolVar(&includeDeleted, "include-deleted", false, "also list deleted locations")
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+
+		locations, err := flags.backend(logger).GetLocations(ctx, includeDeleted)
+		if err != nil {
+			logger.Fatalf("error getting locations: %s", err)
+		}
+		printLocations(locations, flags.json, logger)
+
+	case "create":
+		var params dbschema.CreateLocationParams
+		var capacity, warningThreshold int
+		var parentId int64
+		flags.StringVar(&params.Name, "name", "", "name of the location")
+		flags.StringVar(&params.Description, "description", "", "description of the location")
+		flags.IntVar(&capacity, "capacity", 0, "how many people fit in the location")
+		flags.IntVar(&warningThreshold, "warning-threshold", 0, "occupancy at which the location is almost full")
+		flags.Int64Var(&parentId, "parent-id", 0, "id of the location containing this one")
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+		params.ParentID = pgtype.Int8{Int64: parentId, Valid: flags.isSet("parent-id")}
+		params.Capacity = pgtype.Int4{Int32: int32(capacity), Valid: flags.isSet("capacity")}
+		params.WarningThreshold = pgtype.Int4{Int32: int32(warningThreshold), Valid: flags.isSet("warning-threshold")}
+
+		location, err := flags.backend(logger).CreateLocation(ctx, params)
+		if err != nil {
+			logger.Fatalf("error creating location: %s", err)
+		}
+		printLocations([]dbschema.Location{location}, flags.json, logger)
+
+	case "update":
+		var params dbschema.UpdateLocationParams
+		params.ID, args = parseIdArg(args, logger)
+
+		var name, description string
+		var capacity, warningThreshold int
+		var parentId int64
+		flags.StringVar(&name, "name", "", "name of the location")
+		flags.StringVar(&description, "description", "", "description of the location")
+		flags.IntVar(&capacity, "capacity", 0, "how many people fit in the location")
+		flags.IntVar(&warningThreshold, "warning-threshold", 0, "occupancy at which the location is almost full")
+		flags.Int64Var(&parentId, "parent-id", 0, "id of the location containing this one, 0 for none")
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+
+		params.Name = pgtype.Text{String: name, Valid: flags.isSet("name")}
+		params.Description = pgtype.Text{String: description, Valid: flags.isSet("description")}
+		params.Capacity = pgtype.Int4{Int32: int32(capacity), Valid: flags.isSet("capacity")}
+		params.WarningThreshold = pgtype.Int4{Int32: int32(warningThreshold), Valid: flags.isSet("warning-threshold")}
+		params.ParentID = pgtype.Int8{Int64: parentId, Valid: flags.isSet("parent-id")}
+
+		location, err := flags.backend(logger).UpdateLocation(ctx, params)
+		if err != nil {
+			logger.Fatalf("error updating location: %s", err)
+		}
+		printLocations([]dbschema.Location{location}, flags.json, logger)
+
+	case "delete":
+		var hard bool
+		flags.BoolVar(&hard, "hard", false, "remove the location for good instead of marking it as deleted")
+		id, args := parseIdArg(args, logger)
+		if err := flags.Parse(args); err != nil {
+			logger.Fatal(err)
+		}
+
+		backend := flags.backend(logger)
+		var err error
+		if hard {
+			err = backend.DeleteLocation(ctx, id)
+		} else {
+			_, err = backend.SoftDeleteLocation(ctx, id)
+		}
+		if err != nil {
+			logger.Fatalf("error deleting location: %s", err)
+		}
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatalf("unknown locations command %q", action)
+	}
+}
+
+func adminDetections(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("detections")
+	ctx := context.Background()
+
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatal("expected detections export")
+	}
+
+	flags := newAdminFlags("detections export")
+	cameraId := flags.Int64("camera", 0, "only export detections of this camera")
+	format := flags.String("format", "csv", "output format, csv or json (one object per line)")
+	output := flags.String("output", "-", "file to write to, - for stdout")
+	if err := flags.Parse(args[1:]); err != nil {
+		logger.Fatal(err)
+	}
+
+	if *format != "csv" && *format != "json" {
+		logger.Fatalf("unknown export format %q", *format)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			logger.Fatalf("error creating output file: %s", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	backend := flags.backend(logger)
+
+	csvWriter := csv.NewWriter(out)
+	jsonEncoder := json.NewEncoder(out)
+
+	if *format == "csv" {
+		if err := csvWriter.Write(exportColumns); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	// the export is read as a single stream, paging would skip or repeat detections created meanwhile
+	params := dbschema.ExportPersonDetectionsParams{CameraID: pgtype.Int8{Int64: *cameraId, Valid: *cameraId != 0}}
+	exported := 0
+	err := backend.StreamPersonDetectionsExport(ctx, params, func(row dbschema.ExportPersonDetectionsRow) error {
+		exported++
+		if *format == "csv" {
+			return csvWriter.Write(exportRecord(row))
+		}
+		return jsonEncoder.Encode(&row)
+	})
+	if err != nil {
+		logger.Fatalf("error exporting person detections: %s", err)
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		logger.Fatalf("error writing csv: %s", err)
+	}
+
+	logger.Infow("exported person detections", "count", exported)
+}
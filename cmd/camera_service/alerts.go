@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NotificationsConfig configures the channels alert notifications are sent to, see notify.Config
+type NotificationsConfig struct {
+	notify.Config `mapstructure:",squash"`
+	// WebhookUrl adds an http channel named webhook, it predates the channels
+	WebhookUrl string `mapstructure:"webhook_url"`
+}
+
+// the kinds of alerts opened by the service
+const (
+	alertKindCameraSilent      = "camera_silent"
+	alertKindLocationOccupancy = "location_occupancy"
+)
+
+// newNotificationRouter creates the channels configured by config and the routes between them
+func newNotificationRouter(config NotificationsConfig, logger *zap.SugaredLogger) (*notify.Router, error) {
+	routerConfig := config.Config
+	if config.WebhookUrl != "" {
+		routerConfig.Channels = append([]notify.ChannelConfig{{Name: "webhook", Type: notify.ChannelHTTP, URL: config.WebhookUrl}},
+			routerConfig.Channels...)
+	}
+	return notify.New(routerConfig, logger.Named("notifications"))
+}
+
+// notifyAlert reports the change of alert, failing to deliver the notification does not undo the change
+func notifyAlert(ctx context.Context, notifier notify.Notifier, alert dbschema.Alert, title string, message string, now time.Time, logger *zap.SugaredLogger) {
+	notification := notify.Notification{
+		Kind:           alert.Kind,
+		State:          alert.State,
+		Severity:       alert.Severity,
+		Title:          title,
+		Message:        message,
+		AlertID:        alert.ID,
+		CameraID:       alert.CameraID.Int64,
+		LocationID:     alert.LocationID.Int64,
+		ActivityRuleID: alert.ActivityRuleID.Int64,
+		Time:           now,
+	}
+	if err := notifier.Notify(ctx, notification); err != nil {
+		logger.Errorf("error notifying alert %d: %s", alert.ID, err)
+	}
+}
+
+func alertCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	logger = logger.Named("alertCtx")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			alertId, err := strconv.ParseInt(chi.URLParam(r, "alertId"), 10, 64)
+			if err != nil {
+				err := fmt.Errorf("error parsing alert id: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			alert, err := queries.GetAlert(ctx, alertId)
+
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				HandlePqError(w, r, pgErr, logger)
+			} else if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, "alert not found", http.StatusNotFound)
+			} else if err != nil {
+				err := fmt.Errorf("error getting alert: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, "alert", alert)))
+			}
+		})
+	}
+}
+
+// defaultAlertsCount is how many alerts are listed when the request does not say
+const defaultAlertsCount = 100
+
+func getAlerts(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getAlerts")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		params := dbschema.GetAlertsParams{Count: defaultAlertsCount}
+		if state := query.Get("state"); state != "" {
+			params.State = pgtype.Text{String: state, Valid: true}
+		}
+		if kind := query.Get("kind"); kind != "" {
+			params.Kind = pgtype.Text{String: kind, Valid: true}
+		}
+		if severity := query.Get("severity"); severity != "" {
+			params.Severity = pgtype.Text{String: severity, Valid: true}
+		}
+		for name, param := range map[string]*pgtype.Int8{"camera_id": &params.CameraID, "location_id": &params.LocationID} {
+			idStr := query.Get(name)
+			if idStr == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				err := fmt.Errorf("invalid %s parameter: %w", name, err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			*param = pgtype.Int8{Int64: id, Valid: true}
+		}
+		if offset, err := strconv.ParseInt(query.Get("offset"), 10, 32); err == nil {
+			params.AlertOffset = int32(offset)
+		}
+		if countStr := query.Get("count"); countStr != "" {
+			count, err := strconv.ParseInt(countStr, 10, 32)
+			if err != nil {
+				err := fmt.Errorf("invalid count parameter: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			params.Count = int32(count)
+		}
+
+		alerts, err := queries.GetAlerts(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting alerts: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(alerts)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getAlert(logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getAlert")
+	return func(w http.ResponseWriter, r *http.Request) {
+		alert := r.Context().Value("alert")
+
+		body, err := json.Marshal(alert)
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// AcknowledgeAlertRequest is the optional body of an acknowledgement
+type AcknowledgeAlertRequest struct {
+	// AcknowledgedBy names who is taking care of the alert
+	AcknowledgedBy string `json:"acknowledged_by,omitempty"`
+}
+
+func acknowledgeAlert(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("acknowledgeAlert")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		alert := ctx.Value("alert").(dbschema.Alert)
+
+		var request AcknowledgeAlertRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			err := fmt.Errorf("error decoding request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params := dbschema.AcknowledgeAlertParams{ID: alert.ID}
+		if request.AcknowledgedBy != "" {
+			params.AcknowledgedBy = pgtype.Text{String: request.AcknowledgedBy, Valid: true}
+		}
+
+		alert, err := queries.AcknowledgeAlert(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if errors.Is(err, pgx.ErrNoRows) {
+			// only open alerts can be acknowledged
+			http.Error(w, "the alert is already acknowledged or resolved", http.StatusConflict)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error acknowledging alert: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(alert)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/blobstore"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveConfig configures the export of old person detections to compressed files. Every day of detections is
+// written to its own file, next to a manifest describing it, detections stored or changed in a day after it was
+// archived go to another file of the day. While archiving is enabled, retention never prunes person detections that
+// no archive holds.
+type ArchiveConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// OlderThan is how old the detections of a day must be before the day is archived
+	OlderThan time.Duration `mapstructure:"older_than"`
+	Interval  time.Duration `mapstructure:"interval"`
+	// Format is csv or ndjson, files are always gzip compressed
+	Format    string           `mapstructure:"format"`
+	BatchSize int32            `mapstructure:"batch_size"`
+	Store     blobstore.Config `mapstructure:"store"`
+}
+
+// archiveVersion is the version of the archives written by this binary. Version 1 archives only have the id,
+// camera_id, detection_date and target_direction columns, their manifests have no version
+const archiveVersion = 2
+
+var archiveColumns = []string{"id", "camera_id", "detection_date", "target_direction", "flagged", "normalized_direction",
+	"track_id", "confidence", "bbox_x", "bbox_y", "bbox_width", "bbox_height", "frame_date", "model_version", "excluded"}
+
+// archiveManifest is stored as json next to every archive file
+type archiveManifest struct {
+	Version     int       `json:"version"`
+	Key         string    `json:"key"`
+	Table       string    `json:"table"`
+	Format      string    `json:"format"`
+	Compression string    `json:"compression"`
+	Columns     []string  `json:"columns"`
+	RangeStart  time.Time `json:"range_start"`
+	RangeEnd    time.Time `json:"range_end"`
+	RowCount    int64     `json:"row_count"`
+	Sha256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+var archivedRows = expvar.NewInt("archive_archived_rows")
+var archivedFiles = expvar.NewInt("archive_archived_files")
+
+// archiveKey returns the key of the archive of day, part counts the archives of the day starting at 1
+func archiveKey(day time.Time, part int64, format string) string {
+	name := day.Format(time.DateOnly)
+	if part > 1 {
+		name = fmt.Sprintf("%s.%d", name, part)
+	}
+	return fmt.Sprintf("person_detections/%s/%s.%s.gz", day.Format("2006/01"), name, format)
+}
+
+// manifestKey returns the key of the manifest of the archive stored under key
+func manifestKey(key string) string {
+	for _, format := range []string{"csv", "ndjson"} {
+		if strings.HasSuffix(key, "."+format+".gz") {
+			return strings.TrimSuffix(key, "."+format+".gz") + ".manifest.json"
+		}
+	}
+	return key + ".manifest.json"
+}
+
+type archiveJob struct {
+	config  ArchiveConfig
+	db      *pgxpool.Pool
+	queries *dbschema.Queries
+	store   blobstore.Store
+	logger  *zap.SugaredLogger
+}
+
+func newArchiveJob(config ArchiveConfig, db *pgxpool.Pool, logger *zap.SugaredLogger) (*archiveJob, error) {
+	switch config.Format {
+	case "csv", "ndjson":
+	case "parquet":
+		return nil, errors.New("parquet archives are not supported yet, use csv or ndjson")
+	default:
+		return nil, fmt.Errorf("unknown archive format %q, expected csv or ndjson", config.Format)
+	}
+
+	store, err := blobstore.New(config.Store)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive store: %w", err)
+	}
+
+	return &archiveJob{config: config, db: db, queries: dbschema.New(db), store: store, logger: logger.Named("archive")}, nil
+}
+
+// run archives the old enough days every configured interval until ctx is done
+func (j *archiveJob) run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.archive(ctx); err != nil {
+			j.logger.Errorf("error archiving detections: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// archive writes a file for every day whose detections are all older than the configured threshold and that has
+// detections no archive holds, like the ones stored or moved into the day after it was archived.
+func (j *archiveJob) archive(ctx context.Context) error {
+	cutoff := time.Now().UTC().Add(-j.config.OlderThan)
+	before := time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), 0, 0, 0, 0, time.UTC)
+
+	days, err := j.queries.GetDaysWithUnarchivedDetections(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return fmt.Errorf("error getting days with unarchived detections: %w", err)
+	}
+
+	for _, day := range days {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := j.archiveDay(ctx, day.Day.Time.UTC(), day.Archives+1); err != nil {
+			return fmt.Errorf("error archiving %s: %w", day.Day.Time.UTC().Format(time.DateOnly), err)
+		}
+	}
+
+	return nil
+}
+
+// archiveDay writes the detections of day that no archive holds and its manifest to the store, then records the
+// archive and marks the detections as archived by it. part is the number of the archive within the day. The
+// detections stay locked until then, so they are archived as they are stored.
+func (j *archiveJob) archiveDay(ctx context.Context, day time.Time, part int64) error {
+	rangeStart, rangeEnd := day, day.AddDate(0, 0, 1)
+	key := archiveKey(day, part, j.config.Format)
+
+	// the file is written to disk first, as its hash must be known before it is stored
+	tmp, err := os.CreateTemp("", "camera_service-archive-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	tx, err := j.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	queries := j.queries.WithTx(tx)
+
+	hash := sha256.New()
+	compressed := gzip.NewWriter(io.MultiWriter(tmp, hash))
+	writer := newDetectionWriter(compressed, j.config.Format)
+
+	var ids []int64
+	after := dbschema.PersonDetection{DetectionDate: pgtype.Timestamptz{Time: rangeStart, Valid: true}, ID: -1}
+	for {
+		detections, err := queries.GetPersonDetectionsToArchive(ctx, dbschema.GetPersonDetectionsToArchiveParams{
+			AfterDate: after.DetectionDate,
+			AfterID:   after.ID,
+			ToDate:    pgtype.Timestamptz{Time: rangeEnd, Valid: true},
+			BatchSize: j.config.BatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("error getting detections: %w", err)
+		}
+
+		for _, detection := range detections {
+			if err := writer.write(detection); err != nil {
+				return fmt.Errorf("error writing detection: %w", err)
+			}
+			ids = append(ids, detection.ID)
+		}
+
+		if len(detections) < int(j.config.BatchSize) {
+			break
+		}
+		after = detections[len(detections)-1]
+	}
+	rowCount := int64(len(ids))
+
+	if err := writer.flush(); err != nil {
+		return fmt.Errorf("error writing detections: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("error compressing detections: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error rewinding temporary file: %w", err)
+	}
+
+	manifest := archiveManifest{
+		Version:     archiveVersion,
+		Key:         key,
+		Table:       "person_detections",
+		Format:      j.config.Format,
+		Compression: "gzip",
+		Columns:     archiveColumns,
+		RangeStart:  rangeStart,
+		RangeEnd:    rangeEnd,
+		RowCount:    rowCount,
+		Sha256:      hex.EncodeToString(hash.Sum(nil)),
+		CreatedAt:   time.Now().UTC(),
+	}
+	encodedManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := j.store.Put(ctx, key, "application/gzip", tmp); err != nil {
+		return fmt.Errorf("error storing archive: %w", err)
+	}
+	if err := j.store.Put(ctx, manifestKey(key), "application/json", strings.NewReader(string(encodedManifest))); err != nil {
+		return fmt.Errorf("error storing manifest: %w", err)
+	}
+
+	archive, err := queries.CreateDetectionArchive(ctx, dbschema.CreateDetectionArchiveParams{
+		Key:        key,
+		Format:     manifest.Format,
+		RangeStart: pgtype.Timestamptz{Time: rangeStart, Valid: true},
+		RangeEnd:   pgtype.Timestamptz{Time: rangeEnd, Valid: true},
+		RowCount:   rowCount,
+		Sha256:     manifest.Sha256,
+	})
+	if err != nil {
+		return fmt.Errorf("error recording archive: %w", err)
+	}
+	if _, err := queries.MarkPersonDetectionsArchived(ctx, dbschema.MarkPersonDetectionsArchivedParams{
+		ArchiveID: archive.ID,
+		Ids:       ids,
+		FromDate:  pgtype.Timestamptz{Time: rangeStart, Valid: true},
+		ToDate:    pgtype.Timestamptz{Time: rangeEnd, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("error marking detections as archived: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing archive: %w", err)
+	}
+
+	archivedRows.Add(rowCount)
+	archivedFiles.Add(1)
+	j.logger.Infow("archived detections", "key", key, "rows", rowCount)
+
+	return nil
+}
+
+// detectionWriter encodes detections in one of the archive formats
+type detectionWriter struct {
+	format string
+	csv    *csv.Writer
+	json   *json.Encoder
+	header bool
+}
+
+func newDetectionWriter(w io.Writer, format string) *detectionWriter {
+	if format == "csv" {
+		return &detectionWriter{format: format, csv: csv.NewWriter(w)}
+	}
+	return &detectionWriter{format: format, json: json.NewEncoder(w)}
+}
+
+func (w *detectionWriter) write(detection dbschema.PersonDetection) error {
+	if w.format != "csv" {
+		return w.json.Encode(&detection)
+	}
+
+	if !w.header {
+		if err := w.csv.Write(archiveColumns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	return w.csv.Write([]string{
+		strconv.FormatInt(detection.ID, 10),
+		strconv.FormatInt(detection.CameraID, 10),
+		detection.DetectionDate.Time.UTC().Format(time.RFC3339Nano),
+		string(detection.TargetDirection),
+		strconv.FormatBool(detection.Flagged),
+		detection.NormalizedDirection,
+		detection.TrackID.String,
+		archiveFloat(detection.Confidence),
+		archiveFloat(detection.BboxX),
+		archiveFloat(detection.BboxY),
+		archiveFloat(detection.BboxWidth),
+		archiveFloat(detection.BboxHeight),
+		archiveDate(detection.FrameDate),
+		detection.ModelVersion.String,
+		strconv.FormatBool(detection.Excluded),
+	})
+}
+
+// archiveFloat formats value for a csv archive, missing values are empty
+func archiveFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'g', -1, 64)
+}
+
+// archiveDate formats date for a csv archive, missing dates are empty
+func archiveDate(date pgtype.Timestamptz) string {
+	if !date.Valid {
+		return ""
+	}
+	return date.Time.UTC().Format(time.RFC3339Nano)
+}
+
+func (w *detectionWriter) flush() error {
+	if w.format != "csv" {
+		return nil
+	}
+	if !w.header {
+		// empty days still get a header, so every csv archive is self describing
+		if err := w.csv.Write(archiveColumns); err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// archivedDetection is a detection read from an archive. The normalized direction is empty for the archives that
+// do not have it
+type archivedDetection struct {
+	dbschema.ImportPersonDetectionParams
+	NormalizedDirection string `json:"normalized_direction"`
+}
+
+// readDetections decodes the detections of an archive, calling fn for each of them. The columns missing from older
+// archives are left empty
+func readDetections(r io.Reader, format string, fn func(detection archivedDetection) error) error {
+	if format == "ndjson" {
+		scanner := bufio.NewScanner(r)
+		for line := 1; scanner.Scan(); line++ {
+			var detection archivedDetection
+			if err := json.Unmarshal(scanner.Bytes(), &detection); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			if err := fn(detection); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		return scanner.Err()
+	}
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("error reading csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[column] = i
+	}
+	for _, column := range archiveColumns[:4] {
+		if _, ok := columns[column]; !ok {
+			return fmt.Errorf("csv header is missing the %s column", column)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if err := parseDetectionRecord(record, columns, fn); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// parseDetectionRecord parses a csv archive record whose columns are at the given indexes and calls fn with it
+func parseDetectionRecord(record []string, columns map[string]int, fn func(detection archivedDetection) error) error {
+	// field returns the value of column, empty when the archive does not have it
+	field := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	optionalFloat := func(column string) (*float64, error) {
+		if field(column) == "" {
+			return nil, nil
+		}
+		value, err := strconv.ParseFloat(field(column), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", column, err)
+		}
+		return &value, nil
+	}
+	optionalBool := func(column string) (bool, error) {
+		if field(column) == "" {
+			return false, nil
+		}
+		value, err := strconv.ParseBool(field(column))
+		if err != nil {
+			return false, fmt.Errorf("invalid %s: %w", column, err)
+		}
+		return value, nil
+	}
+
+	var detection archivedDetection
+	var err error
+	if detection.ID, err = strconv.ParseInt(field("id"), 10, 64); err != nil {
+		return fmt.Errorf("invalid id: %w", err)
+	}
+	if detection.CameraID, err = strconv.ParseInt(field("camera_id"), 10, 64); err != nil {
+		return fmt.Errorf("invalid camera_id: %w", err)
+	}
+	detectionDate, err := time.Parse(time.RFC3339Nano, field("detection_date"))
+	if err != nil {
+		return fmt.Errorf("invalid detection_date: %w", err)
+	}
+	detection.DetectionDate = pgtype.Timestamptz{Time: detectionDate, Valid: true}
+	if err := detection.TargetDirection.Scan(field("target_direction")); err != nil {
+		return err
+	}
+	if detection.Flagged, err = optionalBool("flagged"); err != nil {
+		return err
+	}
+	if detection.Excluded, err = optionalBool("excluded"); err != nil {
+		return err
+	}
+	detection.NormalizedDirection = field("normalized_direction")
+	detection.TrackID = pgtype.Text{String: field("track_id"), Valid: field("track_id") != ""}
+	detection.ModelVersion = pgtype.Text{String: field("model_version"), Valid: field("model_version") != ""}
+	if detection.Confidence, err = optionalFloat("confidence"); err != nil {
+		return err
+	}
+	if detection.BboxX, err = optionalFloat("bbox_x"); err != nil {
+		return err
+	}
+	if detection.BboxY, err = optionalFloat("bbox_y"); err != nil {
+		return err
+	}
+	if detection.BboxWidth, err = optionalFloat("bbox_width"); err != nil {
+		return err
+	}
+	if detection.BboxHeight, err = optionalFloat("bbox_height"); err != nil {
+		return err
+	}
+	if frameDate := field("frame_date"); frameDate != "" {
+		date, err := time.Parse(time.RFC3339Nano, frameDate)
+		if err != nil {
+			return fmt.Errorf("invalid frame_date: %w", err)
+		}
+		detection.FrameDate = pgtype.Timestamptz{Time: date, Valid: true}
+	}
+
+	return fn(detection)
+}
+
+// importArchive inserts the detections of the archive stored under key back into the database, in a single
+// transaction. Detections that are already stored are skipped, a detection with the id of a different stored one
+// fails the import. The rollups are not updated, as pruned detections are still counted by them. Imported detections
+// are not marked as archived, so they are archived again before retention prunes them.
+func importArchive(ctx context.Context, db *pgxpool.Pool, store blobstore.Store, key string) (imported int64, total int64, err error) {
+	encodedManifest, err := store.Get(ctx, manifestKey(key))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error getting manifest: %w", err)
+	}
+	var manifest archiveManifest
+	err = json.NewDecoder(encodedManifest).Decode(&manifest)
+	encodedManifest.Close()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error decoding manifest: %w", err)
+	}
+	if manifest.Table != "person_detections" || manifest.Compression != "gzip" {
+		return 0, 0, fmt.Errorf("unsupported archive of table %q compressed with %q", manifest.Table, manifest.Compression)
+	}
+	if manifest.Version > archiveVersion {
+		return 0, 0, fmt.Errorf("archive version %d is newer than the latest version known to this binary (%d)",
+			manifest.Version, archiveVersion)
+	}
+
+	// detections outside of every partition would land in the default partition, and have to be moved out of it
+	// when the partition of their month is created
+	queries := dbschema.New(db)
+	for month := time.Date(manifest.RangeStart.Year(), manifest.RangeStart.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(manifest.RangeEnd); month = month.AddDate(0, 1, 0) {
+		if err := queries.CreatePersonDetectionsPartition(ctx, pgtype.Date{Time: month, Valid: true}); err != nil {
+			return 0, 0, fmt.Errorf("error creating partition for %s: %w", month.Format("2006-01"), err)
+		}
+	}
+
+	file, err := store.Get(ctx, key)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error getting archive: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	decompressed, err := gzip.NewReader(io.TeeReader(file, hash))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error decompressing archive: %w", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	queries = queries.WithTx(tx)
+	if err := queries.SkipRollupMaintenance(ctx); err != nil {
+		return 0, 0, fmt.Errorf("error disabling rollup maintenance: %w", err)
+	}
+
+	err = readDetections(decompressed, manifest.Format, func(detection archivedDetection) error {
+		inserted, err := queries.ImportPersonDetection(ctx, detection.ImportPersonDetectionParams)
+		if err != nil {
+			return err
+		}
+		if inserted > 0 && detection.NormalizedDirection != "" {
+			if err := queries.RestorePersonDetectionNormalizedDirection(ctx, dbschema.RestorePersonDetectionNormalizedDirectionParams{
+				ID:                  detection.ID,
+				DetectionDate:       detection.DetectionDate,
+				NormalizedDirection: detection.NormalizedDirection,
+			}); err != nil {
+				return fmt.Errorf("error restoring normalized direction: %w", err)
+			}
+		}
+		if inserted == 0 {
+			// skipped detections must be the same detection as the stored one, not another with the same id
+			stored, err := queries.GetPersonDetection(ctx, detection.ID)
+			if err != nil {
+				return fmt.Errorf("error getting stored detection %d: %w", detection.ID, err)
+			}
+			if !stored.DetectionDate.Time.Equal(detection.DetectionDate.Time) {
+				return fmt.Errorf("detection %d of %s has the id of a stored detection of %s", detection.ID,
+					detection.DetectionDate.Time.Format(time.RFC3339), stored.DetectionDate.Time.Format(time.RFC3339))
+			}
+		}
+		imported += inserted
+		total++
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error importing archive: %w", err)
+	}
+
+	// the hash covers the whole file, including what follows the compressed stream
+	if _, err := io.Copy(io.Discard, io.TeeReader(file, hash)); err != nil {
+		return 0, 0, fmt.Errorf("error reading archive: %w", err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != manifest.Sha256 {
+		return 0, 0, fmt.Errorf("archive checksum %s does not match the manifest checksum %s", sum, manifest.Sha256)
+	}
+	if total != manifest.RowCount {
+		return 0, 0, fmt.Errorf("archive has %d rows but its manifest lists %d", total, manifest.RowCount)
+	}
+
+	return imported, total, tx.Commit(ctx)
+}
+
+// archive runs the archive commands
+func archive(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("archive")
+
+	if len(args) == 0 || (args[0] != "run" && args[0] != "import") {
+		logger.Fatal("expected archive run or archive import <key>")
+	}
+
+	config := loadConfig(logger)
+	checkDatabaseSchema(config.Db, logger)
+	db := connectToDb(config.Db, logger)
+
+	switch args[0] {
+	case "run":
+		job, err := newArchiveJob(config.Archive, db, logger)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if err := job.archive(context.Background()); err != nil {
+			logger.Fatal(err)
+		}
+	case "import":
+		flags := flag.NewFlagSet("archive import", flag.ExitOnError)
+		flags.Usage = func() {
+			fmt.Fprintln(flags.Output(), "usage: archive import <key>")
+			fmt.Fprintln(flags.Output(), "inserts the detections of an archive back into the database, the key is the path of the archive")
+			fmt.Fprintln(flags.Output(), "file in the configured store, like person_detections/2023/05/2023-05-14.csv.gz")
+		}
+		if err := flags.Parse(args[1:]); err != nil {
+			logger.Fatal(err)
+		}
+		if flags.NArg() != 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		store, err := blobstore.New(config.Archive.Store)
+		if err != nil {
+			logger.Fatalf("error opening archive store: %s", err)
+		}
+
+		key := flags.Arg(0)
+		imported, total, err := importArchive(context.Background(), db, store, key)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infow("imported archive", "key", key, "rows", total, "inserted", imported, "already_stored", total-imported)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/SmartFactory-Tec/camera_service/pkg/blobstore"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveKeys(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		part     int64
+		format   string
+		key      string
+		manifest string
+	}{
+		{1, "csv", "person_detections/2026/01/2026-01-05.csv.gz", "person_detections/2026/01/2026-01-05.manifest.json"},
+		{2, "csv", "person_detections/2026/01/2026-01-05.2.csv.gz", "person_detections/2026/01/2026-01-05.2.manifest.json"},
+		{3, "ndjson", "person_detections/2026/01/2026-01-05.3.ndjson.gz", "person_detections/2026/01/2026-01-05.3.manifest.json"},
+	}
+
+	for _, test := range tests {
+		key := archiveKey(day, test.part, test.format)
+		if key != test.key {
+			t.Errorf("part %d: expected key %s, got %s", test.part, test.key, key)
+		}
+		if manifest := manifestKey(key); manifest != test.manifest {
+			t.Errorf("part %d: expected manifest key %s, got %s", test.part, test.manifest, manifest)
+		}
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	confidence, x, y, width, height := 0.75, 0.1, 0.2, 0.3, 0.4
+	detection := dbschema.PersonDetection{
+		ID:                  7,
+		CameraID:            3,
+		DetectionDate:       pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 10, 30, 0, 500, time.UTC), Valid: true},
+		TargetDirection:     dbenums.DirectionLeft,
+		Flagged:             true,
+		NormalizedDirection: "in",
+		TrackID:             pgtype.Text{String: "track", Valid: true},
+		Confidence:          &confidence,
+		BboxX:               &x,
+		BboxY:               &y,
+		BboxWidth:           &width,
+		BboxHeight:          &height,
+		FrameDate:           pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 10, 29, 59, 0, time.UTC), Valid: true},
+		ModelVersion:        pgtype.Text{String: "v2", Valid: true},
+		Excluded:            true,
+	}
+	bare := dbschema.PersonDetection{
+		ID:                  8,
+		CameraID:            3,
+		DetectionDate:       pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 11, 0, 0, 0, time.UTC), Valid: true},
+		TargetDirection:     dbenums.DirectionLeft,
+		NormalizedDirection: "none",
+	}
+
+	for _, format := range []string{"csv", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			var buffer bytes.Buffer
+			w := newDetectionWriter(&buffer, format)
+			for _, d := range []dbschema.PersonDetection{detection, bare} {
+				if err := w.write(d); err != nil {
+					t.Fatalf("error writing detection: %s", err)
+				}
+			}
+			if err := w.flush(); err != nil {
+				t.Fatalf("error flushing detections: %s", err)
+			}
+
+			var read []dbschema.PersonDetection
+			err := readDetections(&buffer, format, func(d archivedDetection) error {
+				read = append(read, dbschema.PersonDetection{
+					ID:                  d.ID,
+					CameraID:            d.CameraID,
+					DetectionDate:       d.DetectionDate,
+					TargetDirection:     d.TargetDirection,
+					Flagged:             d.Flagged,
+					NormalizedDirection: d.NormalizedDirection,
+					TrackID:             d.TrackID,
+					Confidence:          d.Confidence,
+					BboxX:               d.BboxX,
+					BboxY:               d.BboxY,
+					BboxWidth:           d.BboxWidth,
+					BboxHeight:          d.BboxHeight,
+					FrameDate:           d.FrameDate,
+					ModelVersion:        d.ModelVersion,
+					Excluded:            d.Excluded,
+				})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("error reading detections: %s", err)
+			}
+			for i := range read {
+				// parsed dates have a fixed zone instead of UTC
+				read[i].DetectionDate.Time = read[i].DetectionDate.Time.UTC()
+				read[i].FrameDate.Time = read[i].FrameDate.Time.UTC()
+			}
+			if expected := []dbschema.PersonDetection{detection, bare}; !reflect.DeepEqual(read, expected) {
+				t.Fatalf("expected %+v, got %+v", expected, read)
+			}
+		})
+	}
+}
+
+func TestReadVersion1Archive(t *testing.T) {
+	archive := "id,camera_id,detection_date,target_direction\n7,3,2026-01-05T10:30:00Z,left\n"
+
+	var read []archivedDetection
+	err := readDetections(strings.NewReader(archive), "csv", func(d archivedDetection) error {
+		read = append(read, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error reading detections: %s", err)
+	}
+
+	expected := archivedDetection{ImportPersonDetectionParams: dbschema.ImportPersonDetectionParams{
+		ID:              7,
+		CameraID:        3,
+		DetectionDate:   pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 10, 30, 0, 0, time.UTC), Valid: true},
+		TargetDirection: dbenums.DirectionLeft,
+	}}
+	if len(read) != 1 || !reflect.DeepEqual(read[0], expected) {
+		t.Fatalf("expected %+v, got %+v", expected, read)
+	}
+}
+
+func TestArchiveAgainDetectionsChangedIntoArchivedDays(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	queries := dbschema.New(db)
+	logger := zap.NewNop().Sugar()
+
+	store, err := blobstore.New(blobstore.Config{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("error opening archive store: %s", err)
+	}
+	archiver := &archiveJob{
+		config:  ArchiveConfig{OlderThan: 48 * time.Hour, Format: "csv", BatchSize: 1},
+		db:      db,
+		queries: queries,
+		store:   store,
+		logger:  logger,
+	}
+	retention := newRetentionJob(RetentionConfig{Enabled: true, BatchSize: 100, PersonDetections: 24 * time.Hour},
+		true, db, logger)
+
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+	camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{
+		Name:        "entrance",
+		LocationID:  int32(location.ID),
+		Orientation: dbenums.CameraOrientationHorizontal,
+		Tags:        map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("error creating camera: %s", err)
+	}
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -10)
+	create := func(date time.Time) dbschema.PersonDetection {
+		detection, err := queries.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+			CameraID:        camera.ID,
+			DetectionDate:   pgtype.Timestamptz{Time: date, Valid: true},
+			TargetDirection: dbenums.DirectionLeft,
+		})
+		if err != nil {
+			t.Fatalf("error creating detection: %s", err)
+		}
+		return detection
+	}
+	// archived tells whether detection is stored and held by an archive
+	archived := func(detection dbschema.PersonDetection) (bool, bool) {
+		stored, err := queries.GetPersonDetection(ctx, detection.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, false
+		} else if err != nil {
+			t.Fatalf("error getting detection %d: %s", detection.ID, err)
+		}
+		return true, stored.ArchiveID.Valid
+	}
+	first := create(day.Add(time.Hour))
+	moved := create(day.AddDate(0, 0, 1).Add(time.Hour))
+	if err := archiver.archive(ctx); err != nil {
+		t.Fatalf("error archiving: %s", err)
+	}
+	for _, detection := range []dbschema.PersonDetection{first, moved} {
+		if _, isArchived := archived(detection); !isArchived {
+			t.Fatalf("expected detection %d to be archived", detection.ID)
+		}
+	}
+
+	// moving an archived detection into another archived day, and storing a late one, leaves both unarchived
+	if _, err := queries.UpdatePersonDetection(ctx, dbschema.UpdatePersonDetectionParams{
+		ID:            moved.ID,
+		DetectionDate: pgtype.Timestamptz{Time: day.Add(2 * time.Hour), Valid: true},
+	}); err != nil {
+		t.Fatalf("error moving detection: %s", err)
+	}
+	late := create(day.Add(3 * time.Hour))
+
+	if err := retention.prune(ctx); err != nil {
+		t.Fatalf("error pruning: %s", err)
+	}
+	if stored, _ := archived(first); stored {
+		t.Fatal("expected the archived detection to be pruned")
+	}
+	for _, detection := range []dbschema.PersonDetection{moved, late} {
+		if stored, isArchived := archived(detection); !stored || isArchived {
+			t.Fatalf("expected detection %d to be kept unarchived, stored %t archived %t", detection.ID, stored, isArchived)
+		}
+	}
+
+	if err := archiver.archive(ctx); err != nil {
+		t.Fatalf("error archiving: %s", err)
+	}
+	manifest, err := store.Get(ctx, manifestKey(archiveKey(day, 2, "csv")))
+	if err != nil {
+		t.Fatalf("expected a second archive of the day: %s", err)
+	}
+	manifest.Close()
+
+	if err := retention.prune(ctx); err != nil {
+		t.Fatalf("error pruning: %s", err)
+	}
+	for _, detection := range []dbschema.PersonDetection{moved, late} {
+		if stored, _ := archived(detection); stored {
+			t.Fatalf("expected detection %d to be pruned once archived", detection.ID)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/bulkimport"
+	"github.com/SmartFactory-Tec/camera_service/pkg/client"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+func postImport(kind bulkimport.Kind, queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("postImport")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		format := bulkimport.JSON
+		if formatStr := r.URL.Query().Get("format"); formatStr != "" {
+			var err error
+			if format, err = bulkimport.ParseFormat(formatStr); err != nil {
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+			format = bulkimport.CSV
+		}
+
+		dryRun := false
+		if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+				err := fmt.Errorf("invalid dry_run parameter: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err := bulkimport.Import(ctx, queries, kind, format, r.Body, dryRun)
+		if err != nil {
+			err := fmt.Errorf("error importing %s: %w", kind, err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(result)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusOK
+		if len(result.Errors) > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func printImportResult(result bulkimport.Result, asJson bool, logger *zap.SugaredLogger) {
+	if asJson {
+		printJson(result, logger)
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ROW\tCOLUMN\tERROR")
+		for _, rowErr := range result.Errors {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", rowErr.Row, rowErr.Column, rowErr.Message)
+		}
+		if err := w.Flush(); err != nil {
+			logger.Fatal(err)
+		}
+	}
+}
+
+// adminImport imports a csv or json file of locations, cameras or person detections
+func adminImport(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("import")
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatal("missing import kind, expected locations, cameras or detections")
+	}
+
+	kind, err := bulkimport.ParseKind(args[0])
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	flags := newAdminFlags("import " + args[0])
+	dryRun := flags.Bool("dry-run", false, "only validate the file, nothing is created")
+	formatStr := flags.String("format", "", "csv or json, guessed from the file extension if empty")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: import %s [flags] <file>\n", args[0])
+		fmt.Fprintln(flags.Output(), "every row is validated before anything is created, and all rows are created in a single transaction.")
+		fmt.Fprintln(flags.Output(), "cameras refer to their location by name or location_id, detections to their camera by name or camera_id.")
+		fmt.Fprintln(flags.Output(), "the file is read from stdin when it is -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		logger.Fatal(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	fileName := flags.Arg(0)
+	if *formatStr == "" {
+		*formatStr = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	}
+	format, err := bulkimport.ParseFormat(*formatStr)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	var file io.Reader = os.Stdin
+	if fileName != "-" {
+		f, err := os.Open(fileName)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer f.Close()
+		file = f
+	}
+
+	var result bulkimport.Result
+	if flags.remote != "" {
+		result, err = client.New(flags.remote).Import(ctx, kind, format, file, *dryRun)
+	} else {
+		config := loadConfig(logger)
+		checkDatabaseSchema(config.Db, logger)
+		result, err = bulkimport.Import(ctx, store.NewPostgres(connectToDb(config.Db, logger)), kind, format, file, *dryRun)
+	}
+	if err != nil {
+		logger.Fatalf("error importing %s: %s", kind, err)
+	}
+
+	printImportResult(result, flags.json, logger)
+
+	switch {
+	case len(result.Errors) > 0:
+		logger.Fatalw("import rejected, nothing was created", "rows", result.Rows, "errors", len(result.Errors),
+			"errors_truncated", result.ErrorsTruncated)
+	case result.DryRun:
+		logger.Infow("dry run succeeded, nothing was created", "rows", result.Rows, "would_import", result.Imported)
+	default:
+		logger.Infow("imported", "kind", kind, "rows", result.Rows, "imported", result.Imported)
+	}
+}
@@ -0,0 +1,663 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidCameraSelector = errors.New("invalid camera selector")
+	errCameraGroupNotFound   = errors.New("camera group not found")
+)
+
+type tagFilter struct {
+	key string
+	// value is nil when the camera only needs to have the key
+	value *string
+}
+
+// cameraSelector selects cameras by the tag and group query parameters of a request. Tags are given as key:value,
+// or as key to accept any value, and groups by their id or name. A camera is selected when it has every tag and
+// belongs to every group.
+type cameraSelector struct {
+	tags   []tagFilter
+	groups []string
+}
+
+func parseCameraSelector(r *http.Request) (cameraSelector, error) {
+	query := r.URL.Query()
+	selector := cameraSelector{groups: query["group"]}
+	for _, tag := range query["tag"] {
+		key, value, hasValue := strings.Cut(tag, ":")
+		if key == "" {
+			return cameraSelector{}, fmt.Errorf("%w: tag %q, expected key or key:value", errInvalidCameraSelector, tag)
+		}
+		filter := tagFilter{key: key}
+		if hasValue {
+			filter.value = &value
+		}
+		selector.tags = append(selector.tags, filter)
+	}
+	for _, group := range selector.groups {
+		if group == "" {
+			return cameraSelector{}, fmt.Errorf("%w: empty group", errInvalidCameraSelector)
+		}
+	}
+	return selector, nil
+}
+
+func (s cameraSelector) empty() bool {
+	return len(s.tags) == 0 && len(s.groups) == 0
+}
+
+func (s cameraSelector) hasTags(camera dbschema.Camera) bool {
+	for _, filter := range s.tags {
+		value, ok := camera.Tags[filter.key]
+		if !ok || filter.value != nil && value != *filter.value {
+			return false
+		}
+	}
+	return true
+}
+
+// findCameraGroup gets a group by its id, or by its name when ref is not a number
+func findCameraGroup(ctx context.Context, queries store.Store, ref string) (dbschema.CameraGroup, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return queries.GetCameraGroup(ctx, id)
+	}
+	return queries.GetCameraGroupByName(ctx, ref)
+}
+
+// filter returns the selected cameras out of cameras
+func (s cameraSelector) filter(ctx context.Context, queries store.Store, cameras []dbschema.Camera) ([]dbschema.Camera, error) {
+	// how many of the groups each camera belongs to
+	memberships := map[int64]int{}
+	for _, ref := range s.groups {
+		group, err := findCameraGroup(ctx, queries, ref)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", errCameraGroupNotFound, ref)
+		} else if err != nil {
+			return nil, err
+		}
+
+		cameraIds, err := queries.GetCameraGroupCameraIds(ctx, group.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, cameraId := range cameraIds {
+			memberships[cameraId]++
+		}
+	}
+
+	selected := []dbschema.Camera{}
+	for _, camera := range cameras {
+		if memberships[camera.ID] == len(s.groups) && s.hasTags(camera) {
+			selected = append(selected, camera)
+		}
+	}
+	return selected, nil
+}
+
+// cameraIds returns the ids of the selected cameras, which are all of them when the selector is empty. The
+// result is never nil, so it can be used as a filter of the queries taking camera_ids.
+func (s cameraSelector) cameraIds(ctx context.Context, queries store.Store) ([]int64, error) {
+	cameras, err := queries.GetCameras(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	cameras, err = s.filter(ctx, queries, cameras)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(cameras))
+	for _, camera := range cameras {
+		ids = append(ids, camera.ID)
+	}
+	return ids, nil
+}
+
+// handleCameraSelectorError responds to an error parsing or resolving a cameraSelector
+func handleCameraSelectorError(w http.ResponseWriter, r *http.Request, err error, logger *zap.SugaredLogger) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		HandlePqError(w, r, pgErr, logger)
+	} else if errors.Is(err, errInvalidCameraSelector) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if errors.Is(err, errCameraGroupNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		err := fmt.Errorf("error selecting cameras: %w", err)
+		logger.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// cameraIdsFunc returns the cameras whose detections are counted for a request
+type cameraIdsFunc func(r *http.Request) ([]int64, error)
+
+// selectedCameraIds counts the cameras selected by the tag and group parameters of the request
+func selectedCameraIds(queries store.Store) cameraIdsFunc {
+	return func(r *http.Request) ([]int64, error) {
+		selector, err := parseCameraSelector(r)
+		if err != nil {
+			return nil, err
+		}
+		return selector.cameraIds(r.Context(), queries)
+	}
+}
+
+// cameraGroupCameraIds counts the cameras of the group of the request
+func cameraGroupCameraIds(queries store.Store) cameraIdsFunc {
+	return func(r *http.Request) ([]int64, error) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+		return queries.GetCameraGroupCameraIds(ctx, group.ID)
+	}
+}
+
+func getCamerasDailyPersonDetectionsCount(cameraIds cameraIdsFunc, queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCamerasDailyPersonDetectionsCount")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		days, err := strconv.ParseInt(r.URL.Query().Get("days"), 10, 32)
+		if err != nil {
+			days = 0
+		}
+		months, err := strconv.ParseInt(r.URL.Query().Get("months"), 10, 32)
+		if err != nil {
+			months = 0
+		}
+
+		ids, err := cameraIds(r)
+		if err != nil {
+			handleCameraSelectorError(w, r, err, logger)
+			return
+		}
+
+		dailyPersonDetectionsCount, err := queries.GetCamerasDailyPersonDetectionsCount(ctx, dbschema.GetCamerasDailyPersonDetectionsCountParams{
+			CameraIds: ids,
+			Interval:  pgtype.Interval{Days: int32(days), Months: int32(months), Valid: true},
+		})
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting daily person detections count: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(dailyPersonDetectionsCount)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getCamerasHourlyPersonDetectionsCount(cameraIds cameraIdsFunc, queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCamerasHourlyPersonDetectionsCount")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+		if err != nil {
+			err := fmt.Errorf("request does not contain a valid from parameter: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+		if err != nil {
+			err := fmt.Errorf("request does not contain a valid to parameter: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fromDate := pgtype.Timestamptz{Time: from, Valid: true}
+		toDate := pgtype.Timestamptz{Time: to, Valid: true}
+
+		ids, err := cameraIds(r)
+		if err != nil {
+			handleCameraSelectorError(w, r, err, logger)
+			return
+		}
+
+		// the hourly rollups can only answer ranges made of whole hours
+		var hourlyPersonDetectionsCount []dbschema.GetCamerasHourlyPersonDetectionsCountRow
+		if from.Truncate(time.Hour).Equal(from) && to.Truncate(time.Hour).Equal(to) {
+			hourlyPersonDetectionsCount, err = queries.GetCamerasHourlyPersonDetectionsCount(ctx, dbschema.GetCamerasHourlyPersonDetectionsCountParams{
+				CameraIds: ids,
+				FromDate:  fromDate,
+				ToDate:    toDate,
+			})
+		} else {
+			var rows []dbschema.GetCamerasHourlyPersonDetectionsCountRawRow
+			rows, err = queries.GetCamerasHourlyPersonDetectionsCountRaw(ctx, dbschema.GetCamerasHourlyPersonDetectionsCountRawParams{
+				CameraIds: ids,
+				FromDate:  fromDate,
+				ToDate:    toDate,
+			})
+			hourlyPersonDetectionsCount = make([]dbschema.GetCamerasHourlyPersonDetectionsCountRow, 0, len(rows))
+			for _, row := range rows {
+				hourlyPersonDetectionsCount = append(hourlyPersonDetectionsCount, dbschema.GetCamerasHourlyPersonDetectionsCountRow(row))
+			}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting hourly person detections count: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(hourlyPersonDetectionsCount)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// CameraGroupResponse is a camera group along with the ids of its cameras
+type CameraGroupResponse struct {
+	dbschema.CameraGroup
+	CameraIds []int64 `json:"camera_ids"`
+}
+
+// CreateCameraGroupRequest creates a group, optionally with its initial cameras
+type CreateCameraGroupRequest struct {
+	dbschema.CreateCameraGroupParams
+	CameraIds []int64 `json:"camera_ids,omitempty"`
+}
+
+func cameraGroupCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	logger = logger.Named("cameraGroupCtx")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			groupId, err := strconv.ParseInt(chi.URLParam(r, "groupId"), 10, 64)
+			if err != nil {
+				err := fmt.Errorf("error parsing camera group id: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			group, err := queries.GetCameraGroup(ctx, groupId)
+
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				HandlePqError(w, r, pgErr, logger)
+			} else if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, "camera group not found", http.StatusNotFound)
+			} else if err != nil {
+				err := fmt.Errorf("error getting camera group: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, "cameraGroup", group)))
+			}
+		})
+	}
+}
+
+func getCameraGroups(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraGroups")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		groups, err := queries.GetCameraGroups(ctx)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera groups: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		members, err := queries.GetCameraGroupMembers(ctx)
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera group members: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		cameraIds := make(map[int64][]int64, len(groups))
+		for _, member := range members {
+			cameraIds[member.GroupID] = append(cameraIds[member.GroupID], member.CameraID)
+		}
+
+		responses := make([]CameraGroupResponse, 0, len(groups))
+		for _, group := range groups {
+			ids := cameraIds[group.ID]
+			if ids == nil {
+				ids = []int64{}
+			}
+			responses = append(responses, CameraGroupResponse{CameraGroup: group, CameraIds: ids})
+		}
+
+		body, err := json.Marshal(responses)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func postCameraGroup(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("postCameraGroup")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var request CreateCameraGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			err := fmt.Errorf("error decoding request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Name == "" {
+			http.Error(w, "a camera group needs a name", http.StatusBadRequest)
+			return
+		}
+
+		var response CameraGroupResponse
+		err := queries.InTx(ctx, func(s store.Store) error {
+			group, err := s.CreateCameraGroup(ctx, request.CreateCameraGroupParams)
+			if err != nil {
+				return err
+			}
+			for _, cameraId := range request.CameraIds {
+				err := s.AddCameraGroupMember(ctx, dbschema.AddCameraGroupMemberParams{GroupID: group.ID, CameraID: cameraId})
+				if err != nil {
+					return err
+				}
+			}
+
+			cameraIds, err := s.GetCameraGroupCameraIds(ctx, group.ID)
+			response = CameraGroupResponse{CameraGroup: group, CameraIds: cameraIds}
+			return err
+		})
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error creating camera group: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(response)
+		if err != nil {
+			err := fmt.Errorf("error marshaling body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Location", path.Join(r.URL.String(), fmt.Sprintf("/%d", response.ID)))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getCameraGroup(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraGroup")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+
+		cameraIds, err := queries.GetCameraGroupCameraIds(ctx, group.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera group members: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(CameraGroupResponse{CameraGroup: group, CameraIds: cameraIds})
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func patchCameraGroup(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("patchCameraGroup")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+
+		var params dbschema.UpdateCameraGroupParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err = fmt.Errorf("invalid body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.ID = group.ID
+		if params.Name.Valid && params.Name.String == "" {
+			http.Error(w, "a camera group needs a name", http.StatusBadRequest)
+			return
+		}
+
+		group, err := queries.UpdateCameraGroup(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error updating camera group: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		cameraIds, err := queries.GetCameraGroupCameraIds(ctx, group.ID)
+		if err != nil {
+			err = fmt.Errorf("error getting camera group members: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(CameraGroupResponse{CameraGroup: group, CameraIds: cameraIds})
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func deleteCameraGroup(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("deleteCameraGroup")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+
+		err := queries.DeleteCameraGroup(ctx, group.ID)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+		} else if err != nil {
+			err := fmt.Errorf("error deleting camera group: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+func getCameraGroupCameras(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraGroupCameras")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+
+		cameraIds, err := queries.GetCameraGroupCameraIds(ctx, group.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera group members: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]CameraResponse, 0, len(cameraIds))
+		for _, cameraId := range cameraIds {
+			camera, err := queries.GetCamera(ctx, cameraId)
+			if errors.Is(err, pgx.ErrNoRows) {
+				// removed since its ids were read
+				continue
+			} else if err != nil {
+				err := fmt.Errorf("error getting camera: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, newCameraResponse(camera))
+		}
+
+		body, err := json.Marshal(responses)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func putCameraGroupCamera(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("putCameraGroupCamera")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		err := queries.AddCameraGroupMember(ctx, dbschema.AddCameraGroupMemberParams{GroupID: group.ID, CameraID: camera.ID})
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+		} else if err != nil {
+			err := fmt.Errorf("error adding camera to group: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+func deleteCameraGroupCamera(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("deleteCameraGroupCamera")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		group := ctx.Value("cameraGroup").(dbschema.CameraGroup)
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		err := queries.RemoveCameraGroupMember(ctx, dbschema.RemoveCameraGroupMemberParams{GroupID: group.ID, CameraID: camera.ID})
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+		} else if err != nil {
+			err := fmt.Errorf("error removing camera from group: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCamerasDailyPersonDetectionsCount(t *testing.T) {
+	// days are counted in the local time zone, detections are placed at noon so they never fall on another day
+	today := time.Now()
+	noon := func(daysAgo int) time.Time {
+		return time.Date(today.Year(), today.Month(), today.Day()-daysAgo, 12, 0, 0, 0, time.Local)
+	}
+	date := func(daysAgo int) string {
+		return noon(daysAgo).Format("2006-01-02")
+	}
+	detection := func(cameraId int64, daysAgo int) apiTest {
+		detectionDate := noon(daysAgo)
+		if daysAgo == 0 {
+			detectionDate = today
+		}
+		return apiTest{name: fmt.Sprintf("create detection of camera %d %d days ago", cameraId, daysAgo),
+			method: http.MethodPost, path: "/personDetections",
+			body: fmt.Sprintf(`{"camera_id": %d, "detection_date": %q, "target_direction": "left"}`, cameraId,
+				detectionDate.Format(time.RFC3339Nano)),
+			status: http.StatusCreated}
+	}
+
+	runApiTests(t, []apiTest{
+		testLocation,
+		{name: "create entrance", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "entrance", "connection_string": "rtsp://entrance", "location_id": 1, ` +
+				`"orientation": "horizontal", "tags": {"floor": "1"}}`, status: http.StatusCreated},
+		{name: "create exit", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "exit", "connection_string": "rtsp://exit", "location_id": 1, ` +
+				`"orientation": "horizontal", "tags": {"floor": "2"}}`, status: http.StatusCreated},
+		{name: "create group", method: http.MethodPost, path: "/cameraGroups",
+			body: `{"name": "doors", "camera_ids": [1]}`, status: http.StatusCreated,
+			response: `{"id": 1, "name": "doors", "camera_ids": [1]}`},
+
+		{name: "group without detections", method: http.MethodGet,
+			path: "/cameraGroups/1/dailyPersonDetectionsCount?days=2", status: http.StatusOK,
+			response: fmt.Sprintf(`[{"date": %q, "count": 0}, {"date": %q, "count": 0}, {"date": %q, "count": 0}]`,
+				date(2), date(1), date(0))},
+
+		detection(1, 0),
+		detection(1, 0),
+		detection(1, 2),
+		detection(1, 5),
+		detection(2, 1),
+		detection(2, 0),
+
+		{name: "group", method: http.MethodGet, path: "/cameraGroups/1/dailyPersonDetectionsCount?days=3",
+			status: http.StatusOK,
+			response: fmt.Sprintf(`[{"date": %q, "count": 0}, {"date": %q, "count": 1}, {"date": %q, "count": 0}, `+
+				`{"date": %q, "count": 2}]`, date(3), date(2), date(1), date(0))},
+		{name: "group selector", method: http.MethodGet, path: "/cameras/dailyPersonDetectionsCount?group=doors",
+			status: http.StatusOK, response: fmt.Sprintf(`[{"date": %q, "count": 2}]`, date(0))},
+		{name: "tag", method: http.MethodGet, path: "/cameras/dailyPersonDetectionsCount?days=1&tag=floor:2",
+			status:   http.StatusOK,
+			response: fmt.Sprintf(`[{"date": %q, "count": 1}, {"date": %q, "count": 1}]`, date(1), date(0))},
+		{name: "every camera", method: http.MethodGet, path: "/cameras/dailyPersonDetectionsCount?days=2",
+			status: http.StatusOK,
+			response: fmt.Sprintf(`[{"date": %q, "count": 1}, {"date": %q, "count": 1}, {"date": %q, "count": 3}]`,
+				date(2), date(1), date(0))},
+		{name: "tag without cameras", method: http.MethodGet,
+			path: "/cameras/dailyPersonDetectionsCount?days=1&tag=floor:3", status: http.StatusOK,
+			response: fmt.Sprintf(`[{"date": %q, "count": 0}, {"date": %q, "count": 0}]`, date(1), date(0))},
+		// like the daily counts of a single camera, days that are not a number count today only
+		{name: "invalid days", method: http.MethodGet, path: "/cameraGroups/1/dailyPersonDetectionsCount?days=x",
+			status: http.StatusOK, response: fmt.Sprintf(`[{"date": %q, "count": 2}]`, date(0))},
+		{name: "invalid selector", method: http.MethodGet, path: "/cameras/dailyPersonDetectionsCount?tag=:1",
+			status: http.StatusBadRequest},
+		{name: "missing group", method: http.MethodGet, path: "/cameraGroups/2/dailyPersonDetectionsCount",
+			status: http.StatusNotFound},
+	})
+}
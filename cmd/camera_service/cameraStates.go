@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// the lifecycle states of a camera, only active cameras are expected to detect people
+const (
+	cameraStateActive      = "active"
+	cameraStateMaintenance = "maintenance"
+	cameraStateDisabled    = "disabled"
+	cameraStateRetired     = "retired"
+)
+
+// the ways detections dated within a maintenance window are ingested
+const (
+	maintenanceIngestReject = "reject"
+	maintenanceIngestFlag   = "flag"
+)
+
+func validateCameraState(state string) error {
+	switch state {
+	case cameraStateActive, cameraStateMaintenance, cameraStateDisabled, cameraStateRetired:
+		return nil
+	}
+	return fmt.Errorf("invalid camera state %q, must be active, maintenance, disabled or retired", state)
+}
+
+func validateMaintenanceIngest(ingest string) error {
+	switch ingest {
+	case maintenanceIngestReject, maintenanceIngestFlag:
+		return nil
+	}
+	return fmt.Errorf("invalid ingest %q, must be reject or flag", ingest)
+}
+
+// errDetectionRejected is wrapped by the errors of checkCameraAcceptsDetections for detections that must be rejected
+var errDetectionRejected = errors.New("detection rejected")
+
+// checkCameraAcceptsDetections decides how a new detection of camera dated at date is ingested. Detections of
+// deleted, disabled and retired cameras are rejected, those of cameras in maintenance are flagged, and those dated
+// within a maintenance window are rejected or flagged according to the window. It returns whether the detection
+// must be flagged, or an error wrapping errDetectionRejected when it must be rejected.
+func checkCameraAcceptsDetections(ctx context.Context, queries store.Store, camera dbschema.Camera, date time.Time) (bool, error) {
+	if camera.DeletedAt.Valid {
+		return false, fmt.Errorf("%w: camera %d is deleted", errDetectionRejected, camera.ID)
+	}
+
+	flagged := false
+	switch camera.State {
+	case cameraStateDisabled, cameraStateRetired:
+		return false, fmt.Errorf("%w: camera %d is %s", errDetectionRejected, camera.ID, camera.State)
+	case cameraStateMaintenance:
+		flagged = true
+	}
+
+	window, err := queries.GetMaintenanceWindowAt(ctx, dbschema.GetMaintenanceWindowAtParams{
+		CameraID: camera.ID,
+		At:       pgtype.Timestamptz{Time: date, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return flagged, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error getting maintenance window: %w", err)
+	}
+
+	if window.Ingest == maintenanceIngestReject {
+		return false, fmt.Errorf("%w: camera %d is in maintenance until %s", errDetectionRejected, camera.ID,
+			window.EndsAt.Time.Format(time.RFC3339))
+	}
+	return true, nil
+}
+
+// SetCameraStateRequest moves a camera to another lifecycle state, the reason is kept in its state history
+type SetCameraStateRequest struct {
+	State  string `json:"state"`
+	Reason string `json:"reason"`
+}
+
+func putCameraState(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("putCameraState")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		var request SetCameraStateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			err := fmt.Errorf("error decoding request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateCameraState(request.State); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// setting the current state again leaves the camera and its history as they are
+		var err error
+		if request.State != camera.State {
+			err = queries.InTx(ctx, func(s store.Store) error {
+				updated, err := s.SetCameraState(ctx, dbschema.SetCameraStateParams{ID: camera.ID, State: request.State})
+				if err != nil {
+					return err
+				}
+
+				_, err = s.CreateCameraStateChange(ctx, dbschema.CreateCameraStateChangeParams{
+					CameraID:  camera.ID,
+					FromState: camera.State,
+					ToState:   updated.State,
+					Reason:    request.Reason,
+					ChangedAt: updated.StateChangedAt,
+				})
+				camera = updated
+				return err
+			})
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error setting camera state: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(newCameraResponse(camera))
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getCameraStateHistory(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraStateHistory")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		changes, err := queries.GetCameraStateChanges(ctx, camera.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera state history: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(changes)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func maintenanceWindowCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	logger = logger.Named("maintenanceWindowCtx")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			camera := ctx.Value("camera").(dbschema.Camera)
+
+			windowId, err := strconv.ParseInt(chi.URLParam(r, "maintenanceWindowId"), 10, 64)
+			if err != nil {
+				err := fmt.Errorf("error parsing maintenance window id: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			window, err := queries.GetMaintenanceWindow(ctx, windowId)
+
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				HandlePqError(w, r, pgErr, logger)
+			} else if errors.Is(err, pgx.ErrNoRows) || err == nil && window.CameraID != camera.ID {
+				http.Error(w, "maintenance window not found", http.StatusNotFound)
+			} else if err != nil {
+				err := fmt.Errorf("error getting maintenance window: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, "maintenanceWindow", window)))
+			}
+		})
+	}
+}
+
+func getCameraMaintenanceWindows(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraMaintenanceWindows")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		windows, err := queries.GetMaintenanceWindowsForCamera(ctx, camera.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting maintenance windows: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(windows)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func postCameraMaintenanceWindow(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("postCameraMaintenanceWindow")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		params := dbschema.CreateMaintenanceWindowParams{Ingest: maintenanceIngestReject}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err := fmt.Errorf("error decoding request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.CameraID = camera.ID
+
+		if !params.StartsAt.Valid || !params.EndsAt.Valid {
+			http.Error(w, "starts_at and ends_at are required", http.StatusBadRequest)
+			return
+		}
+		if !params.EndsAt.Time.After(params.StartsAt.Time) {
+			http.Error(w, "ends_at must be after starts_at", http.StatusBadRequest)
+			return
+		}
+		if err := validateMaintenanceIngest(params.Ingest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		window, err := queries.CreateMaintenanceWindow(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error creating maintenance window: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(window)
+		if err != nil {
+			err = fmt.Errorf("error marshaling body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Location", path.Join(r.URL.String(), fmt.Sprintf("/%d", window.ID)))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getMaintenanceWindow(logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getMaintenanceWindow")
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := r.Context().Value("maintenanceWindow").(dbschema.MaintenanceWindow)
+
+		body, err := json.Marshal(window)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func patchMaintenanceWindow(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("patchMaintenanceWindow")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		window := ctx.Value("maintenanceWindow").(dbschema.MaintenanceWindow)
+
+		var params dbschema.UpdateMaintenanceWindowParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err := fmt.Errorf("invalid body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.ID = window.ID
+
+		if params.Ingest.Valid {
+			if err := validateMaintenanceIngest(params.Ingest.String); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		window, err := queries.UpdateMaintenanceWindow(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error updating maintenance window: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(window)
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func deleteMaintenanceWindow(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("deleteMaintenanceWindow")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		window := ctx.Value("maintenanceWindow").(dbschema.MaintenanceWindow)
+
+		err := queries.DeleteMaintenanceWindow(ctx, window.ID)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+		} else if err != nil {
+			err := fmt.Errorf("error deleting maintenance window: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestDetectionsOfCamerasOutOfService(t *testing.T) {
+	detection := func(name string, path string, date string, status int, response string) apiTest {
+		body := fmt.Sprintf(`{"detection_date": %q, "target_direction": "left"}`, date)
+		if path == "/personDetections" {
+			body = fmt.Sprintf(`{"camera_id": 1, "detection_date": %q, "target_direction": "left"}`, date)
+		}
+		return apiTest{name: name, method: http.MethodPost, path: path, body: body, status: status, response: response}
+	}
+	state := func(state string) apiTest {
+		return apiTest{name: "move to " + state, method: http.MethodPut, path: "/cameras/1/state",
+			body: fmt.Sprintf(`{"state": %q, "reason": "test"}`, state), status: http.StatusOK,
+			response: fmt.Sprintf(`{"state": %q}`, state)}
+	}
+
+	runApiTests(t, []apiTest{
+		testLocation,
+		testCamera,
+		detection("active", "/personDetections", "2026-01-05T10:00:00Z", http.StatusCreated,
+			`{"id": 1, "flagged": false}`),
+
+		state("maintenance"),
+		detection("in maintenance", "/personDetections", "2026-01-05T10:00:00Z", http.StatusCreated,
+			`{"id": 2, "flagged": true}`),
+		detection("in maintenance for camera", "/cameras/1/personDetections", "2026-01-05T10:00:00Z",
+			http.StatusCreated, `{"id": 3, "flagged": true}`),
+
+		state("disabled"),
+		detection("disabled", "/personDetections", "2026-01-05T10:00:00Z", http.StatusConflict, ""),
+		detection("disabled for camera", "/cameras/1/personDetections", "2026-01-05T10:00:00Z",
+			http.StatusConflict, ""),
+		state("retired"),
+		detection("retired", "/personDetections", "2026-01-05T10:00:00Z", http.StatusConflict, ""),
+
+		// windows only affect the detections dated within them, whatever the date they are sent at
+		state("active"),
+		{name: "create rejecting window", method: http.MethodPost, path: "/cameras/1/maintenanceWindows",
+			body:   `{"starts_at": "2026-02-01T00:00:00Z", "ends_at": "2026-02-02T00:00:00Z", "description": "lens"}`,
+			status: http.StatusCreated, response: `{"id": 1, "camera_id": 1, "ingest": "reject"}`},
+		{name: "create flagging window", method: http.MethodPost, path: "/cameras/1/maintenanceWindows",
+			body: `{"starts_at": "2026-03-01T00:00:00Z", "ends_at": "2026-03-02T00:00:00Z", "ingest": "flag", ` +
+				`"description": "calibration"}`,
+			status: http.StatusCreated, response: `{"id": 2, "ingest": "flag"}`},
+		{name: "create window with invalid ingest", method: http.MethodPost, path: "/cameras/1/maintenanceWindows",
+			body:   `{"starts_at": "2026-04-01T00:00:00Z", "ends_at": "2026-04-02T00:00:00Z", "ingest": "drop"}`,
+			status: http.StatusBadRequest},
+		detection("within rejecting window", "/personDetections", "2026-02-01T12:00:00Z", http.StatusConflict, ""),
+		detection("within rejecting window for camera", "/cameras/1/personDetections", "2026-02-01T12:00:00Z",
+			http.StatusConflict, ""),
+		detection("after rejecting window", "/personDetections", "2026-02-02T00:00:00Z", http.StatusCreated,
+			`{"id": 4, "flagged": false}`),
+		detection("within flagging window", "/personDetections", "2026-03-01T12:00:00Z", http.StatusCreated,
+			`{"id": 5, "flagged": true}`),
+		detection("within flagging window for camera", "/cameras/1/personDetections", "2026-03-01T12:00:00Z",
+			http.StatusCreated, `{"id": 6, "flagged": true}`),
+		detection("before flagging window", "/personDetections", "2026-02-28T23:59:59Z", http.StatusCreated,
+			`{"id": 7, "flagged": false}`),
+
+		{name: "flag instead of rejecting", method: http.MethodPatch, path: "/cameras/1/maintenanceWindows/1",
+			body: `{"ingest": "flag"}`, status: http.StatusOK, response: `{"id": 1, "ingest": "flag"}`},
+		detection("within window now flagging", "/personDetections", "2026-02-01T12:00:00Z", http.StatusCreated,
+			`{"id": 8, "flagged": true}`),
+		{name: "delete window", method: http.MethodDelete, path: "/cameras/1/maintenanceWindows/1",
+			status: http.StatusOK},
+		detection("within deleted window", "/personDetections", "2026-02-01T12:00:00Z", http.StatusCreated,
+			`{"id": 9, "flagged": false}`),
+
+		{name: "list", method: http.MethodGet, path: "/cameras/1/personDetections?offset=0&count=10&after_id=0",
+			status: http.StatusOK,
+			response: `[{"id": 1, "flagged": false}, {"id": 2, "flagged": true}, {"id": 3, "flagged": true}, ` +
+				`{"id": 4, "flagged": false}, {"id": 5, "flagged": true}, {"id": 6, "flagged": true}, ` +
+				`{"id": 7, "flagged": false}, {"id": 8, "flagged": true}, {"id": 9, "flagged": false}]`},
+	})
+}
@@ -6,16 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 )
 
-func cameraCtx(queries *dbschema.Queries, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+func cameraCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
 	logger = logger.Named("cameraCtx")
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -47,15 +50,104 @@ func cameraCtx(queries *dbschema.Queries, logger *zap.SugaredLogger) func(next h
 	}
 }
 
-func getCameras(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+// CameraResponse is a camera as returned by the api. LocationText repeats the mount description for the clients
+// written before cameras had a structured placement, it is deprecated and will be removed.
+type CameraResponse struct {
+	dbschema.Camera
+	LocationText string `json:"location_text"`
+}
+
+func newCameraResponse(camera dbschema.Camera) CameraResponse {
+	return CameraResponse{Camera: camera, LocationText: camera.MountDescription}
+}
+
+// CreateCameraRequest and UpdateCameraRequest also accept the deprecated location_text in place of mount_description
+type (
+	CreateCameraRequest struct {
+		dbschema.CreateCameraParams
+		LocationText *string `json:"location_text,omitempty"`
+	}
+	UpdateCameraRequest struct {
+		dbschema.UpdateCameraParams
+		LocationText *string `json:"location_text,omitempty"`
+	}
+)
+
+// CameraWithStatus is a camera as listed by GET /cameras, along with the result of its latest connectivity check
+type CameraWithStatus struct {
+	CameraResponse
+	Status dbschema.CameraStatus `json:"status"`
+}
+
+// validateTag returns why key can not be a tag key. Keys can not contain colons, as tag filters are key:value.
+func validateTag(key string) error {
+	if key == "" {
+		return errors.New("tag keys can not be empty")
+	}
+	if strings.Contains(key, ":") {
+		return fmt.Errorf("invalid tag key %q, tag keys can not contain colons", key)
+	}
+	return nil
+}
+
+func validateTags(tags map[string]string) error {
+	for key := range tags {
+		if err := validateTag(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryBool parses the boolean query parameter name, which is false when missing
+func queryBool(r *http.Request, name string) (bool, error) {
+	str := r.URL.Query().Get(name)
+	if str == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %w", name, err)
+	}
+	return value, nil
+}
+
+// unknownCameraStatus is the status of a camera that was not checked yet
+func unknownCameraStatus(cameraId int64) dbschema.CameraStatus {
+	return dbschema.CameraStatus{CameraID: cameraId, Status: "unknown"}
+}
+
+func getCameras(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("GetCameras")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		cameras, err := queries.GetCameras(ctx)
+
+		selector, err := parseCameraSelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		includeDeleted, err := queryBool(r, "include_deleted")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		state := r.URL.Query().Get("state")
+		if state != "" {
+			if err := validateCameraState(state); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		cameras, err := queries.GetCameras(ctx, includeDeleted)
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			HandlePqError(w, r, pgErr, logger)
+			return
 		} else if err != nil {
 			err := fmt.Errorf("error getting cameras: %w", err)
 			logger.Error(err)
@@ -63,7 +155,49 @@ func getCameras(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Handl
 			return
 		}
 
-		body, err := json.Marshal(cameras)
+		if !selector.empty() {
+			cameras, err = selector.filter(ctx, queries, cameras)
+			if err != nil {
+				handleCameraSelectorError(w, r, err, logger)
+				return
+			}
+		}
+		if state != "" {
+			inState := []dbschema.Camera{}
+			for _, camera := range cameras {
+				if camera.State == state {
+					inState = append(inState, camera)
+				}
+			}
+			cameras = inState
+		}
+
+		statuses, err := queries.GetCameraStatuses(ctx)
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera statuses: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		statusByCamera := make(map[int64]dbschema.CameraStatus, len(statuses))
+		for _, status := range statuses {
+			statusByCamera[status.CameraID] = status
+		}
+
+		camerasWithStatus := make([]CameraWithStatus, 0, len(cameras))
+		for _, camera := range cameras {
+			status, ok := statusByCamera[camera.ID]
+			if !ok {
+				status = unknownCameraStatus(camera.ID)
+			}
+			camerasWithStatus = append(camerasWithStatus, CameraWithStatus{CameraResponse: newCameraResponse(camera), Status: status})
+		}
+
+		body, err := json.Marshal(camerasWithStatus)
 		if err != nil {
 			logger.Errorf("error marshaling json body: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -82,9 +216,9 @@ func getCamera(logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("GetCamera")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		camera := ctx.Value("camera")
+		camera := ctx.Value("camera").(dbschema.Camera)
 
-		body, err := json.Marshal(camera)
+		body, err := json.Marshal(newCameraResponse(camera))
 		if err != nil {
 			err := fmt.Errorf("error marshaling json body: %w", err)
 			logger.Error(err)
@@ -99,22 +233,49 @@ func getCamera(logger *zap.SugaredLogger) http.HandlerFunc {
 	}
 }
 
-func postCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func postCamera(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("CreateCamera")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		var params dbschema.CreateCameraParams
+		var request CreateCameraRequest
 
 		dec := json.NewDecoder(r.Body)
 
-		if err := dec.Decode(&params); err != nil {
+		if err := dec.Decode(&request); err != nil {
 			err := fmt.Errorf("error decoding request body: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.LocationText != nil && request.MountDescription == "" {
+			request.MountDescription = *request.LocationText
+		}
+		if request.FieldOfView != nil {
+			if err := request.FieldOfView.Validate(); err != nil {
+				http.Error(w, fmt.Sprintf("invalid field of view: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+		if request.Tags == nil {
+			request.Tags = map[string]string{}
+		}
+		if err := validateTags(request.Tags); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		camera, err := queries.CreateCamera(ctx, params)
+		if err := checkLocationNotDeleted(ctx, queries, int64(request.LocationID)); errors.Is(err, errLocationDeleted) {
+			http.Error(w, fmt.Sprintf("location %d is deleted", request.LocationID), http.StatusConflict)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error getting camera location: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		camera, err := queries.CreateCamera(ctx, request.CreateCameraParams)
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -127,7 +288,7 @@ func postCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Handl
 			return
 		}
 
-		body, err := json.Marshal(camera)
+		body, err := json.Marshal(newCameraResponse(camera))
 		if err != nil {
 			err = fmt.Errorf("error marshaling body: %w", err)
 			logger.Error(err)
@@ -145,7 +306,7 @@ func postCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Handl
 	}
 }
 
-func patchCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func patchCamera(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("patchCamera")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -153,17 +314,42 @@ func patchCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Hand
 
 		dec := json.NewDecoder(r.Body)
 
-		var params dbschema.UpdateCameraParams
+		var request UpdateCameraRequest
 
-		if err := dec.Decode(&params); err != nil {
+		if err := dec.Decode(&request); err != nil {
 			err = fmt.Errorf("invalid body: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		params.ID = camera.ID
+		request.ID = camera.ID
+		if request.LocationText != nil && !request.MountDescription.Valid {
+			request.MountDescription = pgtype.Text{String: *request.LocationText, Valid: true}
+		}
+		if request.FieldOfView != nil {
+			if err := request.FieldOfView.Validate(); err != nil {
+				http.Error(w, fmt.Sprintf("invalid field of view: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := validateTags(request.Tags); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		camera, err := queries.UpdateCamera(ctx, params)
+		if request.LocationID.Valid {
+			if err := checkLocationNotDeleted(ctx, queries, int64(request.LocationID.Int32)); errors.Is(err, errLocationDeleted) {
+				http.Error(w, fmt.Sprintf("location %d is deleted", request.LocationID.Int32), http.StatusConflict)
+				return
+			} else if err != nil {
+				err = fmt.Errorf("error getting camera location: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		camera, err := queries.UpdateCamera(ctx, request.UpdateCameraParams)
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -176,7 +362,7 @@ func patchCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Hand
 			return
 		}
 
-		resBody, err := json.Marshal(camera)
+		resBody, err := json.Marshal(newCameraResponse(camera))
 		if err != nil {
 			err := fmt.Errorf("error marshaling json body: %s", err)
 			logger.Error(err)
@@ -192,17 +378,105 @@ func patchCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Hand
 	}
 }
 
-func deleteCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+// errReassignTarget is returned when the camera given in reassign_to can't receive the detections of a deleted camera
+var errReassignTarget = errors.New("reassign_to must be another camera that is not deleted")
+
+// errDetectionsAndReassign is returned when the detections of a deleted camera are both deleted and reassigned
+var errDetectionsAndReassign = errors.New("detections and reassign_to can't be used together")
+
+// hardDeleteCamera removes a camera for good. Its detections are deleted along with it when deleteDetections is set,
+// or moved to the camera reassignTo when it is not 0, the delete fails while detections remain otherwise.
+func hardDeleteCamera(ctx context.Context, queries store.Store, cameraId int64, deleteDetections bool, reassignTo int64) error {
+	if deleteDetections && reassignTo != 0 {
+		return errDetectionsAndReassign
+	}
+	if reassignTo != 0 && reassignTo == cameraId {
+		return errReassignTarget
+	}
+
+	return queries.InTx(ctx, func(s store.Store) error {
+		if reassignTo != 0 {
+			target, err := s.GetCamera(ctx, reassignTo)
+			if errors.Is(err, pgx.ErrNoRows) || err == nil && target.DeletedAt.Valid {
+				return errReassignTarget
+			} else if err != nil {
+				return err
+			}
+
+			if _, err := s.ReassignPersonDetections(ctx, dbschema.ReassignPersonDetectionsParams{
+				ToCameraID:   reassignTo,
+				FromCameraID: cameraId,
+			}); err != nil {
+				return err
+			}
+			if _, err := s.ReassignCameraDetections(ctx, dbschema.ReassignCameraDetectionsParams{
+				ToCameraID:   reassignTo,
+				FromCameraID: cameraId,
+			}); err != nil {
+				return err
+			}
+		} else if deleteDetections {
+			if _, err := s.DeletePersonDetectionsForCamera(ctx, cameraId); err != nil {
+				return err
+			}
+			if _, err := s.DeleteCameraDetectionsForCamera(ctx, cameraId); err != nil {
+				return err
+			}
+		}
+
+		return s.DeleteCamera(ctx, cameraId)
+	})
+}
+
+// deleteCamera soft deletes a camera, hiding it from the listings and rejecting its new detections. With hard=true the
+// camera is removed for good, its detections are then either deleted with detections=delete or moved to another camera
+// with reassign_to, and the delete fails while detections remain otherwise.
+func deleteCamera(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("DeleteCamera")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		camera := ctx.Value("camera").(dbschema.Camera)
 
-		err := queries.DeleteCamera(ctx, camera.ID)
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		deleteDetections := false
+		switch detections := r.URL.Query().Get("detections"); detections {
+		case "":
+		case "delete":
+			deleteDetections = true
+		default:
+			http.Error(w, fmt.Sprintf("invalid detections parameter %q, must be delete", detections), http.StatusBadRequest)
+			return
+		}
+
+		var reassignTo int64
+		if reassignToStr := r.URL.Query().Get("reassign_to"); reassignToStr != "" {
+			if reassignTo, err = strconv.ParseInt(reassignToStr, 10, 64); err != nil {
+				http.Error(w, fmt.Sprintf("invalid reassign_to parameter: %s", err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if (deleteDetections || reassignTo != 0) && !hard {
+			http.Error(w, "detections and reassign_to are only allowed with hard=true", http.StatusBadRequest)
+			return
+		}
+
+		if !hard {
+			_, err = queries.SoftDeleteCamera(ctx, camera.ID)
+		} else {
+			err = hardDeleteCamera(ctx, queries, camera.ID, deleteDetections, reassignTo)
+		}
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			HandlePqError(w, r, pgErr, logger)
+		} else if errors.Is(err, errReassignTarget) || errors.Is(err, errDetectionsAndReassign) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		} else if err != nil {
 			err := fmt.Errorf("error deleting camera: %w", err)
 			logger.Error(err)
@@ -212,3 +486,137 @@ func deleteCamera(queries *dbschema.Queries, logger *zap.SugaredLogger) http.Han
 		}
 	}
 }
+
+// restoreCamera undoes the soft delete of a camera, its location must not be deleted
+func restoreCamera(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("RestoreCamera")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		location, err := queries.GetLocation(ctx, int64(camera.LocationID))
+		if err != nil {
+			err := fmt.Errorf("error getting camera location: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if location.DeletedAt.Valid {
+			http.Error(w, fmt.Sprintf("location %d of the camera is deleted, restore it first", location.ID), http.StatusConflict)
+			return
+		}
+
+		camera, err = queries.RestoreCamera(ctx, camera.ID)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error restoring camera: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(newCameraResponse(camera))
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// errLocationDeleted is returned when a camera is placed in a soft deleted location
+var errLocationDeleted = errors.New("location is deleted")
+
+// checkLocationNotDeleted returns an error if the location id refers to a soft deleted location. Missing locations are
+// left for the foreign key to report.
+func checkLocationNotDeleted(ctx context.Context, queries store.Store, id int64) error {
+	location, err := queries.GetLocation(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if location.DeletedAt.Valid {
+		return errLocationDeleted
+	}
+	return nil
+}
+
+func getCameraStatus(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("GetCameraStatus")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		status, err := queries.GetCameraStatus(ctx, camera.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if errors.Is(err, pgx.ErrNoRows) {
+			status = unknownCameraStatus(camera.ID)
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera status: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(status)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getCameraLocationHistory(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getCameraLocationHistory")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		assignments, err := queries.GetCameraLocationAssignments(ctx, camera.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting camera location history: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(assignments)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
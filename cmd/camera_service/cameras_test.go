@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCameraHandlers(t *testing.T) {
+	runApiTests(t, []apiTest{
+		testLocation,
+		testCamera,
+		{name: "list", method: http.MethodGet, path: "/cameras", status: http.StatusOK},
+		{name: "get", method: http.MethodGet, path: "/cameras/1", status: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/cameras/100", status: http.StatusNotFound},
+		{name: "get invalid id", method: http.MethodGet, path: "/cameras/entrance", status: http.StatusBadRequest},
+		{name: "create invalid body", method: http.MethodPost, path: "/cameras", body: `{"name": `,
+			status: http.StatusBadRequest},
+		{name: "create in missing location", method: http.MethodPost, path: "/cameras",
+			body:   `{"name": "exit", "connection_string": "rtsp://exit", "location_id": 100, "orientation": "horizontal"}`,
+			status: http.StatusConflict},
+		{name: "update", method: http.MethodPatch, path: "/cameras/1", body: `{"name": "main entrance"}`,
+			status: http.StatusOK},
+		{name: "update missing", method: http.MethodPatch, path: "/cameras/100", body: `{"name": "exit"}`,
+			status: http.StatusNotFound},
+		{name: "soft delete", method: http.MethodDelete, path: "/cameras/1", status: http.StatusOK},
+		{name: "restore", method: http.MethodPost, path: "/cameras/1/restore", status: http.StatusOK},
+		{name: "hard delete", method: http.MethodDelete, path: "/cameras/1?hard=true", status: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/cameras/1", status: http.StatusNotFound},
+	})
+}
+
+func TestHardDeleteCamera(t *testing.T) {
+	// the admin command deletes cameras directly with hardDeleteCamera, it must keep the detections of the api
+	queries := store.NewMemory()
+	ctx := context.Background()
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cameras []dbschema.Camera
+	for _, name := range []string{"entrance", "exit", "aisle"} {
+		camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{Name: name, LocationID: int32(location.ID),
+			Orientation: dbenums.CameraOrientationHorizontal, Tags: map[string]string{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cameras = append(cameras, camera)
+	}
+	for _, camera := range cameras {
+		if _, err := queries.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+			CameraID:        camera.ID,
+			DetectionDate:   pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC), Valid: true},
+			TargetDirection: dbenums.DirectionLeft,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var pgErr *pgconn.PgError
+	if err := hardDeleteCamera(ctx, queries, cameras[0].ID, false, 0); !errors.As(err, &pgErr) || pgErr.Code != "23503" {
+		t.Errorf("expected foreign key violation deleting camera with detections, got %v", err)
+	}
+	if err := hardDeleteCamera(ctx, queries, cameras[0].ID, false, cameras[0].ID); !errors.Is(err, errReassignTarget) {
+		t.Errorf("expected %v reassigning to the camera itself, got %v", errReassignTarget, err)
+	}
+	if err := hardDeleteCamera(ctx, queries, cameras[0].ID, true, cameras[1].ID); !errors.Is(err, errDetectionsAndReassign) {
+		t.Errorf("expected %v, got %v", errDetectionsAndReassign, err)
+	}
+
+	if err := hardDeleteCamera(ctx, queries, cameras[0].ID, false, cameras[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if detection, err := queries.GetPersonDetection(ctx, 1); err != nil || detection.CameraID != cameras[1].ID {
+		t.Errorf("expected detection reassigned to camera %d, got %+v, %v", cameras[1].ID, detection, err)
+	}
+
+	if err := hardDeleteCamera(ctx, queries, cameras[2].ID, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queries.GetPersonDetection(ctx, 3); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected detection deleted with its camera, got %v", err)
+	}
+}
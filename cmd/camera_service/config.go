@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"os"
@@ -13,12 +14,38 @@ type (
 		AllowedOrigins  []string `mapstructure:"allowed_origins"`
 		AllowAllOrigins bool     `mapstructure:"allow_all_origins"`
 	}
+	// MetricsConfig configures the listener serving the expvar metrics at /debug/vars, apart from the api so they
+	// are not exposed along with it
+	MetricsConfig struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Address string `mapstructure:"address"`
+	}
 	Config struct {
 		Port int `mapstructure:"port"`
 
+		Metrics MetricsConfig `mapstructure:"metrics"`
+
 		Db DbConfig `mapstructure:"db"`
 
 		Cors CorsConfig `mapstructure:"cors"`
+
+		Retention RetentionConfig `mapstructure:"retention"`
+
+		Partitions PartitionsConfig `mapstructure:"partitions"`
+
+		Archive ArchiveConfig `mapstructure:"archive"`
+
+		Probe ProbeConfig `mapstructure:"probe"`
+
+		Activity ActivityConfig `mapstructure:"activity"`
+
+		Occupancy OccupancyConfig `mapstructure:"occupancy"`
+
+		Notifications NotificationsConfig `mapstructure:"notifications"`
+
+		FloorPlans FloorPlansConfig `mapstructure:"floor_plans"`
+
+		Detections DetectionsConfig `mapstructure:"detections"`
 	}
 )
 
@@ -52,6 +79,10 @@ func loadConfig(logger *zap.SugaredLogger) Config {
 	// main config
 	configLoader.SetDefault("port", 3000)
 
+	// metrics config
+	configLoader.SetDefault("metrics.enabled", false)
+	configLoader.SetDefault("metrics.address", "localhost:9090")
+
 	// db config
 	configLoader.SetDefault("db.hostname", "localhost")
 	configLoader.SetDefault("db.port", 5432)
@@ -63,6 +94,65 @@ func loadConfig(logger *zap.SugaredLogger) Config {
 	configLoader.SetDefault("cors.allowed_origins", make([]string, 0))
 	configLoader.SetDefault("cors.allow_all_origins", false)
 
+	// retention config
+	configLoader.SetDefault("retention.enabled", false)
+	configLoader.SetDefault("retention.dry_run", false)
+	configLoader.SetDefault("retention.interval", "1h")
+	configLoader.SetDefault("retention.batch_size", 1000)
+	configLoader.SetDefault("retention.person_detections", "0s")
+	configLoader.SetDefault("retention.camera_detections", "0s")
+	configLoader.SetDefault("retention.overrides", make([]map[string]any, 0))
+
+	// partitions config
+	configLoader.SetDefault("partitions.months_ahead", 3)
+	configLoader.SetDefault("partitions.interval", "24h")
+
+	// archive config
+	configLoader.SetDefault("archive.enabled", false)
+	configLoader.SetDefault("archive.older_than", "720h")
+	configLoader.SetDefault("archive.interval", "1h")
+	configLoader.SetDefault("archive.format", "csv")
+	configLoader.SetDefault("archive.batch_size", 10000)
+	configLoader.SetDefault("archive.store.directory", "")
+	configLoader.SetDefault("archive.store.s3.endpoint", "")
+	configLoader.SetDefault("archive.store.s3.region", "")
+	configLoader.SetDefault("archive.store.s3.bucket", "")
+	configLoader.SetDefault("archive.store.s3.access_key_id", "")
+	configLoader.SetDefault("archive.store.s3.secret_access_key", "")
+
+	// probe config
+	configLoader.SetDefault("probe.enabled", false)
+	configLoader.SetDefault("probe.interval", "1m")
+	configLoader.SetDefault("probe.timeout", "5s")
+	configLoader.SetDefault("probe.concurrency", 8)
+
+	// activity config
+	configLoader.SetDefault("activity.enabled", false)
+	configLoader.SetDefault("activity.interval", "1m")
+
+	// occupancy config
+	configLoader.SetDefault("occupancy.enabled", false)
+	configLoader.SetDefault("occupancy.interval", "30s")
+
+	// notifications config
+	configLoader.SetDefault("notifications.webhook_url", "")
+	configLoader.SetDefault("notifications.timeout", "10s")
+	configLoader.SetDefault("notifications.channels", make([]map[string]any, 0))
+	configLoader.SetDefault("notifications.routes", make([]map[string]any, 0))
+
+	// floor plans config
+	configLoader.SetDefault("floor_plans.max_size", 10<<20)
+	configLoader.SetDefault("floor_plans.store.directory", "")
+	configLoader.SetDefault("floor_plans.store.s3.endpoint", "")
+	configLoader.SetDefault("floor_plans.store.s3.region", "")
+	configLoader.SetDefault("floor_plans.store.s3.bucket", "")
+	configLoader.SetDefault("floor_plans.store.s3.access_key_id", "")
+	configLoader.SetDefault("floor_plans.store.s3.secret_access_key", "")
+
+	// detections config
+	configLoader.SetDefault("detections.min_confidence", 0.0)
+	configLoader.SetDefault("detections.idempotency_window", "24h")
+
 	err := configLoader.ReadInConfig()
 
 	if err != nil {
@@ -86,5 +176,43 @@ func loadConfig(logger *zap.SugaredLogger) Config {
 		return Config{}
 	}
 
+	if err := validateConfig(config); err != nil {
+		logger.Fatalf("invalid config: %s", err)
+	}
+
 	return config
 }
+
+// validateConfig rejects the values the background jobs can't run with, like a zero interval, which panics the
+// ticker, or a zero batch size, which never finishes a batched delete
+func validateConfig(config Config) error {
+	positive := []struct {
+		key   string
+		value int64
+	}{
+		{"retention.interval", int64(config.Retention.Interval)},
+		{"retention.batch_size", int64(config.Retention.BatchSize)},
+		{"partitions.interval", int64(config.Partitions.Interval)},
+		{"archive.interval", int64(config.Archive.Interval)},
+		{"archive.batch_size", int64(config.Archive.BatchSize)},
+		{"probe.interval", int64(config.Probe.Interval)},
+		{"probe.timeout", int64(config.Probe.Timeout)},
+		{"probe.concurrency", int64(config.Probe.Concurrency)},
+		{"activity.interval", int64(config.Activity.Interval)},
+		{"occupancy.interval", int64(config.Occupancy.Interval)},
+		{"detections.idempotency_window", int64(config.Detections.IdempotencyWindow)},
+	}
+	for _, field := range positive {
+		if field.value <= 0 {
+			return fmt.Errorf("%s must be greater than zero", field.key)
+		}
+	}
+
+	if config.Partitions.MonthsAhead < 0 {
+		return errors.New("partitions.months_ahead can't be negative")
+	}
+	if config.Detections.MinConfidence < 0 || config.Detections.MinConfidence > 1 {
+		return errors.New("detections.min_confidence must be between 0 and 1")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := Config{
+		Retention:  RetentionConfig{Interval: time.Hour, BatchSize: 1000},
+		Partitions: PartitionsConfig{MonthsAhead: 3, Interval: 24 * time.Hour},
+		Archive:    ArchiveConfig{Interval: time.Hour, BatchSize: 10000},
+		Probe:      ProbeConfig{Interval: time.Minute, Timeout: 5 * time.Second, Concurrency: 8},
+		Activity:   ActivityConfig{Interval: time.Minute},
+		Occupancy:  OccupancyConfig{Interval: 30 * time.Second},
+		Detections: DetectionsConfig{IdempotencyWindow: 24 * time.Hour},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(config *Config)
+		valid  bool
+	}{
+		{"defaults", func(config *Config) {}, true},
+		{"zero retention batch size", func(config *Config) { config.Retention.BatchSize = 0 }, false},
+		{"zero retention interval", func(config *Config) { config.Retention.Interval = 0 }, false},
+		{"negative archive batch size", func(config *Config) { config.Archive.BatchSize = -1 }, false},
+		{"zero archive interval", func(config *Config) { config.Archive.Interval = 0 }, false},
+		{"zero activity interval", func(config *Config) { config.Activity.Interval = 0 }, false},
+		{"zero occupancy interval", func(config *Config) { config.Occupancy.Interval = 0 }, false},
+		{"zero probe concurrency", func(config *Config) { config.Probe.Concurrency = 0 }, false},
+		{"no partitions ahead", func(config *Config) { config.Partitions.MonthsAhead = 0 }, true},
+		{"negative partitions ahead", func(config *Config) { config.Partitions.MonthsAhead = -1 }, false},
+		{"min confidence above one", func(config *Config) { config.Detections.MinConfidence = 1.5 }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := valid
+			test.modify(&config)
+
+			if err := validateConfig(config); test.valid && err != nil {
+				t.Fatalf("expected the config to be valid, got %s", err)
+			} else if !test.valid && err == nil {
+				t.Fatal("expected the config to be rejected")
+			}
+		})
+	}
+}
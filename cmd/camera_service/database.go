@@ -48,12 +48,12 @@ func testConnection(conn *pgxpool.Pool, logger *zap.SugaredLogger) {
 	}
 }
 
-func updateDatabaseSchema(config DbConfig, logger *zap.SugaredLogger) {
+func openMigrationDb(config DbConfig, logger *zap.SugaredLogger) *sql.DB {
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		config.User, config.Password, config.Hostname, config.Port, config.Database)
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		logger.Fatalf("could not connect to database for migrations: %w", err)
+		logger.Fatalf("could not connect to database for migrations: %s", err)
 	}
 	goose.SetBaseFS(migrations.Migrations)
 
@@ -61,6 +61,13 @@ func updateDatabaseSchema(config DbConfig, logger *zap.SugaredLogger) {
 		logger.Fatalf("could not initialize goose loader: %s", err)
 	}
 
+	return db
+}
+
+func updateDatabaseSchema(config DbConfig, logger *zap.SugaredLogger) {
+	db := openMigrationDb(config, logger)
+	defer db.Close()
+
 	if err := goose.Up(db, "."); err != nil {
 		logger.Fatalf("failed to update database schema: %s", err)
 	}
@@ -68,6 +75,46 @@ func updateDatabaseSchema(config DbConfig, logger *zap.SugaredLogger) {
 	logger.Infow("updated database schema")
 }
 
+// latestMigrationVersion returns the version of the newest migration embedded in this binary
+func latestMigrationVersion() (int64, error) {
+	knownMigrations, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	if err != nil {
+		return 0, fmt.Errorf("could not collect embedded migrations: %w", err)
+	}
+
+	last, err := knownMigrations.Last()
+	if err != nil {
+		return 0, fmt.Errorf("could not find latest embedded migration: %w", err)
+	}
+
+	return last.Version, nil
+}
+
+// checkDatabaseSchema refuses to continue if the database schema is newer than what this binary knows about,
+// as that usually means an older binary is being run against a database that was already upgraded
+func checkDatabaseSchema(config DbConfig, logger *zap.SugaredLogger) {
+	db := openMigrationDb(config, logger)
+	defer db.Close()
+
+	current, err := goose.GetDBVersion(db)
+	if err != nil {
+		logger.Fatalf("could not get database schema version: %s", err)
+	}
+
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if current > latest {
+		logger.Fatalf("database schema version %d is newer than the latest version known to this binary (%d), refusing to start", current, latest)
+	} else if current < latest {
+		logger.Warnw("database schema is behind the embedded migrations", "current", current, "latest", latest)
+	} else {
+		logger.Infow("database schema is up to date", "version", current)
+	}
+}
+
 func HandlePqError(w http.ResponseWriter, r *http.Request, err *pgconn.PgError, logger *zap.SugaredLogger) {
 	// TODO create better errors that do not expose internal database names
 	logger = logger.Named("HandlePqError")
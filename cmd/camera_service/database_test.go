@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pressly/goose/v3"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDatabaseUrlVariable names the database the jobs are tested against, the tests that need it are skipped when it
+// is not set. The url must be a postgres:// url, every test gets its own schema so the jobs can commit
+const testDatabaseUrlVariable = "CAMERA_SERVICE_TEST_DATABASE_URL"
+
+// testDatabase returns a pool connected to an empty schema migrated to the latest version, dropped once t is done
+func testDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseUrlVariable)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseUrlVariable)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("error connecting to database: %s", err)
+	}
+	defer conn.Close(ctx)
+
+	schema := fmt.Sprintf("camera_service_test_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(ctx, "create schema "+schema); err != nil {
+		t.Fatalf("error creating schema: %s", err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, url)
+		if err != nil {
+			t.Errorf("error connecting to database: %s", err)
+			return
+		}
+		defer conn.Close(ctx)
+		if _, err := conn.Exec(ctx, "drop schema "+schema+" cascade"); err != nil {
+			t.Errorf("error dropping schema: %s", err)
+		}
+	})
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	url += separator + "search_path=" + schema
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("error connecting to database for migrations: %s", err)
+	}
+	defer db.Close()
+	goose.SetBaseFS(migrations.Migrations)
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("error initializing goose: %s", err)
+	}
+	if err := goose.Up(db, "."); err != nil {
+		t.Fatalf("error migrating database: %s", err)
+	}
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("error connecting to database: %s", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
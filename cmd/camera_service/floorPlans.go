@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/blobstore"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// FloorPlansConfig configures where the floor plan images of locations are stored. Floor plans can not be uploaded
+// while neither a directory nor an s3 endpoint is configured.
+type FloorPlansConfig struct {
+	// MaxSize is the size of the largest image that can be uploaded, in bytes
+	MaxSize int64            `mapstructure:"max_size"`
+	Store   blobstore.Config `mapstructure:"store"`
+}
+
+// newFloorPlanStore opens the store of the floor plan images, nil is returned when no store is configured
+func newFloorPlanStore(config FloorPlansConfig) (blobstore.Store, error) {
+	if config.Store.Directory == "" && config.Store.S3.Endpoint == "" {
+		return nil, nil
+	}
+	store, err := blobstore.New(config.Store)
+	if err != nil {
+		return nil, fmt.Errorf("error opening floor plan store: %w", err)
+	}
+	return store, nil
+}
+
+// floorPlanFormats are the image formats accepted as floor plans, by content type
+var floorPlanFormats = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+}
+
+// FloorPlanResponse is the floor plan of a location, the image itself is served at ImageURL
+type FloorPlanResponse struct {
+	dbschema.FloorPlan
+	ImageURL string `json:"image_url"`
+}
+
+func newFloorPlanResponse(floorPlan dbschema.FloorPlan) FloorPlanResponse {
+	return FloorPlanResponse{
+		FloorPlan: floorPlan,
+		ImageURL:  fmt.Sprintf("/locations/%d/floorPlan/image", floorPlan.LocationID),
+	}
+}
+
+func floorPlanCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	logger = logger.Named("floorPlanCtx")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			location := ctx.Value("location").(dbschema.Location)
+
+			floorPlan, err := queries.GetFloorPlan(ctx, location.ID)
+
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				HandlePqError(w, r, pgErr, logger)
+			} else if errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, "the location has no floor plan", http.StatusNotFound)
+			} else if err != nil {
+				err := fmt.Errorf("error getting floor plan: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, "floorPlan", floorPlan)))
+			}
+		})
+	}
+}
+
+// floorPlanDimensions returns the size of the floor covered by an image of imageWidth by imageHeight pixels, from the
+// width, height and unit query parameters. When only one side is given the other one keeps the aspect ratio of the
+// image, when none is given the floor plan is measured in pixels.
+func floorPlanDimensions(r *http.Request, imageWidth int, imageHeight int) (width float64, height float64, unit string, err error) {
+	query := r.URL.Query()
+	for _, side := range []struct {
+		name  string
+		value *float64
+	}{{"width", &width}, {"height", &height}} {
+		valueStr := query.Get(side.name)
+		if valueStr == "" {
+			continue
+		}
+		if *side.value, err = strconv.ParseFloat(valueStr, 64); err != nil || *side.value <= 0 {
+			return 0, 0, "", fmt.Errorf("invalid %s %q, expected a positive number", side.name, valueStr)
+		}
+	}
+
+	unit = query.Get("unit")
+	switch {
+	case width == 0 && height == 0:
+		width, height = float64(imageWidth), float64(imageHeight)
+		if unit == "" {
+			unit = "px"
+		}
+	case width == 0:
+		width = height * float64(imageWidth) / float64(imageHeight)
+	case height == 0:
+		height = width * float64(imageHeight) / float64(imageWidth)
+	}
+	if unit == "" {
+		unit = "m"
+	}
+	return width, height, unit, nil
+}
+
+func putFloorPlan(queries store.Store, floorPlans blobstore.Store, config FloorPlansConfig, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("putFloorPlan")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		location := ctx.Value("location").(dbschema.Location)
+
+		if floorPlans == nil {
+			http.Error(w, "no floor plan store is configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format, ok := floorPlanFormats[contentType]
+		if err != nil || !ok {
+			http.Error(w, fmt.Sprintf("unsupported floor plan content type %q, expected image/png, image/jpeg or image/gif",
+				r.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxSize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("floor plans can not be larger than %d bytes", config.MaxSize), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error reading request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		imageConfig, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || decodedFormat != format || imageConfig.Width == 0 || imageConfig.Height == 0 {
+			http.Error(w, fmt.Sprintf("the request body is not a valid %s image", format), http.StatusBadRequest)
+			return
+		}
+
+		width, height, unit, err := floorPlanDimensions(r, imageConfig.Width, imageConfig.Height)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		previous, err := queries.GetFloorPlan(ctx, location.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			err := fmt.Errorf("error getting floor plan: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// every upload gets its own key, so clients caching the previous image notice the change
+		key := fmt.Sprintf("floor_plans/%d/%d.%s", location.ID, time.Now().UnixNano(), format)
+		if err := floorPlans.Put(ctx, key, contentType, bytes.NewReader(data)); err != nil {
+			err := fmt.Errorf("error storing floor plan image: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		floorPlan, err := queries.SetFloorPlan(ctx, dbschema.SetFloorPlanParams{
+			LocationID:  location.ID,
+			ImageKey:    key,
+			ContentType: contentType,
+			ImageWidth:  int32(imageConfig.Width),
+			ImageHeight: int32(imageConfig.Height),
+			Width:       width,
+			Height:      height,
+			Unit:        unit,
+		})
+		if err != nil {
+			if err := floorPlans.Delete(ctx, key); err != nil {
+				logger.Errorf("error deleting unused floor plan image %s: %s", key, err)
+			}
+		}
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error storing floor plan: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if previous.ImageKey != "" {
+			if err := floorPlans.Delete(ctx, previous.ImageKey); err != nil {
+				logger.Errorf("error deleting replaced floor plan image %s: %s", previous.ImageKey, err)
+			}
+		}
+
+		body, err := json.Marshal(newFloorPlanResponse(floorPlan))
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getFloorPlan(logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getFloorPlan")
+	return func(w http.ResponseWriter, r *http.Request) {
+		floorPlan := r.Context().Value("floorPlan").(dbschema.FloorPlan)
+
+		body, err := json.Marshal(newFloorPlanResponse(floorPlan))
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func getFloorPlanImage(floorPlans blobstore.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getFloorPlanImage")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		floorPlan := ctx.Value("floorPlan").(dbschema.FloorPlan)
+
+		if floorPlans == nil {
+			http.Error(w, "no floor plan store is configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		image, err := floorPlans.Get(ctx, floorPlan.ImageKey)
+		if errors.Is(err, blobstore.ErrNotFound) {
+			http.Error(w, "the floor plan image is missing from the store", http.StatusNotFound)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting floor plan image: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer image.Close()
+
+		w.Header().Add("Content-Type", floorPlan.ContentType)
+		w.Header().Add("Last-Modified", floorPlan.UpdatedAt.Time.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, image); err != nil {
+			logger.Errorf("error writing floor plan image: %s", err)
+		}
+	}
+}
+
+func patchFloorPlan(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("patchFloorPlan")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		floorPlan := ctx.Value("floorPlan").(dbschema.FloorPlan)
+
+		var params dbschema.UpdateFloorPlanParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			err = fmt.Errorf("invalid body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.LocationID = floorPlan.LocationID
+
+		floorPlan, err := queries.UpdateFloorPlan(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error updating floor plan: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(newFloorPlanResponse(floorPlan))
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func deleteFloorPlan(queries store.Store, floorPlans blobstore.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("deleteFloorPlan")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		floorPlan := ctx.Value("floorPlan").(dbschema.FloorPlan)
+
+		err := queries.DeleteFloorPlan(ctx, floorPlan.LocationID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error deleting floor plan: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if floorPlans != nil {
+			if err := floorPlans.Delete(ctx, floorPlan.ImageKey); err != nil {
+				logger.Errorf("error deleting floor plan image %s: %s", floorPlan.ImageKey, err)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// LocationMap has what a dashboard needs to draw a location: its floor plan, where its cameras are placed on it and
+// what they counted since the occupancy of the location started counting
+type LocationMap struct {
+	Location dbschema.Location `json:"location"`
+	// FloorPlan is null when the location has no floor plan
+	FloorPlan *FloorPlanResponse `json:"floor_plan"`
+	Cameras   []MapCamera        `json:"cameras"`
+	Occupancy LocationOccupancy  `json:"occupancy"`
+}
+
+// MapCamera is a camera of a location map, along with the people it detected, entering and leaving the location
+// since the occupancy started counting
+type MapCamera struct {
+	CameraWithStatus
+	Detections int64 `json:"detections"`
+	Entries    int64 `json:"entries"`
+	Exits      int64 `json:"exits"`
+}
+
+func getLocationMap(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getLocationMap")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		location := ctx.Value("location").(dbschema.Location)
+		now := time.Now()
+
+		locationMap, err := buildLocationMap(ctx, queries, location, now)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(locationMap)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func buildLocationMap(ctx context.Context, queries store.Store, location dbschema.Location, now time.Time) (LocationMap, error) {
+	locationMap := LocationMap{Location: location, Cameras: []MapCamera{}}
+
+	floorPlan, err := queries.GetFloorPlan(ctx, location.ID)
+	if err == nil {
+		response := newFloorPlanResponse(floorPlan)
+		locationMap.FloorPlan = &response
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return LocationMap{}, fmt.Errorf("error getting floor plan: %w", err)
+	}
+
+	_, occupancies, err := getLocationOccupancies(ctx, queries, now)
+	if err != nil {
+		return LocationMap{}, err
+	}
+	locationMap.Occupancy = occupancies[location.ID]
+
+	cameras, err := queries.GetCameras(ctx, false)
+	if err != nil {
+		return LocationMap{}, fmt.Errorf("error getting cameras: %w", err)
+	}
+	statuses, err := queries.GetCameraStatuses(ctx)
+	if err != nil {
+		return LocationMap{}, fmt.Errorf("error getting camera statuses: %w", err)
+	}
+	counts, err := queries.GetLocationCameraCounts(ctx, dbschema.GetLocationCameraCountsParams{
+		Since:      pgtype.Timestamptz{Time: locationMap.Occupancy.Since, Valid: true},
+		LocationID: location.ID,
+	})
+	if err != nil {
+		return LocationMap{}, fmt.Errorf("error getting camera counts: %w", err)
+	}
+
+	statusByCamera := make(map[int64]dbschema.CameraStatus, len(statuses))
+	for _, status := range statuses {
+		statusByCamera[status.CameraID] = status
+	}
+	countsByCamera := make(map[int64]dbschema.GetLocationCameraCountsRow, len(counts))
+	for _, row := range counts {
+		countsByCamera[row.CameraID] = row
+	}
+
+	for _, camera := range cameras {
+		if int64(camera.LocationID) != location.ID {
+			continue
+		}
+		status, ok := statusByCamera[camera.ID]
+		if !ok {
+			status = unknownCameraStatus(camera.ID)
+		}
+		row := countsByCamera[camera.ID]
+		locationMap.Cameras = append(locationMap.Cameras, MapCamera{
+			CameraWithStatus: CameraWithStatus{CameraResponse: newCameraResponse(camera), Status: status},
+			Detections:       row.Detections,
+			Entries:          row.Entries,
+			Exits:            row.Exits,
+		})
+	}
+
+	return locationMap, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"github.com/SmartFactory-Tec/camera_service/pkg/blobstore"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testPng returns a blank png image of width by height pixels
+func testPng(t *testing.T, width int, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFloorPlanHandlers(t *testing.T) {
+	floorPlans, err := blobstore.NewDirectory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{FloorPlans: FloorPlansConfig{MaxSize: 1024}}
+	floorPlan := testPng(t, 40, 20)
+
+	runApiTestsWith(t, config, floorPlans, []apiTest{
+		testLocation,
+		{name: "get missing", method: http.MethodGet, path: "/locations/1/floorPlan", status: http.StatusNotFound},
+		{name: "put without content type", method: http.MethodPut, path: "/locations/1/floorPlan", body: floorPlan,
+			status: http.StatusUnsupportedMediaType},
+		{name: "put unsupported content type", method: http.MethodPut, path: "/locations/1/floorPlan",
+			contentType: "image/svg+xml", body: `<svg xmlns="http://www.w3.org/2000/svg"/>`,
+			status: http.StatusUnsupportedMediaType},
+		{name: "put too large", method: http.MethodPut, path: "/locations/1/floorPlan", contentType: "image/png",
+			body: strings.Repeat("x", 1025), status: http.StatusRequestEntityTooLarge},
+		{name: "put png as jpeg", method: http.MethodPut, path: "/locations/1/floorPlan", contentType: "image/jpeg",
+			body: floorPlan, status: http.StatusBadRequest},
+		{name: "put not an image", method: http.MethodPut, path: "/locations/1/floorPlan", contentType: "image/png",
+			body: "not a png", status: http.StatusBadRequest},
+		{name: "put invalid width", method: http.MethodPut, path: "/locations/1/floorPlan?width=-2",
+			contentType: "image/png", body: floorPlan, status: http.StatusBadRequest},
+		{name: "get still missing", method: http.MethodGet, path: "/locations/1/floorPlan", status: http.StatusNotFound},
+
+		{name: "put measured in pixels", method: http.MethodPut, path: "/locations/1/floorPlan",
+			contentType: "image/png", body: floorPlan, status: http.StatusOK,
+			response: `{"location_id": 1, "content_type": "image/png", "image_width": 40, "image_height": 20, ` +
+				`"width": 40, "height": 20, "unit": "px", "image_url": "/locations/1/floorPlan/image"}`},
+		{name: "put keeping the aspect ratio", method: http.MethodPut, path: "/locations/1/floorPlan?width=10",
+			contentType: "image/png; charset=binary", body: floorPlan, status: http.StatusOK,
+			response: `{"width": 10, "height": 5, "unit": "m"}`},
+		{name: "patch", method: http.MethodPatch, path: "/locations/1/floorPlan", body: `{"unit": "ft"}`,
+			status: http.StatusOK, response: `{"width": 10, "height": 5, "unit": "ft"}`},
+		{name: "get image", method: http.MethodGet, path: "/locations/1/floorPlan/image", status: http.StatusOK},
+
+		{name: "create placed camera", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "entrance", "connection_string": "rtsp://entrance", "location_id": 1, ` +
+				`"orientation": "horizontal", "floor_x": 2.5, "floor_y": 4, "heading": 90}`, status: http.StatusCreated},
+		{name: "map", method: http.MethodGet, path: "/locations/1/map", status: http.StatusOK,
+			response: `{"location": {"id": 1, "name": "hall"}, "floor_plan": {"width": 10, "height": 5, ` +
+				`"image_url": "/locations/1/floorPlan/image"}, "cameras": [{"id": 1, "floor_x": 2.5, "floor_y": 4, ` +
+				`"heading": 90, "detections": 0}], "occupancy": {"location_id": 1}}`},
+
+		{name: "delete", method: http.MethodDelete, path: "/locations/1/floorPlan", status: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/locations/1/floorPlan", status: http.StatusNotFound},
+		{name: "map without floor plan", method: http.MethodGet, path: "/locations/1/map", status: http.StatusOK,
+			response: `{"floor_plan": null}`},
+	})
+}
+
+func TestFloorPlanWithoutStore(t *testing.T) {
+	runApiTests(t, []apiTest{
+		testLocation,
+		{name: "put", method: http.MethodPut, path: "/locations/1/floorPlan", contentType: "image/png",
+			body: testPng(t, 4, 4), status: http.StatusServiceUnavailable},
+	})
+}
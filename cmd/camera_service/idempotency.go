@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"net/http"
+	"time"
+)
+
+// detectors send the same Idempotency-Key when they resend a detection, so that it is only created once per camera
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+var errIdempotencyKeyInUse = errors.New("idempotency key is in use")
+
+// parseIdempotencyKey returns the Idempotency-Key of the request, empty when it does not have one
+func parseIdempotencyKey(r *http.Request) (string, error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("%s header is longer than %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+func idempotencyCutoff(config DetectionsConfig) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(-config.IdempotencyWindow), Valid: true}
+}
+
+// replayPersonDetection returns the detection created for the camera with the key within the idempotency window,
+// and false if there is none
+func replayPersonDetection(ctx context.Context, queries store.Store, config DetectionsConfig, cameraId int64, key string) (dbschema.PersonDetection, bool, error) {
+	if key == "" {
+		return dbschema.PersonDetection{}, false, nil
+	}
+
+	personDetection, err := queries.GetIdempotentPersonDetection(ctx, dbschema.GetIdempotentPersonDetectionParams{
+		CameraID: cameraId,
+		Key:      key,
+		Cutoff:   idempotencyCutoff(config),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dbschema.PersonDetection{}, false, nil
+	} else if err != nil {
+		return dbschema.PersonDetection{}, false, err
+	}
+	return personDetection, true, nil
+}
+
+// createPersonDetection creates the detection and stores the key along with it, unless the key is empty. The key is
+// claimed first, so that a request with the same key waits for the one that claimed it and gets the detection it
+// created instead, with replayed set to true. Keys of detections that are gone are taken over
+func createPersonDetection(ctx context.Context, queries store.Store, config DetectionsConfig, key string, params dbschema.CreatePersonDetectionParams) (personDetection dbschema.PersonDetection, replayed bool, err error) {
+	if key == "" {
+		personDetection, err = queries.CreatePersonDetection(ctx, params)
+		return personDetection, false, err
+	}
+
+	cutoff := idempotencyCutoff(config)
+	err = queries.InTx(ctx, func(s store.Store) error {
+		if _, err := s.DeleteExpiredIdempotencyKeys(ctx, dbschema.DeleteExpiredIdempotencyKeysParams{
+			CameraID: params.CameraID,
+			Cutoff:   cutoff,
+		}); err != nil {
+			return fmt.Errorf("error deleting expired idempotency keys: %w", err)
+		}
+
+		claimed, err := s.ClaimPersonDetectionIdempotencyKey(ctx, dbschema.ClaimPersonDetectionIdempotencyKeyParams{
+			CameraID: params.CameraID,
+			Key:      key,
+		})
+		if err != nil {
+			return fmt.Errorf("error claiming idempotency key: %w", err)
+		}
+
+		if claimed == 0 {
+			locked, err := s.LockPersonDetectionIdempotencyKey(ctx, dbschema.LockPersonDetectionIdempotencyKeyParams{
+				CameraID: params.CameraID,
+				Key:      key,
+			})
+			if err != nil {
+				return fmt.Errorf("error locking idempotency key: %w", err)
+			} else if locked == 0 {
+				return errIdempotencyKeyInUse
+			}
+
+			// the detection is looked up once the key is locked, so that it sees what the previous owner committed
+			personDetection, replayed, err = replayPersonDetection(ctx, s, config, params.CameraID, key)
+			if err != nil || replayed {
+				return err
+			}
+		}
+
+		personDetection, err = s.CreatePersonDetection(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		if err := s.SetPersonDetectionIdempotencyKeyDetection(ctx, dbschema.SetPersonDetectionIdempotencyKeyDetectionParams{
+			CameraID:          params.CameraID,
+			Key:               key,
+			PersonDetectionID: pgtype.Int8{Int64: personDetection.ID, Valid: true},
+			DetectionDate:     personDetection.DetectionDate,
+		}); err != nil {
+			return fmt.Errorf("error storing idempotency key: %w", err)
+		}
+		return nil
+	})
+	return personDetection, replayed, err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"testing"
+	"time"
+)
+
+func TestCreatePersonDetectionWithIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	queries := store.NewMemory()
+	config := DetectionsConfig{IdempotencyWindow: time.Hour}
+
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+	camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{
+		Name:        "entrance",
+		LocationID:  int32(location.ID),
+		Orientation: dbenums.CameraOrientationHorizontal,
+		Tags:        map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("error creating camera: %s", err)
+	}
+	params := dbschema.CreatePersonDetectionParams{
+		CameraID:        camera.ID,
+		DetectionDate:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		TargetDirection: dbenums.DirectionLeft,
+	}
+
+	created, replayed, err := createPersonDetection(ctx, queries, config, "key", params)
+	if err != nil || replayed {
+		t.Fatalf("expected a new detection, got replayed %t and error %v", replayed, err)
+	}
+
+	resent, replayed, err := createPersonDetection(ctx, queries, config, "key", params)
+	if err != nil || !replayed || resent.ID != created.ID {
+		t.Fatalf("expected detection %d to be replayed, got detection %d replayed %t and error %v", created.ID,
+			resent.ID, replayed, err)
+	}
+
+	// the key of a deleted detection is taken over
+	if err := queries.DeletePersonDetection(ctx, created.ID); err != nil {
+		t.Fatalf("error deleting detection: %s", err)
+	}
+	recreated, replayed, err := createPersonDetection(ctx, queries, config, "key", params)
+	if err != nil || replayed || recreated.ID == created.ID {
+		t.Fatalf("expected a new detection, got detection %d replayed %t and error %v", recreated.ID, replayed, err)
+	}
+
+	// a failed creation gives up its claim
+	missing := params
+	missing.CameraID = 1 << 40
+	if _, _, err := createPersonDetection(ctx, queries, config, "other", missing); err == nil {
+		t.Fatal("expected an error creating a detection of a missing camera")
+	}
+	if _, replayed, err := createPersonDetection(ctx, queries, config, "other", params); err != nil || replayed {
+		t.Fatalf("expected a new detection, got replayed %t and error %v", replayed, err)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// LocationTree is a location with all of its descendants
+type LocationTree struct {
+	dbschema.Location
+	Children []LocationTree `json:"children"`
+}
+
+// buildLocationTrees arranges locations as trees, returning the trees of the locations without a parent
+func buildLocationTrees(locations []dbschema.Location) []LocationTree {
+	children := make(map[int64][]dbschema.Location)
+	var roots []dbschema.Location
+	for _, location := range locations {
+		if location.ParentID.Valid {
+			children[location.ParentID.Int64] = append(children[location.ParentID.Int64], location)
+		} else {
+			roots = append(roots, location)
+		}
+	}
+
+	var build func(locations []dbschema.Location) []LocationTree
+	build = func(locations []dbschema.Location) []LocationTree {
+		trees := make([]LocationTree, 0, len(locations))
+		for _, location := range locations {
+			trees = append(trees, LocationTree{Location: location, Children: build(children[location.ID])})
+		}
+		return trees
+	}
+	return build(roots)
+}
+
+func makeGetLocationTreeHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("GetLocationTree")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		includeDeleted, err := queryBool(r, "include_deleted")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		locations, err := queries.GetLocations(ctx, includeDeleted)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting locations: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(buildLocationTrees(locations))
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// withoutDeletedLocations returns the locations that are not soft deleted
+func withoutDeletedLocations(locations []dbschema.Location) []dbschema.Location {
+	kept := []dbschema.Location{}
+	for _, location := range locations {
+		if !location.DeletedAt.Valid {
+			kept = append(kept, location)
+		}
+	}
+	return kept
+}
+
+func makeGetLocationChildrenHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("GetLocationChildren")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		location := ctx.Value("location").(dbschema.Location)
+
+		includeDeleted, err := queryBool(r, "include_deleted")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		children, err := queries.GetLocationChildren(ctx, location.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting location children: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !includeDeleted {
+			children = withoutDeletedLocations(children)
+		}
+
+		body, err := json.Marshal(children)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// countedLocationIds returns the locations whose detections are counted for the location of the request, the
+// location and all of its descendants unless the request sets include_descendants to false
+func countedLocationIds(r *http.Request, queries store.Store) ([]int64, error) {
+	ctx := r.Context()
+	location := ctx.Value("location").(dbschema.Location)
+
+	if r.URL.Query().Get("include_descendants") == "false" {
+		return []int64{location.ID}, nil
+	}
+	return queries.GetLocationSubtreeIds(ctx, location.ID)
+}
+
+func makeGetLocationDailyPersonDetectionsCountHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("GetLocationDailyPersonDetectionsCount")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		days, err := strconv.ParseInt(r.URL.Query().Get("days"), 10, 32)
+		if err != nil {
+			days = 0
+		}
+		months, err := strconv.ParseInt(r.URL.Query().Get("months"), 10, 32)
+		if err != nil {
+			months = 0
+		}
+
+		locationIds, err := countedLocationIds(r, queries)
+		if err != nil {
+			err := fmt.Errorf("error getting descendant locations: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		dailyPersonDetectionsCount, err := queries.GetLocationDailyPersonDetectionsCount(ctx, dbschema.GetLocationDailyPersonDetectionsCountParams{
+			LocationIds: locationIds,
+			Interval:    pgtype.Interval{Days: int32(days), Months: int32(months), Valid: true},
+		})
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting daily person detections count: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(dailyPersonDetectionsCount)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+func makeGetLocationHourlyPersonDetectionsCountHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("GetLocationHourlyPersonDetectionsCount")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+		if err != nil {
+			err := fmt.Errorf("request does not contain a valid from parameter: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+		if err != nil {
+			err := fmt.Errorf("request does not contain a valid to parameter: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fromDate := pgtype.Timestamptz{Time: from, Valid: true}
+		toDate := pgtype.Timestamptz{Time: to, Valid: true}
+
+		locationIds, err := countedLocationIds(r, queries)
+		if err != nil {
+			err := fmt.Errorf("error getting descendant locations: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// the hourly rollups can only answer ranges made of whole hours
+		var hourlyPersonDetectionsCount []dbschema.GetLocationHourlyPersonDetectionsCountRow
+		if from.Truncate(time.Hour).Equal(from) && to.Truncate(time.Hour).Equal(to) {
+			hourlyPersonDetectionsCount, err = queries.GetLocationHourlyPersonDetectionsCount(ctx, dbschema.GetLocationHourlyPersonDetectionsCountParams{
+				LocationIds: locationIds,
+				FromDate:    fromDate,
+				ToDate:      toDate,
+			})
+		} else {
+			var rows []dbschema.GetLocationHourlyPersonDetectionsCountRawRow
+			rows, err = queries.GetLocationHourlyPersonDetectionsCountRaw(ctx, dbschema.GetLocationHourlyPersonDetectionsCountRawParams{
+				LocationIds: locationIds,
+				FromDate:    fromDate,
+				ToDate:      toDate,
+			})
+			hourlyPersonDetectionsCount = make([]dbschema.GetLocationHourlyPersonDetectionsCountRow, 0, len(rows))
+			for _, row := range rows {
+				hourlyPersonDetectionsCount = append(hourlyPersonDetectionsCount, dbschema.GetLocationHourlyPersonDetectionsCountRow(row))
+			}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting hourly person detections count: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(hourlyPersonDetectionsCount)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
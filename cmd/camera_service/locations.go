@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -15,7 +16,7 @@ import (
 	"strconv"
 )
 
-func makeCreateLocationHandler(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func makeCreateLocationHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("CreateLocation")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -62,11 +63,17 @@ func makeCreateLocationHandler(queries *dbschema.Queries, logger *zap.SugaredLog
 	}
 }
 
-func makeGetLocationsHandler(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func makeGetLocationsHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("GetLocations")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		locations, err := queries.GetLocations(ctx)
+		includeDeleted, err := queryBool(r, "include_deleted")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		locations, err := queries.GetLocations(ctx, includeDeleted)
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -93,7 +100,7 @@ func makeGetLocationsHandler(queries *dbschema.Queries, logger *zap.SugaredLogge
 	}
 }
 
-func locationCtx(queries *dbschema.Queries, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+func locationCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
 	logger = logger.Named("locationCtx")
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -147,7 +154,7 @@ func makeGetLocationHandler(logger *zap.SugaredLogger) http.HandlerFunc {
 	}
 }
 
-func makeUpdateLocationHandler(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func makeUpdateLocationHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("UpdateLocation")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -193,18 +200,78 @@ func makeUpdateLocationHandler(queries *dbschema.Queries, logger *zap.SugaredLog
 	}
 }
 
-func makeDeleteLocationHandler(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+// errLocationInUse is returned when soft deleting a location that still has cameras or child locations
+var errLocationInUse = errors.New("location still has cameras or child locations that are not deleted")
+
+// makeDeleteLocationHandler soft deletes a location once its cameras and child locations are deleted, with hard=true
+// the location is removed for good instead, which fails while cameras have been in it unless history=delete
+func makeDeleteLocationHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("DeleteLocation")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		location := ctx.Value("location").(dbschema.Location)
 
-		err := queries.DeleteLocation(ctx, location.ID)
+		hard, err := queryBool(r, "hard")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		deleteHistory := false
+		switch history := r.URL.Query().Get("history"); history {
+		case "":
+		case "delete":
+			deleteHistory = true
+		default:
+			http.Error(w, fmt.Sprintf("invalid history parameter %q, must be delete", history), http.StatusBadRequest)
+			return
+		}
+		if deleteHistory && !hard {
+			http.Error(w, "history is only allowed with hard=true", http.StatusBadRequest)
+			return
+		}
+
+		if hard {
+			err = queries.InTx(ctx, func(s store.Store) error {
+				if deleteHistory {
+					if _, err := s.DeleteCameraLocationAssignmentsForLocation(ctx, int32(location.ID)); err != nil {
+						return err
+					}
+				}
+				return s.DeleteLocation(ctx, location.ID)
+			})
+		} else {
+			err = queries.InTx(ctx, func(s store.Store) error {
+				cameras, err := s.GetCameras(ctx, false)
+				if err != nil {
+					return err
+				}
+				for _, camera := range cameras {
+					if int64(camera.LocationID) == location.ID {
+						return errLocationInUse
+					}
+				}
+
+				children, err := s.GetLocationChildren(ctx, location.ID)
+				if err != nil {
+					return err
+				}
+				if len(withoutDeletedLocations(children)) > 0 {
+					return errLocationInUse
+				}
+
+				_, err = s.SoftDeleteLocation(ctx, location.ID)
+				return err
+			})
+		}
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			HandlePqError(w, r, pgErr, logger)
 			return
+		} else if errors.Is(err, errLocationInUse) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		} else if err != nil {
 			err := fmt.Errorf("error deleting location: %w", err)
 			logger.Error(err)
@@ -216,3 +283,51 @@ func makeDeleteLocationHandler(queries *dbschema.Queries, logger *zap.SugaredLog
 
 	}
 }
+
+// makeRestoreLocationHandler undoes the soft delete of a location, its parent must not be deleted
+func makeRestoreLocationHandler(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("RestoreLocation")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		location := ctx.Value("location").(dbschema.Location)
+
+		if location.ParentID.Valid {
+			parent, err := queries.GetLocation(ctx, location.ParentID.Int64)
+			if err != nil {
+				err := fmt.Errorf("error getting parent location: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if parent.DeletedAt.Valid {
+				http.Error(w, fmt.Sprintf("parent location %d is deleted, restore it first", parent.ID), http.StatusConflict)
+				return
+			}
+		}
+
+		location, err := queries.RestoreLocation(ctx, location.ID)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error restoring location: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(location)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
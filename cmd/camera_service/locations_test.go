@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLocationHandlers(t *testing.T) {
+	runApiTests(t, []apiTest{
+		testLocation,
+		{name: "list", method: http.MethodGet, path: "/locations", status: http.StatusOK},
+		{name: "get", method: http.MethodGet, path: "/locations/1", status: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/locations/100", status: http.StatusNotFound},
+		{name: "get invalid id", method: http.MethodGet, path: "/locations/hall", status: http.StatusBadRequest},
+		{name: "create invalid body", method: http.MethodPost, path: "/locations", body: `{"name": `,
+			status: http.StatusBadRequest},
+		{name: "create with missing parent", method: http.MethodPost, path: "/locations",
+			body: `{"name": "room", "parent_id": 100}`, status: http.StatusConflict},
+		{name: "create with zero capacity", method: http.MethodPost, path: "/locations",
+			body: `{"name": "room", "capacity": 0}`, status: http.StatusConflict},
+		{name: "update", method: http.MethodPatch, path: "/locations/1", body: `{"description": "lobby"}`,
+			status: http.StatusOK},
+		testCamera,
+		{name: "delete with cameras", method: http.MethodDelete, path: "/locations/1?hard=true",
+			status: http.StatusConflict},
+	})
+}
+
+func TestDeleteLocationWithHistory(t *testing.T) {
+	runApiTests(t, []apiTest{
+		testLocation,
+		testCamera,
+		{name: "create lobby", method: http.MethodPost, path: "/locations", body: `{"name": "lobby"}`,
+			status: http.StatusCreated},
+		{name: "move camera to lobby", method: http.MethodPatch, path: "/cameras/1", body: `{"location_id": 2}`,
+			status: http.StatusOK},
+		{name: "history", method: http.MethodGet, path: "/cameras/1/locationHistory", status: http.StatusOK,
+			response: `[{"location_id": 1}, {"location_id": 2}]`},
+
+		{name: "delete with history", method: http.MethodDelete, path: "/locations/1?hard=true",
+			status: http.StatusConflict},
+		{name: "history kept", method: http.MethodGet, path: "/cameras/1/locationHistory", status: http.StatusOK,
+			response: `[{"location_id": 1}, {"location_id": 2}]`},
+		{name: "delete history without hard", method: http.MethodDelete, path: "/locations/1?history=delete",
+			status: http.StatusBadRequest},
+		{name: "delete with invalid history", method: http.MethodDelete, path: "/locations/1?hard=true&history=keep",
+			status: http.StatusBadRequest},
+		{name: "get not deleted", method: http.MethodGet, path: "/locations/1", status: http.StatusOK},
+
+		{name: "delete along with history", method: http.MethodDelete, path: "/locations/1?hard=true&history=delete",
+			status: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/locations/1", status: http.StatusNotFound},
+		{name: "history of other locations kept", method: http.MethodGet, path: "/cameras/1/locationHistory",
+			status: http.StatusOK, response: `[{"location_id": 2}]`},
+		// the current location of a camera can't be deleted along with its history
+		{name: "delete current location", method: http.MethodDelete, path: "/locations/2?hard=true&history=delete",
+			status: http.StatusConflict},
+		{name: "history of current location kept", method: http.MethodGet, path: "/cameras/1/locationHistory",
+			status: http.StatusOK, response: `[{"location_id": 2}]`},
+	})
+}
@@ -15,6 +15,15 @@ func setupLogger() *zap.SugaredLogger {
 	return baseLogger.Sugar().Named("main")
 }
 
+// setupCliLogger returns a logger that writes to stderr, leaving stdout free for command output
+func setupCliLogger() *zap.SugaredLogger {
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	var core = zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(colorable.NewColorableStderr()), zapcore.InfoLevel)
+	baseLogger := zap.New(core)
+	return baseLogger.Sugar().Named("main")
+}
+
 // LogRequests returns a middleware that logs all requests to this router
 func LogRequests(logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -2,85 +2,66 @@ package main
 
 import (
 	"fmt"
-	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
-	"net/http"
+	"os"
+	"strings"
 )
 
-func main() {
-	logger := setupLogger()
-
-	config := loadConfig(logger)
-	dbConfig := config.Db
+const usage = `usage: camera_service [command] [arguments]
 
-	db := connectToDb(dbConfig, logger)
-	updateDatabaseSchema(dbConfig, logger)
-	queries := dbschema.New(db)
+commands:
+  serve [--no-migrate] [--in-memory]        start the http server (default)
+  migrate up|down|status|version|redo       manage the database schema
+  cameras list|create|update|delete         manage cameras
+  locations list|create|update|delete       manage locations
+  detections export                         export person detections as csv or json
+  import locations|cameras|detections       import a csv or json file, validating every row first
+  retention [--dry-run]                     prune expired detections once, following the retention config
+  rollups backfill [--from] [--to]          rebuild the detection count rollups from the raw detections
+  archive run|import <key>                  archive old detections once, or import an archive back
+  openapi                                   print the openapi document of the http api
 
-	var allowedOrigins []string
+the cameras, locations, detections and import commands work directly against the database, or against a running
+service when given --remote <url>. run a command with -h to list its flags.
+`
 
-	if !config.Cors.AllowAllOrigins {
-		allowedOrigins = config.Cors.AllowedOrigins
+func main() {
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
-	r := chi.NewRouter()
-
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins: allowedOrigins,
-		AllowedMethods: []string{"GET", "OPTIONS", "POST", "PATCH"},
-		AllowedHeaders: []string{"*"},
-		ExposedHeaders: []string{"*"},
-	}))
-
-	r.Use(LogRequests(logger))
-
-	r.Route("/locations", func(r chi.Router) {
-		r.Get("/", makeGetLocationsHandler(queries, logger))
-		r.Post("/", makeCreateLocationHandler(queries, logger))
-
-		r.Route("/{locationId}", func(r chi.Router) {
-			r.Use(locationCtx(queries, logger))
-			r.Get("/", makeGetLocationHandler(logger))
-			r.Patch("/", makeUpdateLocationHandler(queries, logger))
-			r.Delete("/", makeDeleteLocationHandler(queries, logger))
-		})
-	})
-
-	r.Route("/cameras", func(r chi.Router) {
-		r.Get("/", getCameras(queries, logger))
-		r.Post("/", postCamera(queries, logger))
-
-		r.Route("/{cameraId}", func(r chi.Router) {
-			r.Use(cameraCtx(queries, logger))
-			r.Get("/", getCamera(logger))
-			r.Patch("/", patchCamera(queries, logger))
-			r.Delete("/", deleteCamera(queries, logger))
-
-			r.Get("/personDetections", getCameraPersonDetections(queries, logger))
-			r.Post("/personDetections", postCameraPersonDetection(queries, logger))
-
-			r.Get("/dailyPersonDetectionsCount", getDailyPersonDetectionsCount(queries, logger))
-		})
-
-	})
-
-	r.Route("/personDetections", func(r chi.Router) {
-		r.Get("/", getPersonDetections(queries, logger))
-		r.Post("/", postPersonDetection(queries, logger))
-
-		r.Route("/{personDetectionId}", func(r chi.Router) {
-			r.Use(personDetectionCtx(queries, logger))
-			r.Get("/", getPersonDetection(logger))
-			r.Patch("/", patchPersonDetection(queries, logger))
-			r.Delete("/", deletePersonDetection(queries, logger))
-		})
-	})
-
-	logger.Infof("starting server on port %d", config.Port)
-	err := http.ListenAndServe(fmt.Sprintf(":%d", config.Port), r)
-	if err != nil {
-		logger.Fatal(fmt.Errorf("http server error: %w", err))
+	// admin commands print their results to stdout, so logs go to stderr instead
+	logger := setupLogger()
+	if command != "serve" {
+		logger = setupCliLogger()
 	}
 
+	switch command {
+	case "serve":
+		serve(args, logger)
+	case "migrate":
+		migrate(args, logger)
+	case "cameras":
+		adminCameras(args, logger)
+	case "locations":
+		adminLocations(args, logger)
+	case "detections":
+		adminDetections(args, logger)
+	case "import":
+		adminImport(args, logger)
+	case "retention":
+		retention(args, logger)
+	case "rollups":
+		rollups(args, logger)
+	case "archive":
+		archive(args, logger)
+	case "openapi":
+		printOpenApi(logger)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatalf("unknown command %q", command)
+	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pressly/goose/v3"
+	"go.uber.org/zap"
+	"os"
+)
+
+// migrate runs one of the goose commands against the embedded migrations
+func migrate(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("migrate")
+
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatal("migrate expects exactly one of up, down, status, version or redo")
+	}
+
+	config := loadConfig(logger)
+
+	db := openMigrationDb(config.Db, logger)
+	defer db.Close()
+
+	var err error
+	switch args[0] {
+	case "up":
+		err = goose.Up(db, ".")
+	case "down":
+		err = goose.Down(db, ".")
+	case "status":
+		err = goose.Status(db, ".")
+	case "version":
+		err = goose.Version(db, ".")
+	case "redo":
+		err = goose.Redo(db, ".")
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		logger.Fatalf("unknown migrate command %q", args[0])
+	}
+
+	if err != nil {
+		logger.Fatalf("migrate %s failed: %s", args[0], err)
+	}
+}
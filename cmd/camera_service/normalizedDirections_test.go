@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNormalizedDirections(t *testing.T) {
+	// detections made now are counted in today's row of the daily counts
+	now := time.Now()
+	hour := now.Truncate(time.Hour)
+	detection := func(id int, cameraId int, direction string, normalized string) apiTest {
+		return apiTest{name: fmt.Sprintf("create %s detection of camera %d", direction, cameraId),
+			method: http.MethodPost, path: "/personDetections",
+			body: fmt.Sprintf(`{"camera_id": %d, "detection_date": %q, "target_direction": %q}`, cameraId,
+				now.Format(time.RFC3339Nano), direction),
+			status: http.StatusCreated,
+			response: fmt.Sprintf(`{"id": %d, "target_direction": %q, "normalized_direction": %q}`, id, direction,
+				normalized)}
+	}
+
+	runApiTests(t, []apiTest{
+		testLocation,
+		{name: "create upright camera", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "upright", "connection_string": "rtsp://upright", "location_id": 1, ` +
+				`"orientation": "horizontal", "entry_direction": "left"}`,
+			status: http.StatusCreated, response: `{"id": 1, "entry_direction": "left"}`},
+		{name: "create inverted camera", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "inverted", "connection_string": "rtsp://inverted", "location_id": 1, ` +
+				`"orientation": "inverted_horizontal", "entry_direction": "left"}`,
+			status: http.StatusCreated, response: `{"id": 2, "entry_direction": "left"}`},
+		{name: "create camera without entry direction", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "aisle", "connection_string": "rtsp://aisle", "location_id": 1, ` +
+				`"orientation": "vertical"}`,
+			status: http.StatusCreated, response: `{"id": 3, "entry_direction": "none"}`},
+
+		detection(1, 1, "left", "in"),
+		detection(2, 1, "right", "out"),
+		detection(3, 1, "none", "none"),
+		// an inverted camera sees everything mirrored
+		detection(4, 2, "left", "out"),
+		detection(5, 2, "right", "in"),
+		detection(6, 2, "right", "in"),
+		detection(7, 3, "left", "none"),
+
+		// the normalized direction is derived again when the direction or the camera change, and only then
+		{name: "turn around", method: http.MethodPatch, path: "/personDetections/1",
+			body: `{"target_direction": "right"}`, status: http.StatusOK,
+			response: `{"target_direction": "right", "normalized_direction": "out"}`},
+		{name: "move to inverted camera", method: http.MethodPatch, path: "/personDetections/1",
+			body: `{"camera_id": 2}`, status: http.StatusOK,
+			response: `{"camera_id": 2, "target_direction": "right", "normalized_direction": "in"}`},
+		{name: "change entry direction", method: http.MethodPatch, path: "/cameras/2",
+			body: `{"entry_direction": "right"}`, status: http.StatusOK, response: `{"entry_direction": "right"}`},
+		{name: "keep normalized direction", method: http.MethodGet, path: "/personDetections/4", status: http.StatusOK,
+			response: `{"target_direction": "left", "normalized_direction": "out"}`},
+		detection(8, 2, "right", "out"),
+
+		{name: "raw and normalized hourly counts", method: http.MethodGet,
+			path: fmt.Sprintf("/cameras/2/hourlyPersonDetectionsCount?from=%s&to=%s",
+				hour.UTC().Format(time.RFC3339), hour.Add(time.Hour).UTC().Format(time.RFC3339)),
+			status: http.StatusOK,
+			response: `[{"target_direction": "left", "normalized_direction": "out", "count": 1}, ` +
+				`{"target_direction": "right", "normalized_direction": "in", "count": 3}, ` +
+				`{"target_direction": "right", "normalized_direction": "out", "count": 1}]`},
+		{name: "daily counts of a camera", method: http.MethodGet, path: "/cameras/2/dailyPersonDetectionsCount",
+			status: http.StatusOK, response: `[{"count": 5, "entries": 3, "exits": 2}]`},
+		{name: "daily counts of a camera without entry direction", method: http.MethodGet,
+			path: "/cameras/3/dailyPersonDetectionsCount", status: http.StatusOK,
+			response: `[{"count": 1, "entries": 0, "exits": 0}]`},
+		{name: "daily counts of every camera", method: http.MethodGet, path: "/cameras/dailyPersonDetectionsCount",
+			status: http.StatusOK, response: `[{"count": 8, "entries": 3, "exits": 3}]`},
+		{name: "daily counts of a location", method: http.MethodGet, path: "/locations/1/dailyPersonDetectionsCount",
+			status: http.StatusOK, response: `[{"count": 8, "entries": 3, "exits": 3}]`},
+	})
+}
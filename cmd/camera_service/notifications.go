@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+func getNotificationChannels(notifications *notify.Router, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getNotificationChannels")
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(notifications.Channels())
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// TestNotificationRequest is the optional body of a test notification, every field has a default
+type TestNotificationRequest struct {
+	// Channel is the name of the channel to test, every channel is tested when empty
+	Channel  string `json:"channel,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// TestNotificationResult tells whether a channel delivered the test notification
+type TestNotificationResult struct {
+	Channel   string `json:"channel"`
+	Delivered bool   `json:"delivered"`
+	Error     string `json:"error,omitempty"`
+}
+
+// the kind of the notifications sent by postTestNotification, routes never see them
+const notificationKindTest = "test"
+
+func postTestNotification(notifications *notify.Router, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("postTestNotification")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		request := TestNotificationRequest{
+			Title:    "Test notification",
+			Message:  "this is a test notification from the camera service",
+			Severity: "warning",
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			err := fmt.Errorf("error decoding request body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		notification := notify.Notification{
+			Kind:     notificationKindTest,
+			State:    "open",
+			Severity: request.Severity,
+			Title:    request.Title,
+			Message:  request.Message,
+			Time:     time.Now(),
+		}
+
+		var channels []string
+		if request.Channel != "" {
+			channels = []string{request.Channel}
+		} else {
+			for _, channel := range notifications.Channels() {
+				channels = append(channels, channel.Name)
+			}
+		}
+
+		results := make([]TestNotificationResult, 0, len(channels))
+		status := http.StatusOK
+		for _, channel := range channels {
+			err := notifications.Test(ctx, channel, notification)
+			if errors.Is(err, notify.ErrUnknownChannel) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			result := TestNotificationResult{Channel: channel, Delivered: err == nil}
+			if err != nil {
+				logger.Errorf("error sending test notification: %s", err)
+				result.Error = err.Error()
+				// the request was fine, the channel is the one failing
+				status = http.StatusBadGateway
+			}
+			results = append(results, result)
+		}
+
+		body, err := json.Marshal(results)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
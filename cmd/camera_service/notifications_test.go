@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPostTestNotification(t *testing.T) {
+	var received []notify.Notification
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification notify.Notification
+		json.NewDecoder(r.Body).Decode(&notification)
+		received = append(received, notification)
+	}))
+	defer working.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	notifications, err := notify.New(notify.Config{Channels: []notify.ChannelConfig{
+		{Name: "working", Type: notify.ChannelHTTP, URL: working.URL},
+		{Name: "failing", Type: notify.ChannelHTTP, URL: failing.URL},
+	}}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("error creating notification router: %s", err)
+	}
+	router := newRouter(Config{}, store.NewMemory(), notifications, nil, zap.NewNop().Sugar())
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		results []TestNotificationResult
+	}{
+		{
+			name:    "one channel",
+			body:    `{"channel": "working", "title": "Hello"}`,
+			status:  http.StatusOK,
+			results: []TestNotificationResult{{Channel: "working", Delivered: true}},
+		},
+		{
+			name:   "every channel",
+			status: http.StatusBadGateway,
+			results: []TestNotificationResult{
+				{Channel: "working", Delivered: true},
+				{Channel: "failing", Delivered: false,
+					Error: "webhook answered with status 503: unavailable"},
+			},
+		},
+		{name: "unknown channel", body: `{"channel": "missing"}`, status: http.StatusNotFound},
+		{name: "invalid body", body: `{"channel": `, status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/notifications/test", strings.NewReader(test.body)))
+			if res.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, res.Code, res.Body.String())
+			}
+			if test.results == nil {
+				return
+			}
+
+			body, _ := io.ReadAll(res.Body)
+			var results []TestNotificationResult
+			if err := json.Unmarshal(body, &results); err != nil {
+				t.Fatalf("error decoding body %s: %s", body, err)
+			}
+			if !reflect.DeepEqual(results, test.results) {
+				t.Fatalf("expected %+v, got %+v", test.results, results)
+			}
+		})
+	}
+
+	if len(received) != 2 || received[0].Title != "Hello" || received[0].Kind != notificationKindTest ||
+		received[1].Title != "Test notification" {
+		t.Fatalf("unexpected notifications %+v", received)
+	}
+}
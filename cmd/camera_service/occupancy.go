@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type OccupancyConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// the occupancy levels of a location, warning starts at the warning threshold and critical above the capacity
+const (
+	occupancyLevelNormal   = "normal"
+	occupancyLevelWarning  = "warning"
+	occupancyLevelCritical = "critical"
+)
+
+// LocationOccupancy is how many people are in a location, counted from the people who entered and left it through
+// the cameras with an entry direction
+type LocationOccupancy struct {
+	LocationID int64 `json:"location_id"`
+	// Since is when counting started, occupancy starts from zero every day at midnight
+	Since     time.Time `json:"since"`
+	Entries   int64     `json:"entries"`
+	Exits     int64     `json:"exits"`
+	Occupancy int64     `json:"occupancy"`
+	// Capacity and WarningThreshold are the ones of the location
+	Capacity         pgtype.Int4 `json:"capacity"`
+	WarningThreshold pgtype.Int4 `json:"warning_threshold"`
+	// Level is normal, warning or critical
+	Level string `json:"level"`
+	// CountedLocationIDs are the locations whose cameras were counted, see locationOccupancies
+	CountedLocationIDs []int64 `json:"counted_location_ids"`
+}
+
+// occupancySince returns when the occupancy counted at now started
+func occupancySince(now time.Time) time.Time {
+	year, month, day := now.In(time.Local).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// locationOccupancies computes the occupancy of every location from the entries and exits counted since since. A
+// location with cameras counting entries is counted at its own doors, a location without them adds up the occupancy
+// of its children, so a building is the sum of its floors unless the doors of the building are watched. A location
+// whose doors were watched since since by a camera that has been moved out of it is counted at its doors as well.
+func locationOccupancies(locations []dbschema.Location, cameras []dbschema.Camera, counts []dbschema.GetLocationOccupanciesRow, since time.Time) map[int64]LocationOccupancy {
+	counting := make(map[int64]bool)
+	for _, camera := range cameras {
+		if camera.EntryDirection != dbenums.DirectionNone {
+			counting[int64(camera.LocationID)] = true
+		}
+	}
+	countsByLocation := make(map[int64]dbschema.GetLocationOccupanciesRow, len(counts))
+	for _, row := range counts {
+		countsByLocation[int64(row.LocationID)] = row
+		// the counts follow where the cameras were, a camera moved out since still counted the doors it watched
+		counting[int64(row.LocationID)] = true
+	}
+	children := make(map[int64][]dbschema.Location)
+	for _, location := range locations {
+		if location.ParentID.Valid {
+			children[location.ParentID.Int64] = append(children[location.ParentID.Int64], location)
+		}
+	}
+
+	occupancies := make(map[int64]LocationOccupancy, len(locations))
+	var occupancyOf func(location dbschema.Location) LocationOccupancy
+	occupancyOf = func(location dbschema.Location) LocationOccupancy {
+		if occupancy, ok := occupancies[location.ID]; ok {
+			return occupancy
+		}
+
+		occupancy := LocationOccupancy{
+			LocationID:         location.ID,
+			Since:              since,
+			Capacity:           location.Capacity,
+			WarningThreshold:   location.WarningThreshold,
+			Level:              occupancyLevelNormal,
+			CountedLocationIDs: []int64{},
+		}
+		if counting[location.ID] {
+			row := countsByLocation[location.ID]
+			occupancy.Entries, occupancy.Exits = row.Entries, row.Exits
+			occupancy.Occupancy = row.Entries - row.Exits
+			// people leaving that were never seen entering, like the ones that were already in at midnight
+			if occupancy.Occupancy < 0 {
+				occupancy.Occupancy = 0
+			}
+			occupancy.CountedLocationIDs = append(occupancy.CountedLocationIDs, location.ID)
+		} else {
+			for _, child := range children[location.ID] {
+				childOccupancy := occupancyOf(child)
+				occupancy.Entries += childOccupancy.Entries
+				occupancy.Exits += childOccupancy.Exits
+				occupancy.Occupancy += childOccupancy.Occupancy
+				occupancy.CountedLocationIDs = append(occupancy.CountedLocationIDs, childOccupancy.CountedLocationIDs...)
+			}
+		}
+
+		switch {
+		case location.Capacity.Valid && occupancy.Occupancy > int64(location.Capacity.Int32):
+			occupancy.Level = occupancyLevelCritical
+		case location.WarningThreshold.Valid && occupancy.Occupancy >= int64(location.WarningThreshold.Int32):
+			occupancy.Level = occupancyLevelWarning
+		}
+
+		occupancies[location.ID] = occupancy
+		return occupancy
+	}
+
+	for _, location := range locations {
+		occupancyOf(location)
+	}
+	return occupancies
+}
+
+// getLocationOccupancies reads what locationOccupancies needs and computes the occupancy of every location at now
+func getLocationOccupancies(ctx context.Context, queries store.Store, now time.Time) ([]dbschema.Location, map[int64]LocationOccupancy, error) {
+	locations, err := queries.GetLocations(ctx, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting locations: %w", err)
+	}
+	cameras, err := queries.GetCameras(ctx, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting cameras: %w", err)
+	}
+
+	since := occupancySince(now)
+	counts, err := queries.GetLocationOccupancies(ctx, dbschema.GetLocationOccupanciesParams{
+		Since: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting occupancy: %w", err)
+	}
+
+	return locations, locationOccupancies(locations, cameras, counts, since), nil
+}
+
+func getLocationOccupancy(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getLocationOccupancy")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		location := ctx.Value("location").(dbschema.Location)
+
+		_, occupancies, err := getLocationOccupancies(ctx, queries, time.Now())
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(occupancies[location.ID])
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+type occupancyJob struct {
+	config   OccupancyConfig
+	queries  store.Store
+	notifier notify.Notifier
+	logger   *zap.SugaredLogger
+}
+
+func newOccupancyJob(config OccupancyConfig, queries store.Store, notifier notify.Notifier, logger *zap.SugaredLogger) *occupancyJob {
+	return &occupancyJob{config: config, queries: queries, notifier: notifier, logger: logger.Named("occupancy")}
+}
+
+// run evaluates the occupancy of every location every configured interval until ctx is done
+func (j *occupancyJob) run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.evaluateAll(ctx, time.Now()); err != nil {
+			j.logger.Errorf("error evaluating occupancy: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *occupancyJob) evaluateAll(ctx context.Context, now time.Time) error {
+	locations, occupancies, err := getLocationOccupancies(ctx, j.queries, now)
+	if err != nil {
+		return err
+	}
+
+	for _, location := range locations {
+		if err := j.evaluate(ctx, location, occupancies[location.ID], now); err != nil {
+			j.logger.Errorf("error evaluating occupancy of location %d: %s", location.ID, err)
+		}
+	}
+	return nil
+}
+
+// evaluate opens an alert when the occupancy of location reaches its warning threshold, escalates it once the
+// location is over capacity, and resolves it when the occupancy drops below the threshold again
+func (j *occupancyJob) evaluate(ctx context.Context, location dbschema.Location, occupancy LocationOccupancy, now time.Time) error {
+	alert, err := j.queries.GetUnresolvedLocationAlert(ctx, dbschema.GetUnresolvedLocationAlertParams{
+		LocationID: pgtype.Int8{Int64: location.ID, Valid: true},
+		Kind:       alertKindLocationOccupancy,
+	})
+	open := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("error getting alert: %w", err)
+	}
+
+	var title, message string
+	switch occupancy.Level {
+	case occupancyLevelCritical:
+		title = fmt.Sprintf("Location %s is over capacity", location.Name)
+		message = fmt.Sprintf("location %q has %d people, its capacity is %d", location.Name, occupancy.Occupancy, location.Capacity.Int32)
+	case occupancyLevelWarning:
+		title = fmt.Sprintf("Location %s is almost full", location.Name)
+		message = fmt.Sprintf("location %q has %d people, the warning threshold is %d", location.Name, occupancy.Occupancy, location.WarningThreshold.Int32)
+	default:
+		if !open {
+			return nil
+		}
+		title = fmt.Sprintf("Location %s is below its warning threshold", location.Name)
+		message = fmt.Sprintf("location %q has %d people", location.Name, occupancy.Occupancy)
+		if alert, err = j.queries.ResolveAlert(ctx, alert.ID); err != nil {
+			return fmt.Errorf("error resolving alert: %w", err)
+		}
+		notifyAlert(ctx, j.notifier, alert, title, message, now, j.logger)
+		return nil
+	}
+
+	// the occupancy level is also the severity of the alert
+	severity := occupancy.Level
+	switch {
+	case !open:
+		alert, err = j.queries.CreateAlert(ctx, dbschema.CreateAlertParams{
+			Kind:       alertKindLocationOccupancy,
+			Severity:   severity,
+			LocationID: pgtype.Int8{Int64: location.ID, Valid: true},
+			Message:    message,
+		})
+		if err != nil {
+			return fmt.Errorf("error opening alert: %w", err)
+		}
+	case alert.Severity != severity:
+		alert, err = j.queries.UpdateAlertSeverity(ctx, dbschema.UpdateAlertSeverityParams{
+			ID:       alert.ID,
+			Severity: severity,
+			Message:  message,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating alert: %w", err)
+		}
+		// only escalations are worth a notification
+		if severity != occupancyLevelCritical {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	notifyAlert(ctx, j.notifier, alert, title, message, now, j.logger)
+	return nil
+}
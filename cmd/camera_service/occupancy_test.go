@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLocationOccupancy(t *testing.T) {
+	// detections made now are counted in the occupancy since midnight
+	now := time.Now().Format(time.RFC3339Nano)
+	detection := func(direction string) apiTest {
+		return apiTest{name: "create " + direction + " detection", method: http.MethodPost, path: "/personDetections",
+			body:   fmt.Sprintf(`{"camera_id": 1, "detection_date": %q, "target_direction": %q}`, now, direction),
+			status: http.StatusCreated}
+	}
+
+	runApiTests(t, []apiTest{
+		{name: "create building", method: http.MethodPost, path: "/locations", body: `{"name": "building", "capacity": 10}`,
+			status: http.StatusCreated},
+		{name: "create hall", method: http.MethodPost, path: "/locations",
+			body: `{"name": "hall", "parent_id": 1, "capacity": 3, "warning_threshold": 2}`, status: http.StatusCreated},
+		{name: "create office", method: http.MethodPost, path: "/locations", body: `{"name": "office", "parent_id": 1}`,
+			status: http.StatusCreated},
+		{name: "create hall door", method: http.MethodPost, path: "/cameras",
+			body: `{"name": "hall door", "connection_string": "rtsp://door", "location_id": 2, "orientation": "horizontal", ` +
+				`"entry_direction": "left"}`, status: http.StatusCreated},
+		{name: "empty", method: http.MethodGet, path: "/locations/2/occupancy", status: http.StatusOK,
+			response: `{"location_id": 2, "entries": 0, "exits": 0, "occupancy": 0, "level": "normal", "counted_location_ids": [2]}`},
+
+		detection("left"),
+		detection("left"),
+		detection("right"),
+		detection("none"),
+		{name: "entries and exits", method: http.MethodGet, path: "/locations/2/occupancy", status: http.StatusOK,
+			response: `{"location_id": 2, "entries": 2, "exits": 1, "occupancy": 1, "capacity": 3, "warning_threshold": 2, ` +
+				`"level": "normal", "counted_location_ids": [2]}`},
+		detection("left"),
+		{name: "warning threshold", method: http.MethodGet, path: "/locations/2/occupancy", status: http.StatusOK,
+			response: `{"occupancy": 2, "level": "warning"}`},
+		detection("left"),
+		detection("left"),
+		{name: "above capacity", method: http.MethodGet, path: "/locations/2/occupancy", status: http.StatusOK,
+			response: `{"entries": 5, "exits": 1, "occupancy": 4, "level": "critical"}`},
+
+		// a location without cameras adds up its children
+		{name: "parent", method: http.MethodGet, path: "/locations/1/occupancy", status: http.StatusOK,
+			response: `{"location_id": 1, "entries": 5, "exits": 1, "occupancy": 4, "capacity": 10, "level": "normal", ` +
+				`"counted_location_ids": [2]}`},
+		{name: "child without cameras", method: http.MethodGet, path: "/locations/3/occupancy", status: http.StatusOK,
+			response: `{"location_id": 3, "occupancy": 0, "counted_location_ids": []}`},
+
+		// people leaving that were never seen entering do not make the occupancy negative
+		detection("right"),
+		detection("right"),
+		detection("right"),
+		detection("right"),
+		detection("right"),
+		detection("right"),
+		{name: "more exits than entries", method: http.MethodGet, path: "/locations/2/occupancy", status: http.StatusOK,
+			response: `{"entries": 5, "exits": 7, "occupancy": 0, "level": "normal"}`},
+	})
+}
@@ -0,0 +1,713 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/bulkimport"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const apiVersion = "0.2.0"
+
+type apiParameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	// Example is a value of the type of the parameter, its schema is generated from it
+	Example any
+}
+
+// apiOperation documents a single route of the router. The openapi document is generated from apiOperations and
+// verifyApiDocumentation makes sure every registered route is listed there.
+type apiOperation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	// Parameters lists the query parameters, path parameters are derived from Path
+	Parameters []apiParameter
+	// Request is a value of the type of the json request body, nil if the operation does not take a body
+	Request any
+	// RequestContentType is the content type of a binary request body, used instead of Request
+	RequestContentType string
+	// Response is a value of the type of the json response body, nil if the operation does not return a body
+	Response       any
+	ResponseStatus int
+	// ContentType overrides the content type of the response, the response schema is omitted when set
+	ContentType string
+}
+
+var paginationParameters = []apiParameter{
+	{Name: "offset", In: "query", Description: "amount of detections to skip", Example: int32(0)},
+	{Name: "count", In: "query", Description: "maximum amount of detections to return", Required: true, Example: int32(0)},
+}
+
+// cameraSelectorParameters select cameras by tag and group, see cameraSelector
+var cameraSelectorParameters = []apiParameter{
+	{Name: "tag", In: "query", Description: "only include the cameras with this tag, given as key:value or as key to " +
+		"accept any value, can be repeated", Example: ""},
+	{Name: "group", In: "query", Description: "only include the cameras of this camera group, given by id or name, " +
+		"can be repeated", Example: ""},
+}
+
+// detectionFilterParameters filter the detections by the metadata reported with them, see detectionFilters
+var detectionFilterParameters = []apiParameter{
+	{Name: "track_id", In: "query", Description: "only include the detections of this track", Example: ""},
+	{Name: "min_confidence", In: "query", Description: "only include the detections with at least this confidence",
+		Example: float64(0)},
+	{Name: "model_version", In: "query", Description: "only include the detections of this model version", Example: ""},
+	{Name: "excluded", In: "query", Description: "only include the detections that are left out of the counts, or " +
+		"only the ones that are not", Example: false},
+	{Name: "after_id", In: "query", Description: "only include the detections with a greater id, that is the ones " +
+		"created after it, sorted by id instead of newest first so the last id of a page is the after_id of the next",
+		Example: int64(0)},
+}
+
+var idempotencyKeyParameter = apiParameter{Name: idempotencyKeyHeader, In: "header",
+	Description: "identifies the detection for its camera, a detection sent again with the same key within the " +
+		"idempotency window is not created again, the original one is returned with status 200 and an " +
+		idempotentReplayedHeader + " header", Example: ""}
+
+var locationDescendantsParameter = apiParameter{Name: "include_descendants", In: "query",
+	Description: "whether the descendants of the location are counted, true by default", Example: false}
+
+var includeDeletedParameter = apiParameter{Name: "include_deleted", In: "query",
+	Description: "whether soft deleted records are listed, false by default", Example: false}
+
+var hardDeleteParameter = apiParameter{Name: "hard", In: "query",
+	Description: "remove the record for good instead of marking it as deleted, false by default", Example: false}
+
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/openapi.json", Tag: "documentation", Summary: "Get this openapi document", ContentType: "application/json"},
+	{Method: "GET", Path: "/docs", Tag: "documentation", Summary: "Browse the api documentation", ContentType: "text/html"},
+	{Method: "GET", Path: "/docs/swagger-ui-bundle.js", Tag: "documentation", Summary: "Get the script of the api documentation",
+		ContentType: "text/javascript"},
+	{Method: "GET", Path: "/docs/swagger-ui.css", Tag: "documentation", Summary: "Get the styles of the api documentation",
+		ContentType: "text/css"},
+
+	{Method: "GET", Path: "/locations", Tag: "locations", Summary: "List all locations",
+		Parameters: []apiParameter{includeDeletedParameter}, Response: []dbschema.Location{}},
+	{Method: "POST", Path: "/locations", Tag: "locations", Summary: "Create a location",
+		Request: dbschema.CreateLocationParams{}, Response: dbschema.Location{}, ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/locations/{locationId}", Tag: "locations", Summary: "Get a location", Response: dbschema.Location{}},
+	{Method: "PATCH", Path: "/locations/{locationId}", Tag: "locations", Summary: "Update the given fields of a location",
+		Request: dbschema.UpdateLocationParams{}, Response: dbschema.Location{}},
+	{Method: "DELETE", Path: "/locations/{locationId}", Tag: "locations",
+		Summary: "Delete a location, it is hidden from the listings until restored and can only be deleted once its " +
+			"cameras and children are",
+		Parameters: []apiParameter{
+			hardDeleteParameter,
+			{Name: "history", In: "query", Description: "set to delete to forget that cameras were ever in the " +
+				"location on a hard delete, which fails while they have been otherwise. Their detections of that " +
+				"time are no longer counted towards any location", Example: ""},
+		}},
+	{Method: "POST", Path: "/locations/{locationId}/restore", Tag: "locations",
+		Summary: "Restore a deleted location, its parent must not be deleted", Response: dbschema.Location{}},
+	{Method: "GET", Path: "/locations/tree", Tag: "locations",
+		Summary:    "List the locations without a parent, each with all of its descendants",
+		Parameters: []apiParameter{includeDeletedParameter}, Response: []LocationTree{}},
+	{Method: "GET", Path: "/locations/{locationId}/children", Tag: "locations",
+		Summary:    "List the locations whose parent is the given location",
+		Parameters: []apiParameter{includeDeletedParameter}, Response: []dbschema.Location{}},
+	{Method: "GET", Path: "/locations/{locationId}/occupancy", Tag: "locations",
+		Summary: "Get how many people are in a location since midnight, counted by the cameras of the location with an " +
+			"entry direction, or added up from its children when it has none",
+		Response: LocationOccupancy{}},
+	{Method: "GET", Path: "/locations/{locationId}/dailyPersonDetectionsCount", Tag: "locations",
+		Summary: "Count the detections of the cameras of a location and of its descendants per day, counting back from " +
+			"today, with the ones normalized to in and out",
+		Parameters: []apiParameter{
+			{Name: "days", In: "query", Description: "amount of days to include", Example: int32(0)},
+			{Name: "months", In: "query", Description: "amount of months to include, added to days", Example: int32(0)},
+			locationDescendantsParameter,
+		},
+		Response: []dbschema.GetLocationDailyPersonDetectionsCountRow{}},
+	{Method: "GET", Path: "/locations/{locationId}/hourlyPersonDetectionsCount", Tag: "locations",
+		Summary: "Count the detections of the cameras of a location and of its descendants per hour, raw direction " +
+			"and normalized direction, hours without detections are omitted",
+		Parameters: []apiParameter{
+			{Name: "from", In: "query", Description: "start of the range, inclusive", Required: true, Example: time.Time{}},
+			{Name: "to", In: "query", Description: "end of the range, exclusive", Required: true, Example: time.Time{}},
+			locationDescendantsParameter,
+		},
+		Response: []dbschema.GetLocationHourlyPersonDetectionsCountRow{}},
+
+	{Method: "GET", Path: "/locations/{locationId}/map", Tag: "locations",
+		Summary:  "Get the floor plan of a location, where its cameras are placed and what they counted today",
+		Response: LocationMap{}},
+	{Method: "PUT", Path: "/locations/{locationId}/floorPlan", Tag: "locations",
+		Summary: "Upload the floor plan image of a location, a png, jpeg or gif image replacing the previous one",
+		Parameters: []apiParameter{
+			{Name: "width", In: "query", Description: "width of the floor covered by the image, keeps the aspect ratio of the image when only the height is given, the width of the image in pixels by default", Example: float64(0)},
+			{Name: "height", In: "query", Description: "height of the floor covered by the image, keeps the aspect ratio of the image when only the width is given, the height of the image in pixels by default", Example: float64(0)},
+			{Name: "unit", In: "query", Description: "unit of the width and height, m by default or px when neither is given", Example: ""},
+		},
+		RequestContentType: "image/*", Response: FloorPlanResponse{}},
+	{Method: "GET", Path: "/locations/{locationId}/floorPlan", Tag: "locations",
+		Summary: "Get the floor plan of a location", Response: FloorPlanResponse{}},
+	{Method: "PATCH", Path: "/locations/{locationId}/floorPlan", Tag: "locations",
+		Summary: "Update the dimensions of the floor plan of a location",
+		Request: dbschema.UpdateFloorPlanParams{}, Response: FloorPlanResponse{}},
+	{Method: "DELETE", Path: "/locations/{locationId}/floorPlan", Tag: "locations", Summary: "Delete the floor plan of a location"},
+	{Method: "GET", Path: "/locations/{locationId}/floorPlan/image", Tag: "locations",
+		Summary: "Get the floor plan image of a location", ContentType: "image/*"},
+
+	{Method: "GET", Path: "/cameras", Tag: "cameras", Summary: "List all cameras with their connectivity status",
+		Parameters: append([]apiParameter{
+			includeDeletedParameter,
+			{Name: "state", In: "query", Description: "only include the cameras in this lifecycle state", Example: ""},
+		}, cameraSelectorParameters...),
+		Response: []CameraWithStatus{}},
+	{Method: "POST", Path: "/cameras", Tag: "cameras",
+		Summary: "Create a camera. The entry direction is the direction of the people entering its location as seen " +
+			"by an upright camera, the detections of the camera are normalized to in or out from it and the " +
+			"orientation of the camera when they are stored",
+		Request: CreateCameraRequest{}, Response: CameraResponse{}, ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/cameras/dailyPersonDetectionsCount", Tag: "person detections",
+		Summary: "Count the detections of the selected cameras, or of every camera, per day, counting back from today, " +
+			"with the ones normalized to in and out",
+		Parameters: append([]apiParameter{
+			{Name: "days", In: "query", Description: "amount of days to include", Example: int32(0)},
+			{Name: "months", In: "query", Description: "amount of months to include, added to days", Example: int32(0)},
+		}, cameraSelectorParameters...),
+		Response: []dbschema.GetCamerasDailyPersonDetectionsCountRow{}},
+	{Method: "GET", Path: "/cameras/hourlyPersonDetectionsCount", Tag: "person detections",
+		Summary: "Count the detections of the selected cameras, or of every camera, per hour, raw direction and " +
+			"normalized direction, hours without detections are omitted",
+		Parameters: append([]apiParameter{
+			{Name: "from", In: "query", Description: "start of the range, inclusive", Required: true, Example: time.Time{}},
+			{Name: "to", In: "query", Description: "end of the range, exclusive", Required: true, Example: time.Time{}},
+		}, cameraSelectorParameters...),
+		Response: []dbschema.GetCamerasHourlyPersonDetectionsCountRow{}},
+	{Method: "GET", Path: "/cameras/{cameraId}", Tag: "cameras", Summary: "Get a camera", Response: CameraResponse{}},
+	{Method: "PATCH", Path: "/cameras/{cameraId}", Tag: "cameras",
+		Summary: "Update the given fields of a camera, a new orientation or entry direction only applies to the " +
+			"detections stored afterwards",
+		Request: UpdateCameraRequest{}, Response: CameraResponse{}},
+	{Method: "DELETE", Path: "/cameras/{cameraId}", Tag: "cameras",
+		Summary: "Delete a camera, it is hidden from the listings and its detections are rejected until restored",
+		Parameters: []apiParameter{
+			hardDeleteParameter,
+			{Name: "detections", In: "query", Description: "set to delete to remove the detections of the camera " +
+				"along with it on a hard delete, which fails while detections remain otherwise", Example: ""},
+			{Name: "reassign_to", In: "query", Description: "id of another camera that receives the detections of " +
+				"the camera on a hard delete", Example: int64(0)},
+		}},
+	{Method: "POST", Path: "/cameras/{cameraId}/restore", Tag: "cameras",
+		Summary: "Restore a deleted camera, its location must not be deleted", Response: CameraResponse{}},
+	{Method: "GET", Path: "/cameras/{cameraId}/status", Tag: "cameras",
+		Summary:  "Get the latest connectivity check of a camera, the status is unknown until it is checked",
+		Response: dbschema.CameraStatus{}},
+	{Method: "PUT", Path: "/cameras/{cameraId}/state", Tag: "cameras",
+		Summary: "Move a camera to another lifecycle state, active, maintenance, disabled or retired. Detections of " +
+			"disabled and retired cameras are rejected, those of cameras in maintenance are flagged, and only active " +
+			"cameras raise silence alerts",
+		Request: SetCameraStateRequest{}, Response: CameraResponse{}},
+	{Method: "GET", Path: "/cameras/{cameraId}/stateHistory", Tag: "cameras",
+		Summary: "List the state changes of a camera, oldest first", Response: []dbschema.CameraStateChange{}},
+	{Method: "GET", Path: "/cameras/{cameraId}/locationHistory", Tag: "cameras",
+		Summary: "List the locations a camera has been in, oldest first. The first one starts at -infinity and the " +
+			"current one has no end. Location aggregates count every detection towards the location its camera was " +
+			"in when it was made",
+		Response: []dbschema.CameraLocationAssignment{}},
+	{Method: "GET", Path: "/cameras/{cameraId}/maintenanceWindows", Tag: "cameras",
+		Summary: "List the scheduled maintenance of a camera", Response: []dbschema.MaintenanceWindow{}},
+	{Method: "POST", Path: "/cameras/{cameraId}/maintenanceWindows", Tag: "cameras",
+		Summary: "Schedule the maintenance of a camera. Detections dated within the window are rejected, or flagged " +
+			"with ingest set to flag, and silence alerts are not raised while it is in progress. The camera id of " +
+			"the body is ignored",
+		Request: dbschema.CreateMaintenanceWindowParams{}, Response: dbschema.MaintenanceWindow{},
+		ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/cameras/{cameraId}/maintenanceWindows/{maintenanceWindowId}", Tag: "cameras",
+		Summary: "Get a maintenance window", Response: dbschema.MaintenanceWindow{}},
+	{Method: "PATCH", Path: "/cameras/{cameraId}/maintenanceWindows/{maintenanceWindowId}", Tag: "cameras",
+		Summary: "Update the given fields of a maintenance window", Request: dbschema.UpdateMaintenanceWindowParams{},
+		Response: dbschema.MaintenanceWindow{}},
+	{Method: "DELETE", Path: "/cameras/{cameraId}/maintenanceWindows/{maintenanceWindowId}", Tag: "cameras",
+		Summary: "Delete a maintenance window"},
+	{Method: "GET", Path: "/cameras/{cameraId}/activityRules", Tag: "alerts",
+		Summary: "List the activity expected from a camera", Response: []dbschema.ActivityRule{}},
+	{Method: "POST", Path: "/cameras/{cameraId}/activityRules", Tag: "alerts",
+		Summary: "Expect at least min_detections detections every window_minutes on the given weekdays (0 is sunday) " +
+			"between active_from and active_to, alerting when the camera goes silent. Only window_minutes is required, " +
+			"the camera id of the body is ignored",
+		Request: dbschema.CreateActivityRuleParams{}, Response: dbschema.ActivityRule{}, ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/cameras/{cameraId}/activityRules/{activityRuleId}", Tag: "alerts",
+		Summary: "Get an activity rule", Response: dbschema.ActivityRule{}},
+	{Method: "PATCH", Path: "/cameras/{cameraId}/activityRules/{activityRuleId}", Tag: "alerts",
+		Summary: "Update the given fields of an activity rule", Request: dbschema.UpdateActivityRuleParams{},
+		Response: dbschema.ActivityRule{}},
+	{Method: "DELETE", Path: "/cameras/{cameraId}/activityRules/{activityRuleId}", Tag: "alerts",
+		Summary: "Delete an activity rule along with its alerts"},
+	{Method: "GET", Path: "/cameras/{cameraId}/personDetections", Tag: "person detections",
+		Summary:    "List the detections of a camera, newest first",
+		Parameters: append(append([]apiParameter{}, paginationParameters...), detectionFilterParameters...),
+		Response:   []dbschema.PersonDetection{}},
+	{Method: "POST", Path: "/cameras/{cameraId}/personDetections", Tag: "person detections",
+		Summary: "Create a detection for a camera, the camera id of the body is ignored. Detections with a confidence " +
+			"below the configured minimum are stored but excluded from the counts",
+		Parameters: []apiParameter{idempotencyKeyParameter}, Request: CreatePersonDetectionRequest{},
+		Response: dbschema.PersonDetection{}, ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/cameras/{cameraId}/dailyPersonDetectionsCount", Tag: "person detections",
+		Summary: "Count the detections of a camera per day, counting back from today, with the ones normalized to in and " +
+			"out",
+		Parameters: []apiParameter{
+			{Name: "days", In: "query", Description: "amount of days to include", Example: int32(0)},
+			{Name: "months", In: "query", Description: "amount of months to include, added to days", Example: int32(0)},
+		},
+		Response: []dbschema.GetDailyPersonDetectionsCountRow{}},
+	{Method: "GET", Path: "/cameras/{cameraId}/hourlyPersonDetectionsCount", Tag: "person detections",
+		Summary: "Count the detections of a camera per hour, raw direction and normalized direction, hours without " +
+			"detections are omitted",
+		Parameters: []apiParameter{
+			{Name: "from", In: "query", Description: "start of the range, inclusive", Required: true, Example: time.Time{}},
+			{Name: "to", In: "query", Description: "end of the range, exclusive", Required: true, Example: time.Time{}},
+		},
+		Response: []dbschema.GetHourlyPersonDetectionsCountRow{}},
+
+	{Method: "GET", Path: "/cameraGroups", Tag: "camera groups", Summary: "List all camera groups with the ids of their cameras",
+		Response: []CameraGroupResponse{}},
+	{Method: "POST", Path: "/cameraGroups", Tag: "camera groups", Summary: "Create a camera group, optionally with its cameras",
+		Request: CreateCameraGroupRequest{}, Response: CameraGroupResponse{}, ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/cameraGroups/{groupId}", Tag: "camera groups", Summary: "Get a camera group",
+		Response: CameraGroupResponse{}},
+	{Method: "PATCH", Path: "/cameraGroups/{groupId}", Tag: "camera groups", Summary: "Update the given fields of a camera group",
+		Request: dbschema.UpdateCameraGroupParams{}, Response: CameraGroupResponse{}},
+	{Method: "DELETE", Path: "/cameraGroups/{groupId}", Tag: "camera groups",
+		Summary: "Delete a camera group, its cameras are kept"},
+	{Method: "GET", Path: "/cameraGroups/{groupId}/cameras", Tag: "camera groups", Summary: "List the cameras of a group",
+		Response: []CameraResponse{}},
+	{Method: "PUT", Path: "/cameraGroups/{groupId}/cameras/{cameraId}", Tag: "camera groups",
+		Summary: "Add a camera to a group, nothing changes if it already belongs to it"},
+	{Method: "DELETE", Path: "/cameraGroups/{groupId}/cameras/{cameraId}", Tag: "camera groups",
+		Summary: "Remove a camera from a group"},
+	{Method: "GET", Path: "/cameraGroups/{groupId}/dailyPersonDetectionsCount", Tag: "camera groups",
+		Summary: "Count the detections of the cameras of a group per day, counting back from today, with the ones " +
+			"normalized to in and out",
+		Parameters: []apiParameter{
+			{Name: "days", In: "query", Description: "amount of days to include", Example: int32(0)},
+			{Name: "months", In: "query", Description: "amount of months to include, added to days", Example: int32(0)},
+		},
+		Response: []dbschema.GetCamerasDailyPersonDetectionsCountRow{}},
+	{Method: "GET", Path: "/cameraGroups/{groupId}/hourlyPersonDetectionsCount", Tag: "camera groups",
+		Summary: "Count the detections of the cameras of a group per hour, raw direction and normalized direction, " +
+			"hours without detections are omitted",
+		Parameters: []apiParameter{
+			{Name: "from", In: "query", Description: "start of the range, inclusive", Required: true, Example: time.Time{}},
+			{Name: "to", In: "query", Description: "end of the range, exclusive", Required: true, Example: time.Time{}},
+		},
+		Response: []dbschema.GetCamerasHourlyPersonDetectionsCountRow{}},
+
+	{Method: "GET", Path: "/personDetections", Tag: "person detections",
+		Summary: "List the detections of all cameras, or of the selected cameras, newest first",
+		Parameters: append(append(append([]apiParameter{}, paginationParameters...), cameraSelectorParameters...),
+			detectionFilterParameters...),
+		Response: []dbschema.PersonDetection{}},
+	{Method: "POST", Path: "/personDetections", Tag: "person detections",
+		Summary: "Create a detection. Detections with a confidence below the configured minimum are stored but " +
+			"excluded from the counts",
+		Parameters: []apiParameter{idempotencyKeyParameter}, Request: CreatePersonDetectionRequest{},
+		Response: dbschema.PersonDetection{}, ResponseStatus: http.StatusCreated},
+	{Method: "GET", Path: "/personDetections/export", Tag: "person detections",
+		Summary: "Stream the detections of all cameras as csv or newline delimited json, oldest first, with the camera and location names",
+		Parameters: append([]apiParameter{
+			{Name: "format", In: "query", Description: "csv, the default, or ndjson", Example: ""},
+			{Name: "from", In: "query", Description: "start of the range, inclusive", Example: time.Time{}},
+			{Name: "to", In: "query", Description: "end of the range, exclusive", Example: time.Time{}},
+			{Name: "camera_id", In: "query", Description: "only export the detections of this camera", Example: int64(0)},
+			{Name: "location_id", In: "query", Description: "only export the detections of the cameras of this location and of its descendants", Example: int64(0)},
+		}, cameraSelectorParameters...),
+		ContentType: "text/csv"},
+	{Method: "GET", Path: "/personDetections/latest", Tag: "person detections",
+		Summary:  "Get the detection with the greatest id, that is the last one created, 404 when there are none",
+		Response: dbschema.PersonDetection{}},
+	{Method: "GET", Path: "/personDetections/{personDetectionId}", Tag: "person detections", Summary: "Get a detection",
+		Response: dbschema.PersonDetection{}},
+	{Method: "PATCH", Path: "/personDetections/{personDetectionId}", Tag: "person detections",
+		Summary: "Update the given fields of a detection. Detections with a confidence below the configured minimum " +
+			"are excluded from the counts",
+		Request:  UpdatePersonDetectionRequest{},
+		Response: dbschema.PersonDetection{}},
+	{Method: "DELETE", Path: "/personDetections/{personDetectionId}", Tag: "person detections", Summary: "Delete a detection"},
+
+	{Method: "GET", Path: "/alerts", Tag: "alerts", Summary: "List alerts, the most recently opened first",
+		Parameters: []apiParameter{
+			{Name: "state", In: "query", Description: "only list alerts in this state, open, acknowledged or resolved", Example: ""},
+			{Name: "kind", In: "query", Description: "only list alerts of this kind, camera_silent or location_occupancy", Example: ""},
+			{Name: "severity", In: "query", Description: "only list alerts of this severity, warning or critical", Example: ""},
+			{Name: "camera_id", In: "query", Description: "only list the alerts of this camera", Example: int64(0)},
+			{Name: "location_id", In: "query", Description: "only list the alerts of this location", Example: int64(0)},
+			{Name: "offset", In: "query", Description: "amount of alerts to skip", Example: int32(0)},
+			{Name: "count", In: "query", Description: "maximum amount of alerts to return, 100 by default", Example: int32(0)},
+		},
+		Response: []dbschema.Alert{}},
+	{Method: "GET", Path: "/alerts/{alertId}", Tag: "alerts", Summary: "Get an alert", Response: dbschema.Alert{}},
+	{Method: "POST", Path: "/alerts/{alertId}/acknowledge", Tag: "alerts",
+		Summary: "Acknowledge an open alert, it stays acknowledged until it is resolved or escalated, the body is optional",
+		Request: AcknowledgeAlertRequest{}, Response: dbschema.Alert{}},
+
+	{Method: "GET", Path: "/notifications/channels", Tag: "notifications",
+		Summary: "List the configured notification channels", Response: []notify.Channel{}},
+	{Method: "POST", Path: "/notifications/test", Tag: "notifications",
+		Summary: "Send a test notification through a channel, or through every channel when none is given, " +
+			"skipping routing, deduplication and rate limiting. Answers 502 when a channel fails, the body is optional",
+		Request: TestNotificationRequest{}, Response: []TestNotificationResult{}},
+
+	{Method: "POST", Path: "/import/locations", Tag: "import",
+		Summary:    "Import locations from a json array or a csv file with the same columns, names must be unique",
+		Parameters: importParameters, Request: []bulkimport.LocationRow{}, Response: bulkimport.Result{}},
+	{Method: "POST", Path: "/import/cameras", Tag: "import",
+		Summary:    "Import cameras from a json array or a csv file with the same columns, locations are resolved by name",
+		Parameters: importParameters, Request: []bulkimport.CameraRow{}, Response: bulkimport.Result{}},
+	{Method: "POST", Path: "/import/personDetections", Tag: "import",
+		Summary:    "Import historical detections from a json array or a csv file with the same columns, cameras are resolved by name",
+		Parameters: importParameters, Request: []bulkimport.PersonDetectionRow{}, Response: bulkimport.Result{}},
+}
+
+var importParameters = []apiParameter{
+	{Name: "format", In: "query", Description: "csv or json, taken from the content type if empty", Example: ""},
+	{Name: "dry_run", In: "query", Description: "only validate the rows, nothing is created", Example: false},
+}
+
+// schemaOverrides contains the schemas of types that are not serialized according to their go structure
+var schemaOverrides = map[reflect.Type]map[string]any{
+	reflect.TypeOf(pgtype.Text{}):        {"type": "string", "nullable": true},
+	reflect.TypeOf(pgtype.Int4{}):        {"type": "integer", "format": "int32", "nullable": true},
+	reflect.TypeOf(pgtype.Int8{}):        {"type": "integer", "format": "int64", "nullable": true},
+	reflect.TypeOf(pgtype.Float8{}):      {"type": "number", "format": "double", "nullable": true},
+	reflect.TypeOf(pgtype.Bool{}):        {"type": "boolean", "nullable": true},
+	reflect.TypeOf(pgtype.Date{}):        {"type": "string", "format": "date", "nullable": true},
+	reflect.TypeOf(pgtype.Timestamptz{}): {"type": "string", "format": "date-time", "nullable": true},
+	reflect.TypeOf(pgtype.UUID{}):        {"type": "string", "format": "uuid", "nullable": true},
+	reflect.TypeOf(time.Time{}):          {"type": "string", "format": "date-time"},
+	reflect.TypeOf(json.RawMessage{}):    {},
+
+	reflect.TypeOf(dbenums.Orientation("")): {"type": "string", "enum": []string{
+		string(dbenums.CameraOrientationVertical), string(dbenums.CameraOrientationHorizontal),
+		string(dbenums.CameraOrientationInvertedVertical), string(dbenums.CameraOrientationInvertedHorizontal)}},
+	reflect.TypeOf(dbenums.NullOrientation{}): {"type": "string", "nullable": true, "enum": []any{
+		string(dbenums.CameraOrientationVertical), string(dbenums.CameraOrientationHorizontal),
+		string(dbenums.CameraOrientationInvertedVertical), string(dbenums.CameraOrientationInvertedHorizontal), nil}},
+	reflect.TypeOf(dbenums.Direction("")): {"type": "string", "enum": []string{
+		string(dbenums.DirectionLeft), string(dbenums.DirectionRight), string(dbenums.DirectionNone)}},
+	reflect.TypeOf(dbenums.NullDirection{}): {"type": "string", "nullable": true, "enum": []any{
+		string(dbenums.DirectionLeft), string(dbenums.DirectionRight), string(dbenums.DirectionNone), nil}},
+}
+
+// schemaGenerator builds openapi schemas from go types, collecting named structs as reusable components
+type schemaGenerator struct {
+	components map[string]any
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	if override, ok := schemaOverrides[t]; ok {
+		return override
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := map[string]any{}
+		for k, v := range g.schemaFor(t.Elem()) {
+			schema[k] = v
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			// reserve the name first, in case the struct refers to itself
+			g.components[t.Name()] = nil
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := g.structSchema(field.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			if embeddedRequired, ok := embedded["required"].([]string); ok {
+				required = append(required, embeddedRequired...)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.schemaFor(field.Type)
+		properties[name] = schema
+		if schema["nullable"] != true && !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+var pathParameterRegexp = regexp.MustCompile(`{([^}]+)}`)
+
+// buildOpenApiDocument generates the openapi document of every operation in apiOperations
+func buildOpenApiDocument() map[string]any {
+	generator := schemaGenerator{components: map[string]any{}}
+	paths := map[string]map[string]any{}
+
+	for _, op := range apiOperations {
+		var parameters []any
+		for _, match := range pathParameterRegexp.FindAllStringSubmatch(op.Path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "integer", "format": "int64"},
+			})
+		}
+		for _, param := range op.Parameters {
+			parameters = append(parameters, map[string]any{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.Required,
+				"schema":      generator.schemaFor(reflect.TypeOf(param.Example)),
+			})
+		}
+
+		status := op.ResponseStatus
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := map[string]any{"description": http.StatusText(status)}
+		if op.ContentType != "" {
+			response["content"] = map[string]any{op.ContentType: map[string]any{}}
+		} else if op.Response != nil {
+			response["content"] = map[string]any{
+				"application/json": map[string]any{"schema": generator.schemaFor(reflect.TypeOf(op.Response))},
+			}
+		}
+
+		operation := map[string]any{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationId(op),
+			"responses": map[string]any{
+				fmt.Sprint(status): response,
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+				},
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.RequestContentType != "" {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					op.RequestContentType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+				},
+			}
+		} else if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": generator.schemaFor(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]any{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "camera_service",
+			"description": "Stores the cameras of the smart factory, their locations and the people they detect",
+			"version":     apiVersion,
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": generator.components},
+	}
+}
+
+// operationId derives an id from the method and path of the operation, like get_cameras_cameraId
+func operationId(op apiOperation) string {
+	path := strings.NewReplacer("{", "", "}", "", ".", "_").Replace(op.Path)
+	return strings.ToLower(op.Method) + strings.ReplaceAll(path, "/", "_")
+}
+
+// normalizeRoutePattern turns a chi route pattern into the form used by apiOperations
+func normalizeRoutePattern(route string) string {
+	route = strings.ReplaceAll(route, "/*/", "/")
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// verifyApiDocumentation returns an error listing every route registered in r that is missing from apiOperations,
+// and every operation in apiOperations that does not exist in r
+func verifyApiDocumentation(r chi.Routes) error {
+	documented := map[string]bool{}
+	for _, op := range apiOperations {
+		documented[op.Method+" "+op.Path] = false
+	}
+
+	var undocumented []string
+	err := chi.Walk(r, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		key := method + " " + normalizeRoutePattern(route)
+		if _, ok := documented[key]; ok {
+			documented[key] = true
+		} else {
+			undocumented = append(undocumented, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking routes: %w", err)
+	}
+
+	var missing []string
+	for key, found := range documented {
+		if !found {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(undocumented) == 0 && len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(undocumented)
+	sort.Strings(missing)
+	return fmt.Errorf("the openapi document is out of sync with the router, undocumented routes: %v, documented routes that do not exist: %v",
+		undocumented, missing)
+}
+
+func getOpenApiDocument(logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getOpenApiDocument")
+
+	body, err := json.Marshal(buildOpenApiDocument())
+	if err != nil {
+		logger.Fatalf("error marshaling openapi document: %s", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// docsAssets holds the swagger ui dist (v5.29.1, apache 2.0 licensed), served from /docs so that the documentation
+// works without access to a cdn
+//
+//go:embed docs
+var docsAssets embed.FS
+
+const apiDocsPage = `<!DOCTYPE html>
+<html>
+<head>
+    <title>camera_service api</title>
+    <meta charset="utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="docs/swagger-ui.css"/>
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="docs/swagger-ui-bundle.js"></script>
+<script>
+    window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`
+
+func getApiDocs(logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getApiDocs")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(apiDocsPage)); err != nil {
+			logger.Errorf("error writing html body: %s", err)
+		}
+	}
+}
+
+// getApiDocsAssets serves the scripts and styles the documentation page loads
+func getApiDocsAssets() http.Handler {
+	return http.FileServer(http.FS(docsAssets))
+}
+
+// printOpenApi verifies the api documentation and prints the openapi document to stdout
+func printOpenApi(logger *zap.SugaredLogger) {
+	logger = logger.Named("openapi")
+
+	if err := verifyApiDocumentation(newRouter(Config{}, nil, nil, nil, logger)); err != nil {
+		logger.Fatal(err)
+	}
+
+	printJson(buildOpenApiDocument(), logger)
+}
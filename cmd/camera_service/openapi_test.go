@@ -0,0 +1,28 @@
+package main
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApiDocumentationCoversRouter(t *testing.T) {
+	if err := verifyApiDocumentation(newRouter(Config{}, nil, nil, nil, zap.NewNop().Sugar())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApiDocsAreServedLocally(t *testing.T) {
+	router := newRouter(Config{}, nil, nil, nil, zap.NewNop().Sugar())
+
+	for _, path := range []string{"/docs", "/docs/swagger-ui-bundle.js", "/docs/swagger-ui.css"} {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != http.StatusOK {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusOK, res.Code)
+		} else if res.Body.Len() == 0 {
+			t.Errorf("GET %s: empty body", path)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"time"
+)
+
+type PartitionsConfig struct {
+	// MonthsAhead is how many months after the current one get their person detections partition in advance
+	MonthsAhead int           `mapstructure:"months_ahead"`
+	Interval    time.Duration `mapstructure:"interval"`
+}
+
+type partitionMaintenanceJob struct {
+	config  PartitionsConfig
+	queries *dbschema.Queries
+	logger  *zap.SugaredLogger
+}
+
+func newPartitionMaintenanceJob(config PartitionsConfig, queries *dbschema.Queries, logger *zap.SugaredLogger) *partitionMaintenanceJob {
+	return &partitionMaintenanceJob{config: config, queries: queries, logger: logger.Named("partitions")}
+}
+
+// run creates the upcoming partitions every configured interval until ctx is done
+func (j *partitionMaintenanceJob) run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.createUpcomingPartitions(ctx); err != nil {
+			j.logger.Errorf("error creating partitions: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// createUpcomingPartitions makes sure the partitions of the current month and the configured months ahead exist,
+// so detections never land in the default partition
+func (j *partitionMaintenanceJob) createUpcomingPartitions(ctx context.Context) error {
+	year, month, _ := time.Now().Date()
+	current := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= j.config.MonthsAhead; i++ {
+		month := current.AddDate(0, i, 0)
+		if err := j.queries.CreatePersonDetectionsPartition(ctx, pgtype.Date{Time: month, Valid: true}); err != nil {
+			return fmt.Errorf("error creating partition for %s: %w", month.Format("2006-01"), err)
+		}
+	}
+
+	j.logger.Debugw("person detections partitions are ready", "until", current.AddDate(0, j.config.MonthsAhead, 0).Format("2006-01"))
+	return nil
+}
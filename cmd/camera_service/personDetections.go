@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
 	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -14,9 +17,116 @@ import (
 	"net/http"
 	"path"
 	"strconv"
+	"time"
 )
 
-func personDetectionCtx(queries *dbschema.Queries, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
+type DetectionsConfig struct {
+	// detections reported with a confidence below it are stored but left out of every count
+	MinConfidence float64 `mapstructure:"min_confidence"`
+	// how long the Idempotency-Key of a detection is remembered
+	IdempotencyWindow time.Duration `mapstructure:"idempotency_window"`
+}
+
+type (
+	// CreatePersonDetectionRequest is a detection as reported by a detector. Whether it is flagged or excluded is not
+	// up to the client, it is decided from the minimum confidence and the maintenance of its camera
+	CreatePersonDetectionRequest struct {
+		CameraID        int64              `json:"camera_id"`
+		DetectionDate   pgtype.Timestamptz `json:"detection_date"`
+		TargetDirection dbenums.Direction  `json:"target_direction"`
+		TrackID         pgtype.Text        `json:"track_id"`
+		Confidence      *float64           `json:"confidence"`
+		BboxX           *float64           `json:"bbox_x"`
+		BboxY           *float64           `json:"bbox_y"`
+		BboxWidth       *float64           `json:"bbox_width"`
+		BboxHeight      *float64           `json:"bbox_height"`
+		FrameDate       pgtype.Timestamptz `json:"frame_date"`
+		ModelVersion    pgtype.Text        `json:"model_version"`
+	}
+	// UpdatePersonDetectionRequest are the fields of a detection that can be corrected, the ones left out are kept
+	UpdatePersonDetectionRequest struct {
+		CameraID        pgtype.Int8           `json:"camera_id"`
+		DetectionDate   pgtype.Timestamptz    `json:"detection_date"`
+		TargetDirection dbenums.NullDirection `json:"target_direction"`
+	}
+)
+
+func (r CreatePersonDetectionRequest) params() dbschema.CreatePersonDetectionParams {
+	return dbschema.CreatePersonDetectionParams{
+		CameraID:        r.CameraID,
+		DetectionDate:   r.DetectionDate,
+		TargetDirection: r.TargetDirection,
+		TrackID:         r.TrackID,
+		Confidence:      r.Confidence,
+		BboxX:           r.BboxX,
+		BboxY:           r.BboxY,
+		BboxWidth:       r.BboxWidth,
+		BboxHeight:      r.BboxHeight,
+		FrameDate:       r.FrameDate,
+		ModelVersion:    r.ModelVersion,
+	}
+}
+
+// checkDetectionMetadata reports the metadata of a new detection that the database would reject
+func checkDetectionMetadata(params dbschema.CreatePersonDetectionParams) error {
+	if params.Confidence != nil && (*params.Confidence < 0 || *params.Confidence > 1) {
+		return errors.New("confidence must be between 0 and 1")
+	}
+	bbox := []*float64{params.BboxX, params.BboxY, params.BboxWidth, params.BboxHeight}
+	for _, value := range bbox {
+		if (value == nil) != (params.BboxX == nil) {
+			return errors.New("bbox_x, bbox_y, bbox_width and bbox_height must be given together")
+		}
+	}
+	if params.BboxWidth != nil && (*params.BboxWidth <= 0 || *params.BboxHeight <= 0) {
+		return errors.New("bbox_width and bbox_height must be positive")
+	}
+	return nil
+}
+
+// detectionFilters are the optional filters of the detection listings
+type detectionFilters struct {
+	trackId       pgtype.Text
+	minConfidence pgtype.Float8
+	modelVersion  pgtype.Text
+	excluded      pgtype.Bool
+	afterId       pgtype.Int8
+}
+
+func parseDetectionFilters(r *http.Request) (detectionFilters, error) {
+	query := r.URL.Query()
+	var filters detectionFilters
+	if query.Has("track_id") {
+		filters.trackId = pgtype.Text{String: query.Get("track_id"), Valid: true}
+	}
+	if query.Has("min_confidence") {
+		minConfidence, err := strconv.ParseFloat(query.Get("min_confidence"), 64)
+		if err != nil {
+			return detectionFilters{}, fmt.Errorf("invalid min_confidence parameter: %w", err)
+		}
+		filters.minConfidence = pgtype.Float8{Float64: minConfidence, Valid: true}
+	}
+	if query.Has("model_version") {
+		filters.modelVersion = pgtype.Text{String: query.Get("model_version"), Valid: true}
+	}
+	if query.Has("excluded") {
+		excluded, err := strconv.ParseBool(query.Get("excluded"))
+		if err != nil {
+			return detectionFilters{}, fmt.Errorf("invalid excluded parameter: %w", err)
+		}
+		filters.excluded = pgtype.Bool{Bool: excluded, Valid: true}
+	}
+	if query.Has("after_id") {
+		afterId, err := strconv.ParseInt(query.Get("after_id"), 10, 64)
+		if err != nil {
+			return detectionFilters{}, fmt.Errorf("invalid after_id parameter: %w", err)
+		}
+		filters.afterId = pgtype.Int8{Int64: afterId, Valid: true}
+	}
+	return filters, nil
+}
+
+func personDetectionCtx(queries store.Store, logger *zap.SugaredLogger) func(next http.Handler) http.Handler {
 	logger = logger.Named("personDetectionCtx")
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +160,7 @@ func personDetectionCtx(queries *dbschema.Queries, logger *zap.SugaredLogger) fu
 	}
 }
 
-func getPersonDetections(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func getPersonDetections(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("getPersonDetections")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -67,11 +177,31 @@ func getPersonDetections(queries *dbschema.Queries, logger *zap.SugaredLogger) h
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		filters, err := parseDetectionFilters(r)
+		if err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		params := dbschema.GetPersonDetectionsParams{
+			TrackID:         filters.trackId,
+			MinConfidence:   filters.minConfidence,
+			ModelVersion:    filters.modelVersion,
+			Excluded:        filters.excluded,
+			AfterID:         filters.afterId,
 			DetectionOffset: int32(offset),
 			Count:           int32(count),
 		}
 
+		selector, err := parseCameraSelector(r)
+		if err == nil && !selector.empty() {
+			params.CameraIds, err = selector.cameraIds(ctx, queries)
+		}
+		if err != nil {
+			handleCameraSelectorError(w, r, err, logger)
+			return
+		}
+
 		personDetections, err := queries.GetPersonDetections(ctx, params)
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -99,6 +229,43 @@ func getPersonDetections(queries *dbschema.Queries, logger *zap.SugaredLogger) h
 	}
 }
 
+// getLatestPersonDetection responds with the detection with the greatest id, clients streaming new detections with
+// after_id start from it
+func getLatestPersonDetection(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getLatestPersonDetection")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		personDetection, err := queries.GetLatestPersonDetection(ctx)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "there are no person detections", http.StatusNotFound)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting latest person detection: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(personDetection)
+		if err != nil {
+			err := fmt.Errorf("error marshaling json body: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
 func getPersonDetection(logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("GetPersonDetectionHandler")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -120,53 +287,111 @@ func getPersonDetection(logger *zap.SugaredLogger) http.HandlerFunc {
 	}
 }
 
-func postPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func postPersonDetection(config DetectionsConfig, queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("postPersonDetection")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		dec := json.NewDecoder(r.Body)
 
-		var params dbschema.CreatePersonDetectionParams
-		if err := dec.Decode(&params); err != nil {
+		var request CreatePersonDetectionRequest
+		if err := dec.Decode(&request); err != nil {
 			err := fmt.Errorf("error decoding request body: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		params := request.params()
 
-		personDetection, err := queries.CreatePersonDetection(ctx, params)
-
-		var pqErr *pgconn.PgError
-		if errors.As(err, &pqErr) {
-			HandlePqError(w, r, pqErr, logger)
+		key, err := parseIdempotencyKey(r)
+		if err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
-		} else if err != nil {
-			err = fmt.Errorf("error creating person detection: %w", err)
+		}
+		if personDetection, replayed, err := replayPersonDetection(ctx, queries, config, params.CameraID, key); err != nil {
+			err = fmt.Errorf("error getting replayed person detection: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
+		} else if replayed {
+			writeCreatedPersonDetection(w, r, personDetection, true, logger)
+			return
 		}
 
-		body, err := json.Marshal(personDetection)
-		if err != nil {
-			err = fmt.Errorf("error marshaling person detection: %w", err)
+		if err := checkDetectionMetadata(params); err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.Excluded = params.Confidence != nil && *params.Confidence < config.MinConfidence
+
+		// unknown cameras are left for the foreign key to report
+		camera, err := queries.GetCamera(ctx, params.CameraID)
+		if err == nil {
+			var flagged bool
+			flagged, err = checkCameraAcceptsDetections(ctx, queries, camera, params.DetectionDate.Time)
+			params.Flagged = flagged
+		} else if errors.Is(err, pgx.ErrNoRows) {
+			err = nil
+		}
+		if errors.Is(err, errDetectionRejected) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error checking camera: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Add("Location", path.Join(r.URL.String(), fmt.Sprintf("/%d", personDetection.ID)))
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		if _, err = w.Write(body); err != nil {
-			err = fmt.Errorf("error writing body: %w", err)
+		personDetection, replayed, err := createPersonDetection(ctx, queries, config, key, params)
+
+		var pqErr *pgconn.PgError
+		if errors.As(err, &pqErr) {
+			HandlePqError(w, r, pqErr, logger)
+			return
+		} else if errors.Is(err, errIdempotencyKeyInUse) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error creating person detection: %w", err)
 			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		writeCreatedPersonDetection(w, r, personDetection, replayed, logger)
+	}
+}
+
+// writeCreatedPersonDetection responds with a created detection, or with the one created before by a request with
+// the same Idempotency-Key when replayed
+func writeCreatedPersonDetection(w http.ResponseWriter, r *http.Request, personDetection dbschema.PersonDetection, replayed bool, logger *zap.SugaredLogger) {
+	body, err := json.Marshal(personDetection)
+	if err != nil {
+		err = fmt.Errorf("error marshaling person detection: %w", err)
+		logger.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusCreated
+	if replayed {
+		status = http.StatusOK
+		w.Header().Add(idempotentReplayedHeader, "true")
+	}
+
+	w.Header().Add("Location", path.Join(r.URL.String(), fmt.Sprintf("/%d", personDetection.ID)))
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err = w.Write(body); err != nil {
+		err = fmt.Errorf("error writing body: %w", err)
+		logger.Error(err)
 	}
 }
 
-func getDailyPersonDetectionsCount(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func getDailyPersonDetectionsCount(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("getDailyPersonDetectionsCount")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -223,7 +448,7 @@ func getDailyPersonDetectionsCount(queries *dbschema.Queries, logger *zap.Sugare
 	}
 }
 
-func patchPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func patchPersonDetection(config DetectionsConfig, queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("UpdatePersonDetection")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -231,16 +456,23 @@ func patchPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger)
 
 		dec := json.NewDecoder(r.Body)
 
-		var params dbschema.UpdatePersonDetectionParams
+		var request UpdatePersonDetectionRequest
 
-		if err := dec.Decode(&params); err != nil {
+		if err := dec.Decode(&request); err != nil {
 			err = fmt.Errorf("invalid request body: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		params.ID = personDetection.ID
+		params := dbschema.UpdatePersonDetectionParams{
+			ID:              personDetection.ID,
+			CameraID:        request.CameraID,
+			DetectionDate:   request.DetectionDate,
+			TargetDirection: request.TargetDirection,
+			// moved detections are held to the minimum confidence in effect now
+			MinConfidence: config.MinConfidence,
+		}
 
 		personDetection, err := queries.UpdatePersonDetection(ctx, params)
 		var pgErr *pgconn.PgError
@@ -269,7 +501,7 @@ func patchPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger)
 	}
 }
 
-func deletePersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func deletePersonDetection(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("DeletePersonDetection")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -292,7 +524,7 @@ func deletePersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger)
 	}
 }
 
-func getCameraPersonDetections(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func getCameraPersonDetections(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("GetPersonDetectionsByCamera")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -312,8 +544,19 @@ func getCameraPersonDetections(queries *dbschema.Queries, logger *zap.SugaredLog
 			return
 		}
 
+		filters, err := parseDetectionFilters(r)
+		if err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		params := dbschema.GetPersonDetectionsForCameraParams{
 			CameraID:        camera.ID,
+			TrackID:         filters.trackId,
+			MinConfidence:   filters.minConfidence,
+			ModelVersion:    filters.modelVersion,
+			Excluded:        filters.excluded,
+			AfterID:         filters.afterId,
 			DetectionOffset: int32(offset),
 			Count:           int32(count),
 		}
@@ -344,7 +587,7 @@ func getCameraPersonDetections(queries *dbschema.Queries, logger *zap.SugaredLog
 	}
 }
 
-func postCameraPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLogger) http.HandlerFunc {
+func postCameraPersonDetection(config DetectionsConfig, queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
 	logger = logger.Named("postPersonDetection")
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -352,22 +595,61 @@ func postCameraPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLog
 
 		dec := json.NewDecoder(r.Body)
 
-		var params dbschema.CreatePersonDetectionParams
-		if err := dec.Decode(&params); err != nil {
+		var request CreatePersonDetectionRequest
+		if err := dec.Decode(&request); err != nil {
 			err := fmt.Errorf("error decoding request body: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		params := request.params()
 
 		params.CameraID = camera.ID
 
-		personDetection, err := queries.CreatePersonDetection(ctx, params)
+		key, err := parseIdempotencyKey(r)
+		if err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if personDetection, replayed, err := replayPersonDetection(ctx, queries, config, camera.ID, key); err != nil {
+			err = fmt.Errorf("error getting replayed person detection: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if replayed {
+			writeCreatedPersonDetection(w, r, personDetection, true, logger)
+			return
+		}
+
+		if err := checkDetectionMetadata(params); err != nil {
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.Excluded = params.Confidence != nil && *params.Confidence < config.MinConfidence
+
+		flagged, err := checkCameraAcceptsDetections(ctx, queries, camera, params.DetectionDate.Time)
+		if errors.Is(err, errDetectionRejected) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("error checking camera: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		params.Flagged = flagged
+
+		personDetection, replayed, err := createPersonDetection(ctx, queries, config, key, params)
 
 		var pqErr *pgconn.PgError
 		if errors.As(err, &pqErr) {
 			HandlePqError(w, r, pqErr, logger)
 			return
+		} else if errors.Is(err, errIdempotencyKeyInUse) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
 		} else if err != nil {
 			err = fmt.Errorf("error creating person detection: %w", err)
 			logger.Error(err)
@@ -375,20 +657,274 @@ func postCameraPersonDetection(queries *dbschema.Queries, logger *zap.SugaredLog
 			return
 		}
 
-		body, err := json.Marshal(personDetection)
+		writeCreatedPersonDetection(w, r, personDetection, replayed, logger)
+	}
+}
+
+func getHourlyPersonDetectionsCount(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("getHourlyPersonDetectionsCount")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		camera := ctx.Value("camera").(dbschema.Camera)
+
+		from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+		if err != nil {
+			err := fmt.Errorf("request does not contain a valid from parameter: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
 		if err != nil {
-			err = fmt.Errorf("error marshaling person detection: %w", err)
+			err := fmt.Errorf("request does not contain a valid to parameter: %w", err)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fromDate := pgtype.Timestamptz{Time: from, Valid: true}
+		toDate := pgtype.Timestamptz{Time: to, Valid: true}
+
+		// the hourly rollups can only answer ranges made of whole hours
+		var hourlyPersonDetectionsCount []dbschema.GetHourlyPersonDetectionsCountRow
+		if from.Truncate(time.Hour).Equal(from) && to.Truncate(time.Hour).Equal(to) {
+			hourlyPersonDetectionsCount, err = queries.GetHourlyPersonDetectionsCount(ctx, dbschema.GetHourlyPersonDetectionsCountParams{
+				CameraID: camera.ID,
+				FromDate: fromDate,
+				ToDate:   toDate,
+			})
+		} else {
+			var rows []dbschema.GetHourlyPersonDetectionsCountRawRow
+			rows, err = queries.GetHourlyPersonDetectionsCountRaw(ctx, dbschema.GetHourlyPersonDetectionsCountRawParams{
+				CameraID: camera.ID,
+				FromDate: fromDate,
+				ToDate:   toDate,
+			})
+			hourlyPersonDetectionsCount = make([]dbschema.GetHourlyPersonDetectionsCountRow, 0, len(rows))
+			for _, row := range rows {
+				hourlyPersonDetectionsCount = append(hourlyPersonDetectionsCount, dbschema.GetHourlyPersonDetectionsCountRow(row))
+			}
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error getting hourly person detections count: %w", err)
 			logger.Error(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Add("Location", path.Join(r.URL.String(), fmt.Sprintf("/%d", personDetection.ID)))
+		body, err := json.Marshal(hourlyPersonDetectionsCount)
+		if err != nil {
+			logger.Errorf("error marshaling json body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		if _, err = w.Write(body); err != nil {
-			err = fmt.Errorf("error writing body: %w", err)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(body); err != nil {
+			logger.Errorf("error writing json body: %s", err)
+		}
+	}
+}
+
+// exportFlushInterval is how many exported rows are written between flushes of the response
+const exportFlushInterval = 1000
+
+var exportColumns = []string{"id", "camera_id", "camera_name", "location_id", "location_name", "detection_date", "target_direction",
+	"normalized_direction", "flagged", "excluded", "track_id", "confidence", "bbox_x", "bbox_y", "bbox_width",
+	"bbox_height", "frame_date", "model_version"}
+
+// exportRecord formats row as the csv record of exportColumns, missing values are empty
+func exportRecord(row dbschema.ExportPersonDetectionsRow) []string {
+	// detections made in a location that was deleted since are exported without one
+	locationId := ""
+	if row.LocationID.Valid {
+		locationId = strconv.FormatInt(row.LocationID.Int64, 10)
+	}
+	return []string{
+		strconv.FormatInt(row.ID, 10),
+		strconv.FormatInt(row.CameraID, 10),
+		row.CameraName,
+		locationId,
+		row.LocationName.String,
+		row.DetectionDate.Time.Format(time.RFC3339Nano),
+		string(row.TargetDirection),
+		row.NormalizedDirection,
+		strconv.FormatBool(row.Flagged),
+		strconv.FormatBool(row.Excluded),
+		row.TrackID.String,
+		archiveFloat(row.Confidence),
+		archiveFloat(row.BboxX),
+		archiveFloat(row.BboxY),
+		archiveFloat(row.BboxWidth),
+		archiveFloat(row.BboxHeight),
+		archiveDate(row.FrameDate),
+		row.ModelVersion.String,
+	}
+}
+
+func exportPersonDetections(queries store.Store, logger *zap.SugaredLogger) http.HandlerFunc {
+	logger = logger.Named("exportPersonDetections")
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "ndjson" {
+			err := fmt.Errorf("invalid format %q, expected csv or ndjson", format)
+			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var params dbschema.ExportPersonDetectionsParams
+		if fromStr := query.Get("from"); fromStr != "" {
+			from, err := time.Parse(time.RFC3339, fromStr)
+			if err != nil {
+				err := fmt.Errorf("invalid from parameter: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			params.FromDate = pgtype.Timestamptz{Time: from, Valid: true}
+		}
+		if toStr := query.Get("to"); toStr != "" {
+			to, err := time.Parse(time.RFC3339, toStr)
+			if err != nil {
+				err := fmt.Errorf("invalid to parameter: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			params.ToDate = pgtype.Timestamptz{Time: to, Valid: true}
+		}
+		if cameraIdStr := query.Get("camera_id"); cameraIdStr != "" {
+			cameraId, err := strconv.ParseInt(cameraIdStr, 10, 64)
+			if err != nil {
+				err := fmt.Errorf("invalid camera_id parameter: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			params.CameraID = pgtype.Int8{Int64: cameraId, Valid: true}
+		}
+		if locationIdStr := query.Get("location_id"); locationIdStr != "" {
+			locationId, err := strconv.ParseInt(locationIdStr, 10, 64)
+			if err != nil {
+				err := fmt.Errorf("invalid location_id parameter: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// the detections of the descendants of the location are exported as well
+			params.LocationIds, err = queries.GetLocationSubtreeIds(ctx, locationId)
+			if err != nil {
+				err := fmt.Errorf("error getting descendant locations: %w", err)
+				logger.Error(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if len(params.LocationIds) == 0 {
+				http.Error(w, "location not found", http.StatusNotFound)
+				return
+			}
+		}
+		selector, err := parseCameraSelector(r)
+		if err == nil && !selector.empty() {
+			params.CameraIds, err = selector.cameraIds(ctx, queries)
+		}
+		if err != nil {
+			handleCameraSelectorError(w, r, err, logger)
+			return
+		}
+
+		flusher, _ := w.(http.Flusher)
+		csvWriter := csv.NewWriter(w)
+		jsonEncoder := json.NewEncoder(w)
+
+		// the response is only started with the first row, so errors running the query still get a proper status
+		var rowCount int
+		start := func() error {
+			if format == "csv" {
+				w.Header().Add("Content-Type", "text/csv")
+				w.Header().Add("Content-Disposition", `attachment; filename="person_detections.csv"`)
+				w.WriteHeader(http.StatusOK)
+				return csvWriter.Write(exportColumns)
+			}
+			w.Header().Add("Content-Type", "application/x-ndjson")
+			w.Header().Add("Content-Disposition", `attachment; filename="person_detections.ndjson"`)
+			w.WriteHeader(http.StatusOK)
+			return nil
+		}
+		flush := func() error {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		}
+
+		err = queries.StreamPersonDetectionsExport(ctx, params, func(row dbschema.ExportPersonDetectionsRow) error {
+			if rowCount == 0 {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			rowCount++
+
+			var err error
+			if format == "csv" {
+				err = csvWriter.Write(exportRecord(row))
+			} else {
+				err = jsonEncoder.Encode(&row)
+			}
+			if err != nil {
+				return err
+			}
+
+			if rowCount%exportFlushInterval == 0 {
+				return flush()
+			}
+			return nil
+		})
+
+		if err != nil && rowCount > 0 {
+			// the status was already sent, the truncated body is all that can signal the error
+			logger.Errorf("error exporting person detections after %d rows: %s", rowCount, err)
+			return
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			HandlePqError(w, r, pgErr, logger)
+			return
+		} else if err != nil {
+			err := fmt.Errorf("error exporting person detections: %w", err)
 			logger.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if rowCount == 0 {
+			if err := start(); err != nil {
+				logger.Errorf("error writing export: %s", err)
+				return
+			}
+		}
+		if err := flush(); err != nil {
+			logger.Errorf("error writing export: %s", err)
 		}
 	}
 }
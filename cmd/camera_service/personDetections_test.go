@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPersonDetectionHandlers(t *testing.T) {
+	runApiTests(t, []apiTest{
+		testLocation,
+		testCamera,
+		{name: "create", method: http.MethodPost, path: "/personDetections",
+			body:   `{"camera_id": 1, "detection_date": "2026-01-05T10:00:00Z", "target_direction": "left"}`,
+			status: http.StatusCreated},
+		{name: "create for camera", method: http.MethodPost, path: "/cameras/1/personDetections",
+			body: `{"detection_date": "2026-01-05T11:00:00Z", "target_direction": "right"}`, status: http.StatusCreated},
+		{name: "create for missing camera", method: http.MethodPost, path: "/personDetections",
+			body:   `{"camera_id": 100, "detection_date": "2026-01-05T10:00:00Z", "target_direction": "left"}`,
+			status: http.StatusConflict},
+		{name: "create with invalid confidence", method: http.MethodPost, path: "/personDetections",
+			body:   `{"camera_id": 1, "detection_date": "2026-01-05T10:00:00Z", "target_direction": "left", "confidence": 2}`,
+			status: http.StatusBadRequest},
+		{name: "create invalid body", method: http.MethodPost, path: "/personDetections", body: `{"camera_id": `,
+			status: http.StatusBadRequest},
+		{name: "list", method: http.MethodGet, path: "/personDetections?offset=0&count=10", status: http.StatusOK},
+		{name: "list for camera", method: http.MethodGet, path: "/cameras/1/personDetections?offset=0&count=10",
+			status: http.StatusOK},
+		{name: "list with invalid filter", method: http.MethodGet, path: "/personDetections?offset=0&count=10&min_confidence=high",
+			status: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/personDetections/1", status: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/personDetections/100", status: http.StatusNotFound},
+		{name: "update", method: http.MethodPatch, path: "/personDetections/1", body: `{"target_direction": "right"}`,
+			status: http.StatusOK},
+		{name: "delete camera with detections", method: http.MethodDelete, path: "/cameras/1?hard=true",
+			status: http.StatusConflict},
+		{name: "delete", method: http.MethodDelete, path: "/personDetections/1", status: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/personDetections/1", status: http.StatusNotFound},
+	})
+}
+
+func TestDetectionsExcludedAndFlaggedByTheService(t *testing.T) {
+	config := Config{Detections: DetectionsConfig{MinConfidence: 0.5}}
+	runApiTestsWith(t, config, nil, []apiTest{
+		testLocation,
+		testCamera,
+		{name: "create below min confidence", method: http.MethodPost, path: "/personDetections",
+			body: `{"camera_id": 1, "detection_date": "2026-01-05T10:00:00Z", "target_direction": "left", ` +
+				`"confidence": 0.4, "excluded": false, "flagged": true}`,
+			status: http.StatusCreated, response: `{"id": 1, "confidence": 0.4, "excluded": true, "flagged": false}`},
+		{name: "create at min confidence", method: http.MethodPost, path: "/personDetections",
+			body: `{"camera_id": 1, "detection_date": "2026-01-05T10:00:00Z", "target_direction": "left", ` +
+				`"confidence": 0.5, "excluded": true}`,
+			status: http.StatusCreated, response: `{"id": 2, "excluded": false, "flagged": false}`},
+		{name: "create without confidence", method: http.MethodPost, path: "/personDetections",
+			body: `{"camera_id": 1, "detection_date": "2026-01-05T10:00:00Z", "target_direction": "left", ` +
+				`"excluded": true}`,
+			status: http.StatusCreated, response: `{"id": 3, "excluded": false}`},
+		{name: "create for camera below min confidence", method: http.MethodPost, path: "/cameras/1/personDetections",
+			body: `{"detection_date": "2026-01-05T10:00:00Z", "target_direction": "left", "confidence": 0.1, ` +
+				`"flagged": true}`,
+			status: http.StatusCreated, response: `{"id": 4, "excluded": true, "flagged": false}`},
+		{name: "create for camera above min confidence", method: http.MethodPost, path: "/cameras/1/personDetections",
+			body: `{"detection_date": "2026-01-05T10:00:00Z", "target_direction": "left", "confidence": 0.9, ` +
+				`"excluded": true}`,
+			status: http.StatusCreated, response: `{"id": 5, "excluded": false}`},
+
+		{name: "patch ignores excluded and flagged", method: http.MethodPatch, path: "/personDetections/2",
+			body:   `{"target_direction": "right", "excluded": true, "flagged": true, "min_confidence": 1}`,
+			status: http.StatusOK, response: `{"id": 2, "target_direction": "right", "excluded": false, "flagged": false}`},
+		{name: "patch keeps excluded", method: http.MethodPatch, path: "/personDetections/1",
+			body: `{"target_direction": "right", "excluded": false}`, status: http.StatusOK,
+			response: `{"id": 1, "excluded": true}`},
+		{name: "excluded listing", method: http.MethodGet,
+			path: "/personDetections?offset=0&count=10&after_id=0&excluded=true", status: http.StatusOK,
+			response: `[{"id": 1}, {"id": 4}]`},
+	})
+}
+
+func TestPatchedDetectionsHeldToMinConfidence(t *testing.T) {
+	// the detection was stored while the minimum confidence was lower
+	queries := store.NewMemory()
+	ctx := context.Background()
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{Name: "entrance", LocationID: int32(location.ID),
+		Orientation: dbenums.CameraOrientationHorizontal, Tags: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confidence := 0.3
+	for i := 0; i < 2; i++ {
+		if _, err := queries.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+			CameraID:        camera.ID,
+			DetectionDate:   pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC), Valid: true},
+			TargetDirection: dbenums.DirectionLeft,
+			Confidence:      &confidence,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := Config{Detections: DetectionsConfig{MinConfidence: 0.5}}
+	runApiTestsOn(t, config, queries, nil, []apiTest{
+		{name: "stored included", method: http.MethodGet, path: "/personDetections/1", status: http.StatusOK,
+			response: `{"confidence": 0.3, "excluded": false}`},
+		{name: "patch", method: http.MethodPatch, path: "/personDetections/1", body: `{"target_direction": "right"}`,
+			status: http.StatusOK, response: `{"target_direction": "right", "excluded": true}`},
+		{name: "patch without changes", method: http.MethodPatch, path: "/personDetections/2", body: `{}`,
+			status: http.StatusOK, response: `{"excluded": true}`},
+	})
+}
+
+func TestExportPersonDetectionsColumns(t *testing.T) {
+	queries := store.NewMemory()
+	ctx := context.Background()
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{Name: "entrance", LocationID: int32(location.ID),
+		Orientation: dbenums.CameraOrientationHorizontal, EntryDirection: dbenums.NullDirection{Direction: dbenums.DirectionLeft, Valid: true}, Tags: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confidence := 0.75
+	if _, err := queries.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+		CameraID:        camera.ID,
+		DetectionDate:   pgtype.Timestamptz{Time: time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC), Valid: true},
+		TargetDirection: dbenums.DirectionLeft,
+		Flagged:         true,
+		TrackID:         pgtype.Text{String: "track-1", Valid: true},
+		Confidence:      &confidence,
+		ModelVersion:    pgtype.Text{String: "v2", Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the admin command writes the same records, both must keep every column of the api
+	w := httptest.NewRecorder()
+	exportPersonDetections(queries, zap.NewNop().Sugar())(w, httptest.NewRequest(http.MethodGet, "/personDetections/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{exportColumns, {"1", "1", "entrance", "1", "hall", "2026-01-05T10:00:00Z", "left", "in",
+		"true", "false", "track-1", "0.75", "", "", "", "", "", "v2"}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected %q, got %q", expected, records)
+	}
+}
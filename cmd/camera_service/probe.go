@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ProbeConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout limits each check, including connecting and waiting for the rtsp response
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Concurrency is how many cameras are checked at the same time
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+var probeResults = expvar.NewMap("camera_probe_results")
+
+// defaultPorts are the ports used for connection strings that do not include one
+var defaultPorts = map[string]string{
+	"rtsp":  "554",
+	"rtsps": "322",
+	"http":  "80",
+	"https": "443",
+}
+
+type probeJob struct {
+	config  ProbeConfig
+	queries store.Store
+	logger  *zap.SugaredLogger
+}
+
+func newProbeJob(config ProbeConfig, queries store.Store, logger *zap.SugaredLogger) *probeJob {
+	return &probeJob{config: config, queries: queries, logger: logger.Named("probe")}
+}
+
+// run checks every camera every configured interval until ctx is done
+func (j *probeJob) run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.probeAll(ctx); err != nil {
+			j.logger.Errorf("error probing cameras: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeAll checks every camera and stores the results
+func (j *probeJob) probeAll(ctx context.Context) error {
+	cameras, err := j.queries.GetCameras(ctx, false)
+	if err != nil {
+		return fmt.Errorf("error getting cameras: %w", err)
+	}
+
+	concurrency := j.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for _, camera := range cameras {
+		camera := camera
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			j.probeCamera(ctx, camera)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// probeCamera checks a single camera and stores the result
+func (j *probeJob) probeCamera(ctx context.Context, camera dbschema.Camera) {
+	checkedAt := time.Now()
+	latency, err := probe(ctx, camera.ConnectionString, j.config.Timeout)
+
+	params := dbschema.UpsertCameraStatusParams{
+		CameraID:  camera.ID,
+		Status:    "online",
+		CheckedAt: pgtype.Timestamptz{Time: checkedAt, Valid: true},
+	}
+	if err != nil {
+		params.Status = "offline"
+		params.Error = pgtype.Text{String: err.Error(), Valid: true}
+		j.logger.Debugw("camera is offline", "camera", camera.ID, "error", err)
+	} else {
+		params.LatencyMs = pgtype.Int4{Int32: int32(latency.Milliseconds()), Valid: true}
+	}
+	probeResults.Add(params.Status, 1)
+
+	if _, err := j.queries.UpsertCameraStatus(ctx, params); err != nil {
+		// the camera may have been deleted while it was checked
+		j.logger.Warnf("error storing status of camera %d: %s", camera.ID, err)
+	}
+}
+
+// probe checks whether the stream of connectionString is reachable, returning how long the check took. Rtsp
+// streams must answer an OPTIONS request, any other stream only needs to accept a tcp connection.
+func probe(ctx context.Context, connectionString string, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	streamUrl, err := url.Parse(connectionString)
+	if err != nil || streamUrl.Host == "" {
+		// connection strings like host:port are not urls
+		streamUrl = &url.URL{Host: connectionString}
+	}
+
+	host := streamUrl.Host
+	if streamUrl.Port() == "" {
+		port, ok := defaultPorts[streamUrl.Scheme]
+		if !ok {
+			return 0, fmt.Errorf("connection string %q does not include a port", connectionString)
+		}
+		host = net.JoinHostPort(streamUrl.Hostname(), port)
+	}
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if streamUrl.Scheme == "rtsp" {
+		if deadline, ok := ctx.Deadline(); ok {
+			if err := conn.SetDeadline(deadline); err != nil {
+				return 0, err
+			}
+		}
+		if err := rtspOptions(conn, streamUrl); err != nil {
+			return 0, err
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// rtspOptions sends an rtsp OPTIONS request for streamUrl and reads the status line of the response. Any rtsp
+// response counts, as a camera asking for credentials is still reachable.
+func rtspOptions(conn net.Conn, streamUrl *url.URL) error {
+	// credentials do not belong in the request line
+	requestUrl := *streamUrl
+	requestUrl.User = nil
+
+	request := fmt.Sprintf("OPTIONS %s RTSP/1.0\r\nCSeq: 1\r\nUser-Agent: camera_service\r\n\r\n", requestUrl.String())
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return fmt.Errorf("error sending rtsp request: %w", err)
+	}
+
+	statusLine, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("error reading rtsp response: %w", err)
+	}
+	if !strings.HasPrefix(statusLine, "RTSP/1.0 ") {
+		return errors.New("the stream did not answer with an rtsp response")
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"go.uber.org/zap"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRtspServer listens on a local port and answers every connection with respond, sending the request line it
+// got on the returned channel
+func fakeRtspServer(t *testing.T, respond func(conn net.Conn)) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	requestLines := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				requestLine, _ := bufio.NewReader(conn).ReadString('\n')
+				select {
+				case requestLines <- strings.TrimSpace(requestLine):
+				default:
+				}
+				respond(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), requestLines
+}
+
+func TestProbe(t *testing.T) {
+	answer := func(response string) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			fmt.Fprint(conn, response)
+		}
+	}
+	// silent keeps the connection open without answering until the client closes it
+	silent := func(conn net.Conn) {
+		buffer := make([]byte, 1)
+		conn.Read(buffer)
+	}
+
+	tests := []struct {
+		name    string
+		respond func(conn net.Conn)
+		// the connection string, %s is replaced by the address of the fake server
+		connectionString string
+		valid            bool
+		timeout          bool
+	}{
+		{name: "rtsp response", respond: answer("RTSP/1.0 200 OK\r\nCSeq: 1\r\n\r\n"),
+			connectionString: "rtsp://%s/stream", valid: true},
+		{name: "rtsp asking for credentials", respond: answer("RTSP/1.0 401 Unauthorized\r\nCSeq: 1\r\n\r\n"),
+			connectionString: "rtsp://%s/stream", valid: true},
+		{name: "not rtsp", respond: answer("HTTP/1.1 400 Bad Request\r\n\r\n"),
+			connectionString: "rtsp://%s/stream"},
+		{name: "no response", respond: silent, connectionString: "rtsp://%s/stream", timeout: true},
+		{name: "tcp stream", respond: silent, connectionString: "http://%s/stream", valid: true},
+		{name: "host and port", respond: silent, connectionString: "%s", valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, _ := fakeRtspServer(t, test.respond)
+
+			latency, err := probe(context.Background(), fmt.Sprintf(test.connectionString, address), 200*time.Millisecond)
+			if test.valid {
+				if err != nil {
+					t.Fatalf("expected the stream to be reachable, got %s", err)
+				}
+				if latency <= 0 {
+					t.Fatalf("expected a positive latency, got %s", latency)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected the stream to be unreachable")
+			}
+			if timeout := errors.Is(err, os.ErrDeadlineExceeded); timeout != test.timeout {
+				t.Fatalf("expected timeout %t, got %s", test.timeout, err)
+			}
+		})
+	}
+}
+
+func TestProbeDefaultPorts(t *testing.T) {
+	address, requestLines := fakeRtspServer(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "RTSP/1.0 200 OK\r\nCSeq: 1\r\n\r\n")
+	})
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatalf("error parsing address: %s", err)
+	}
+
+	previous := defaultPorts["rtsp"]
+	defaultPorts["rtsp"] = port
+	t.Cleanup(func() { defaultPorts["rtsp"] = previous })
+
+	if _, err := probe(context.Background(), "rtsp://127.0.0.1/stream", time.Second); err != nil {
+		t.Fatalf("expected the default rtsp port to be used, got %s", err)
+	}
+	if requestLine := <-requestLines; requestLine != "OPTIONS rtsp://127.0.0.1/stream RTSP/1.0" {
+		t.Fatalf("unexpected request line %q", requestLine)
+	}
+
+	if _, err := probe(context.Background(), "unknown://127.0.0.1/stream", time.Second); err == nil {
+		t.Fatal("expected an error for a scheme without a default port")
+	}
+}
+
+func TestProbeStripsCredentials(t *testing.T) {
+	address, requestLines := fakeRtspServer(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "RTSP/1.0 200 OK\r\nCSeq: 1\r\n\r\n")
+	})
+
+	if _, err := probe(context.Background(), "rtsp://admin:secret@"+address+"/stream", time.Second); err != nil {
+		t.Fatalf("expected the stream to be reachable, got %s", err)
+	}
+
+	requestLine := <-requestLines
+	if expected := "OPTIONS rtsp://" + address + "/stream RTSP/1.0"; requestLine != expected {
+		t.Fatalf("expected request line %q, got %q", expected, requestLine)
+	}
+}
+
+func TestProbeCameraStoresStatus(t *testing.T) {
+	ctx := context.Background()
+	queries := store.NewMemory()
+	job := newProbeJob(ProbeConfig{Timeout: time.Second}, queries, zap.NewNop().Sugar())
+
+	address, _ := fakeRtspServer(t, func(conn net.Conn) {
+		fmt.Fprint(conn, "RTSP/1.0 200 OK\r\nCSeq: 1\r\n\r\n")
+	})
+	location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+
+	for _, test := range []struct {
+		connectionString string
+		status           string
+	}{
+		{"rtsp://" + address + "/stream", "online"},
+		{"unknown://127.0.0.1/stream", "offline"},
+	} {
+		camera, err := queries.CreateCamera(ctx, dbschema.CreateCameraParams{
+			Name:             "entrance",
+			ConnectionString: test.connectionString,
+			LocationID:       int32(location.ID),
+			Orientation:      dbenums.CameraOrientationHorizontal,
+			Tags:             map[string]string{},
+		})
+		if err != nil {
+			t.Fatalf("error creating camera: %s", err)
+		}
+
+		job.probeCamera(ctx, camera)
+
+		status, err := queries.GetCameraStatus(ctx, camera.ID)
+		if err != nil {
+			t.Fatalf("error getting camera status: %s", err)
+		}
+		if status.Status != test.status {
+			t.Fatalf("%s: expected status %s, got %s", test.connectionString, test.status, status.Status)
+		}
+		if online := test.status == "online"; status.LatencyMs.Valid != online || status.Error.Valid == online {
+			t.Fatalf("%s: unexpected latency %v and error %v", test.connectionString, status.LatencyMs, status.Error)
+		}
+	}
+}
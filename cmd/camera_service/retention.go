@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"flag"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type (
+	// RetentionOverride replaces the retention of the detections of a single camera, or of every camera in a
+	// location. Camera overrides take precedence over location overrides. A zero duration keeps the retention
+	// that would apply otherwise.
+	RetentionOverride struct {
+		CameraId         int64         `mapstructure:"camera_id"`
+		LocationId       int64         `mapstructure:"location_id"`
+		PersonDetections time.Duration `mapstructure:"person_detections"`
+		CameraDetections time.Duration `mapstructure:"camera_detections"`
+	}
+	RetentionConfig struct {
+		Enabled bool `mapstructure:"enabled"`
+		// DryRun only counts and logs what would be deleted
+		DryRun    bool          `mapstructure:"dry_run"`
+		Interval  time.Duration `mapstructure:"interval"`
+		BatchSize int32         `mapstructure:"batch_size"`
+		// PersonDetections and CameraDetections are how long rows of each table are kept, zero keeps them forever.
+		// Pruning does not change the detection rollups, so aggregated counts outlive the raw detections.
+		PersonDetections time.Duration       `mapstructure:"person_detections"`
+		CameraDetections time.Duration       `mapstructure:"camera_detections"`
+		Overrides        []RetentionOverride `mapstructure:"overrides"`
+	}
+)
+
+var retentionDeletedRows = expvar.NewMap("retention_deleted_rows")
+var retentionDroppedPartitions = expvar.NewInt("retention_dropped_partitions")
+
+// retentionFor returns how long the detections of camera should be kept, per table
+func (c RetentionConfig) retentionFor(camera dbschema.Camera) (personDetections time.Duration, cameraDetections time.Duration) {
+	personDetections, cameraDetections = c.PersonDetections, c.CameraDetections
+
+	// location overrides are applied first so camera overrides win
+	for _, override := range c.Overrides {
+		if override.CameraId == 0 && override.LocationId == int64(camera.LocationID) {
+			if override.PersonDetections != 0 {
+				personDetections = override.PersonDetections
+			}
+			if override.CameraDetections != 0 {
+				cameraDetections = override.CameraDetections
+			}
+		}
+	}
+	for _, override := range c.Overrides {
+		if override.CameraId == camera.ID {
+			if override.PersonDetections != 0 {
+				personDetections = override.PersonDetections
+			}
+			if override.CameraDetections != 0 {
+				cameraDetections = override.CameraDetections
+			}
+		}
+	}
+
+	return personDetections, cameraDetections
+}
+
+// shortestPersonDetectionsRetention returns the shortest time any camera keeps its person detections for, zero when
+// they are kept forever or retention is disabled
+func (c RetentionConfig) shortestPersonDetectionsRetention() time.Duration {
+	if !c.Enabled {
+		return 0
+	}
+
+	shortest := c.PersonDetections
+	for _, override := range c.Overrides {
+		if override.PersonDetections > 0 && (shortest == 0 || override.PersonDetections < shortest) {
+			shortest = override.PersonDetections
+		}
+	}
+	return shortest
+}
+
+type retentionJob struct {
+	config RetentionConfig
+	// archived limits pruning of person detections to the ones already archived
+	archived bool
+	db       *pgxpool.Pool
+	queries  *dbschema.Queries
+	logger   *zap.SugaredLogger
+}
+
+func newRetentionJob(config RetentionConfig, archived bool, db *pgxpool.Pool, logger *zap.SugaredLogger) *retentionJob {
+	return &retentionJob{config: config, archived: archived, db: db, queries: dbschema.New(db), logger: logger.Named("retention")}
+}
+
+// deleteWithoutRollups runs a delete in a transaction that does not update the detection rollups
+func (j *retentionJob) deleteWithoutRollups(ctx context.Context, deleteBatch func(queries *dbschema.Queries) (int64, error)) (int64, error) {
+	tx, err := j.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	queries := j.queries.WithTx(tx)
+	if err := queries.SkipRollupMaintenance(ctx); err != nil {
+		return 0, fmt.Errorf("error disabling rollup maintenance: %w", err)
+	}
+
+	deleted, err := deleteBatch(queries)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit(ctx)
+}
+
+// run prunes expired rows every configured interval until ctx is done
+func (j *retentionJob) run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.prune(ctx); err != nil {
+			j.logger.Errorf("error pruning expired rows: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune deletes, or counts when running dry, the expired rows of every camera
+func (j *retentionJob) prune(ctx context.Context) error {
+	cameras, err := j.queries.GetCameras(ctx, true)
+	if err != nil {
+		return fmt.Errorf("error getting cameras: %w", err)
+	}
+
+	now := time.Now()
+	var totalPersonDetections, totalCameraDetections int64
+
+	if err := j.dropExpiredPartitions(ctx, cameras, now); err != nil {
+		return err
+	}
+
+	for _, camera := range cameras {
+		personDetectionsRetention, cameraDetectionsRetention := j.config.retentionFor(camera)
+
+		if personDetectionsRetention > 0 {
+			cutoff := pgtype.Timestamptz{Time: now.Add(-personDetectionsRetention), Valid: true}
+			removed, err := j.pruneTable(ctx, "person_detections", camera, cutoff,
+				func() (int64, error) {
+					if j.archived {
+						return j.queries.CountExpiredArchivedPersonDetections(ctx, dbschema.CountExpiredArchivedPersonDetectionsParams{
+							CameraID: camera.ID,
+							Cutoff:   cutoff,
+						})
+					}
+					return j.queries.CountExpiredPersonDetections(ctx, dbschema.CountExpiredPersonDetectionsParams{
+						CameraID: camera.ID,
+						Cutoff:   cutoff,
+					})
+				},
+				func() (int64, error) {
+					return j.deleteWithoutRollups(ctx, func(queries *dbschema.Queries) (int64, error) {
+						// detections are only pruned once an archive holds them
+						if j.archived {
+							return queries.DeleteExpiredArchivedPersonDetections(ctx, dbschema.DeleteExpiredArchivedPersonDetectionsParams{
+								CameraID:  camera.ID,
+								Cutoff:    cutoff,
+								BatchSize: j.config.BatchSize,
+							})
+						}
+						return queries.DeleteExpiredPersonDetections(ctx, dbschema.DeleteExpiredPersonDetectionsParams{
+							CameraID:  camera.ID,
+							Cutoff:    cutoff,
+							BatchSize: j.config.BatchSize,
+						})
+					})
+				})
+			totalPersonDetections += removed
+			if err != nil {
+				return err
+			}
+		}
+
+		if cameraDetectionsRetention > 0 {
+			cutoff := pgtype.Timestamptz{Time: now.Add(-cameraDetectionsRetention), Valid: true}
+			removed, err := j.pruneTable(ctx, "camera_detections", camera, cutoff,
+				func() (int64, error) {
+					return j.queries.CountExpiredCameraDetections(ctx, dbschema.CountExpiredCameraDetectionsParams{
+						CameraID: camera.ID,
+						Cutoff:   cutoff,
+					})
+				},
+				func() (int64, error) {
+					return j.deleteWithoutRollups(ctx, func(queries *dbschema.Queries) (int64, error) {
+						return queries.DeleteExpiredCameraDetections(ctx, dbschema.DeleteExpiredCameraDetectionsParams{
+							CameraID:  camera.ID,
+							Cutoff:    cutoff,
+							BatchSize: j.config.BatchSize,
+						})
+					})
+				})
+			totalCameraDetections += removed
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if j.config.DryRun {
+		j.logger.Infow("dry run, rows that would have been pruned",
+			"person_detections", totalPersonDetections, "camera_detections", totalCameraDetections)
+	} else {
+		j.logger.Infow("pruned expired rows",
+			"person_detections", totalPersonDetections, "camera_detections", totalCameraDetections)
+	}
+
+	return nil
+}
+
+// dropExpiredPartitions drops the person detections partitions whose whole range expired. Partitions hold the
+// detections of every camera, so they are only dropped once they expired for the camera with the longest retention.
+func (j *retentionJob) dropExpiredPartitions(ctx context.Context, cameras []dbschema.Camera, now time.Time) error {
+	if len(cameras) == 0 {
+		return nil
+	}
+
+	var longestRetention time.Duration
+	for _, camera := range cameras {
+		personDetectionsRetention, _ := j.config.retentionFor(camera)
+		if personDetectionsRetention <= 0 {
+			// this camera keeps its detections forever
+			return nil
+		}
+		if personDetectionsRetention > longestRetention {
+			longestRetention = personDetectionsRetention
+		}
+	}
+	cutoff := now.Add(-longestRetention)
+
+	partitions, err := j.queries.GetPersonDetectionsPartitions(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting person detections partitions: %w", err)
+	}
+
+	for _, partition := range partitions {
+		if partition.RangeEnd.Time.After(cutoff) {
+			// partitions are sorted by range, so the following ones did not expire either
+			break
+		}
+
+		if j.archived {
+			unarchived, err := j.queries.CountUnarchivedPersonDetections(ctx, dbschema.CountUnarchivedPersonDetectionsParams{
+				FromDate: partition.RangeStart,
+				ToDate:   partition.RangeEnd,
+			})
+			if err != nil {
+				return fmt.Errorf("error counting unarchived detections of partition %s: %w", partition.Name, err)
+			}
+			if unarchived > 0 {
+				j.logger.Infow("keeping expired partition until its detections are archived", "partition", partition.Name,
+					"unarchived", unarchived)
+				break
+			}
+		}
+
+		if j.config.DryRun {
+			j.logger.Infow("would drop expired partition", "partition", partition.Name, "cutoff", cutoff)
+			continue
+		}
+
+		if err := j.queries.DropPersonDetectionsPartition(ctx, partition.Name); err != nil {
+			return fmt.Errorf("error dropping partition %s: %w", partition.Name, err)
+		}
+		retentionDroppedPartitions.Add(1)
+		j.logger.Infow("dropped expired partition", "partition", partition.Name, "cutoff", cutoff)
+	}
+
+	return nil
+}
+
+// pruneTable deletes the expired rows of a camera in batches, returning how many were removed. When running dry
+// the rows are only counted.
+func (j *retentionJob) pruneTable(ctx context.Context, table string, camera dbschema.Camera, cutoff pgtype.Timestamptz,
+	count func() (int64, error), deleteBatch func() (int64, error)) (int64, error) {
+	if j.config.DryRun {
+		expired, err := count()
+		if err != nil {
+			return 0, fmt.Errorf("error counting expired %s of camera %d: %w", table, camera.ID, err)
+		}
+		if expired > 0 {
+			j.logger.Infow("would prune expired rows", "table", table, "camera", camera.ID, "cutoff", cutoff.Time, "rows", expired)
+		}
+		return expired, nil
+	}
+
+	var removed int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		deleted, err := deleteBatch()
+		if err != nil {
+			return removed, fmt.Errorf("error pruning expired %s of camera %d: %w", table, camera.ID, err)
+		}
+		removed += deleted
+		retentionDeletedRows.Add(table, deleted)
+
+		if deleted < int64(j.config.BatchSize) {
+			break
+		}
+	}
+
+	if removed > 0 {
+		j.logger.Infow("pruned expired rows", "table", table, "camera", camera.ID, "cutoff", cutoff.Time, "rows", removed)
+	}
+	return removed, nil
+}
+
+// retention runs a single retention pass from the command line
+func retention(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("retention")
+
+	flags := flag.NewFlagSet("retention", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be deleted")
+	if err := flags.Parse(args); err != nil {
+		logger.Fatal(err)
+	}
+
+	config := loadConfig(logger)
+	checkDatabaseSchema(config.Db, logger)
+	db := connectToDb(config.Db, logger)
+
+	retentionConfig := config.Retention
+	retentionConfig.DryRun = retentionConfig.DryRun || *dryRun
+
+	if err := newRetentionJob(retentionConfig, config.Archive.Enabled, db, logger).prune(context.Background()); err != nil {
+		logger.Fatal(err)
+	}
+}
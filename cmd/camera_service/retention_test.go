@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShortestPersonDetectionsRetention(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RetentionConfig
+		expected time.Duration
+	}{
+		{"disabled", RetentionConfig{PersonDetections: time.Hour}, 0},
+		{"kept forever", RetentionConfig{Enabled: true}, 0},
+		{"default", RetentionConfig{Enabled: true, PersonDetections: 48 * time.Hour}, 48 * time.Hour},
+		{"shorter override", RetentionConfig{Enabled: true, PersonDetections: 48 * time.Hour,
+			Overrides: []RetentionOverride{{CameraId: 1, PersonDetections: 24 * time.Hour}}}, 24 * time.Hour},
+		{"override of a camera kept forever otherwise", RetentionConfig{Enabled: true,
+			Overrides: []RetentionOverride{{LocationId: 1, PersonDetections: 72 * time.Hour}}}, 72 * time.Hour},
+		{"override keeping the default", RetentionConfig{Enabled: true, PersonDetections: 48 * time.Hour,
+			Overrides: []RetentionOverride{{CameraId: 1, CameraDetections: time.Hour}}}, 48 * time.Hour},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.config.shortestPersonDetectionsRetention(); actual != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
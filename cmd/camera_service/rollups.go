@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+// backfillRollups rebuilds the hourly and daily detection rollups of the days in [from, to) from the raw
+// detections. The detections table is locked against writes while the rollups are rebuilt.
+func backfillRollups(ctx context.Context, db *pgxpool.Pool, from time.Time, to time.Time) (hourly int64, daily int64, err error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "lock table person_detections in share mode"); err != nil {
+		return 0, 0, fmt.Errorf("error locking person detections: %w", err)
+	}
+
+	queries := dbschema.New(tx)
+	fromDate := pgtype.Date{Time: from, Valid: true}
+	toDate := pgtype.Date{Time: to, Valid: true}
+
+	if err := queries.DeleteHourlyRollups(ctx, dbschema.DeleteHourlyRollupsParams{FromDate: fromDate, ToDate: toDate}); err != nil {
+		return 0, 0, fmt.Errorf("error deleting hourly rollups: %w", err)
+	}
+	if err := queries.DeleteDailyRollups(ctx, dbschema.DeleteDailyRollupsParams{FromDate: fromDate, ToDate: toDate}); err != nil {
+		return 0, 0, fmt.Errorf("error deleting daily rollups: %w", err)
+	}
+
+	hourly, err = queries.BackfillHourlyRollups(ctx, dbschema.BackfillHourlyRollupsParams{FromDate: fromDate, ToDate: toDate})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error backfilling hourly rollups: %w", err)
+	}
+	daily, err = queries.BackfillDailyRollups(ctx, dbschema.BackfillDailyRollupsParams{FromDate: fromDate, ToDate: toDate})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error backfilling daily rollups: %w", err)
+	}
+
+	return hourly, daily, tx.Commit(ctx)
+}
+
+// rollups runs the rollup maintenance commands
+func rollups(args []string, logger *zap.SugaredLogger) {
+	logger = logger.Named("rollups")
+
+	if len(args) == 0 || args[0] != "backfill" {
+		logger.Fatal("expected rollups backfill")
+	}
+
+	flags := flag.NewFlagSet("rollups backfill", flag.ExitOnError)
+	fromStr := flags.String("from", "", "first day to rebuild, as yyyy-mm-dd, the day of the oldest stored detection by default")
+	toStr := flags.String("to", "", "day after the last day to rebuild, as yyyy-mm-dd, tomorrow if empty")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "rebuilds the detection rollups from the raw detections. days that retention may have pruned")
+		fmt.Fprintln(flags.Output(), "detections of are refused, as their counts would be lost.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		logger.Fatal(err)
+	}
+
+	var from, to time.Time
+	var err error
+	if *fromStr != "" {
+		if from, err = time.Parse(time.DateOnly, *fromStr); err != nil {
+			logger.Fatalf("invalid from date: %s", err)
+		}
+	}
+	if *toStr != "" {
+		if to, err = time.Parse(time.DateOnly, *toStr); err != nil {
+			logger.Fatalf("invalid to date: %s", err)
+		}
+	}
+
+	config := loadConfig(logger)
+	checkDatabaseSchema(config.Db, logger)
+	db := connectToDb(config.Db, logger)
+
+	// the days are taken from the database, in the time zone the rollups count days in
+	retention := config.Retention.shortestPersonDetectionsRetention()
+	days, err := dbschema.New(db).GetRollupBackfillDays(context.Background(),
+		pgtype.Interval{Microseconds: retention.Microseconds(), Valid: true})
+	if err != nil {
+		logger.Fatalf("error getting the days to backfill: %s", err)
+	}
+	if to.IsZero() {
+		to = days.Tomorrow.Time
+	}
+
+	// the day of the retention cutoff is partially pruned, only the days after it still have all their detections
+	var retained time.Time
+	if retention > 0 {
+		retained = days.FirstRetainedDay.Time
+	}
+
+	if from.IsZero() {
+		if !days.OldestDay.Valid {
+			logger.Info("there are no detections to backfill rollups from")
+			return
+		}
+		from = days.OldestDay.Time
+		if from.Before(retained) {
+			from = retained
+		}
+	} else if from.Before(retained) {
+		logger.Fatalf("refusing to backfill from %s, retention may have pruned detections before %s",
+			from.Format(time.DateOnly), retained.Format(time.DateOnly))
+	}
+
+	hourly, daily, err := backfillRollups(context.Background(), db, from, to)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infow("backfilled rollups", "from", from.Format(time.DateOnly), "to", to.Format(time.DateOnly), "hourly_rows", hourly,
+		"daily_rows", daily)
+}
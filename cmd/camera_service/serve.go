@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"flag"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/blobstore"
+	"github.com/SmartFactory-Tec/camera_service/pkg/bulkimport"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/notify"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/cors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+func serve(args []string, logger *zap.SugaredLogger) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	noMigrate := flags.Bool("no-migrate", false, "do not apply pending database migrations on startup")
+	inMemory := flags.Bool("in-memory", false, "keep all data in memory instead of using the database, for testing")
+	if err := flags.Parse(args); err != nil {
+		logger.Fatal(err)
+	}
+
+	config := loadConfig(logger)
+	dbConfig := config.Db
+
+	var queries store.Store
+	if *inMemory {
+		logger.Warn("running with an in-memory store, all data will be lost on exit")
+		queries = store.NewMemory()
+	} else {
+		db := connectToDb(dbConfig, logger)
+		checkDatabaseSchema(dbConfig, logger)
+		if !*noMigrate {
+			updateDatabaseSchema(dbConfig, logger)
+		}
+		queries = store.NewPostgres(db)
+
+		go newPartitionMaintenanceJob(config.Partitions, dbschema.New(db), logger).run(context.Background())
+
+		if config.Archive.Enabled {
+			archiveJob, err := newArchiveJob(config.Archive, db, logger)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			go archiveJob.run(context.Background())
+		}
+
+		if config.Retention.Enabled {
+			go newRetentionJob(config.Retention, config.Archive.Enabled, db, logger).run(context.Background())
+		}
+	}
+
+	if config.Probe.Enabled {
+		go newProbeJob(config.Probe, queries, logger).run(context.Background())
+	}
+
+	notificationRouter, err := newNotificationRouter(config.Notifications, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	// notifications are always logged, whatever channels they are routed to
+	notifier := notify.Multi{notify.Log{Logger: logger.Named("notifications")}, notificationRouter}
+	if config.Activity.Enabled {
+		go newActivityJob(config.Activity, queries, notifier, logger).run(context.Background())
+	}
+	if config.Occupancy.Enabled {
+		go newOccupancyJob(config.Occupancy, queries, notifier, logger).run(context.Background())
+	}
+
+	floorPlans, err := newFloorPlanStore(config.FloorPlans)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if floorPlans == nil {
+		logger.Info("no floor plan store is configured, floor plans can not be uploaded")
+	}
+
+	r := newRouter(config, queries, notificationRouter, floorPlans, logger)
+	if err := verifyApiDocumentation(r); err != nil {
+		logger.Fatal(err)
+	}
+
+	if config.Metrics.Enabled {
+		go func() {
+			logger.Infof("serving metrics on %s", config.Metrics.Address)
+			if err := http.ListenAndServe(config.Metrics.Address, newMetricsRouter()); err != nil {
+				logger.Fatal(fmt.Errorf("metrics server error: %w", err))
+			}
+		}()
+	}
+
+	logger.Infof("starting server on port %d", config.Port)
+	err = http.ListenAndServe(fmt.Sprintf(":%d", config.Port), r)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("http server error: %w", err))
+	}
+}
+
+// newMetricsRouter creates the router of the metrics listener, it only serves the expvar metrics at /debug/vars
+func newMetricsRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/debug/vars", expvar.Handler().ServeHTTP)
+	return r
+}
+
+// newRouter creates the router with every route of the api. The handlers only capture their dependencies, so
+// the router can also be built with nil dependencies to inspect its routes.
+func newRouter(config Config, queries store.Store, notifications *notify.Router, floorPlans blobstore.Store, logger *zap.SugaredLogger) chi.Router {
+	var allowedOrigins []string
+
+	if !config.Cors.AllowAllOrigins {
+		allowedOrigins = config.Cors.AllowedOrigins
+	}
+
+	r := chi.NewRouter()
+
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{"GET", "OPTIONS", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"*"},
+	}))
+
+	r.Use(LogRequests(logger))
+
+	r.Get("/openapi.json", getOpenApiDocument(logger))
+	r.Get("/docs", getApiDocs(logger))
+	r.Get("/docs/swagger-ui-bundle.js", getApiDocsAssets().ServeHTTP)
+	r.Get("/docs/swagger-ui.css", getApiDocsAssets().ServeHTTP)
+
+	r.Route("/locations", func(r chi.Router) {
+		r.Get("/", makeGetLocationsHandler(queries, logger))
+		r.Post("/", makeCreateLocationHandler(queries, logger))
+		r.Get("/tree", makeGetLocationTreeHandler(queries, logger))
+
+		r.Route("/{locationId}", func(r chi.Router) {
+			r.Use(locationCtx(queries, logger))
+			r.Get("/", makeGetLocationHandler(logger))
+			r.Patch("/", makeUpdateLocationHandler(queries, logger))
+			r.Delete("/", makeDeleteLocationHandler(queries, logger))
+			r.Post("/restore", makeRestoreLocationHandler(queries, logger))
+			r.Get("/children", makeGetLocationChildrenHandler(queries, logger))
+			r.Get("/occupancy", getLocationOccupancy(queries, logger))
+			r.Get("/dailyPersonDetectionsCount", makeGetLocationDailyPersonDetectionsCountHandler(queries, logger))
+			r.Get("/hourlyPersonDetectionsCount", makeGetLocationHourlyPersonDetectionsCountHandler(queries, logger))
+			r.Get("/map", getLocationMap(queries, logger))
+
+			r.Route("/floorPlan", func(r chi.Router) {
+				r.Put("/", putFloorPlan(queries, floorPlans, config.FloorPlans, logger))
+
+				r.Group(func(r chi.Router) {
+					r.Use(floorPlanCtx(queries, logger))
+					r.Get("/", getFloorPlan(logger))
+					r.Patch("/", patchFloorPlan(queries, logger))
+					r.Delete("/", deleteFloorPlan(queries, floorPlans, logger))
+					r.Get("/image", getFloorPlanImage(floorPlans, logger))
+				})
+			})
+		})
+	})
+
+	r.Route("/cameras", func(r chi.Router) {
+		r.Get("/", getCameras(queries, logger))
+		r.Post("/", postCamera(queries, logger))
+		r.Get("/dailyPersonDetectionsCount", getCamerasDailyPersonDetectionsCount(selectedCameraIds(queries), queries, logger))
+		r.Get("/hourlyPersonDetectionsCount", getCamerasHourlyPersonDetectionsCount(selectedCameraIds(queries), queries, logger))
+
+		r.Route("/{cameraId}", func(r chi.Router) {
+			r.Use(cameraCtx(queries, logger))
+			r.Get("/", getCamera(logger))
+			r.Patch("/", patchCamera(queries, logger))
+			r.Delete("/", deleteCamera(queries, logger))
+			r.Post("/restore", restoreCamera(queries, logger))
+
+			r.Get("/personDetections", getCameraPersonDetections(queries, logger))
+			r.Post("/personDetections", postCameraPersonDetection(config.Detections, queries, logger))
+
+			r.Get("/status", getCameraStatus(queries, logger))
+			r.Put("/state", putCameraState(queries, logger))
+			r.Get("/stateHistory", getCameraStateHistory(queries, logger))
+			r.Get("/locationHistory", getCameraLocationHistory(queries, logger))
+
+			r.Route("/maintenanceWindows", func(r chi.Router) {
+				r.Get("/", getCameraMaintenanceWindows(queries, logger))
+				r.Post("/", postCameraMaintenanceWindow(queries, logger))
+
+				r.Route("/{maintenanceWindowId}", func(r chi.Router) {
+					r.Use(maintenanceWindowCtx(queries, logger))
+					r.Get("/", getMaintenanceWindow(logger))
+					r.Patch("/", patchMaintenanceWindow(queries, logger))
+					r.Delete("/", deleteMaintenanceWindow(queries, logger))
+				})
+			})
+
+			r.Route("/activityRules", func(r chi.Router) {
+				r.Get("/", getCameraActivityRules(queries, logger))
+				r.Post("/", postCameraActivityRule(queries, logger))
+
+				r.Route("/{activityRuleId}", func(r chi.Router) {
+					r.Use(activityRuleCtx(queries, logger))
+					r.Get("/", getActivityRule(logger))
+					r.Patch("/", patchActivityRule(queries, logger))
+					r.Delete("/", deleteActivityRule(queries, logger))
+				})
+			})
+
+			r.Get("/dailyPersonDetectionsCount", getDailyPersonDetectionsCount(queries, logger))
+			r.Get("/hourlyPersonDetectionsCount", getHourlyPersonDetectionsCount(queries, logger))
+		})
+
+	})
+
+	r.Route("/cameraGroups", func(r chi.Router) {
+		r.Get("/", getCameraGroups(queries, logger))
+		r.Post("/", postCameraGroup(queries, logger))
+
+		r.Route("/{groupId}", func(r chi.Router) {
+			r.Use(cameraGroupCtx(queries, logger))
+			r.Get("/", getCameraGroup(queries, logger))
+			r.Patch("/", patchCameraGroup(queries, logger))
+			r.Delete("/", deleteCameraGroup(queries, logger))
+			r.Get("/dailyPersonDetectionsCount", getCamerasDailyPersonDetectionsCount(cameraGroupCameraIds(queries), queries, logger))
+			r.Get("/hourlyPersonDetectionsCount", getCamerasHourlyPersonDetectionsCount(cameraGroupCameraIds(queries), queries, logger))
+
+			r.Route("/cameras", func(r chi.Router) {
+				r.Get("/", getCameraGroupCameras(queries, logger))
+
+				r.Route("/{cameraId}", func(r chi.Router) {
+					r.Use(cameraCtx(queries, logger))
+					r.Put("/", putCameraGroupCamera(queries, logger))
+					r.Delete("/", deleteCameraGroupCamera(queries, logger))
+				})
+			})
+		})
+	})
+
+	r.Route("/alerts", func(r chi.Router) {
+		r.Get("/", getAlerts(queries, logger))
+
+		r.Route("/{alertId}", func(r chi.Router) {
+			r.Use(alertCtx(queries, logger))
+			r.Get("/", getAlert(logger))
+			r.Post("/acknowledge", acknowledgeAlert(queries, logger))
+		})
+	})
+
+	r.Route("/notifications", func(r chi.Router) {
+		r.Get("/channels", getNotificationChannels(notifications, logger))
+		r.Post("/test", postTestNotification(notifications, logger))
+	})
+
+	r.Route("/import", func(r chi.Router) {
+		r.Post("/locations", postImport(bulkimport.Locations, queries, logger))
+		r.Post("/cameras", postImport(bulkimport.Cameras, queries, logger))
+		r.Post("/personDetections", postImport(bulkimport.PersonDetections, queries, logger))
+	})
+
+	r.Route("/personDetections", func(r chi.Router) {
+		r.Get("/", getPersonDetections(queries, logger))
+		r.Post("/", postPersonDetection(config.Detections, queries, logger))
+		r.Get("/export", exportPersonDetections(queries, logger))
+		r.Get("/latest", getLatestPersonDetection(queries, logger))
+
+		r.Route("/{personDetectionId}", func(r chi.Router) {
+			r.Use(personDetectionCtx(queries, logger))
+			r.Get("/", getPersonDetection(logger))
+			r.Patch("/", patchPersonDetection(config.Detections, queries, logger))
+			r.Delete("/", deletePersonDetection(queries, logger))
+		})
+	})
+
+	return r
+}
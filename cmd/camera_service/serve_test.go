@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/SmartFactory-Tec/camera_service/pkg/blobstore"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apiTest is one request against the router and the status it must get back. The requests of a table run in order
+// against the same store, so a request can rely on the records created by the ones before it
+type apiTest struct {
+	name        string
+	method      string
+	path        string
+	contentType string
+	body        string
+	status      int
+	// response, when set, is json the response body must match. Objects only need to have the keys listed, arrays
+	// must have exactly the elements listed, in order
+	response string
+}
+
+func runApiTests(t *testing.T, tests []apiTest) {
+	runApiTestsWith(t, Config{}, nil, tests)
+}
+
+// runApiTestsWith runs tests against a router with config and floorPlans
+func runApiTestsWith(t *testing.T, config Config, floorPlans blobstore.Store, tests []apiTest) {
+	runApiTestsOn(t, config, store.NewMemory(), floorPlans, tests)
+}
+
+// runApiTestsOn runs tests against a router with config and floorPlans backed by queries, for tests that need to set
+// up records the api can't create
+func runApiTestsOn(t *testing.T, config Config, queries store.Store, floorPlans blobstore.Store, tests []apiTest) {
+	router := newRouter(config, queries, nil, floorPlans, zap.NewNop().Sugar())
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		if res.Code != test.status {
+			t.Fatalf("%s: %s %s: expected status %d, got %d: %s", test.name, test.method, test.path, test.status,
+				res.Code, strings.TrimSpace(res.Body.String()))
+		}
+		if test.response == "" {
+			continue
+		}
+
+		var expected, actual any
+		if err := json.Unmarshal([]byte(test.response), &expected); err != nil {
+			t.Fatalf("%s: invalid expected response: %s", test.name, err)
+		}
+		if err := json.Unmarshal(res.Body.Bytes(), &actual); err != nil {
+			t.Fatalf("%s: %s %s: invalid json response %q: %s", test.name, test.method, test.path, res.Body.String(), err)
+		}
+		if !jsonMatches(expected, actual) {
+			t.Fatalf("%s: %s %s: expected a response matching %s, got %s", test.name, test.method, test.path,
+				test.response, strings.TrimSpace(res.Body.String()))
+		}
+	}
+}
+
+// jsonMatches returns whether the decoded json actual matches expected, as described in apiTest.response
+func jsonMatches(expected any, actual any) bool {
+	switch expected := expected.(type) {
+	case map[string]any:
+		actual, ok := actual.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range expected {
+			actualValue, ok := actual[key]
+			if !ok || !jsonMatches(value, actualValue) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		actual, ok := actual.([]any)
+		if !ok || len(actual) != len(expected) {
+			return false
+		}
+		for i := range expected {
+			if !jsonMatches(expected[i], actual[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// testLocation and testCamera create location 1 and camera 1 in it
+var (
+	testLocation = apiTest{name: "create location", method: http.MethodPost, path: "/locations",
+		body: `{"name": "hall", "description": "main hall"}`, status: http.StatusCreated}
+	testCamera = apiTest{name: "create camera", method: http.MethodPost, path: "/cameras",
+		body:   `{"name": "entrance", "connection_string": "rtsp://entrance", "location_id": 1, "orientation": "horizontal"}`,
+		status: http.StatusCreated}
+)
+
+func TestMetricsAreServedApartFromApi(t *testing.T) {
+	res := httptest.NewRecorder()
+	newRouter(Config{}, nil, nil, nil, zap.NewNop().Sugar()).ServeHTTP(res,
+		httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if res.Code != http.StatusNotFound {
+		t.Errorf("api: expected status %d, got %d", http.StatusNotFound, res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	newMetricsRouter().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var metrics map[string]any
+	if res.Code != http.StatusOK {
+		t.Fatalf("metrics: expected status %d, got %d", http.StatusOK, res.Code)
+	} else if err := json.Unmarshal(res.Body.Bytes(), &metrics); err != nil {
+		t.Fatalf("metrics: invalid json: %s", err)
+	}
+	if _, ok := metrics["retention_deleted_rows"]; !ok {
+		t.Errorf("metrics: expected retention_deleted_rows, got %v", metrics)
+	}
+}
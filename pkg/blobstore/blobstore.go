@@ -0,0 +1,34 @@
+// Package blobstore stores files by key, either in a local directory or in an S3 compatible object store.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by Get when there is no file stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// Store stores files by key. Keys use forward slashes to separate their parts, like person_detections/2023/a.csv.gz
+type Store interface {
+	Put(ctx context.Context, key string, contentType string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures a store, the s3 store is used when S3.Endpoint is set
+type Config struct {
+	Directory string   `mapstructure:"directory"`
+	S3        S3Config `mapstructure:"s3"`
+}
+
+func New(config Config) (Store, error) {
+	if config.S3.Endpoint != "" {
+		return NewS3(config.S3)
+	}
+	if config.Directory == "" {
+		return nil, errors.New("either a directory or an s3 endpoint must be configured")
+	}
+	return NewDirectory(config.Directory)
+}
@@ -0,0 +1,91 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directory stores files below a local directory
+type Directory struct {
+	root string
+}
+
+func NewDirectory(root string) (*Directory, error) {
+	if err := os.MkdirAll(root, 0777); err != nil {
+		return nil, fmt.Errorf("error creating blob directory: %w", err)
+	}
+	return &Directory{root: root}, nil
+}
+
+// path returns the file path of key, rejecting keys that would escape the root directory
+func (d *Directory) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(d.root, clean), nil
+}
+
+func (d *Directory) Put(ctx context.Context, key string, contentType string, r io.Reader) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return fmt.Errorf("error creating blob directory: %w", err)
+	}
+
+	// write to a temporary file first, so readers never see a partially written blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing blob file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing blob file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error moving blob file into place: %w", err)
+	}
+	return nil
+}
+
+func (d *Directory) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error opening blob file: %w", err)
+	}
+	return file, nil
+}
+
+func (d *Directory) Delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error deleting blob file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package bulkimport imports locations, cameras and person detections from csv or json files. Every row is
+// validated before anything is written, and then all rows are written in a single transaction, so an import
+// either creates every record or none of them.
+package bulkimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgconn"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	Locations        Kind = "locations"
+	Cameras          Kind = "cameras"
+	PersonDetections Kind = "personDetections"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+)
+
+// LocationRow, CameraRow and PersonDetectionRow list the columns of each kind of import: the header of csv files
+// and the keys of the objects of json files. Columns marked omitempty are optional.
+type (
+	// LocationRow refers to its parent by name, the parent must exist already or be imported by an earlier row
+	LocationRow struct {
+		Name             string `json:"name"`
+		Description      string `json:"description,omitempty"`
+		Capacity         int32  `json:"capacity,omitempty"`
+		WarningThreshold int32  `json:"warning_threshold,omitempty"`
+		Parent           string `json:"parent,omitempty"`
+	}
+	// CameraRow refers to its location by name or by id, if both are given they must match. LocationText is the
+	// former name of MountDescription, kept so older files still import.
+	CameraRow struct {
+		Name             string              `json:"name"`
+		ConnectionString string              `json:"connection_string"`
+		Location         string              `json:"location,omitempty"`
+		LocationID       int32               `json:"location_id,omitempty"`
+		Orientation      dbenums.Orientation `json:"orientation,omitempty"`
+		EntryDirection   dbenums.Direction   `json:"entry_direction,omitempty"`
+		MountDescription string              `json:"mount_description,omitempty"`
+		LocationText     string              `json:"location_text,omitempty"`
+		FloorX           float64             `json:"floor_x,omitempty"`
+		FloorY           float64             `json:"floor_y,omitempty"`
+		Heading          float64             `json:"heading,omitempty"`
+		MountingHeight   float64             `json:"mounting_height,omitempty"`
+	}
+	// PersonDetectionRow refers to its camera by name or by id, if both are given they must match
+	PersonDetectionRow struct {
+		Camera          string            `json:"camera,omitempty"`
+		CameraID        int64             `json:"camera_id,omitempty"`
+		DetectionDate   time.Time         `json:"detection_date"`
+		TargetDirection dbenums.Direction `json:"target_direction,omitempty"`
+	}
+)
+
+// maxErrors limits how many row errors are reported, so a file with the wrong columns does not produce an error
+// per row
+const maxErrors = 1000
+
+// RowError describes why a row can not be imported. Rows are numbered from 1 in the order of the file, not
+// counting the csv header. Row 0 refers to the whole file.
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type Result struct {
+	Kind   Kind `json:"kind"`
+	DryRun bool `json:"dry_run"`
+	Rows   int  `json:"rows"`
+	// Imported is how many records were created, or would have been created by a dry run. It is zero when there
+	// are errors.
+	Imported int        `json:"imported"`
+	Errors   []RowError `json:"errors"`
+	// ErrorsTruncated is set when there were more errors than the ones listed
+	ErrorsTruncated bool `json:"errors_truncated"`
+}
+
+func (r *Result) addError(row int, column string, format string, args ...any) {
+	if len(r.Errors) >= maxErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, RowError{Row: row, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+// ParseKind returns the kind named s, as used in the import urls and commands
+func ParseKind(s string) (Kind, error) {
+	switch Kind(s) {
+	case Locations, Cameras, PersonDetections:
+		return Kind(s), nil
+	case "detections":
+		return PersonDetections, nil
+	}
+	return "", fmt.Errorf("unknown import kind %q, expected locations, cameras or personDetections", s)
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case CSV, JSON:
+		return Format(s), nil
+	}
+	return "", fmt.Errorf("unknown import format %q, expected csv or json", s)
+}
+
+// op creates the record of a validated row
+type op struct {
+	row    int
+	create func(ctx context.Context, s store.Store) error
+}
+
+var errDryRun = errors.New("dry run")
+
+// Import reads the records of kind from r and creates them in s. Rows that can not be imported are reported in
+// the result, the returned error is only set when the import could not run at all.
+func Import(ctx context.Context, s store.Store, kind Kind, format Format, r io.Reader, dryRun bool) (Result, error) {
+	result := Result{Kind: kind, DryRun: dryRun, Errors: []RowError{}}
+
+	rows := rowTypes[kind]
+	if rows == nil {
+		return result, fmt.Errorf("unknown import kind %q", kind)
+	}
+
+	records, err := readRecords(r, format, columnsOf(rows), &result)
+	if err != nil {
+		return result, err
+	}
+	result.Rows = len(records)
+
+	// rows are planned even when the file had problems, so every error is reported at once
+	var ops []op
+	switch kind {
+	case Locations:
+		ops, err = planLocations(ctx, s, records, &result)
+	case Cameras:
+		ops, err = planCameras(ctx, s, records, &result)
+	case PersonDetections:
+		ops, err = planPersonDetections(ctx, s, records, &result)
+	}
+	if err != nil || len(result.Errors) > 0 {
+		sort.SliceStable(result.Errors, func(i, j int) bool {
+			return result.Errors[i].Row < result.Errors[j].Row
+		})
+		return result, err
+	}
+
+	err = s.InTx(ctx, func(tx store.Store) error {
+		if kind == PersonDetections {
+			if err := createPartitions(ctx, tx, records); err != nil {
+				return err
+			}
+		}
+
+		for _, op := range ops {
+			if err := op.create(ctx, tx); err != nil {
+				var pgErr *pgconn.PgError
+				if errors.As(err, &pgErr) {
+					message := pgErr.Message
+					if pgErr.Detail != "" {
+						message += ": " + pgErr.Detail
+					}
+					result.addError(op.row, "", "%s", message)
+				}
+				return err
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if errors.Is(err, errDryRun) {
+		err = nil
+	} else if err != nil && len(result.Errors) > 0 {
+		// the failing row was reported, the transaction was rolled back
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+
+	result.Imported = len(ops)
+	return result, nil
+}
+
+var rowTypes = map[Kind]any{
+	Locations:        LocationRow{},
+	Cameras:          CameraRow{},
+	PersonDetections: PersonDetectionRow{},
+}
+
+// columnsOf returns the json names of the fields of row
+func columnsOf(row any) []string {
+	t := reflect.TypeOf(row)
+	columns := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		columns = append(columns, name)
+	}
+	return columns
+}
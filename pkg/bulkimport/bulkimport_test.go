@@ -0,0 +1,367 @@
+package bulkimport
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a memory store with the location hall and its camera entrance
+func newTestStore(t *testing.T) (*store.Memory, dbschema.Location, dbschema.Camera) {
+	t.Helper()
+	ctx := context.Background()
+	s := store.NewMemory()
+
+	location, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+	camera, err := s.CreateCamera(ctx, dbschema.CreateCameraParams{
+		Name:             "entrance",
+		ConnectionString: "rtsp://entrance",
+		LocationID:       int32(location.ID),
+		Orientation:      dbenums.CameraOrientationHorizontal,
+		Tags:             map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("error creating camera: %s", err)
+	}
+	return s, location, camera
+}
+
+func personDetections(t *testing.T, s store.Store) []dbschema.PersonDetection {
+	t.Helper()
+	personDetections, err := s.GetPersonDetections(context.Background(), dbschema.GetPersonDetectionsParams{Count: 100})
+	if err != nil {
+		t.Fatalf("error getting person detections: %s", err)
+	}
+	return personDetections
+}
+
+func TestImportReportsEveryRowError(t *testing.T) {
+	s, _, _ := newTestStore(t)
+
+	file := `camera,camera_id,detection_date,target_direction
+entrance,,2023-05-01T10:00:00Z,left
+entrance,,yesterday,left
+lobby,,2023-05-01T10:00:00Z,
+entrance,,2023-05-01T10:00:00Z,up
+,,2023-05-01T10:00:00Z,
+entrance,7,2023-05-01T10:00:00Z,
+`
+	result, err := Import(context.Background(), s, PersonDetections, CSV, strings.NewReader(file), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+
+	expected := []RowError{
+		{Row: 2, Column: "detection_date", Message: `invalid date "yesterday", expected a date like 2006-01-02T15:04:05Z`},
+		{Row: 3, Column: "camera", Message: `there is no camera named "lobby"`},
+		{Row: 4, Column: "target_direction", Message: `invalid direction "up"`},
+		{Row: 5, Column: "camera", Message: "either camera or camera_id is required"},
+		{Row: 6, Column: "camera_id", Message: "there is no camera with id 7"},
+	}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+	if result.Rows != 6 || result.Imported != 0 {
+		t.Errorf("got %d rows and %d imported, expected 6 rows and none imported", result.Rows, result.Imported)
+	}
+	// the valid first row is not imported either
+	if got := personDetections(t, s); len(got) != 0 {
+		t.Errorf("%d detections were created", len(got))
+	}
+}
+
+func TestImportReportsUnknownColumns(t *testing.T) {
+	s, _, _ := newTestStore(t)
+
+	result, err := Import(context.Background(), s, Locations, CSV, strings.NewReader("name,floor\nlobby,2\n"), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected := []RowError{{Row: 0, Column: "floor", Message: `unknown column "floor"`}}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+
+	result, err = Import(context.Background(), s, Locations, JSON, strings.NewReader(`[{"name": "lobby"}, {"name": "office", "floor": 2}]`), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected = []RowError{{Row: 2, Column: "floor", Message: `unknown column "floor"`}}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+}
+
+func TestImportLocationsWithParents(t *testing.T) {
+	s, hall, _ := newTestStore(t)
+	ctx := context.Background()
+
+	// a parent may be imported by an earlier row of the same file
+	file := `[
+		{"name": "first floor", "parent": "hall", "capacity": 50, "warning_threshold": 40},
+		{"name": "office", "parent": "first floor"}
+	]`
+	result, err := Import(ctx, s, Locations, JSON, strings.NewReader(file), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	if len(result.Errors) > 0 || result.Imported != 2 {
+		t.Fatalf("got %d imported and errors %+v, expected 2 imported", result.Imported, result.Errors)
+	}
+
+	parents := map[string]pgtype.Int8{}
+	locations, err := s.GetLocations(ctx, false)
+	if err != nil {
+		t.Fatalf("error getting locations: %s", err)
+	}
+	ids := map[string]int64{}
+	for _, location := range locations {
+		parents[location.Name] = location.ParentID
+		ids[location.Name] = location.ID
+	}
+	if parents["first floor"].Int64 != hall.ID || parents["office"].Int64 != ids["first floor"] {
+		t.Errorf("got parents %+v, expected first floor in hall and office in first floor", parents)
+	}
+
+	// names stay unique, also within a file
+	result, err = Import(ctx, s, Locations, CSV, strings.NewReader("name,parent\nhall,\nlobby,attic\nlobby,\nlobby,\n"), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected := []RowError{
+		{Row: 1, Column: "name", Message: `a location named "hall" already exists`},
+		{Row: 2, Column: "parent", Message: `there is no location named "attic"`},
+		{Row: 4, Column: "name", Message: `the location "lobby" is also imported by row 3`},
+	}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+}
+
+func TestImportResolvesNames(t *testing.T) {
+	s, hall, entrance := newTestStore(t)
+	ctx := context.Background()
+
+	// a second location named hall makes the name ambiguous
+	other, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+	lobby, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{Name: "lobby"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+
+	file := `name,connection_string,location,location_id
+exit,rtsp://exit,lobby,
+stairs,rtsp://stairs,,` + itoa(hall.ID) + `
+door,rtsp://door,hall,` + itoa(other.ID) + `
+window,rtsp://window,hall,
+roof,rtsp://roof,lobby,` + itoa(hall.ID) + `
+`
+	result, err := Import(ctx, s, Cameras, CSV, strings.NewReader(file), true)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected := []RowError{
+		{Row: 4, Column: "location", Message: `2 locations are named "hall", use location_id instead`},
+		{Row: 5, Column: "location", Message: `the location named "lobby" does not have id ` + itoa(hall.ID)},
+	}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+
+	file = `name,connection_string,location,location_id
+exit,rtsp://exit,lobby,
+stairs,rtsp://stairs,,` + itoa(hall.ID) + `
+door,rtsp://door,hall,` + itoa(other.ID) + `
+`
+	result, err = Import(ctx, s, Cameras, CSV, strings.NewReader(file), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	if len(result.Errors) > 0 || result.Imported != 3 {
+		t.Fatalf("got %d imported and errors %+v, expected 3 imported", result.Imported, result.Errors)
+	}
+
+	cameras, err := s.GetCameras(ctx, false)
+	if err != nil {
+		t.Fatalf("error getting cameras: %s", err)
+	}
+	locations := map[string]int32{}
+	for _, camera := range cameras {
+		locations[camera.Name] = camera.LocationID
+	}
+	expectedLocations := map[string]int32{
+		"entrance": int32(hall.ID),
+		"exit":     int32(lobby.ID),
+		"stairs":   int32(hall.ID),
+		"door":     int32(other.ID),
+	}
+	if !reflect.DeepEqual(locations, expectedLocations) {
+		t.Errorf("got camera locations %v, expected %v", locations, expectedLocations)
+	}
+
+	// detections refer to their camera by name
+	result, err = Import(ctx, s, PersonDetections, JSON, strings.NewReader(`[
+		{"camera": "entrance", "detection_date": "2023-05-01T10:00:00Z", "target_direction": "left"},
+		{"camera_id": `+itoa(entrance.ID)+`, "camera": "entrance", "detection_date": "2023-05-01T11:00:00Z"}
+	]`), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	if len(result.Errors) > 0 || result.Imported != 2 {
+		t.Fatalf("got %d imported and errors %+v, expected 2 imported", result.Imported, result.Errors)
+	}
+	for _, personDetection := range personDetections(t, s) {
+		if personDetection.CameraID != entrance.ID {
+			t.Errorf("detection %d was imported for camera %d", personDetection.ID, personDetection.CameraID)
+		}
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	s, _, _ := newTestStore(t)
+
+	file := "camera,detection_date\nentrance,2023-05-01T10:00:00Z\nentrance,2023-05-01T11:00:00Z\n"
+	result, err := Import(context.Background(), s, PersonDetections, CSV, strings.NewReader(file), true)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected := Result{Kind: PersonDetections, DryRun: true, Rows: 2, Imported: 2, Errors: []RowError{}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("got %+v, expected %+v", result, expected)
+	}
+	if got := personDetections(t, s); len(got) != 0 {
+		t.Errorf("the dry run created %d detections", len(got))
+	}
+}
+
+// failingStore fails creating the detection with failAt as its date like a constraint of the database would, and
+// records the partitions created in its transactions
+type failingStore struct {
+	store.Store
+	failAt     time.Time
+	partitions *[]time.Time
+}
+
+func (s *failingStore) InTx(ctx context.Context, fn func(s store.Store) error) error {
+	return s.Store.InTx(ctx, func(tx store.Store) error {
+		return fn(&failingStore{Store: tx, failAt: s.failAt, partitions: s.partitions})
+	})
+}
+
+func (s *failingStore) CreatePersonDetection(ctx context.Context, arg dbschema.CreatePersonDetectionParams) (dbschema.PersonDetection, error) {
+	if arg.DetectionDate.Time.Equal(s.failAt) {
+		return dbschema.PersonDetection{}, &pgconn.PgError{
+			Code:    "23514",
+			Message: `new row for relation "person_detections" violates check constraint`,
+			Detail:  "Failing row contains the test date.",
+		}
+	}
+	return s.Store.CreatePersonDetection(ctx, arg)
+}
+
+func (s *failingStore) CreatePersonDetectionsPartition(ctx context.Context, month pgtype.Date) error {
+	*s.partitions = append(*s.partitions, month.Time)
+	return nil
+}
+
+func TestImportRollsBackWhenALaterRowFails(t *testing.T) {
+	memory, _, _ := newTestStore(t)
+	s := &failingStore{Store: memory, failAt: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC), partitions: &[]time.Time{}}
+
+	file := `camera,detection_date
+entrance,2023-05-01T10:00:00Z
+entrance,2023-05-01T11:00:00Z
+entrance,2023-05-01T12:00:00Z
+entrance,2023-05-01T13:00:00Z
+`
+	result, err := Import(context.Background(), s, PersonDetections, CSV, strings.NewReader(file), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected := []RowError{{Row: 3, Message: `new row for relation "person_detections" violates check constraint: ` +
+		"Failing row contains the test date."}}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+	if result.Imported != 0 {
+		t.Errorf("got %d imported, expected none", result.Imported)
+	}
+	if got := personDetections(t, memory); len(got) != 0 {
+		t.Errorf("%d detections were kept after the import failed", len(got))
+	}
+}
+
+func TestImportCreatesPartitions(t *testing.T) {
+	memory, _, _ := newTestStore(t)
+	partitions := []time.Time{}
+	s := &failingStore{Store: memory, partitions: &partitions}
+
+	// the months are those of the utc dates, the last row is in may in utc
+	file := `camera,detection_date
+entrance,2023-01-15T10:00:00Z
+entrance,2023-01-20T10:00:00Z
+entrance,2022-12-31T23:00:00Z
+entrance,2023-04-30T22:00:00-03:00
+`
+	result, err := Import(context.Background(), s, PersonDetections, CSV, strings.NewReader(file), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	if len(result.Errors) > 0 || result.Imported != 4 {
+		t.Fatalf("got %d imported and errors %+v, expected 4 imported", result.Imported, result.Errors)
+	}
+
+	months := map[time.Time]int{}
+	for _, partition := range partitions {
+		months[partition]++
+	}
+	expected := map[time.Time]int{
+		time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC): 1,
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC):  1,
+		time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC):  1,
+	}
+	if !reflect.DeepEqual(months, expected) {
+		t.Errorf("created partitions %v, expected %v", months, expected)
+	}
+	if got := personDetections(t, memory); len(got) != 4 {
+		t.Errorf("got %d detections, expected 4", len(got))
+	}
+}
+
+func TestImportWithoutRows(t *testing.T) {
+	s, _, _ := newTestStore(t)
+
+	result, err := Import(context.Background(), s, Cameras, CSV, strings.NewReader(""), false)
+	if err != nil {
+		t.Fatalf("error importing: %s", err)
+	}
+	expected := []RowError{{Row: 0, Message: "the file is empty, expected a header row"}}
+	if !reflect.DeepEqual(result.Errors, expected) {
+		t.Errorf("got errors %+v, expected %+v", result.Errors, expected)
+	}
+
+	if _, err := Import(context.Background(), s, Kind("floors"), CSV, strings.NewReader(""), false); err == nil {
+		t.Error("expected an error importing an unknown kind")
+	}
+	if _, err := ParseKind("detections"); err != nil {
+		t.Errorf("detections is not accepted as a kind: %s", err)
+	}
+}
+
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
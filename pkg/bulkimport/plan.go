@@ -0,0 +1,319 @@
+package bulkimport
+
+import (
+	"context"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/store"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strconv"
+	"time"
+)
+
+// the plan functions validate every record against the stored data, reporting problems in result, and return the
+// operations creating the records
+
+func planLocations(ctx context.Context, s store.Store, records []record, result *Result) ([]op, error) {
+	locations, err := s.GetLocations(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error getting locations: %w", err)
+	}
+
+	// cameras are imported referring to their location by name, so names must stay unique
+	names := map[string]int{}
+	// ids is filled with the ids of the imported locations as they are created, so later rows can use them as parent
+	ids := map[string]int64{}
+	for _, location := range locations {
+		names[location.Name] = 0
+		ids[location.Name] = location.ID
+	}
+
+	ops := make([]op, 0, len(records))
+	for i, rec := range records {
+		row := i + 1
+
+		params := dbschema.CreateLocationParams{Name: rec["name"], Description: rec["description"]}
+		if params.Name == "" {
+			result.addError(row, "name", "a name is required")
+			continue
+		}
+		valid := true
+		for column, value := range map[string]*pgtype.Int4{"capacity": &params.Capacity, "warning_threshold": &params.WarningThreshold} {
+			valueStr, ok := rec[column]
+			if !ok {
+				continue
+			}
+			i, err := strconv.ParseInt(valueStr, 10, 32)
+			if err != nil || i <= 0 {
+				result.addError(row, column, "invalid %s %q, expected a positive number", column, valueStr)
+				valid = false
+				continue
+			}
+			*value = pgtype.Int4{Int32: int32(i), Valid: true}
+		}
+		if params.Capacity.Valid && params.WarningThreshold.Valid && params.WarningThreshold.Int32 > params.Capacity.Int32 {
+			result.addError(row, "warning_threshold", "the warning threshold can not be above the capacity")
+			valid = false
+		}
+		parent, hasParent := rec["parent"]
+		if hasParent {
+			if _, ok := names[parent]; !ok {
+				result.addError(row, "parent", "there is no location named %q", parent)
+				valid = false
+			} else if parent == params.Name {
+				result.addError(row, "parent", "a location can not be its own parent")
+				valid = false
+			}
+		}
+		if !valid {
+			continue
+		}
+		if previous, ok := names[params.Name]; ok {
+			if previous == 0 {
+				result.addError(row, "name", "a location named %q already exists", params.Name)
+			} else {
+				result.addError(row, "name", "the location %q is also imported by row %d", params.Name, previous)
+			}
+			continue
+		}
+		names[params.Name] = row
+
+		ops = append(ops, op{row: row, create: func(ctx context.Context, s store.Store) error {
+			if hasParent {
+				params.ParentID = pgtype.Int8{Int64: ids[parent], Valid: true}
+			}
+			location, err := s.CreateLocation(ctx, params)
+			if err != nil {
+				return err
+			}
+			ids[location.Name] = location.ID
+			return nil
+		}})
+	}
+
+	return ops, nil
+}
+
+// resolveId resolves a record referring to another record by name, by id or by both, returning false after
+// reporting the problem when the reference is missing, unknown or ambiguous
+func resolveId(row int, rec record, nameColumn string, idColumn string, ids map[int64]bool, byName map[string][]int64, result *Result) (int64, bool) {
+	name, hasName := rec[nameColumn]
+	idStr, hasId := rec[idColumn]
+
+	if !hasName && !hasId {
+		result.addError(row, nameColumn, "either %s or %s is required", nameColumn, idColumn)
+		return 0, false
+	}
+
+	var id int64
+	if hasId {
+		var err error
+		if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+			result.addError(row, idColumn, "invalid id %q", idStr)
+			return 0, false
+		}
+		if !ids[id] {
+			result.addError(row, idColumn, "there is no %s with id %d", nameColumn, id)
+			return 0, false
+		}
+	}
+
+	if hasName {
+		matches := byName[name]
+		switch {
+		case len(matches) == 0:
+			result.addError(row, nameColumn, "there is no %s named %q", nameColumn, name)
+			return 0, false
+		case hasId:
+			for _, match := range matches {
+				if match == id {
+					return id, true
+				}
+			}
+			result.addError(row, nameColumn, "the %s named %q does not have id %d", nameColumn, name, id)
+			return 0, false
+		case len(matches) > 1:
+			result.addError(row, nameColumn, "%d %ss are named %q, use %s instead", len(matches), nameColumn, name, idColumn)
+			return 0, false
+		}
+		id = matches[0]
+	}
+
+	return id, true
+}
+
+func planCameras(ctx context.Context, s store.Store, records []record, result *Result) ([]op, error) {
+	locations, err := s.GetLocations(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error getting locations: %w", err)
+	}
+
+	ids := map[int64]bool{}
+	byName := map[string][]int64{}
+	for _, location := range locations {
+		ids[location.ID] = true
+		byName[location.Name] = append(byName[location.Name], location.ID)
+	}
+
+	ops := make([]op, 0, len(records))
+	for i, rec := range records {
+		row := i + 1
+		valid := true
+
+		params := dbschema.CreateCameraParams{
+			Name:             rec["name"],
+			ConnectionString: rec["connection_string"],
+			Orientation:      dbenums.CameraOrientationHorizontal,
+			MountDescription: rec["mount_description"],
+			Tags:             map[string]string{},
+		}
+		if locationText, ok := rec["location_text"]; ok {
+			if _, ok := rec["mount_description"]; ok {
+				result.addError(row, "location_text", "location_text is the former name of mount_description, use only one of them")
+				valid = false
+			}
+			params.MountDescription = locationText
+		}
+		if params.Name == "" {
+			result.addError(row, "name", "a name is required")
+			valid = false
+		}
+		if params.ConnectionString == "" {
+			result.addError(row, "connection_string", "a connection string is required")
+			valid = false
+		}
+		if orientation, ok := rec["orientation"]; ok {
+			if err := params.Orientation.Scan(orientation); err != nil {
+				result.addError(row, "orientation", "invalid orientation %q", orientation)
+				valid = false
+			}
+		}
+		if entryDirection, ok := rec["entry_direction"]; ok {
+			if err := params.EntryDirection.Scan(entryDirection); err != nil {
+				result.addError(row, "entry_direction", "invalid direction %q", entryDirection)
+				valid = false
+			}
+		}
+		for _, column := range []struct {
+			name  string
+			value **float64
+		}{{"floor_x", &params.FloorX}, {"floor_y", &params.FloorY}, {"heading", &params.Heading}, {"mounting_height", &params.MountingHeight}} {
+			valueStr, ok := rec[column.name]
+			if !ok {
+				continue
+			}
+			f, err := strconv.ParseFloat(valueStr, 64)
+			if err != nil {
+				result.addError(row, column.name, "invalid %s %q, expected a number", column.name, valueStr)
+				valid = false
+				continue
+			}
+			*column.value = &f
+		}
+		if (params.FloorX == nil) != (params.FloorY == nil) {
+			result.addError(row, "floor_x", "floor_x and floor_y must be given together")
+			valid = false
+		}
+		if params.Heading != nil && (*params.Heading < 0 || *params.Heading >= 360) {
+			result.addError(row, "heading", "the heading must be at least 0 and less than 360 degrees")
+			valid = false
+		}
+		if params.MountingHeight != nil && *params.MountingHeight <= 0 {
+			result.addError(row, "mounting_height", "the mounting height must be positive")
+			valid = false
+		}
+
+		locationId, ok := resolveId(row, rec, "location", "location_id", ids, byName, result)
+		if !ok || !valid {
+			continue
+		}
+		params.LocationID = int32(locationId)
+
+		ops = append(ops, op{row: row, create: func(ctx context.Context, s store.Store) error {
+			_, err := s.CreateCamera(ctx, params)
+			return err
+		}})
+	}
+
+	return ops, nil
+}
+
+func planPersonDetections(ctx context.Context, s store.Store, records []record, result *Result) ([]op, error) {
+	cameras, err := s.GetCameras(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cameras: %w", err)
+	}
+
+	ids := map[int64]bool{}
+	byName := map[string][]int64{}
+	for _, camera := range cameras {
+		ids[camera.ID] = true
+		byName[camera.Name] = append(byName[camera.Name], camera.ID)
+	}
+
+	ops := make([]op, 0, len(records))
+	for i, rec := range records {
+		row := i + 1
+		valid := true
+
+		params := dbschema.CreatePersonDetectionParams{TargetDirection: dbenums.DirectionNone}
+		if dateStr, ok := rec["detection_date"]; !ok {
+			result.addError(row, "detection_date", "a detection date is required")
+			valid = false
+		} else if date, err := time.Parse(time.RFC3339Nano, dateStr); err != nil {
+			result.addError(row, "detection_date", "invalid date %q, expected a date like 2006-01-02T15:04:05Z", dateStr)
+			valid = false
+		} else {
+			params.DetectionDate = pgtype.Timestamptz{Time: date, Valid: true}
+		}
+		if direction, ok := rec["target_direction"]; ok {
+			if err := params.TargetDirection.Scan(direction); err != nil {
+				result.addError(row, "target_direction", "invalid direction %q", direction)
+				valid = false
+			}
+		}
+
+		cameraId, ok := resolveId(row, rec, "camera", "camera_id", ids, byName, result)
+		if !ok || !valid {
+			continue
+		}
+		params.CameraID = cameraId
+
+		ops = append(ops, op{row: row, create: func(ctx context.Context, s store.Store) error {
+			_, err := s.CreatePersonDetection(ctx, params)
+			return err
+		}})
+	}
+
+	return ops, nil
+}
+
+// partitioner is implemented by stores that partition person detections by month
+type partitioner interface {
+	CreatePersonDetectionsPartition(ctx context.Context, month pgtype.Date) error
+}
+
+// createPartitions creates the partitions of the months of the imported detections. Historical detections would
+// otherwise land in the default partition, which blocks creating the partition of their month later on.
+func createPartitions(ctx context.Context, s store.Store, records []record) error {
+	p, ok := s.(partitioner)
+	if !ok {
+		return nil
+	}
+
+	months := map[time.Time]bool{}
+	for _, rec := range records {
+		// the records were validated already
+		date, _ := time.Parse(time.RFC3339Nano, rec["detection_date"])
+		year, month, _ := date.UTC().Date()
+		months[time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)] = true
+	}
+
+	for month := range months {
+		if err := p.CreatePersonDetectionsPartition(ctx, pgtype.Date{Time: month, Valid: true}); err != nil {
+			return fmt.Errorf("error creating partition for %s: %w", month.Format("2006-01"), err)
+		}
+	}
+	return nil
+}
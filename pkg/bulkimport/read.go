@@ -0,0 +1,117 @@
+package bulkimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// record holds the non empty columns of a row, by column name
+type record map[string]string
+
+// readRecords decodes every row of r. Problems with the file itself, like unknown columns or malformed rows, are
+// reported in result.
+func readRecords(r io.Reader, format Format, columns []string, result *Result) ([]record, error) {
+	known := map[string]bool{}
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	switch format {
+	case CSV:
+		return readCsvRecords(r, known, result)
+	case JSON:
+		return readJsonRecords(r, known, result)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+func readCsvRecords(r io.Reader, known map[string]bool, result *Result) ([]record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		result.addError(0, "", "the file is empty, expected a header row")
+		return nil, nil
+	} else if err != nil {
+		result.addError(0, "", "invalid csv header: %s", err)
+		return nil, nil
+	}
+
+	for i, column := range header {
+		// spreadsheet programs like to start their csv files with a byte order mark
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		header[i] = column
+		if !known[column] {
+			result.addError(0, column, "unknown column %q", column)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return nil, nil
+	}
+
+	var records []record
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// the rest of the file can not be trusted after a malformed row
+			result.addError(row, "", "invalid csv row: %s", parseErr.Err)
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading csv: %w", err)
+		}
+
+		rec := record{}
+		for i, field := range fields {
+			if field = strings.TrimSpace(field); field != "" {
+				rec[header[i]] = field
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+func readJsonRecords(r io.Reader, known map[string]bool, result *Result) ([]record, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var objects []map[string]any
+	if err := decoder.Decode(&objects); err != nil {
+		result.addError(0, "", "invalid json, expected an array of objects: %s", err)
+		return nil, nil
+	}
+
+	records := make([]record, 0, len(objects))
+	for i, object := range objects {
+		row := i + 1
+		rec := record{}
+		for column, value := range object {
+			if !known[column] {
+				result.addError(row, column, "unknown column %q", column)
+				continue
+			}
+
+			switch value := value.(type) {
+			case nil:
+			case string:
+				if value = strings.TrimSpace(value); value != "" {
+					rec[column] = value
+				}
+			case json.Number:
+				rec[column] = value.String()
+			default:
+				result.addError(row, column, "expected a string or a number")
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,390 @@
+// Package client implements a typed client for the camera_service http api.
+//
+// The request and response types are the ones used by the service itself, so they always match the json
+// sent over the wire.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type (
+	Camera                              = dbschema.Camera
+	CameraStatus                        = dbschema.CameraStatus
+	CreateCameraParams                  = dbschema.CreateCameraParams
+	UpdateCameraParams                  = dbschema.UpdateCameraParams
+	Location                            = dbschema.Location
+	CreateLocationParams                = dbschema.CreateLocationParams
+	UpdateLocationParams                = dbschema.UpdateLocationParams
+	PersonDetection                     = dbschema.PersonDetection
+	CreatePersonDetectionParams         = dbschema.CreatePersonDetectionParams
+	UpdatePersonDetectionParams         = dbschema.UpdatePersonDetectionParams
+	GetPersonDetectionsParams           = dbschema.GetPersonDetectionsParams
+	GetPersonDetectionsForCameraParams  = dbschema.GetPersonDetectionsForCameraParams
+	GetDailyPersonDetectionsCountParams = dbschema.GetDailyPersonDetectionsCountParams
+	DailyPersonDetectionsCount          = dbschema.GetDailyPersonDetectionsCountRow
+)
+
+// Client is a client for the camera_service http api, it is safe for concurrent use
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient makes the client send its requests through httpClient instead of a default client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy replaces the retry policy used when ingesting person detections
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New creates a client for the service running at baseUrl, for example http://localhost:3000
+func New(baseUrl string, options ...Option) *Client {
+	c := &Client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// do sends a request with an optional json body and decodes the json response into out, if not nil
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in any, out any) error {
+	return c.doWithHeader(ctx, method, path, query, nil, in, out)
+}
+
+// doWithHeader works like do, adding header to the request
+func (c *Client) doWithHeader(ctx context.Context, method string, path string, query url.Values, header http.Header, in any, out any) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("error marshaling request body: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	reqUrl := c.baseUrl + path
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(res.Body)
+		return &APIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: res.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response body: %w", err)
+	}
+
+	return nil
+}
+
+// doWithRetry works like do, but retries according to the client retry policy on network errors and on
+// responses that indicate a temporary failure. Every attempt is sent with the same Idempotency-Key, so that the
+// service does not create the same detection twice when only its response was lost
+func (c *Client) doWithRetry(ctx context.Context, method string, path string, in any, out any) error {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Idempotency-Key": {key}}
+
+	for attempt := 1; ; attempt++ {
+		err = c.doWithHeader(ctx, method, path, nil, header, in, out)
+
+		var apiErr *APIError
+		if err == nil || (errors.As(err, &apiErr) && !apiErr.retryable()) || attempt >= c.retry.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(c.retry.backoff(attempt)):
+		}
+	}
+}
+
+// newIdempotencyKey returns a random key, unique for every request that is not a retry
+func newIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("error generating idempotency key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func includeDeletedQuery(includeDeleted bool) url.Values {
+	if !includeDeleted {
+		return nil
+	}
+	return url.Values{"include_deleted": {"true"}}
+}
+
+func hardDeleteQuery() url.Values {
+	return url.Values{"hard": {"true"}}
+}
+
+func paginationQuery(offset int32, count int32) url.Values {
+	query := url.Values{}
+	query.Set("offset", fmt.Sprint(offset))
+	query.Set("count", fmt.Sprint(count))
+	return query
+}
+
+// withDetectionFilters adds the optional filters of the detection listings to query
+func withDetectionFilters(query url.Values, trackId pgtype.Text, minConfidence pgtype.Float8, modelVersion pgtype.Text, excluded pgtype.Bool, afterId pgtype.Int8) url.Values {
+	if trackId.Valid {
+		query.Set("track_id", trackId.String)
+	}
+	if minConfidence.Valid {
+		query.Set("min_confidence", fmt.Sprint(minConfidence.Float64))
+	}
+	if modelVersion.Valid {
+		query.Set("model_version", modelVersion.String)
+	}
+	if excluded.Valid {
+		query.Set("excluded", fmt.Sprint(excluded.Bool))
+	}
+	if afterId.Valid {
+		query.Set("after_id", fmt.Sprint(afterId.Int64))
+	}
+	return query
+}
+
+func (c *Client) GetLocations(ctx context.Context, includeDeleted bool) ([]Location, error) {
+	var locations []Location
+	err := c.do(ctx, http.MethodGet, "/locations", includeDeletedQuery(includeDeleted), nil, &locations)
+	return locations, err
+}
+
+func (c *Client) GetLocation(ctx context.Context, id int64) (Location, error) {
+	var location Location
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/locations/%d", id), nil, nil, &location)
+	return location, err
+}
+
+func (c *Client) CreateLocation(ctx context.Context, arg CreateLocationParams) (Location, error) {
+	var location Location
+	err := c.do(ctx, http.MethodPost, "/locations", nil, &arg, &location)
+	return location, err
+}
+
+// UpdateLocation changes the fields of the location arg.ID that are set in arg
+func (c *Client) UpdateLocation(ctx context.Context, arg UpdateLocationParams) (Location, error) {
+	var location Location
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/locations/%d", arg.ID), nil, &arg, &location)
+	return location, err
+}
+
+// SoftDeleteLocation marks a location as deleted, hiding it from the listings until it is restored
+func (c *Client) SoftDeleteLocation(ctx context.Context, id int64) (Location, error) {
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/locations/%d", id), nil, nil, nil); err != nil {
+		return Location{}, err
+	}
+	return c.GetLocation(ctx, id)
+}
+
+func (c *Client) RestoreLocation(ctx context.Context, id int64) (Location, error) {
+	var location Location
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/locations/%d/restore", id), nil, nil, &location)
+	return location, err
+}
+
+// DeleteLocation removes a location for good
+func (c *Client) DeleteLocation(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/locations/%d", id), hardDeleteQuery(), nil, nil)
+}
+
+func (c *Client) GetCameras(ctx context.Context, includeDeleted bool) ([]Camera, error) {
+	var cameras []Camera
+	err := c.do(ctx, http.MethodGet, "/cameras", includeDeletedQuery(includeDeleted), nil, &cameras)
+	return cameras, err
+}
+
+func (c *Client) GetCamera(ctx context.Context, id int64) (Camera, error) {
+	var camera Camera
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/cameras/%d", id), nil, nil, &camera)
+	return camera, err
+}
+
+// GetCameraStatus returns the latest connectivity check of a camera, with status unknown if it was not checked yet
+func (c *Client) GetCameraStatus(ctx context.Context, id int64) (CameraStatus, error) {
+	var status CameraStatus
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/cameras/%d/status", id), nil, nil, &status)
+	return status, err
+}
+
+func (c *Client) CreateCamera(ctx context.Context, arg CreateCameraParams) (Camera, error) {
+	var camera Camera
+	err := c.do(ctx, http.MethodPost, "/cameras", nil, &arg, &camera)
+	return camera, err
+}
+
+// UpdateCamera changes the fields of the camera arg.ID that are set in arg
+func (c *Client) UpdateCamera(ctx context.Context, arg UpdateCameraParams) (Camera, error) {
+	var camera Camera
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/cameras/%d", arg.ID), nil, &arg, &camera)
+	return camera, err
+}
+
+// SoftDeleteCamera marks a camera as deleted, hiding it from the listings and rejecting its detections until it is
+// restored
+func (c *Client) SoftDeleteCamera(ctx context.Context, id int64) (Camera, error) {
+	if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/cameras/%d", id), nil, nil, nil); err != nil {
+		return Camera{}, err
+	}
+	return c.GetCamera(ctx, id)
+}
+
+func (c *Client) RestoreCamera(ctx context.Context, id int64) (Camera, error) {
+	var camera Camera
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/cameras/%d/restore", id), nil, nil, &camera)
+	return camera, err
+}
+
+// DeleteCamera removes a camera for good, failing while it still has detections
+func (c *Client) DeleteCamera(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/cameras/%d", id), hardDeleteQuery(), nil, nil)
+}
+
+// HardDeleteCamera removes a camera for good, deleting its detections with it when deleteDetections is set or moving
+// them to the camera reassignTo when it is not 0
+func (c *Client) HardDeleteCamera(ctx context.Context, id int64, deleteDetections bool, reassignTo int64) error {
+	query := hardDeleteQuery()
+	if deleteDetections {
+		query.Set("detections", "delete")
+	}
+	if reassignTo != 0 {
+		query.Set("reassign_to", fmt.Sprint(reassignTo))
+	}
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/cameras/%d", id), query, nil, nil)
+}
+
+// GetDailyPersonDetectionsCount returns the amount of detections per day of a camera, for the days included in
+// arg.Interval counting back from today. Only the days and months of the interval are used.
+func (c *Client) GetDailyPersonDetectionsCount(ctx context.Context, arg GetDailyPersonDetectionsCountParams) ([]DailyPersonDetectionsCount, error) {
+	query := url.Values{}
+	query.Set("days", fmt.Sprint(arg.Interval.Days))
+	query.Set("months", fmt.Sprint(arg.Interval.Months))
+
+	var counts []DailyPersonDetectionsCount
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/cameras/%d/dailyPersonDetectionsCount", arg.CameraID), query, nil, &counts)
+	return counts, err
+}
+
+// GetPersonDetections returns a page of detections of all cameras, newest first, or by id when AfterID is set
+func (c *Client) GetPersonDetections(ctx context.Context, arg GetPersonDetectionsParams) ([]PersonDetection, error) {
+	var personDetections []PersonDetection
+	query := withDetectionFilters(paginationQuery(arg.DetectionOffset, arg.Count), arg.TrackID, arg.MinConfidence, arg.ModelVersion, arg.Excluded, arg.AfterID)
+	err := c.do(ctx, http.MethodGet, "/personDetections", query, nil, &personDetections)
+	return personDetections, err
+}
+
+// GetPersonDetectionsForCamera returns a page of detections of a single camera, newest first, or by id when AfterID
+// is set
+func (c *Client) GetPersonDetectionsForCamera(ctx context.Context, arg GetPersonDetectionsForCameraParams) ([]PersonDetection, error) {
+	var personDetections []PersonDetection
+	path := fmt.Sprintf("/cameras/%d/personDetections", arg.CameraID)
+	query := withDetectionFilters(paginationQuery(arg.DetectionOffset, arg.Count), arg.TrackID, arg.MinConfidence, arg.ModelVersion, arg.Excluded, arg.AfterID)
+	err := c.do(ctx, http.MethodGet, path, query, nil, &personDetections)
+	return personDetections, err
+}
+
+// GetLatestPersonDetection returns the detection with the greatest id, the error matches ErrNotFound when there
+// are no detections
+func (c *Client) GetLatestPersonDetection(ctx context.Context) (PersonDetection, error) {
+	var personDetection PersonDetection
+	err := c.do(ctx, http.MethodGet, "/personDetections/latest", nil, nil, &personDetection)
+	return personDetection, err
+}
+
+func (c *Client) GetPersonDetection(ctx context.Context, id int64) (PersonDetection, error) {
+	var personDetection PersonDetection
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/personDetections/%d", id), nil, nil, &personDetection)
+	return personDetection, err
+}
+
+// CreatePersonDetection ingests a single detection, retrying according to the client retry policy. arg.Flagged and
+// arg.Excluded are ignored, the service decides them from its minimum confidence and the maintenance of the camera
+func (c *Client) CreatePersonDetection(ctx context.Context, arg CreatePersonDetectionParams) (PersonDetection, error) {
+	var personDetection PersonDetection
+	err := c.doWithRetry(ctx, http.MethodPost, "/personDetections", &arg, &personDetection)
+	return personDetection, err
+}
+
+// UpdatePersonDetection changes the fields of the detection arg.ID that are set in arg, arg.MinConfidence is ignored
+func (c *Client) UpdatePersonDetection(ctx context.Context, arg UpdatePersonDetectionParams) (PersonDetection, error) {
+	var personDetection PersonDetection
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/personDetections/%d", arg.ID), nil, &arg, &personDetection)
+	return personDetection, err
+}
+
+func (c *Client) DeletePersonDetection(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/personDetections/%d", id), nil, nil, nil)
+}
+
+// CreateCameraPersonDetection ingests a single detection for cameraId, ignoring arg.CameraID, arg.Flagged and
+// arg.Excluded. Requests are retried according to the client retry policy.
+func (c *Client) CreateCameraPersonDetection(ctx context.Context, cameraId int64, arg CreatePersonDetectionParams) (PersonDetection, error) {
+	var personDetection PersonDetection
+	err := c.doWithRetry(ctx, http.MethodPost, fmt.Sprintf("/cameras/%d/personDetections", cameraId), &arg, &personDetection)
+	return personDetection, err
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrBadRequest is matched by errors.Is for api errors with status 400
+	ErrBadRequest = errors.New("bad request")
+	// ErrNotFound is matched by errors.Is for api errors with status 404
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is matched by errors.Is for api errors with status 409, returned for example when a referenced
+	// id does not exist or when deleting a record that is still referenced
+	ErrConflict = errors.New("conflict")
+	// ErrServer is matched by errors.Is for api errors with a 5xx status
+	ErrServer = errors.New("server error")
+)
+
+// APIError is returned when the api answers with a non 2xx status code
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the error message sent by the api
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// retryable returns whether a request that failed with this error may succeed if sent again
+func (e *APIError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
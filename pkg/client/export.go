@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type (
+	ExportPersonDetectionsParams = dbschema.ExportPersonDetectionsParams
+	ExportPersonDetectionsRow    = dbschema.ExportPersonDetectionsRow
+)
+
+// StreamPersonDetectionsExport reads the newline delimited json export and calls fn for every detection, oldest
+// first, stopping at the first error. The export is a single query, so unlike paging through the listings it does
+// not skip or repeat detections created while it runs. The api selects cameras by id or location, arg.LocationIds
+// and arg.CameraIds must be empty. The timeout of the http client covers reading the whole export.
+func (c *Client) StreamPersonDetectionsExport(ctx context.Context, arg ExportPersonDetectionsParams, fn func(row ExportPersonDetectionsRow) error) error {
+	if len(arg.LocationIds) > 0 || len(arg.CameraIds) > 0 {
+		return errors.New("the export api does not take location or camera id lists")
+	}
+
+	path := "/personDetections/export"
+	query := url.Values{}
+	query.Set("format", "ndjson")
+	if arg.FromDate.Valid {
+		query.Set("from", arg.FromDate.Time.Format(time.RFC3339Nano))
+	}
+	if arg.ToDate.Valid {
+		query.Set("to", arg.ToDate.Time.Format(time.RFC3339Nano))
+	}
+	if arg.CameraID.Valid {
+		query.Set("camera_id", fmt.Sprint(arg.CameraID.Int64))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(res.Body)
+		return &APIError{
+			Method:     http.MethodGet,
+			Path:       path,
+			StatusCode: res.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(res.Body))
+	for {
+		var row ExportPersonDetectionsRow
+		if err := dec.Decode(&row); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error decoding exported detection: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
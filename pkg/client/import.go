@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/bulkimport"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	ImportKind     = bulkimport.Kind
+	ImportFormat   = bulkimport.Format
+	ImportResult   = bulkimport.Result
+	ImportRowError = bulkimport.RowError
+)
+
+// Import uploads a csv or json file of locations, cameras or person detections. An import rejected because of
+// invalid rows is not an error: nothing is created and the rows are listed in the Errors of the result.
+func (c *Client) Import(ctx context.Context, kind ImportKind, format ImportFormat, r io.Reader, dryRun bool) (ImportResult, error) {
+	path := "/import/" + string(kind)
+	query := url.Values{}
+	query.Set("format", string(format))
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl+path+"?"+query.Encode(), r)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if format == bulkimport.CSV {
+		req.Header.Set("Content-Type", "text/csv")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("error sending request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusUnprocessableEntity {
+		msg, _ := io.ReadAll(res.Body)
+		return ImportResult{}, &APIError{
+			Method:     http.MethodPost,
+			Path:       path,
+			StatusCode: res.StatusCode,
+			Message:    strings.TrimSpace(string(msg)),
+		}
+	}
+
+	var result ImportResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return ImportResult{}, fmt.Errorf("error decoding response body: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchError is returned by CreatePersonDetections when some of the detections could not be ingested
+type BatchError struct {
+	// Errors maps the index of each failed detection in the batch to its error
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d detections of the batch could not be ingested", len(e.Errors))
+}
+
+// CreatePersonDetections ingests a batch of detections using up to concurrency parallel requests, each retried
+// according to the client retry policy. The returned slice has the same order as batch, the entries of failed
+// detections are left empty and listed in the returned *BatchError.
+func (c *Client) CreatePersonDetections(ctx context.Context, batch []CreatePersonDetectionParams, concurrency int) ([]PersonDetection, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	created := make([]PersonDetection, len(batch))
+	errs := make(map[int]error)
+	var errsMutex sync.Mutex
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				personDetection, err := c.CreatePersonDetection(ctx, batch[i])
+				if err != nil {
+					errsMutex.Lock()
+					errs[i] = err
+					errsMutex.Unlock()
+					continue
+				}
+				created[i] = personDetection
+			}
+		}()
+	}
+
+	for i := range batch {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return created, &BatchError{Errors: errs}
+	}
+	return created, nil
+}
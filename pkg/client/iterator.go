@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+)
+
+// DefaultPageSize is the amount of detections requested at a time by iterators when no page size is given
+const DefaultPageSize = 500
+
+// PersonDetectionIterator walks over all the pages of a detection listing, newest first. Use it like:
+//
+//	it := c.IteratePersonDetections(ctx, 0)
+//	for it.Next() {
+//		personDetection := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type PersonDetectionIterator struct {
+	ctx      context.Context
+	fetch    func(ctx context.Context, offset int32, count int32) ([]PersonDetection, error)
+	pageSize int32
+	offset   int32
+	page     []PersonDetection
+	current  PersonDetection
+	done     bool
+	err      error
+}
+
+func newPersonDetectionIterator(ctx context.Context, pageSize int32,
+	fetch func(ctx context.Context, offset int32, count int32) ([]PersonDetection, error)) *PersonDetectionIterator {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &PersonDetectionIterator{ctx: ctx, fetch: fetch, pageSize: pageSize}
+}
+
+// IteratePersonDetections iterates over the detections of all cameras
+func (c *Client) IteratePersonDetections(ctx context.Context, pageSize int32) *PersonDetectionIterator {
+	return newPersonDetectionIterator(ctx, pageSize, func(ctx context.Context, offset int32, count int32) ([]PersonDetection, error) {
+		return c.GetPersonDetections(ctx, GetPersonDetectionsParams{DetectionOffset: offset, Count: count})
+	})
+}
+
+// IterateCameraPersonDetections iterates over the detections of a single camera
+func (c *Client) IterateCameraPersonDetections(ctx context.Context, cameraId int64, pageSize int32) *PersonDetectionIterator {
+	return newPersonDetectionIterator(ctx, pageSize, func(ctx context.Context, offset int32, count int32) ([]PersonDetection, error) {
+		return c.GetPersonDetectionsForCamera(ctx, GetPersonDetectionsForCameraParams{
+			CameraID:        cameraId,
+			DetectionOffset: offset,
+			Count:           count,
+		})
+	})
+}
+
+// Next advances the iterator, fetching the next page when needed. It returns false when there are no more
+// detections or an error happened.
+func (it *PersonDetectionIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+
+		page, err := it.fetch(it.ctx, it.offset, it.pageSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.offset += int32(len(page))
+		it.page = page
+		it.done = int32(len(page)) < it.pageSize
+
+		if len(page) == 0 {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Value returns the detection the iterator currently points to
+func (it *PersonDetectionIterator) Value() PersonDetection {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *PersonDetectionIterator) Err() error {
+	return it.err
+}
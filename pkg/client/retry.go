@@ -0,0 +1,43 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how failed ingest requests are retried. Requests are retried on network errors and on
+// 5xx and 429 responses, waiting an exponentially growing, jittered backoff between attempts. Every attempt carries
+// the same Idempotency-Key, services that predate idempotency keys ignore it and may store a detection twice when
+// only its response was lost.
+type RetryPolicy struct {
+	// MaxAttempts is the total amount of times a request is sent, values below 2 disable retries
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// NoRetry sends every request only once
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns how long to wait after the given failed attempt, starting at 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	// full jitter keeps many clients from retrying in lockstep
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// PersonDetectionStream delivers newly created detections as they appear
+type PersonDetectionStream struct {
+	// C receives every new detection, it is closed when the stream stops
+	C   <-chan PersonDetection
+	err error
+}
+
+// Err returns the error that stopped the stream, it must only be called after C is closed
+func (s *PersonDetectionStream) Err() error {
+	return s.err
+}
+
+// StreamPersonDetections polls the api every interval and sends detections created after it returns to
+// the returned stream, in the order they were created. If cameraId is not 0 only detections of that camera are
+// streamed. The stream stops when ctx is cancelled or a request fails.
+//
+// New detections are recognized by their id: the stream starts after the latest detection of any camera, and every
+// poll asks for the detections after the greatest id seen so far, which the api sorts by id, page by page until
+// none are left. Detections created with an older date are streamed as well.
+func (c *Client) StreamPersonDetections(ctx context.Context, cameraId int64, interval time.Duration) *PersonDetectionStream {
+	ch := make(chan PersonDetection)
+	stream := &PersonDetectionStream{C: ch}
+
+	fetch := func(afterId int64) ([]PersonDetection, error) {
+		if cameraId != 0 {
+			return c.GetPersonDetectionsForCamera(ctx, GetPersonDetectionsForCameraParams{
+				CameraID: cameraId,
+				AfterID:  pgtype.Int8{Int64: afterId, Valid: true},
+				Count:    DefaultPageSize,
+			})
+		}
+		return c.GetPersonDetections(ctx, GetPersonDetectionsParams{
+			AfterID: pgtype.Int8{Int64: afterId, Valid: true},
+			Count:   DefaultPageSize,
+		})
+	}
+
+	fail := func(err error) {
+		if ctx.Err() == nil {
+			stream.err = err
+		}
+	}
+
+	// the stream starts before returning, so every detection created after it returns is streamed
+	latest, err := c.GetLatestPersonDetection(ctx)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		fail(err)
+		close(ch)
+		return stream
+	}
+
+	go func() {
+		defer close(ch)
+
+		lastId := latest.ID
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			for {
+				page, err := fetch(lastId)
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				for _, personDetection := range page {
+					select {
+					case ch <- personDetection:
+						lastId = personDetection.ID
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				if len(page) < DefaultPageSize {
+					break
+				}
+			}
+		}
+	}()
+
+	return stream
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDetectionsApi serves the latest detection and the after_id listings like the api, detections are sorted by id
+// and every listing must be paged by cursor only
+type fakeDetectionsApi struct {
+	t       *testing.T
+	mutex   sync.Mutex
+	created []PersonDetection
+	// onList runs after a listing page is computed, before it is sent, to create detections while a poll runs
+	onList func()
+}
+
+func (f *fakeDetectionsApi) create(cameraId int64, count int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for i := 0; i < count; i++ {
+		f.created = append(f.created, PersonDetection{
+			ID:                  int64(len(f.created) + 1),
+			CameraID:            cameraId,
+			TargetDirection:     dbenums.DirectionLeft,
+			NormalizedDirection: string(dbenums.DirectionLeft),
+		})
+	}
+}
+
+func (f *fakeDetectionsApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	var body any
+	switch r.URL.Path {
+	case "/personDetections/latest":
+		if len(f.created) == 0 {
+			f.mutex.Unlock()
+			http.Error(w, "there are no person detections", http.StatusNotFound)
+			return
+		}
+		body = f.created[len(f.created)-1]
+	case "/personDetections", "/cameras/2/personDetections":
+		query := r.URL.Query()
+		if offset := query.Get("offset"); offset != "" && offset != "0" {
+			f.t.Errorf("listing requested with offset %s", offset)
+		}
+		afterId, err := strconv.ParseInt(query.Get("after_id"), 10, 64)
+		if err != nil {
+			f.t.Errorf("listing requested without after_id: %s", err)
+		}
+		count, _ := strconv.Atoi(query.Get("count"))
+
+		page := []PersonDetection{}
+		for _, personDetection := range f.created {
+			if personDetection.ID > afterId && len(page) < count &&
+				(r.URL.Path == "/personDetections" || personDetection.CameraID == 2) {
+				page = append(page, personDetection)
+			}
+		}
+		body = page
+	default:
+		f.mutex.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	onList := f.onList
+	f.mutex.Unlock()
+
+	if onList != nil && r.URL.Path != "/personDetections/latest" {
+		onList()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		f.t.Error(err)
+	}
+}
+
+// receive reads count detections from the stream, failing the test if they do not arrive in time
+func receive(t *testing.T, stream *PersonDetectionStream, count int) []PersonDetection {
+	t.Helper()
+	var received []PersonDetection
+	timeout := time.After(5 * time.Second)
+	for len(received) < count {
+		select {
+		case personDetection, ok := <-stream.C:
+			if !ok {
+				t.Fatalf("stream stopped after %d of %d detections: %v", len(received), count, stream.Err())
+			}
+			received = append(received, personDetection)
+		case <-timeout:
+			t.Fatalf("received %d of %d detections", len(received), count)
+		}
+	}
+	return received
+}
+
+// expectIds fails the test unless the ids of personDetections are exactly from to to, in order
+func expectIds(t *testing.T, personDetections []PersonDetection, from int64, to int64) {
+	t.Helper()
+	if len(personDetections) != int(to-from+1) {
+		t.Fatalf("got %d detections, expected ids %d to %d", len(personDetections), from, to)
+	}
+	for i, personDetection := range personDetections {
+		if personDetection.ID != from+int64(i) {
+			t.Fatalf("detection %d has id %d, expected %d", i, personDetection.ID, from+int64(i))
+		}
+	}
+}
+
+func TestStreamPersonDetectionsDeliversEachDetectionOnce(t *testing.T) {
+	api := &fakeDetectionsApi{t: t}
+	api.create(1, 3)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := New(server.URL).StreamPersonDetections(ctx, 0, 10*time.Millisecond)
+
+	// the detections created before the stream started are skipped, the ones created while a poll pages through
+	// the listing are delivered by that poll or the next one
+	var once sync.Once
+	api.mutex.Lock()
+	api.onList = func() {
+		once.Do(func() { api.create(1, 7) })
+	}
+	api.mutex.Unlock()
+	api.create(1, 2*DefaultPageSize+10)
+	expectIds(t, receive(t, stream, 2*DefaultPageSize+17), 4, 2*DefaultPageSize+20)
+
+	api.create(1, 5)
+	expectIds(t, receive(t, stream, 5), 2*DefaultPageSize+21, 2*DefaultPageSize+25)
+
+	select {
+	case personDetection := <-stream.C:
+		t.Fatalf("detection %d was delivered again", personDetection.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	for range stream.C {
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("cancelled stream reported %s", err)
+	}
+}
+
+func TestStreamPersonDetectionsOfCamera(t *testing.T) {
+	api := &fakeDetectionsApi{t: t}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// without any detection the stream starts from the first one
+	stream := New(server.URL).StreamPersonDetections(ctx, 2, 10*time.Millisecond)
+
+	api.create(1, 2)
+	api.create(2, 3)
+	api.create(1, 1)
+	api.create(2, 1)
+
+	received := receive(t, stream, 4)
+	if !sort.SliceIsSorted(received, func(i, j int) bool { return received[i].ID < received[j].ID }) {
+		t.Errorf("detections were not delivered in order: %v", received)
+	}
+	for _, personDetection := range received {
+		if personDetection.CameraID != 2 {
+			t.Errorf("detection %d of camera %d was delivered", personDetection.ID, personDetection.CameraID)
+		}
+	}
+	expectIds(t, received[:3], 3, 5)
+}
+
+func TestStreamPersonDetectionsStopsOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database is down", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	stream := New(server.URL).StreamPersonDetections(context.Background(), 0, 10*time.Millisecond)
+	for range stream.C {
+	}
+	if err := stream.Err(); !errors.Is(err, ErrServer) {
+		t.Errorf("stream stopped with %v, expected a server error", err)
+	}
+}
@@ -9,82 +9,120 @@ import (
 	"context"
 
 	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/geometry"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createCamera = `-- name: CreateCamera :one
-insert into cameras(name, connection_string, location_text, location_id, orientation)
-values ($1, $2, $3, $4, $5)
-returning id, name, connection_string, location_text, location_id, orientation
+const CreateCamera = `-- name: CreateCamera :one
+insert into cameras(name, connection_string, location_id, orientation, mount_description, floor_x, floor_y, heading,
+                    mounting_height, field_of_view, tags, entry_direction)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, coalesce($12::direction, 'none'))
+returning id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
 `
 
 type CreateCameraParams struct {
-	Name             string              `json:"name"`
-	ConnectionString string              `json:"connection_string"`
-	LocationText     string              `json:"location_text"`
-	LocationID       int32               `json:"location_id"`
-	Orientation      dbenums.Orientation `json:"orientation"`
+	Name             string                `json:"name"`
+	ConnectionString string                `json:"connection_string"`
+	LocationID       int32                 `json:"location_id"`
+	Orientation      dbenums.Orientation   `json:"orientation"`
+	MountDescription string                `json:"mount_description"`
+	FloorX           *float64              `json:"floor_x"`
+	FloorY           *float64              `json:"floor_y"`
+	Heading          *float64              `json:"heading"`
+	MountingHeight   *float64              `json:"mounting_height"`
+	FieldOfView      geometry.Polygon      `json:"field_of_view"`
+	Tags             map[string]string     `json:"tags"`
+	EntryDirection   dbenums.NullDirection `json:"entry_direction"`
 }
 
 func (q *Queries) CreateCamera(ctx context.Context, arg CreateCameraParams) (Camera, error) {
-	row := q.db.QueryRow(ctx, createCamera,
+	row := q.db.QueryRow(ctx, CreateCamera,
 		arg.Name,
 		arg.ConnectionString,
-		arg.LocationText,
 		arg.LocationID,
 		arg.Orientation,
+		arg.MountDescription,
+		arg.FloorX,
+		arg.FloorY,
+		arg.Heading,
+		arg.MountingHeight,
+		arg.FieldOfView,
+		arg.Tags,
+		arg.EntryDirection,
 	)
 	var i Camera
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.ConnectionString,
-		&i.LocationText,
 		&i.LocationID,
 		&i.Orientation,
+		&i.EntryDirection,
+		&i.MountDescription,
+		&i.FloorX,
+		&i.FloorY,
+		&i.Heading,
+		&i.MountingHeight,
+		&i.FieldOfView,
+		&i.Tags,
+		&i.DeletedAt,
+		&i.State,
+		&i.StateChangedAt,
 	)
 	return i, err
 }
 
-const deleteCamera = `-- name: DeleteCamera :exec
+const DeleteCamera = `-- name: DeleteCamera :exec
 delete
 from cameras
 where id = $1
 `
 
 func (q *Queries) DeleteCamera(ctx context.Context, id int64) error {
-	_, err := q.db.Exec(ctx, deleteCamera, id)
+	_, err := q.db.Exec(ctx, DeleteCamera, id)
 	return err
 }
 
-const getCamera = `-- name: GetCamera :one
-select id, name, connection_string, location_text, location_id, orientation
+const GetCamera = `-- name: GetCamera :one
+select id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
 from cameras
 where id = $1
 `
 
 func (q *Queries) GetCamera(ctx context.Context, id int64) (Camera, error) {
-	row := q.db.QueryRow(ctx, getCamera, id)
+	row := q.db.QueryRow(ctx, GetCamera, id)
 	var i Camera
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.ConnectionString,
-		&i.LocationText,
 		&i.LocationID,
 		&i.Orientation,
+		&i.EntryDirection,
+		&i.MountDescription,
+		&i.FloorX,
+		&i.FloorY,
+		&i.Heading,
+		&i.MountingHeight,
+		&i.FieldOfView,
+		&i.Tags,
+		&i.DeletedAt,
+		&i.State,
+		&i.StateChangedAt,
 	)
 	return i, err
 }
 
-const getCameras = `-- name: GetCameras :many
-select id, name, connection_string, location_text, location_id, orientation
+const GetCameras = `-- name: GetCameras :many
+select id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
 from cameras
+where $1::bool
+   or deleted_at is null
 order by id
 `
 
-func (q *Queries) GetCameras(ctx context.Context) ([]Camera, error) {
-	rows, err := q.db.Query(ctx, getCameras)
+func (q *Queries) GetCameras(ctx context.Context, includeDeleted bool) ([]Camera, error) {
+	rows, err := q.db.Query(ctx, GetCameras, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -96,9 +134,19 @@ func (q *Queries) GetCameras(ctx context.Context) ([]Camera, error) {
 			&i.ID,
 			&i.Name,
 			&i.ConnectionString,
-			&i.LocationText,
 			&i.LocationID,
 			&i.Orientation,
+			&i.EntryDirection,
+			&i.MountDescription,
+			&i.FloorX,
+			&i.FloorY,
+			&i.Heading,
+			&i.MountingHeight,
+			&i.FieldOfView,
+			&i.Tags,
+			&i.DeletedAt,
+			&i.State,
+			&i.StateChangedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -110,43 +158,174 @@ func (q *Queries) GetCameras(ctx context.Context) ([]Camera, error) {
 	return items, nil
 }
 
-const updateCamera = `-- name: UpdateCamera :one
+const RestoreCamera = `-- name: RestoreCamera :one
+update cameras
+set deleted_at = null
+where id = $1
+returning id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
+`
+
+func (q *Queries) RestoreCamera(ctx context.Context, id int64) (Camera, error) {
+	row := q.db.QueryRow(ctx, RestoreCamera, id)
+	var i Camera
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.ConnectionString,
+		&i.LocationID,
+		&i.Orientation,
+		&i.EntryDirection,
+		&i.MountDescription,
+		&i.FloorX,
+		&i.FloorY,
+		&i.Heading,
+		&i.MountingHeight,
+		&i.FieldOfView,
+		&i.Tags,
+		&i.DeletedAt,
+		&i.State,
+		&i.StateChangedAt,
+	)
+	return i, err
+}
+
+const SetCameraState = `-- name: SetCameraState :one
+update cameras
+set state            = $2,
+    state_changed_at = now()
+where id = $1
+returning id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
+`
+
+type SetCameraStateParams struct {
+	ID    int64  `json:"id"`
+	State string `json:"state"`
+}
+
+func (q *Queries) SetCameraState(ctx context.Context, arg SetCameraStateParams) (Camera, error) {
+	row := q.db.QueryRow(ctx, SetCameraState, arg.ID, arg.State)
+	var i Camera
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.ConnectionString,
+		&i.LocationID,
+		&i.Orientation,
+		&i.EntryDirection,
+		&i.MountDescription,
+		&i.FloorX,
+		&i.FloorY,
+		&i.Heading,
+		&i.MountingHeight,
+		&i.FieldOfView,
+		&i.Tags,
+		&i.DeletedAt,
+		&i.State,
+		&i.StateChangedAt,
+	)
+	return i, err
+}
+
+const SoftDeleteCamera = `-- name: SoftDeleteCamera :one
+update cameras
+set deleted_at = coalesce(deleted_at, now())
+where id = $1
+returning id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
+`
+
+// hides the camera, deleting it again keeps the date it was first deleted at
+func (q *Queries) SoftDeleteCamera(ctx context.Context, id int64) (Camera, error) {
+	row := q.db.QueryRow(ctx, SoftDeleteCamera, id)
+	var i Camera
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.ConnectionString,
+		&i.LocationID,
+		&i.Orientation,
+		&i.EntryDirection,
+		&i.MountDescription,
+		&i.FloorX,
+		&i.FloorY,
+		&i.Heading,
+		&i.MountingHeight,
+		&i.FieldOfView,
+		&i.Tags,
+		&i.DeletedAt,
+		&i.State,
+		&i.StateChangedAt,
+	)
+	return i, err
+}
+
+const UpdateCamera = `-- name: UpdateCamera :one
 update cameras
 set name              = coalesce($2, name),
     connection_string = coalesce($3, connection_string),
-    location_text     = coalesce($4, location_text),
-    location_id       = coalesce($5, location_id),
-    orientation       = coalesce($6, orientation)
+    location_id       = coalesce($4, location_id),
+    orientation       = coalesce($5, orientation),
+    entry_direction   = coalesce($6, entry_direction),
+    mount_description = coalesce($7, mount_description),
+    floor_x           = coalesce($8, floor_x),
+    floor_y           = coalesce($9, floor_y),
+    heading           = coalesce($10, heading),
+    mounting_height   = coalesce($11, mounting_height),
+    field_of_view     = coalesce($12, field_of_view),
+    tags              = coalesce($13, tags)
 where id = $1
-returning id, name, connection_string, location_text, location_id, orientation
+returning id, name, connection_string, location_id, orientation, entry_direction, mount_description, floor_x, floor_y, heading, mounting_height, field_of_view, tags, deleted_at, state, state_changed_at
 `
 
 type UpdateCameraParams struct {
 	ID               int64                   `json:"id"`
 	Name             pgtype.Text             `json:"name"`
 	ConnectionString pgtype.Text             `json:"connection_string"`
-	LocationText     pgtype.Text             `json:"location_text"`
 	LocationID       pgtype.Int4             `json:"location_id"`
 	Orientation      dbenums.NullOrientation `json:"orientation"`
+	EntryDirection   dbenums.NullDirection   `json:"entry_direction"`
+	MountDescription pgtype.Text             `json:"mount_description"`
+	FloorX           *float64                `json:"floor_x"`
+	FloorY           *float64                `json:"floor_y"`
+	Heading          *float64                `json:"heading"`
+	MountingHeight   *float64                `json:"mounting_height"`
+	FieldOfView      geometry.Polygon        `json:"field_of_view"`
+	Tags             map[string]string       `json:"tags"`
 }
 
 func (q *Queries) UpdateCamera(ctx context.Context, arg UpdateCameraParams) (Camera, error) {
-	row := q.db.QueryRow(ctx, updateCamera,
+	row := q.db.QueryRow(ctx, UpdateCamera,
 		arg.ID,
 		arg.Name,
 		arg.ConnectionString,
-		arg.LocationText,
 		arg.LocationID,
 		arg.Orientation,
+		arg.EntryDirection,
+		arg.MountDescription,
+		arg.FloorX,
+		arg.FloorY,
+		arg.Heading,
+		arg.MountingHeight,
+		arg.FieldOfView,
+		arg.Tags,
 	)
 	var i Camera
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.ConnectionString,
-		&i.LocationText,
 		&i.LocationID,
 		&i.Orientation,
+		&i.EntryDirection,
+		&i.MountDescription,
+		&i.FloorX,
+		&i.FloorY,
+		&i.Heading,
+		&i.MountingHeight,
+		&i.FieldOfView,
+		&i.Tags,
+		&i.DeletedAt,
+		&i.State,
+		&i.StateChangedAt,
 	)
 	return i, err
 }
@@ -11,56 +11,143 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createLocation = `-- name: CreateLocation :one
-insert into locations (name, description)
-values ($1, $2)
-returning id, name, description
+const CreateLocation = `-- name: CreateLocation :one
+insert into locations (name, description, capacity, warning_threshold, parent_id)
+values ($1, $2, $3, $4, $5)
+returning id, name, description, capacity, warning_threshold, parent_id, deleted_at
 `
 
 type CreateLocationParams struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name             string      `json:"name"`
+	Description      string      `json:"description"`
+	Capacity         pgtype.Int4 `json:"capacity"`
+	WarningThreshold pgtype.Int4 `json:"warning_threshold"`
+	ParentID         pgtype.Int8 `json:"parent_id"`
 }
 
 func (q *Queries) CreateLocation(ctx context.Context, arg CreateLocationParams) (Location, error) {
-	row := q.db.QueryRow(ctx, createLocation, arg.Name, arg.Description)
+	row := q.db.QueryRow(ctx, CreateLocation,
+		arg.Name,
+		arg.Description,
+		arg.Capacity,
+		arg.WarningThreshold,
+		arg.ParentID,
+	)
 	var i Location
-	err := row.Scan(&i.ID, &i.Name, &i.Description)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Capacity,
+		&i.WarningThreshold,
+		&i.ParentID,
+		&i.DeletedAt,
+	)
 	return i, err
 }
 
-const deleteLocation = `-- name: DeleteLocation :exec
+const DeleteLocation = `-- name: DeleteLocation :exec
 delete
 from locations
 where id = $1
 `
 
 func (q *Queries) DeleteLocation(ctx context.Context, id int64) error {
-	_, err := q.db.Exec(ctx, deleteLocation, id)
+	_, err := q.db.Exec(ctx, DeleteLocation, id)
 	return err
 }
 
-const getLocation = `-- name: GetLocation :one
-select id, name, description
+const GetLocation = `-- name: GetLocation :one
+select id, name, description, capacity, warning_threshold, parent_id, deleted_at
 from locations
 where id = $1
 `
 
 func (q *Queries) GetLocation(ctx context.Context, id int64) (Location, error) {
-	row := q.db.QueryRow(ctx, getLocation, id)
+	row := q.db.QueryRow(ctx, GetLocation, id)
 	var i Location
-	err := row.Scan(&i.ID, &i.Name, &i.Description)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Capacity,
+		&i.WarningThreshold,
+		&i.ParentID,
+		&i.DeletedAt,
+	)
 	return i, err
 }
 
-const getLocations = `-- name: GetLocations :many
-select id, name, description
+const GetLocationCameraCounts = `-- name: GetLocationCameraCounts :many
+select cameras.id                                                                                as camera_id,
+       count(person_detections.id)                                                               as detections,
+       count(person_detections.id) filter (where person_detections.normalized_direction = 'in')  as entries,
+       count(person_detections.id) filter (where person_detections.normalized_direction = 'out') as exits
+from cameras
+         left join person_detections on person_detections.camera_id = cameras.id and
+                                        person_detections.detection_date >= $1 and
+                                        not person_detections.excluded and
+                                        camera_location_at(cameras.id, person_detections.detection_date) =
+                                        $2
+where exists(select
+             from camera_location_assignments
+             where camera_location_assignments.camera_id = cameras.id
+               and camera_location_assignments.location_id = $2
+               and (camera_location_assignments.valid_to is null or
+                    camera_location_assignments.valid_to > $1))
+group by cameras.id
+order by cameras.id
+`
+
+type GetLocationCameraCountsParams struct {
+	Since      pgtype.Timestamptz `json:"since"`
+	LocationID int64              `json:"location_id"`
+}
+
+type GetLocationCameraCountsRow struct {
+	CameraID   int64 `json:"camera_id"`
+	Detections int64 `json:"detections"`
+	Entries    int64 `json:"entries"`
+	Exits      int64 `json:"exits"`
+}
+
+// counts the detections of every camera of a location since the given date, and for the cameras with an entry
+// direction the people who entered and left the location through them. Cameras that were moved out of the location
+// since the given date are included with the detections they made while in it
+func (q *Queries) GetLocationCameraCounts(ctx context.Context, arg GetLocationCameraCountsParams) ([]GetLocationCameraCountsRow, error) {
+	rows, err := q.db.Query(ctx, GetLocationCameraCounts, arg.Since, arg.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetLocationCameraCountsRow{}
+	for rows.Next() {
+		var i GetLocationCameraCountsRow
+		if err := rows.Scan(
+			&i.CameraID,
+			&i.Detections,
+			&i.Entries,
+			&i.Exits,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetLocationChildren = `-- name: GetLocationChildren :many
+select id, name, description, capacity, warning_threshold, parent_id, deleted_at
 from locations
+where parent_id = $1::bigint
 order by id
 `
 
-func (q *Queries) GetLocations(ctx context.Context) ([]Location, error) {
-	rows, err := q.db.Query(ctx, getLocations)
+func (q *Queries) GetLocationChildren(ctx context.Context, parentID int64) ([]Location, error) {
+	rows, err := q.db.Query(ctx, GetLocationChildren, parentID)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +155,62 @@ func (q *Queries) GetLocations(ctx context.Context) ([]Location, error) {
 	items := []Location{}
 	for rows.Next() {
 		var i Location
-		if err := rows.Scan(&i.ID, &i.Name, &i.Description); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Capacity,
+			&i.WarningThreshold,
+			&i.ParentID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetLocationOccupancies = `-- name: GetLocationOccupancies :many
+select camera_location_at(camera_id, detection_date)::int as location_id,
+       count(*) filter (where normalized_direction = 'in')  as entries,
+       count(*) filter (where normalized_direction = 'out') as exits
+from person_detections
+where normalized_direction <> 'none'
+  and not excluded
+  and detection_date >= $1
+  and ($2::int is null or camera_location_at(camera_id, detection_date) = $2)
+group by 1
+order by 1
+`
+
+type GetLocationOccupanciesParams struct {
+	Since      pgtype.Timestamptz `json:"since"`
+	LocationID pgtype.Int4        `json:"location_id"`
+}
+
+type GetLocationOccupanciesRow struct {
+	LocationID int32 `json:"location_id"`
+	Entries    int64 `json:"entries"`
+	Exits      int64 `json:"exits"`
+}
+
+// counts the people who entered and left every location since the given date, through the cameras with an entry
+// direction when the detections were made, from their normalized direction. Detections count towards the location
+// their camera was in when they were made
+func (q *Queries) GetLocationOccupancies(ctx context.Context, arg GetLocationOccupanciesParams) ([]GetLocationOccupanciesRow, error) {
+	rows, err := q.db.Query(ctx, GetLocationOccupancies, arg.Since, arg.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetLocationOccupanciesRow{}
+	for rows.Next() {
+		var i GetLocationOccupanciesRow
+		if err := rows.Scan(&i.LocationID, &i.Entries, &i.Exits); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -79,23 +221,163 @@ func (q *Queries) GetLocations(ctx context.Context) ([]Location, error) {
 	return items, nil
 }
 
-const updateLocation = `-- name: UpdateLocation :one
+const GetLocationSubtreeIds = `-- name: GetLocationSubtreeIds :many
+with recursive subtree(id, depth) as (select locations.id, 0
+                                      from locations
+                                      where locations.id = $1
+                                      union all
+                                      select locations.id, subtree.depth + 1
+                                      from locations
+                                               join subtree on locations.parent_id = subtree.id)
+select id
+from subtree
+order by depth, id
+`
+
+// returns the id of the location followed by the ids of all of its descendants, nothing if the location does not
+// exist
+func (q *Queries) GetLocationSubtreeIds(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, GetLocationSubtreeIds, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetLocations = `-- name: GetLocations :many
+select id, name, description, capacity, warning_threshold, parent_id, deleted_at
+from locations
+where $1::bool
+   or deleted_at is null
+order by id
+`
+
+func (q *Queries) GetLocations(ctx context.Context, includeDeleted bool) ([]Location, error) {
+	rows, err := q.db.Query(ctx, GetLocations, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Location{}
+	for rows.Next() {
+		var i Location
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Capacity,
+			&i.WarningThreshold,
+			&i.ParentID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const RestoreLocation = `-- name: RestoreLocation :one
+update locations
+set deleted_at = null
+where id = $1
+returning id, name, description, capacity, warning_threshold, parent_id, deleted_at
+`
+
+func (q *Queries) RestoreLocation(ctx context.Context, id int64) (Location, error) {
+	row := q.db.QueryRow(ctx, RestoreLocation, id)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Capacity,
+		&i.WarningThreshold,
+		&i.ParentID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const SoftDeleteLocation = `-- name: SoftDeleteLocation :one
+update locations
+set deleted_at = coalesce(deleted_at, now())
+where id = $1
+returning id, name, description, capacity, warning_threshold, parent_id, deleted_at
+`
+
+// hides the location, deleting it again keeps the date it was first deleted at
+func (q *Queries) SoftDeleteLocation(ctx context.Context, id int64) (Location, error) {
+	row := q.db.QueryRow(ctx, SoftDeleteLocation, id)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Capacity,
+		&i.WarningThreshold,
+		&i.ParentID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const UpdateLocation = `-- name: UpdateLocation :one
 update locations
-set name       = coalesce($2, name),
-    description= coalesce($3, name)
+set name              = coalesce($2, name),
+    description       = coalesce($3, description),
+    capacity          = coalesce($4, capacity),
+    warning_threshold = coalesce($5, warning_threshold),
+    parent_id         = case
+                            when $6::bigint = 0 then null
+                            else coalesce($6, parent_id) end
 where id = $1
-returning id, name, description
+returning id, name, description, capacity, warning_threshold, parent_id, deleted_at
 `
 
 type UpdateLocationParams struct {
-	ID          int64       `json:"id"`
-	Name        pgtype.Text `json:"name"`
-	Description pgtype.Text `json:"description"`
+	ID               int64       `json:"id"`
+	Name             pgtype.Text `json:"name"`
+	Description      pgtype.Text `json:"description"`
+	Capacity         pgtype.Int4 `json:"capacity"`
+	WarningThreshold pgtype.Int4 `json:"warning_threshold"`
+	ParentID         pgtype.Int8 `json:"parent_id"`
 }
 
+// a parent_id of 0 moves the location to the top of the hierarchy
 func (q *Queries) UpdateLocation(ctx context.Context, arg UpdateLocationParams) (Location, error) {
-	row := q.db.QueryRow(ctx, updateLocation, arg.ID, arg.Name, arg.Description)
+	row := q.db.QueryRow(ctx, UpdateLocation,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.Capacity,
+		arg.WarningThreshold,
+		arg.ParentID,
+	)
 	var i Location
-	err := row.Scan(&i.ID, &i.Name, &i.Description)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Capacity,
+		&i.WarningThreshold,
+		&i.ParentID,
+		&i.DeletedAt,
+	)
 	return i, err
 }
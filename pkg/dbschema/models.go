@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/geometry"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -99,13 +100,51 @@ func (ns NullOrientation) Value() (driver.Value, error) {
 	return string(ns.Orientation), nil
 }
 
+type ActivityRule struct {
+	ID            int64   `json:"id"`
+	CameraID      int64   `json:"camera_id"`
+	Name          string  `json:"name"`
+	MinDetections int32   `json:"min_detections"`
+	WindowMinutes int32   `json:"window_minutes"`
+	Weekdays      []int32 `json:"weekdays"`
+	ActiveFrom    string  `json:"active_from"`
+	ActiveTo      string  `json:"active_to"`
+	Timezone      string  `json:"timezone"`
+	Enabled       bool    `json:"enabled"`
+}
+
+type Alert struct {
+	ID             int64              `json:"id"`
+	Kind           string             `json:"kind"`
+	State          string             `json:"state"`
+	CameraID       pgtype.Int8        `json:"camera_id"`
+	ActivityRuleID pgtype.Int8        `json:"activity_rule_id"`
+	Message        string             `json:"message"`
+	OpenedAt       pgtype.Timestamptz `json:"opened_at"`
+	ResolvedAt     pgtype.Timestamptz `json:"resolved_at"`
+	Severity       string             `json:"severity"`
+	LocationID     pgtype.Int8        `json:"location_id"`
+	AcknowledgedAt pgtype.Timestamptz `json:"acknowledged_at"`
+	AcknowledgedBy pgtype.Text        `json:"acknowledged_by"`
+}
+
 type Camera struct {
 	ID               int64               `json:"id"`
 	Name             string              `json:"name"`
 	ConnectionString string              `json:"connection_string"`
-	LocationText     string              `json:"location_text"`
 	LocationID       int32               `json:"location_id"`
 	Orientation      dbenums.Orientation `json:"orientation"`
+	EntryDirection   dbenums.Direction   `json:"entry_direction"`
+	MountDescription string              `json:"mount_description"`
+	FloorX           *float64            `json:"floor_x"`
+	FloorY           *float64            `json:"floor_y"`
+	Heading          *float64            `json:"heading"`
+	MountingHeight   *float64            `json:"mounting_height"`
+	FieldOfView      geometry.Polygon    `json:"field_of_view"`
+	Tags             map[string]string   `json:"tags"`
+	DeletedAt        pgtype.Timestamptz  `json:"deleted_at"`
+	State            string              `json:"state"`
+	StateChangedAt   pgtype.Timestamptz  `json:"state_changed_at"`
 }
 
 type CameraDetection struct {
@@ -118,13 +157,136 @@ type CameraDetection struct {
 	DetectionDate     pgtype.Timestamptz `json:"detection_date"`
 }
 
-type Location struct {
+type CameraGroup struct {
 	ID          int64  `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
 
+type CameraGroupMember struct {
+	GroupID  int64 `json:"group_id"`
+	CameraID int64 `json:"camera_id"`
+}
+
+type CameraLocationAssignment struct {
+	ID         int64              `json:"id"`
+	CameraID   int64              `json:"camera_id"`
+	LocationID int32              `json:"location_id"`
+	ValidFrom  pgtype.Timestamptz `json:"valid_from"`
+	ValidTo    pgtype.Timestamptz `json:"valid_to"`
+}
+
+type CameraStateChange struct {
+	ID        int64              `json:"id"`
+	CameraID  int64              `json:"camera_id"`
+	FromState string             `json:"from_state"`
+	ToState   string             `json:"to_state"`
+	Reason    string             `json:"reason"`
+	ChangedAt pgtype.Timestamptz `json:"changed_at"`
+}
+
+type CameraStatus struct {
+	CameraID            int64              `json:"camera_id"`
+	Status              string             `json:"status"`
+	LatencyMs           pgtype.Int4        `json:"latency_ms"`
+	CheckedAt           pgtype.Timestamptz `json:"checked_at"`
+	LastSeenAt          pgtype.Timestamptz `json:"last_seen_at"`
+	Error               pgtype.Text        `json:"error"`
+	ConsecutiveFailures int32              `json:"consecutive_failures"`
+}
+
+type DetectionArchive struct {
+	ID         int64              `json:"id"`
+	Key        string             `json:"key"`
+	Format     string             `json:"format"`
+	RangeStart pgtype.Timestamptz `json:"range_start"`
+	RangeEnd   pgtype.Timestamptz `json:"range_end"`
+	RowCount   int64              `json:"row_count"`
+	Sha256     string             `json:"sha256"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type FloorPlan struct {
+	LocationID  int64              `json:"location_id"`
+	ImageKey    string             `json:"image_key"`
+	ContentType string             `json:"content_type"`
+	ImageWidth  int32              `json:"image_width"`
+	ImageHeight int32              `json:"image_height"`
+	Width       float64            `json:"width"`
+	Height      float64            `json:"height"`
+	Unit        string             `json:"unit"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Location struct {
+	ID               int64              `json:"id"`
+	Name             string             `json:"name"`
+	Description      string             `json:"description"`
+	Capacity         pgtype.Int4        `json:"capacity"`
+	WarningThreshold pgtype.Int4        `json:"warning_threshold"`
+	ParentID         pgtype.Int8        `json:"parent_id"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type MaintenanceWindow struct {
+	ID          int64              `json:"id"`
+	CameraID    int64              `json:"camera_id"`
+	StartsAt    pgtype.Timestamptz `json:"starts_at"`
+	EndsAt      pgtype.Timestamptz `json:"ends_at"`
+	Ingest      string             `json:"ingest"`
+	Description string             `json:"description"`
+}
+
 type PersonDetection struct {
+	ID                  int64              `json:"id"`
+	CameraID            int64              `json:"camera_id"`
+	DetectionDate       pgtype.Timestamptz `json:"detection_date"`
+	TargetDirection     dbenums.Direction  `json:"target_direction"`
+	Flagged             bool               `json:"flagged"`
+	NormalizedDirection string             `json:"normalized_direction"`
+	TrackID             pgtype.Text        `json:"track_id"`
+	Confidence          *float64           `json:"confidence"`
+	BboxX               *float64           `json:"bbox_x"`
+	BboxY               *float64           `json:"bbox_y"`
+	BboxWidth           *float64           `json:"bbox_width"`
+	BboxHeight          *float64           `json:"bbox_height"`
+	FrameDate           pgtype.Timestamptz `json:"frame_date"`
+	ModelVersion        pgtype.Text        `json:"model_version"`
+	Excluded            bool               `json:"excluded"`
+	ArchiveID           pgtype.Int8        `json:"archive_id"`
+}
+
+type PersonDetectionDailyCount struct {
+	CameraID            int64             `json:"camera_id"`
+	Bucket              pgtype.Date       `json:"bucket"`
+	TargetDirection     dbenums.Direction `json:"target_direction"`
+	Count               int64             `json:"count"`
+	NormalizedDirection string            `json:"normalized_direction"`
+}
+
+type PersonDetectionHourlyCount struct {
+	CameraID            int64              `json:"camera_id"`
+	Bucket              pgtype.Timestamptz `json:"bucket"`
+	TargetDirection     dbenums.Direction  `json:"target_direction"`
+	Count               int64              `json:"count"`
+	NormalizedDirection string             `json:"normalized_direction"`
+}
+
+type PersonDetectionIdempotencyKey struct {
+	CameraID          int64              `json:"camera_id"`
+	Key               string             `json:"key"`
+	PersonDetectionID pgtype.Int8        `json:"person_detection_id"`
+	DetectionDate     pgtype.Timestamptz `json:"detection_date"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+}
+
+type PersonDetectionPartition struct {
+	Name       string             `json:"name"`
+	RangeStart pgtype.Timestamptz `json:"range_start"`
+	RangeEnd   pgtype.Timestamptz `json:"range_end"`
+}
+
+type PersonDetectionsDefault struct {
 	ID              int64              `json:"id"`
 	CameraID        int64              `json:"camera_id"`
 	DetectionDate   pgtype.Timestamptz `json:"detection_date"`
@@ -12,53 +12,293 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createPersonDetection = `-- name: CreatePersonDetection :one
-insert into person_detections(camera_id, detection_date, target_direction)
-values ($1, $2, $3)
-returning id, camera_id, detection_date, target_direction
+const CountPersonDetectionsForCamera = `-- name: CountPersonDetectionsForCamera :one
+select count(*)
+from person_detections
+where camera_id = $1
+  and detection_date >= $2
+  and detection_date < $3
+  and not excluded
+`
+
+type CountPersonDetectionsForCameraParams struct {
+	CameraID int64              `json:"camera_id"`
+	FromDate pgtype.Timestamptz `json:"from_date"`
+	ToDate   pgtype.Timestamptz `json:"to_date"`
+}
+
+func (q *Queries) CountPersonDetectionsForCamera(ctx context.Context, arg CountPersonDetectionsForCameraParams) (int64, error) {
+	row := q.db.QueryRow(ctx, CountPersonDetectionsForCamera, arg.CameraID, arg.FromDate, arg.ToDate)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreatePersonDetection = `-- name: CreatePersonDetection :one
+insert into person_detections(camera_id, detection_date, target_direction, flagged, track_id, confidence, bbox_x, bbox_y,
+                              bbox_width, bbox_height, frame_date, model_version, excluded)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+returning id, camera_id, detection_date, target_direction, flagged, normalized_direction, track_id, confidence, bbox_x, bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded, archive_id
 `
 
 type CreatePersonDetectionParams struct {
 	CameraID        int64              `json:"camera_id"`
 	DetectionDate   pgtype.Timestamptz `json:"detection_date"`
 	TargetDirection dbenums.Direction  `json:"target_direction"`
+	Flagged         bool               `json:"flagged"`
+	TrackID         pgtype.Text        `json:"track_id"`
+	Confidence      *float64           `json:"confidence"`
+	BboxX           *float64           `json:"bbox_x"`
+	BboxY           *float64           `json:"bbox_y"`
+	BboxWidth       *float64           `json:"bbox_width"`
+	BboxHeight      *float64           `json:"bbox_height"`
+	FrameDate       pgtype.Timestamptz `json:"frame_date"`
+	ModelVersion    pgtype.Text        `json:"model_version"`
+	Excluded        bool               `json:"excluded"`
 }
 
 func (q *Queries) CreatePersonDetection(ctx context.Context, arg CreatePersonDetectionParams) (PersonDetection, error) {
-	row := q.db.QueryRow(ctx, createPersonDetection, arg.CameraID, arg.DetectionDate, arg.TargetDirection)
+	row := q.db.QueryRow(ctx, CreatePersonDetection,
+		arg.CameraID,
+		arg.DetectionDate,
+		arg.TargetDirection,
+		arg.Flagged,
+		arg.TrackID,
+		arg.Confidence,
+		arg.BboxX,
+		arg.BboxY,
+		arg.BboxWidth,
+		arg.BboxHeight,
+		arg.FrameDate,
+		arg.ModelVersion,
+		arg.Excluded,
+	)
 	var i PersonDetection
 	err := row.Scan(
 		&i.ID,
 		&i.CameraID,
 		&i.DetectionDate,
 		&i.TargetDirection,
+		&i.Flagged,
+		&i.NormalizedDirection,
+		&i.TrackID,
+		&i.Confidence,
+		&i.BboxX,
+		&i.BboxY,
+		&i.BboxWidth,
+		&i.BboxHeight,
+		&i.FrameDate,
+		&i.ModelVersion,
+		&i.Excluded,
+		&i.ArchiveID,
 	)
 	return i, err
 }
 
-const deletePersonDetection = `-- name: DeletePersonDetection :exec
+const DeletePersonDetection = `-- name: DeletePersonDetection :exec
 delete
 from person_detections
 where id = $1
 `
 
 func (q *Queries) DeletePersonDetection(ctx context.Context, id int64) error {
-	_, err := q.db.Exec(ctx, deletePersonDetection, id)
+	_, err := q.db.Exec(ctx, DeletePersonDetection, id)
 	return err
 }
 
-const getDailyPersonDetectionsCount = `-- name: GetDailyPersonDetectionsCount :many
-with detection_dates as (select detection_date::date
-                         from person_detections
-                         where camera_id = $1)
-select date_series.date::date as date,
-       count(detection_dates.detection_date) as count
+const DeletePersonDetectionsForCamera = `-- name: DeletePersonDetectionsForCamera :execrows
+delete
+from person_detections
+where camera_id = $1
+`
+
+func (q *Queries) DeletePersonDetectionsForCamera(ctx context.Context, cameraID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, DeletePersonDetectionsForCamera, cameraID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ExportPersonDetections = `-- name: ExportPersonDetections :many
+select person_detections.id,
+       person_detections.camera_id,
+       cameras.name   as camera_name,
+       locations.id   as location_id,
+       locations.name as location_name,
+       person_detections.detection_date,
+       person_detections.target_direction,
+       person_detections.normalized_direction,
+       person_detections.flagged,
+       person_detections.excluded,
+       person_detections.track_id,
+       person_detections.confidence,
+       person_detections.bbox_x,
+       person_detections.bbox_y,
+       person_detections.bbox_width,
+       person_detections.bbox_height,
+       person_detections.frame_date,
+       person_detections.model_version
+from person_detections
+         join cameras on cameras.id = person_detections.camera_id
+         left join locations on locations.id = camera_location_at(cameras.id, person_detections.detection_date)
+where ($1::timestamptz is null or person_detections.detection_date >= $1)
+  and ($2::timestamptz is null or person_detections.detection_date < $2)
+  and ($3::bigint is null or person_detections.camera_id = $3)
+  and ($4::bigint[] is null or locations.id = any ($4))
+  and ($5::bigint[] is null or person_detections.camera_id = any ($5))
+order by person_detections.detection_date, person_detections.id
+`
+
+type ExportPersonDetectionsParams struct {
+	FromDate    pgtype.Timestamptz `json:"from_date"`
+	ToDate      pgtype.Timestamptz `json:"to_date"`
+	CameraID    pgtype.Int8        `json:"camera_id"`
+	LocationIds []int64            `json:"location_ids"`
+	CameraIds   []int64            `json:"camera_ids"`
+}
+
+type ExportPersonDetectionsRow struct {
+	ID                  int64              `json:"id"`
+	CameraID            int64              `json:"camera_id"`
+	CameraName          string             `json:"camera_name"`
+	LocationID          pgtype.Int8        `json:"location_id"`
+	LocationName        pgtype.Text        `json:"location_name"`
+	DetectionDate       pgtype.Timestamptz `json:"detection_date"`
+	TargetDirection     dbenums.Direction  `json:"target_direction"`
+	NormalizedDirection string             `json:"normalized_direction"`
+	Flagged             bool               `json:"flagged"`
+	Excluded            bool               `json:"excluded"`
+	TrackID             pgtype.Text        `json:"track_id"`
+	Confidence          *float64           `json:"confidence"`
+	BboxX               *float64           `json:"bbox_x"`
+	BboxY               *float64           `json:"bbox_y"`
+	BboxWidth           *float64           `json:"bbox_width"`
+	BboxHeight          *float64           `json:"bbox_height"`
+	FrameDate           pgtype.Timestamptz `json:"frame_date"`
+	ModelVersion        pgtype.Text        `json:"model_version"`
+}
+
+func (q *Queries) ExportPersonDetections(ctx context.Context, arg ExportPersonDetectionsParams) ([]ExportPersonDetectionsRow, error) {
+	rows, err := q.db.Query(ctx, ExportPersonDetections,
+		arg.FromDate,
+		arg.ToDate,
+		arg.CameraID,
+		arg.LocationIds,
+		arg.CameraIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExportPersonDetectionsRow{}
+	for rows.Next() {
+		var i ExportPersonDetectionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CameraID,
+			&i.CameraName,
+			&i.LocationID,
+			&i.LocationName,
+			&i.DetectionDate,
+			&i.TargetDirection,
+			&i.NormalizedDirection,
+			&i.Flagged,
+			&i.Excluded,
+			&i.TrackID,
+			&i.Confidence,
+			&i.BboxX,
+			&i.BboxY,
+			&i.BboxWidth,
+			&i.BboxHeight,
+			&i.FrameDate,
+			&i.ModelVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetCamerasDailyPersonDetectionsCount = `-- name: GetCamerasDailyPersonDetectionsCount :many
+with daily_counts as (select bucket,
+                             sum(count)                                             as count,
+                             sum(count) filter (where normalized_direction = 'in')  as entries,
+                             sum(count) filter (where normalized_direction = 'out') as exits
+                      from person_detection_daily_counts
+                      where camera_id = any ($2::bigint[])
+                      group by bucket)
+select date_series.date::date                   as date,
+       coalesce(daily_counts.count, 0)::bigint   as count,
+       coalesce(daily_counts.entries, 0)::bigint as entries,
+       coalesce(daily_counts.exits, 0)::bigint   as exits
+from (select(current_date - b.offs) as date
+      from (select generate_series(0, current_date - (current_date - $1::interval)::date,
+                                   1) as offs) as b) as date_series
+         left outer join daily_counts
+                         on (date_series.date::date = daily_counts.bucket)
+order by date_series.date
+`
+
+type GetCamerasDailyPersonDetectionsCountParams struct {
+	Interval  pgtype.Interval `json:"interval"`
+	CameraIds []int64         `json:"camera_ids"`
+}
+
+type GetCamerasDailyPersonDetectionsCountRow struct {
+	Date    pgtype.Date `json:"date"`
+	Count   int64       `json:"count"`
+	Entries int64       `json:"entries"`
+	Exits   int64       `json:"exits"`
+}
+
+// counts the detections of the given cameras per day, like GetDailyPersonDetectionsCount
+func (q *Queries) GetCamerasDailyPersonDetectionsCount(ctx context.Context, arg GetCamerasDailyPersonDetectionsCountParams) ([]GetCamerasDailyPersonDetectionsCountRow, error) {
+	rows, err := q.db.Query(ctx, GetCamerasDailyPersonDetectionsCount, arg.Interval, arg.CameraIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetCamerasDailyPersonDetectionsCountRow{}
+	for rows.Next() {
+		var i GetCamerasDailyPersonDetectionsCountRow
+		if err := rows.Scan(
+			&i.Date,
+			&i.Count,
+			&i.Entries,
+			&i.Exits,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetDailyPersonDetectionsCount = `-- name: GetDailyPersonDetectionsCount :many
+with daily_counts as (select bucket,
+                             sum(count)                                             as count,
+                             sum(count) filter (where normalized_direction = 'in')  as entries,
+                             sum(count) filter (where normalized_direction = 'out') as exits
+                      from person_detection_daily_counts
+                      where camera_id = $1
+                      group by bucket)
+select date_series.date::date                   as date,
+       coalesce(daily_counts.count, 0)::bigint   as count,
+       coalesce(daily_counts.entries, 0)::bigint as entries,
+       coalesce(daily_counts.exits, 0)::bigint   as exits
 from (select(current_date - b.offs) as date
       from (select generate_series(0, current_date - (current_date - $2::interval)::date,
                                    1) as offs) as b) as date_series
-         left outer join detection_dates
-                         on (date_series.date::date = detection_date)
-group by date_series.date
+         left outer join daily_counts
+                         on (date_series.date::date = daily_counts.bucket)
 order by date_series.date
 `
 
@@ -68,12 +308,14 @@ type GetDailyPersonDetectionsCountParams struct {
 }
 
 type GetDailyPersonDetectionsCountRow struct {
-	Date  pgtype.Date `json:"date"`
-	Count int64       `json:"count"`
+	Date    pgtype.Date `json:"date"`
+	Count   int64       `json:"count"`
+	Entries int64       `json:"entries"`
+	Exits   int64       `json:"exits"`
 }
 
 func (q *Queries) GetDailyPersonDetectionsCount(ctx context.Context, arg GetDailyPersonDetectionsCountParams) ([]GetDailyPersonDetectionsCountRow, error) {
-	rows, err := q.db.Query(ctx, getDailyPersonDetectionsCount, arg.CameraID, arg.Interval)
+	rows, err := q.db.Query(ctx, GetDailyPersonDetectionsCount, arg.CameraID, arg.Interval)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +323,12 @@ func (q *Queries) GetDailyPersonDetectionsCount(ctx context.Context, arg GetDail
 	items := []GetDailyPersonDetectionsCountRow{}
 	for rows.Next() {
 		var i GetDailyPersonDetectionsCountRow
-		if err := rows.Scan(&i.Date, &i.Count); err != nil {
+		if err := rows.Scan(
+			&i.Date,
+			&i.Count,
+			&i.Entries,
+			&i.Exits,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -92,38 +339,184 @@ func (q *Queries) GetDailyPersonDetectionsCount(ctx context.Context, arg GetDail
 	return items, nil
 }
 
-const getPersonDetection = `-- name: GetPersonDetection :one
-select id, camera_id, detection_date, target_direction
+const GetLatestPersonDetection = `-- name: GetLatestPersonDetection :one
+select id, camera_id, detection_date, target_direction, flagged, normalized_direction, track_id, confidence, bbox_x, bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded, archive_id
+from person_detections
+order by id desc
+limit 1
+`
+
+func (q *Queries) GetLatestPersonDetection(ctx context.Context) (PersonDetection, error) {
+	row := q.db.QueryRow(ctx, GetLatestPersonDetection)
+	var i PersonDetection
+	err := row.Scan(
+		&i.ID,
+		&i.CameraID,
+		&i.DetectionDate,
+		&i.TargetDirection,
+		&i.Flagged,
+		&i.NormalizedDirection,
+		&i.TrackID,
+		&i.Confidence,
+		&i.BboxX,
+		&i.BboxY,
+		&i.BboxWidth,
+		&i.BboxHeight,
+		&i.FrameDate,
+		&i.ModelVersion,
+		&i.Excluded,
+		&i.ArchiveID,
+	)
+	return i, err
+}
+
+const GetLocationDailyPersonDetectionsCount = `-- name: GetLocationDailyPersonDetectionsCount :many
+with moves as (select distinct camera_id, valid_from::date as bucket
+               from camera_location_assignments
+               where valid_from > '-infinity'
+                 and valid_from <> valid_from::date::timestamptz
+                 and valid_from::date >= (current_date - $1::interval)::date),
+     daily_counts as (select counts.bucket,
+                             sum(counts.count)                                                    as count,
+                             sum(counts.count) filter (where counts.normalized_direction = 'in')  as entries,
+                             sum(counts.count) filter (where counts.normalized_direction = 'out') as exits
+                      from (select daily.bucket, daily.normalized_direction, daily.count
+                            from person_detection_daily_counts as daily
+                            where camera_location_at(daily.camera_id, daily.bucket::timestamptz) =
+                                  any ($2::bigint[])
+                              and not exists(select 1
+                                             from moves
+                                             where moves.camera_id = daily.camera_id
+                                               and moves.bucket = daily.bucket)
+                            union all
+                            select moves.bucket, person_detections.normalized_direction, 1
+                            from moves
+                                     join person_detections
+                                          on person_detections.camera_id = moves.camera_id and
+                                             person_detections.detection_date >= moves.bucket::timestamptz and
+                                             person_detections.detection_date < (moves.bucket + 1)::timestamptz
+                            where camera_location_at(person_detections.camera_id, person_detections.detection_date) =
+                                  any ($2::bigint[])
+                              and not person_detections.excluded) as counts
+                      group by counts.bucket)
+select date_series.date::date                   as date,
+       coalesce(daily_counts.count, 0)::bigint   as count,
+       coalesce(daily_counts.entries, 0)::bigint as entries,
+       coalesce(daily_counts.exits, 0)::bigint   as exits
+from (select(current_date - b.offs) as date
+      from (select generate_series(0, current_date - (current_date - $1::interval)::date,
+                                   1) as offs) as b) as date_series
+         left outer join daily_counts
+                         on (date_series.date::date = daily_counts.bucket)
+order by date_series.date
+`
+
+type GetLocationDailyPersonDetectionsCountParams struct {
+	Interval    pgtype.Interval `json:"interval"`
+	LocationIds []int64         `json:"location_ids"`
+}
+
+type GetLocationDailyPersonDetectionsCountRow struct {
+	Date    pgtype.Date `json:"date"`
+	Count   int64       `json:"count"`
+	Entries int64       `json:"entries"`
+	Exits   int64       `json:"exits"`
+}
+
+// counts the detections made in the given locations per day, like GetDailyPersonDetectionsCount. A camera counts
+// towards the location it was in at the start of each day, except in the days it was moved in, which are counted
+// from the detections that are still stored
+func (q *Queries) GetLocationDailyPersonDetectionsCount(ctx context.Context, arg GetLocationDailyPersonDetectionsCountParams) ([]GetLocationDailyPersonDetectionsCountRow, error) {
+	rows, err := q.db.Query(ctx, GetLocationDailyPersonDetectionsCount, arg.Interval, arg.LocationIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetLocationDailyPersonDetectionsCountRow{}
+	for rows.Next() {
+		var i GetLocationDailyPersonDetectionsCountRow
+		if err := rows.Scan(
+			&i.Date,
+			&i.Count,
+			&i.Entries,
+			&i.Exits,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetPersonDetection = `-- name: GetPersonDetection :one
+select id, camera_id, detection_date, target_direction, flagged, normalized_direction, track_id, confidence, bbox_x, bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded, archive_id
 from person_detections
 where id = $1
 `
 
 func (q *Queries) GetPersonDetection(ctx context.Context, id int64) (PersonDetection, error) {
-	row := q.db.QueryRow(ctx, getPersonDetection, id)
+	row := q.db.QueryRow(ctx, GetPersonDetection, id)
 	var i PersonDetection
 	err := row.Scan(
 		&i.ID,
 		&i.CameraID,
 		&i.DetectionDate,
 		&i.TargetDirection,
+		&i.Flagged,
+		&i.NormalizedDirection,
+		&i.TrackID,
+		&i.Confidence,
+		&i.BboxX,
+		&i.BboxY,
+		&i.BboxWidth,
+		&i.BboxHeight,
+		&i.FrameDate,
+		&i.ModelVersion,
+		&i.Excluded,
+		&i.ArchiveID,
 	)
 	return i, err
 }
 
-const getPersonDetections = `-- name: GetPersonDetections :many
-select id, camera_id, detection_date, target_direction
+const GetPersonDetections = `-- name: GetPersonDetections :many
+select id, camera_id, detection_date, target_direction, flagged, normalized_direction, track_id, confidence, bbox_x, bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded, archive_id
 from person_detections
-order by detection_date desc
-offset $1::int limit $2::int
+where ($1::bigint[] is null or camera_id = any ($1))
+  and ($2::text is null or track_id = $2)
+  and ($3::float8 is null or confidence >= $3)
+  and ($4::text is null or model_version = $4)
+  and ($5::boolean is null or excluded = $5)
+  and ($6::bigint is null or id > $6)
+order by case when $6::bigint is null then detection_date end desc,
+         case when $6::bigint is not null then id end
+offset $7::int limit $8::int
 `
 
 type GetPersonDetectionsParams struct {
-	DetectionOffset int32 `json:"detection_offset"`
-	Count           int32 `json:"count"`
+	CameraIds       []int64       `json:"camera_ids"`
+	TrackID         pgtype.Text   `json:"track_id"`
+	MinConfidence   pgtype.Float8 `json:"min_confidence"`
+	ModelVersion    pgtype.Text   `json:"model_version"`
+	Excluded        pgtype.Bool   `json:"excluded"`
+	AfterID         pgtype.Int8   `json:"after_id"`
+	DetectionOffset int32         `json:"detection_offset"`
+	Count           int32         `json:"count"`
 }
 
 func (q *Queries) GetPersonDetections(ctx context.Context, arg GetPersonDetectionsParams) ([]PersonDetection, error) {
-	rows, err := q.db.Query(ctx, getPersonDetections, arg.DetectionOffset, arg.Count)
+	rows, err := q.db.Query(ctx, GetPersonDetections,
+		arg.CameraIds,
+		arg.TrackID,
+		arg.MinConfidence,
+		arg.ModelVersion,
+		arg.Excluded,
+		arg.AfterID,
+		arg.DetectionOffset,
+		arg.Count,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +529,18 @@ func (q *Queries) GetPersonDetections(ctx context.Context, arg GetPersonDetectio
 			&i.CameraID,
 			&i.DetectionDate,
 			&i.TargetDirection,
+			&i.Flagged,
+			&i.NormalizedDirection,
+			&i.TrackID,
+			&i.Confidence,
+			&i.BboxX,
+			&i.BboxY,
+			&i.BboxWidth,
+			&i.BboxHeight,
+			&i.FrameDate,
+			&i.ModelVersion,
+			&i.Excluded,
+			&i.ArchiveID,
 		); err != nil {
 			return nil, err
 		}
@@ -147,22 +552,42 @@ func (q *Queries) GetPersonDetections(ctx context.Context, arg GetPersonDetectio
 	return items, nil
 }
 
-const getPersonDetectionsForCamera = `-- name: GetPersonDetectionsForCamera :many
-select id, camera_id, detection_date, target_direction
+const GetPersonDetectionsForCamera = `-- name: GetPersonDetectionsForCamera :many
+select id, camera_id, detection_date, target_direction, flagged, normalized_direction, track_id, confidence, bbox_x, bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded, archive_id
 from person_detections
 where camera_id = $1
-order by detection_date desc
-offset $2::int limit $3::int
+  and ($2::text is null or track_id = $2)
+  and ($3::float8 is null or confidence >= $3)
+  and ($4::text is null or model_version = $4)
+  and ($5::boolean is null or excluded = $5)
+  and ($6::bigint is null or id > $6)
+order by case when $6::bigint is null then detection_date end desc,
+         case when $6::bigint is not null then id end
+offset $7::int limit $8::int
 `
 
 type GetPersonDetectionsForCameraParams struct {
-	CameraID        int64 `json:"camera_id"`
-	DetectionOffset int32 `json:"detection_offset"`
-	Count           int32 `json:"count"`
+	CameraID        int64         `json:"camera_id"`
+	TrackID         pgtype.Text   `json:"track_id"`
+	MinConfidence   pgtype.Float8 `json:"min_confidence"`
+	ModelVersion    pgtype.Text   `json:"model_version"`
+	Excluded        pgtype.Bool   `json:"excluded"`
+	AfterID         pgtype.Int8   `json:"after_id"`
+	DetectionOffset int32         `json:"detection_offset"`
+	Count           int32         `json:"count"`
 }
 
 func (q *Queries) GetPersonDetectionsForCamera(ctx context.Context, arg GetPersonDetectionsForCameraParams) ([]PersonDetection, error) {
-	rows, err := q.db.Query(ctx, getPersonDetectionsForCamera, arg.CameraID, arg.DetectionOffset, arg.Count)
+	rows, err := q.db.Query(ctx, GetPersonDetectionsForCamera,
+		arg.CameraID,
+		arg.TrackID,
+		arg.MinConfidence,
+		arg.ModelVersion,
+		arg.Excluded,
+		arg.AfterID,
+		arg.DetectionOffset,
+		arg.Count,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -175,6 +600,18 @@ func (q *Queries) GetPersonDetectionsForCamera(ctx context.Context, arg GetPerso
 			&i.CameraID,
 			&i.DetectionDate,
 			&i.TargetDirection,
+			&i.Flagged,
+			&i.NormalizedDirection,
+			&i.TrackID,
+			&i.Confidence,
+			&i.BboxX,
+			&i.BboxY,
+			&i.BboxWidth,
+			&i.BboxHeight,
+			&i.FrameDate,
+			&i.ModelVersion,
+			&i.Excluded,
+			&i.ArchiveID,
 		); err != nil {
 			return nil, err
 		}
@@ -186,13 +623,34 @@ func (q *Queries) GetPersonDetectionsForCamera(ctx context.Context, arg GetPerso
 	return items, nil
 }
 
-const updatePersonDetection = `-- name: UpdatePersonDetection :one
+const ReassignPersonDetections = `-- name: ReassignPersonDetections :execrows
+update person_detections
+set camera_id = $1
+where camera_id = $2
+`
+
+type ReassignPersonDetectionsParams struct {
+	ToCameraID   int64 `json:"to_camera_id"`
+	FromCameraID int64 `json:"from_camera_id"`
+}
+
+// moves the detections of a camera to another one, the rollups follow through their trigger
+func (q *Queries) ReassignPersonDetections(ctx context.Context, arg ReassignPersonDetectionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, ReassignPersonDetections, arg.ToCameraID, arg.FromCameraID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const UpdatePersonDetection = `-- name: UpdatePersonDetection :one
 update person_detections
 set camera_id        = coalesce($2, camera_id),
     detection_date   = coalesce($3, detection_date),
-    target_direction = coalesce($4, target_direction)
+    target_direction = coalesce($4, target_direction),
+    excluded         = excluded or coalesce(confidence < $5::float8, false)
 where id = $1
-returning id, camera_id, detection_date, target_direction
+returning id, camera_id, detection_date, target_direction, flagged, normalized_direction, track_id, confidence, bbox_x, bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded, archive_id
 `
 
 type UpdatePersonDetectionParams struct {
@@ -200,14 +658,18 @@ type UpdatePersonDetectionParams struct {
 	CameraID        pgtype.Int8           `json:"camera_id"`
 	DetectionDate   pgtype.Timestamptz    `json:"detection_date"`
 	TargetDirection dbenums.NullDirection `json:"target_direction"`
+	MinConfidence   float64               `json:"min_confidence"`
 }
 
+// the minimum confidence is applied again, detections are never included back as they may have been excluded when
+// they were reported
 func (q *Queries) UpdatePersonDetection(ctx context.Context, arg UpdatePersonDetectionParams) (PersonDetection, error) {
-	row := q.db.QueryRow(ctx, updatePersonDetection,
+	row := q.db.QueryRow(ctx, UpdatePersonDetection,
 		arg.ID,
 		arg.CameraID,
 		arg.DetectionDate,
 		arg.TargetDirection,
+		arg.MinConfidence,
 	)
 	var i PersonDetection
 	err := row.Scan(
@@ -215,6 +677,18 @@ func (q *Queries) UpdatePersonDetection(ctx context.Context, arg UpdatePersonDet
 		&i.CameraID,
 		&i.DetectionDate,
 		&i.TargetDirection,
+		&i.Flagged,
+		&i.NormalizedDirection,
+		&i.TrackID,
+		&i.Confidence,
+		&i.BboxX,
+		&i.BboxY,
+		&i.BboxWidth,
+		&i.BboxHeight,
+		&i.FrameDate,
+		&i.ModelVersion,
+		&i.Excluded,
+		&i.ArchiveID,
 	)
 	return i, err
 }
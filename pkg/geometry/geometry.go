@@ -0,0 +1,29 @@
+// Package geometry has the shapes drawn on the floor plans of locations. Coordinates are in the units of the
+// floor plan, with the origin at its top left corner, x growing to the right and y growing downwards.
+package geometry
+
+import (
+	"errors"
+	"math"
+)
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Polygon is a closed shape, its last point connects back to the first one. It is stored as a json array of points.
+type Polygon []Point
+
+// Validate returns why polygon is not a usable shape, or nil when it is
+func (polygon Polygon) Validate() error {
+	if len(polygon) < 3 {
+		return errors.New("a polygon needs at least 3 points")
+	}
+	for _, point := range polygon {
+		if math.IsNaN(point.X) || math.IsInf(point.X, 0) || math.IsNaN(point.Y) || math.IsInf(point.Y, 0) {
+			return errors.New("the coordinates of a polygon must be finite numbers")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+-- +goose Up
+-- retention deletes the expired detections of each camera in batches
+create index person_detections_camera_date on person_detections (camera_id, detection_date);
+create index camera_detections_camera_date on camera_detections (camera_id, detection_date);
+
+-- +goose Down
+drop index camera_detections_camera_date;
+drop index person_detections_camera_date;
@@ -0,0 +1,79 @@
+-- +goose Up
+create table person_detection_hourly_counts
+(
+    camera_id        bigint                   not null references cameras on delete cascade,
+    bucket           timestamp with time zone not null,
+    target_direction direction                not null,
+    count            bigint                   not null,
+    primary key (camera_id, bucket, target_direction)
+);
+
+create table person_detection_daily_counts
+(
+    camera_id        bigint    not null references cameras on delete cascade,
+    bucket           date      not null,
+    target_direction direction not null,
+    count            bigint    not null,
+    primary key (camera_id, bucket, target_direction)
+);
+
+-- keeps the rollup tables in sync with person_detections. Setting camera_service.skip_rollups to 'on' in a
+-- transaction disables the maintenance, so that pruning old detections keeps their counts.
+-- +goose StatementBegin
+create function maintain_person_detection_rollups() returns trigger as
+$$
+begin
+    if current_setting('camera_service.skip_rollups', true) = 'on' then
+        return null;
+    end if;
+
+    if tg_op in ('UPDATE', 'DELETE') then
+        update person_detection_hourly_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = date_trunc('hour', old.detection_date)
+          and target_direction = old.target_direction;
+
+        update person_detection_daily_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = old.detection_date::date
+          and target_direction = old.target_direction;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') then
+        insert into person_detection_hourly_counts (camera_id, bucket, target_direction, count)
+        values (new.camera_id, date_trunc('hour', new.detection_date), new.target_direction, 1)
+        on conflict (camera_id, bucket, target_direction) do update set count = person_detection_hourly_counts.count + 1;
+
+        insert into person_detection_daily_counts (camera_id, bucket, target_direction, count)
+        values (new.camera_id, new.detection_date::date, new.target_direction, 1)
+        on conflict (camera_id, bucket, target_direction) do update set count = person_detection_daily_counts.count + 1;
+    end if;
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger person_detection_rollups
+    after insert or update of camera_id, detection_date, target_direction or delete
+    on person_detections
+    for each row
+execute function maintain_person_detection_rollups();
+
+insert into person_detection_hourly_counts (camera_id, bucket, target_direction, count)
+select camera_id, date_trunc('hour', detection_date), target_direction, count(*)
+from person_detections
+group by 1, 2, 3;
+
+insert into person_detection_daily_counts (camera_id, bucket, target_direction, count)
+select camera_id, detection_date::date, target_direction, count(*)
+from person_detections
+group by 1, 2, 3;
+
+-- +goose Down
+drop trigger person_detection_rollups on person_detections;
+drop function maintain_person_detection_rollups();
+drop table person_detection_daily_counts;
+drop table person_detection_hourly_counts;
@@ -0,0 +1,122 @@
+-- +goose Up
+-- person_detections becomes a table partitioned by month of detection_date. Partitions are named
+-- person_detections_yyyy_mm and listed in person_detection_partitions, detections outside of every partition end up
+-- in person_detections_default.
+create table person_detection_partitions
+(
+    name        text primary key,
+    range_start timestamp with time zone not null,
+    range_end   timestamp with time zone not null
+);
+
+-- +goose StatementBegin
+create function create_person_detections_partition(month date) returns void as
+$$
+declare
+    partition_name text        := 'person_detections_' || to_char(month, 'YYYY_MM');
+    range_start    timestamptz := date_trunc('month', month);
+    range_end      timestamptz := date_trunc('month', month) + interval '1 month';
+begin
+    if exists(select 1 from person_detection_partitions where name = partition_name) then
+        return;
+    end if;
+
+    execute format('create table %I partition of person_detections for values from (%L) to (%L)',
+                   partition_name, range_start, range_end);
+
+    insert into person_detection_partitions (name, range_start, range_end)
+    values (partition_name, range_start, range_end);
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create function drop_person_detections_partition(partition_name text) returns void as
+$$
+begin
+    if not exists(select 1 from person_detection_partitions where name = partition_name) then
+        raise exception 'unknown person detections partition %', partition_name;
+    end if;
+
+    execute format('drop table %I', partition_name);
+
+    delete from person_detection_partitions where name = partition_name;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+alter table person_detections rename to person_detections_unpartitioned;
+alter index person_detections_pkey rename to person_detections_unpartitioned_pkey;
+drop index person_detection_dates;
+drop index person_detections_camera_date;
+alter sequence person_detections_id_seq owned by none;
+
+create table person_detections
+(
+    id               bigint                   not null default nextval('person_detections_id_seq'),
+    camera_id        bigint                   not null references cameras,
+    detection_date   timestamp with time zone not null default clock_timestamp(),
+    target_direction direction                not null default 'none',
+    primary key (id, detection_date)
+) partition by range (detection_date);
+
+alter sequence person_detections_id_seq owned by person_detections.id;
+
+create index person_detection_dates on person_detections (detection_date);
+create index person_detections_camera_date on person_detections (camera_id, detection_date);
+
+create table person_detections_default partition of person_detections default;
+
+select create_person_detections_partition(month::date)
+from generate_series(date_trunc('month', coalesce((select min(detection_date) from person_detections_unpartitioned), now())),
+                     date_trunc('month', now()) + interval '3 months',
+                     interval '1 month') as month;
+
+-- the rollups already count these detections, the trigger is only created after copying them
+insert into person_detections (id, camera_id, detection_date, target_direction)
+select id, camera_id, detection_date, target_direction
+from person_detections_unpartitioned;
+
+drop table person_detections_unpartitioned;
+
+create trigger person_detection_rollups
+    after insert or update of camera_id, detection_date, target_direction or delete
+    on person_detections
+    for each row
+execute function maintain_person_detection_rollups();
+
+-- +goose Down
+alter table person_detections rename to person_detections_partitioned;
+alter index person_detections_pkey rename to person_detections_partitioned_pkey;
+drop index person_detection_dates;
+drop index person_detections_camera_date;
+alter sequence person_detections_id_seq owned by none;
+
+create table person_detections
+(
+    id               bigint primary key       not null default nextval('person_detections_id_seq'),
+    camera_id        bigint                   not null references cameras,
+    detection_date   timestamp with time zone not null default clock_timestamp(),
+    target_direction direction                not null default 'none'
+);
+
+alter sequence person_detections_id_seq owned by person_detections.id;
+
+create index person_detection_dates on person_detections (detection_date);
+create index person_detections_camera_date on person_detections (camera_id, detection_date);
+
+insert into person_detections (id, camera_id, detection_date, target_direction)
+select id, camera_id, detection_date, target_direction
+from person_detections_partitioned;
+
+drop table person_detections_partitioned;
+
+create trigger person_detection_rollups
+    after insert or update of camera_id, detection_date, target_direction or delete
+    on person_detections
+    for each row
+execute function maintain_person_detection_rollups();
+
+drop function drop_person_detections_partition(text);
+drop function create_person_detections_partition(date);
+drop table person_detection_partitions;
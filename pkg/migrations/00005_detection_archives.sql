@@ -0,0 +1,18 @@
+-- +goose Up
+-- every file written by the archive job, person detections in [range_start, range_end) are archived in key
+create table detection_archives
+(
+    id          bigserial primary key,
+    key         text                     not null unique,
+    format      text                     not null,
+    range_start timestamp with time zone not null,
+    range_end   timestamp with time zone not null,
+    row_count   bigint                   not null,
+    sha256      text                     not null,
+    created_at  timestamp with time zone not null default now()
+);
+
+create index detection_archives_range_end on detection_archives (range_end);
+
+-- +goose Down
+drop table detection_archives;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- the latest connectivity check of every camera, written by the prober
+create table camera_statuses
+(
+    camera_id            bigint primary key references cameras on delete cascade,
+    status               text                     not null check (status in ('online', 'offline')),
+    latency_ms           int,
+    checked_at           timestamp with time zone not null,
+    last_seen_at         timestamp with time zone,
+    error                text,
+    consecutive_failures int                      not null default 0
+);
+
+-- +goose Down
+drop table camera_statuses;
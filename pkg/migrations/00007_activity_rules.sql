@@ -0,0 +1,41 @@
+-- +goose Up
+-- the activity expected from a camera: at least min_detections detections every window_minutes, while the rule is
+-- active. Rules are active on the given weekdays (0 is sunday) between active_from and active_to, in the time zone
+-- of the rule. Rules where active_from comes after active_to span midnight.
+create table activity_rules
+(
+    id             bigserial primary key,
+    camera_id      bigint  not null references cameras on delete cascade,
+    name           text    not null default '',
+    min_detections int     not null default 1 check (min_detections > 0),
+    window_minutes int     not null check (window_minutes > 0),
+    weekdays       int[]   not null default '{0,1,2,3,4,5,6}' check (weekdays <@ '{0,1,2,3,4,5,6}'),
+    active_from    text    not null default '00:00' check (active_from ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    active_to      text    not null default '24:00' check (active_to ~ '^(([01][0-9]|2[0-3]):[0-5][0-9]|24:00)$'),
+    timezone       text    not null default 'UTC',
+    enabled        boolean not null default true,
+    check (active_from <> active_to)
+);
+
+create index activity_rules_camera_id on activity_rules (camera_id);
+
+-- problems found by the service, kept after they are resolved
+create table alerts
+(
+    id               bigserial primary key,
+    kind             text                     not null,
+    state            text                     not null default 'open' check (state in ('open', 'resolved')),
+    camera_id        bigint references cameras on delete cascade,
+    activity_rule_id bigint references activity_rules on delete cascade,
+    message          text                     not null,
+    opened_at        timestamp with time zone not null default now(),
+    resolved_at      timestamp with time zone
+);
+
+-- a rule has at most one unresolved alert
+create unique index alerts_activity_rule_id_unresolved on alerts (activity_rule_id) where state <> 'resolved';
+create index alerts_opened_at on alerts (opened_at);
+
+-- +goose Down
+drop table alerts;
+drop table activity_rules;
@@ -0,0 +1,48 @@
+-- +goose Up
+-- the occupancy of a location is the people who entered it today minus the ones who left, as seen by the cameras
+-- of the location with an entry direction
+alter table locations
+    add column capacity          int check (capacity > 0),
+    add column warning_threshold int check (warning_threshold > 0),
+    add constraint locations_warning_threshold_capacity_check
+        check (warning_threshold is null or capacity is null or warning_threshold <= capacity);
+
+-- the direction people walk in the image when they enter the location of the camera, none if the camera does not
+-- count occupancy
+alter table cameras
+    add column entry_direction direction not null default 'none';
+
+alter table alerts
+    drop constraint alerts_state_check,
+    add constraint alerts_state_check check (state in ('open', 'acknowledged', 'resolved')),
+    add column severity        text not null default 'warning' check (severity in ('warning', 'critical')),
+    add column location_id     bigint references locations on delete cascade,
+    add column acknowledged_at timestamp with time zone,
+    add column acknowledged_by text;
+
+-- a location has at most one unresolved occupancy alert
+create unique index alerts_location_id_unresolved on alerts (location_id)
+    where state <> 'resolved' and kind = 'location_occupancy';
+
+-- +goose Down
+drop index alerts_location_id_unresolved;
+
+update alerts
+set state = 'open'
+where state = 'acknowledged';
+
+alter table alerts
+    drop column acknowledged_by,
+    drop column acknowledged_at,
+    drop column location_id,
+    drop column severity,
+    drop constraint alerts_state_check,
+    add constraint alerts_state_check check (state in ('open', 'resolved'));
+
+alter table cameras
+    drop column entry_direction;
+
+alter table locations
+    drop constraint locations_warning_threshold_capacity_check,
+    drop column warning_threshold,
+    drop column capacity;
@@ -0,0 +1,59 @@
+-- +goose Up
+-- locations form a tree, like a site containing buildings containing floors containing zones
+alter table locations
+    add column parent_id bigint references locations,
+    add constraint locations_parent_id_check check (parent_id <> id);
+
+create index locations_parent_id on locations (parent_id);
+
+-- +goose StatementBegin
+-- the check constraint only rejects a location being its own parent, this also rejects it being its own ancestor.
+-- Changes of parents are serialized by a transaction lock, otherwise two concurrent ones, like a becoming the child of
+-- b while b becomes the child of a, would each miss the other and commit a cycle. Once the lock is held the ancestors
+-- are read from a new snapshot, at the default read committed isolation, that includes the changes committed by the
+-- transaction that held it before
+create function locations_prevent_cycle() returns trigger as
+$$
+begin
+    -- a location being its own parent is left to the check constraint
+    if new.parent_id is null or new.parent_id = new.id then
+        return new;
+    end if;
+
+    perform pg_advisory_xact_lock('locations'::regclass::oid::bigint);
+    if exists(
+        with recursive ancestors(id) as (select new.parent_id
+                                         union
+                                         select locations.parent_id
+                                         from locations
+                                                  join ancestors on locations.id = ancestors.id
+                                         where locations.parent_id is not null)
+        select
+        from ancestors
+        where id = new.id) then
+        raise exception using
+            errcode = 'check_violation',
+            message = 'new row for relation "locations" violates check constraint "locations_parent_id_cycle"',
+            table = 'locations',
+            constraint = 'locations_parent_id_cycle';
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger locations_prevent_cycle
+    before insert or update of parent_id
+    on locations
+    for each row
+execute function locations_prevent_cycle();
+
+-- +goose Down
+drop trigger locations_prevent_cycle on locations;
+drop function locations_prevent_cycle();
+
+drop index locations_parent_id;
+
+alter table locations
+    drop constraint locations_parent_id_check,
+    drop column parent_id;
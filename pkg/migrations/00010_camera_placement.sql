@@ -0,0 +1,36 @@
+-- +goose Up
+-- the placement of a camera within its location, replacing the free text location_text. floor_x and floor_y are the
+-- position of the camera on the floor plan of its location, heading is where the camera looks in degrees clockwise
+-- from the top of the floor plan and mounting_height is in meters above the floor.
+alter table cameras
+    add column mount_description text not null default '',
+    add column floor_x           double precision,
+    add column floor_y           double precision,
+    add column heading           double precision,
+    add column mounting_height   double precision,
+    add constraint cameras_floor_position_check check ((floor_x is null) = (floor_y is null)),
+    add constraint cameras_heading_check check (heading >= 0 and heading < 360),
+    add constraint cameras_mounting_height_check check (mounting_height > 0);
+
+update cameras
+set mount_description = location_text;
+
+alter table cameras
+    drop column location_text;
+
+-- +goose Down
+alter table cameras
+    add column location_text text not null default '';
+
+update cameras
+set location_text = mount_description;
+
+alter table cameras
+    drop constraint cameras_floor_position_check,
+    drop constraint cameras_heading_check,
+    drop constraint cameras_mounting_height_check,
+    drop column mount_description,
+    drop column floor_x,
+    drop column floor_y,
+    drop column heading,
+    drop column mounting_height;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- the floor plan image of a location, the image itself is kept in the floor plan store under image_key. The image
+-- covers width by height units of the floor, camera positions and fields of view are given in those units.
+create table floor_plans
+(
+    location_id  bigint primary key references locations on delete cascade,
+    image_key    text             not null,
+    content_type text             not null,
+    image_width  int              not null,
+    image_height int              not null,
+    width        double precision not null check (width > 0),
+    height       double precision not null check (height > 0),
+    unit         text             not null,
+    updated_at   timestamptz      not null default now()
+);
+
+-- the area a camera sees, as a json array of points on the floor plan of its location
+alter table cameras
+    add column field_of_view jsonb,
+    add constraint cameras_field_of_view_check
+        check (jsonb_typeof(field_of_view) = 'array' and jsonb_array_length(field_of_view) >= 3);
+
+-- +goose Down
+alter table cameras
+    drop constraint cameras_field_of_view_check,
+    drop column field_of_view;
+
+drop table floor_plans;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- free form key/value tags, like {"zone": "loading-dock"}, used to select cameras across locations
+alter table cameras
+    add column tags jsonb not null default '{}',
+    add constraint cameras_tags_check check (jsonb_typeof(tags) = 'object');
+
+-- named sets of cameras, counted together
+create table camera_groups
+(
+    id          bigserial primary key,
+    name        text not null,
+    description text not null default '',
+    constraint camera_groups_name_key unique (name)
+);
+
+create table camera_group_members
+(
+    group_id  bigint not null references camera_groups on delete cascade,
+    camera_id bigint not null references cameras on delete cascade,
+    primary key (group_id, camera_id)
+);
+
+create index camera_group_members_camera_id on camera_group_members (camera_id);
+
+-- +goose Down
+drop table camera_group_members;
+drop table camera_groups;
+
+alter table cameras
+    drop constraint cameras_tags_check,
+    drop column tags;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- deleted cameras and locations are kept, along with their detections, until they are deleted for good
+alter table cameras
+    add column deleted_at timestamptz;
+
+alter table locations
+    add column deleted_at timestamptz;
+
+-- +goose Down
+alter table locations
+    drop column deleted_at;
+
+alter table cameras
+    drop column deleted_at;
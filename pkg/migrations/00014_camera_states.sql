@@ -0,0 +1,51 @@
+-- +goose Up
+-- where a camera is in its lifecycle. Only active cameras are expected to detect people, cameras in maintenance have
+-- their detections flagged, disabled and retired cameras have them rejected
+alter table cameras
+    add column state            text                     not null default 'active',
+    add column state_changed_at timestamp with time zone not null default now(),
+    add constraint cameras_state_check check (state in ('active', 'maintenance', 'disabled', 'retired'));
+
+-- every state change of a camera
+create table camera_state_changes
+(
+    id         bigserial primary key,
+    camera_id  bigint                   not null references cameras on delete cascade,
+    from_state text                     not null,
+    to_state   text                     not null,
+    reason     text                     not null default '',
+    changed_at timestamp with time zone not null
+);
+
+create index camera_state_changes_camera_id on camera_state_changes (camera_id, changed_at);
+
+-- scheduled maintenance of a camera, its detections dated within the window are rejected or flagged according to
+-- ingest, and its silence alerts are not raised while the window is in progress
+create table maintenance_windows
+(
+    id          bigserial primary key,
+    camera_id   bigint                   not null references cameras on delete cascade,
+    starts_at   timestamp with time zone not null,
+    ends_at     timestamp with time zone not null,
+    ingest      text                     not null default 'reject' check (ingest in ('reject', 'flag')),
+    description text                     not null default '',
+    constraint maintenance_windows_range_check check (ends_at > starts_at)
+);
+
+create index maintenance_windows_camera_id on maintenance_windows (camera_id, starts_at);
+
+-- detections received while their camera was in maintenance
+alter table person_detections
+    add column flagged boolean not null default false;
+
+-- +goose Down
+alter table person_detections
+    drop column flagged;
+
+drop table maintenance_windows;
+drop table camera_state_changes;
+
+alter table cameras
+    drop constraint cameras_state_check,
+    drop column state_changed_at,
+    drop column state;
@@ -0,0 +1,66 @@
+-- +goose Up
+-- the location every camera was in over time, so that detections stay attributed to the location their camera was
+-- in when they were made. The first assignment of a camera starts at -infinity and the current one has no end. A
+-- location can't be deleted while it is in the history of a camera, its assignments must be deleted explicitly first.
+create table camera_location_assignments
+(
+    id          bigserial primary key,
+    camera_id   bigint                   not null references cameras on delete cascade,
+    location_id int                      not null references locations on delete restrict,
+    valid_from  timestamp with time zone not null,
+    valid_to    timestamp with time zone,
+    constraint camera_location_assignments_range_check check (valid_to > valid_from)
+);
+
+create index camera_location_assignments_camera_id on camera_location_assignments (camera_id, valid_from);
+
+insert into camera_location_assignments (camera_id, location_id, valid_from)
+select id, location_id, '-infinity'
+from cameras;
+
+-- +goose StatementBegin
+create function record_camera_location() returns trigger as
+$$
+begin
+    if tg_op = 'UPDATE' then
+        if new.location_id = old.location_id then
+            return null;
+        end if;
+
+        update camera_location_assignments
+        set valid_to = now()
+        where camera_id = new.id
+          and valid_to is null;
+    end if;
+
+    insert into camera_location_assignments (camera_id, location_id, valid_from)
+    values (new.id, new.location_id, case when tg_op = 'INSERT' then '-infinity'::timestamptz else now() end);
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger camera_location_assignments
+    after insert or update of location_id
+    on cameras
+    for each row
+execute function record_camera_location();
+
+-- the location the camera was in at the given date
+-- +goose StatementBegin
+create function camera_location_at(camera bigint, at timestamptz) returns int as
+$$
+select location_id
+from camera_location_assignments
+where camera_id = camera
+  and valid_from <= at
+  and (valid_to is null or valid_to > at)
+$$ language sql stable;
+-- +goose StatementEnd
+
+-- +goose Down
+drop function camera_location_at(bigint, timestamptz);
+drop trigger camera_location_assignments on cameras;
+drop function record_camera_location();
+drop table camera_location_assignments;
@@ -0,0 +1,217 @@
+-- +goose Up
+-- the direction of a detection relative to the place its camera watches, in or out, derived when the detection is
+-- stored from the orientation and the entry direction of its camera. The entry direction is given as seen by an
+-- upright camera, inverted cameras see everything mirrored so their raw direction is flipped before comparing it.
+-- Cameras without an entry direction and detections without a direction are none.
+-- +goose StatementBegin
+create function normalize_direction(target direction, camera_orientation orientation, entry direction) returns text as
+$$
+select case
+           when target = 'none' or entry = 'none' then 'none'
+           when (target = entry) <> (camera_orientation in ('inverted_vertical', 'inverted_horizontal')) then 'in'
+           else 'out'
+           end
+$$ language sql immutable;
+-- +goose StatementEnd
+
+alter table person_detections
+    add column normalized_direction text not null default 'none',
+    add constraint person_detections_normalized_direction_check
+        check (normalized_direction in ('in', 'out', 'none'));
+
+alter table person_detection_hourly_counts
+    add column normalized_direction text not null default 'none';
+
+alter table person_detection_daily_counts
+    add column normalized_direction text not null default 'none';
+
+-- the entry direction of the cameras is kept as it was set, the existing detections and counts are normalized with it
+-- like the new ones, so inverted cameras that counted everyone backwards are counted right, history included
+update person_detections
+set normalized_direction = normalize_direction(person_detections.target_direction, cameras.orientation,
+                                               cameras.entry_direction)
+from cameras
+where cameras.id = person_detections.camera_id;
+
+update person_detection_hourly_counts
+set normalized_direction = normalize_direction(person_detection_hourly_counts.target_direction, cameras.orientation,
+                                               cameras.entry_direction)
+from cameras
+where cameras.id = person_detection_hourly_counts.camera_id;
+
+update person_detection_daily_counts
+set normalized_direction = normalize_direction(person_detection_daily_counts.target_direction, cameras.orientation,
+                                               cameras.entry_direction)
+from cameras
+where cameras.id = person_detection_daily_counts.camera_id;
+
+alter table person_detection_hourly_counts
+    drop constraint person_detection_hourly_counts_pkey,
+    add primary key (camera_id, bucket, target_direction, normalized_direction);
+
+alter table person_detection_daily_counts
+    drop constraint person_detection_daily_counts_pkey,
+    add primary key (camera_id, bucket, target_direction, normalized_direction);
+
+-- +goose StatementBegin
+create function set_normalized_direction() returns trigger as
+$$
+begin
+    -- updates setting the same camera and direction keep the direction derived when the detection was stored
+    if tg_op = 'UPDATE' and new.camera_id = old.camera_id and new.target_direction = old.target_direction then
+        return new;
+    end if;
+
+    -- a missing camera is left to the foreign key
+    new.normalized_direction := coalesce((select normalize_direction(new.target_direction, orientation, entry_direction)
+                                          from cameras
+                                          where id = new.camera_id), 'none');
+    return new;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger person_detection_normalized_direction
+    before insert or update of camera_id, target_direction
+    on person_detections
+    for each row
+execute function set_normalized_direction();
+
+-- +goose StatementBegin
+create or replace function maintain_person_detection_rollups() returns trigger as
+$$
+begin
+    if current_setting('camera_service.skip_rollups', true) = 'on' then
+        return null;
+    end if;
+
+    if tg_op in ('UPDATE', 'DELETE') then
+        update person_detection_hourly_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = date_trunc('hour', old.detection_date)
+          and target_direction = old.target_direction
+          and normalized_direction = old.normalized_direction;
+
+        update person_detection_daily_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = old.detection_date::date
+          and target_direction = old.target_direction
+          and normalized_direction = old.normalized_direction;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') then
+        insert into person_detection_hourly_counts (camera_id, bucket, target_direction, normalized_direction, count)
+        values (new.camera_id, date_trunc('hour', new.detection_date), new.target_direction, new.normalized_direction, 1)
+        on conflict (camera_id, bucket, target_direction, normalized_direction) do update set count = person_detection_hourly_counts.count + 1;
+
+        insert into person_detection_daily_counts (camera_id, bucket, target_direction, normalized_direction, count)
+        values (new.camera_id, new.detection_date::date, new.target_direction, new.normalized_direction, 1)
+        on conflict (camera_id, bucket, target_direction, normalized_direction) do update set count = person_detection_daily_counts.count + 1;
+    end if;
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+create or replace function maintain_person_detection_rollups() returns trigger as
+$$
+begin
+    if current_setting('camera_service.skip_rollups', true) = 'on' then
+        return null;
+    end if;
+
+    if tg_op in ('UPDATE', 'DELETE') then
+        update person_detection_hourly_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = date_trunc('hour', old.detection_date)
+          and target_direction = old.target_direction;
+
+        update person_detection_daily_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = old.detection_date::date
+          and target_direction = old.target_direction;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') then
+        insert into person_detection_hourly_counts (camera_id, bucket, target_direction, count)
+        values (new.camera_id, date_trunc('hour', new.detection_date), new.target_direction, 1)
+        on conflict (camera_id, bucket, target_direction) do update set count = person_detection_hourly_counts.count + 1;
+
+        insert into person_detection_daily_counts (camera_id, bucket, target_direction, count)
+        values (new.camera_id, new.detection_date::date, new.target_direction, 1)
+        on conflict (camera_id, bucket, target_direction) do update set count = person_detection_daily_counts.count + 1;
+    end if;
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+drop trigger person_detection_normalized_direction on person_detections;
+drop function set_normalized_direction();
+
+-- merges the counts that only differ in their normalized direction into one of them
+with merged as (select camera_id, bucket, target_direction, min(normalized_direction) as normalized_direction, sum(count) as count
+                from person_detection_hourly_counts
+                group by 1, 2, 3)
+update person_detection_hourly_counts
+set count = merged.count
+from merged
+where person_detection_hourly_counts.camera_id = merged.camera_id
+  and person_detection_hourly_counts.bucket = merged.bucket
+  and person_detection_hourly_counts.target_direction = merged.target_direction
+  and person_detection_hourly_counts.normalized_direction = merged.normalized_direction;
+
+delete
+from person_detection_hourly_counts
+using (select camera_id, bucket, target_direction, min(normalized_direction) as normalized_direction
+       from person_detection_hourly_counts
+       group by 1, 2, 3) as kept
+where person_detection_hourly_counts.camera_id = kept.camera_id
+  and person_detection_hourly_counts.bucket = kept.bucket
+  and person_detection_hourly_counts.target_direction = kept.target_direction
+  and person_detection_hourly_counts.normalized_direction <> kept.normalized_direction;
+
+with merged as (select camera_id, bucket, target_direction, min(normalized_direction) as normalized_direction, sum(count) as count
+                from person_detection_daily_counts
+                group by 1, 2, 3)
+update person_detection_daily_counts
+set count = merged.count
+from merged
+where person_detection_daily_counts.camera_id = merged.camera_id
+  and person_detection_daily_counts.bucket = merged.bucket
+  and person_detection_daily_counts.target_direction = merged.target_direction
+  and person_detection_daily_counts.normalized_direction = merged.normalized_direction;
+
+delete
+from person_detection_daily_counts
+using (select camera_id, bucket, target_direction, min(normalized_direction) as normalized_direction
+       from person_detection_daily_counts
+       group by 1, 2, 3) as kept
+where person_detection_daily_counts.camera_id = kept.camera_id
+  and person_detection_daily_counts.bucket = kept.bucket
+  and person_detection_daily_counts.target_direction = kept.target_direction
+  and person_detection_daily_counts.normalized_direction <> kept.normalized_direction;
+
+alter table person_detection_daily_counts
+    drop constraint person_detection_daily_counts_pkey,
+    drop column normalized_direction,
+    add primary key (camera_id, bucket, target_direction);
+
+alter table person_detection_hourly_counts
+    drop constraint person_detection_hourly_counts_pkey,
+    drop column normalized_direction,
+    add primary key (camera_id, bucket, target_direction);
+
+alter table person_detections
+    drop constraint person_detections_normalized_direction_check,
+    drop column normalized_direction;
+
+drop function normalize_direction(direction, orientation, direction);
@@ -0,0 +1,133 @@
+-- +goose Up
+-- what the detector reported along with a detection, all optional. track_id identifies the same person across the
+-- detections of a camera, confidence is between 0 and 1, the bounding box is where the person is in the frame and
+-- frame_date is when the frame was captured. Excluded detections, like the ones below the minimum confidence, are
+-- stored but left out of every count.
+alter table person_detections
+    add column track_id      text,
+    add column confidence    double precision,
+    add column bbox_x        double precision,
+    add column bbox_y        double precision,
+    add column bbox_width    double precision,
+    add column bbox_height   double precision,
+    add column frame_date    timestamp with time zone,
+    add column model_version text,
+    add column excluded      boolean not null default false,
+    add constraint person_detections_confidence_check check (confidence >= 0 and confidence <= 1),
+    add constraint person_detections_bbox_check check ((bbox_x is null) = (bbox_y is null) and
+                                                       (bbox_x is null) = (bbox_width is null) and
+                                                       (bbox_x is null) = (bbox_height is null)),
+    add constraint person_detections_bbox_size_check check (bbox_width > 0 and bbox_height > 0);
+
+create index person_detections_track_id on person_detections (camera_id, track_id) where track_id is not null;
+
+-- +goose StatementBegin
+create or replace function maintain_person_detection_rollups() returns trigger as
+$$
+begin
+    if current_setting('camera_service.skip_rollups', true) = 'on' then
+        return null;
+    end if;
+
+    if tg_op in ('UPDATE', 'DELETE') and not old.excluded then
+        update person_detection_hourly_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = date_trunc('hour', old.detection_date)
+          and target_direction = old.target_direction
+          and normalized_direction = old.normalized_direction;
+
+        update person_detection_daily_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = old.detection_date::date
+          and target_direction = old.target_direction
+          and normalized_direction = old.normalized_direction;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') and not new.excluded then
+        insert into person_detection_hourly_counts (camera_id, bucket, target_direction, normalized_direction, count)
+        values (new.camera_id, date_trunc('hour', new.detection_date), new.target_direction, new.normalized_direction, 1)
+        on conflict (camera_id, bucket, target_direction, normalized_direction) do update set count = person_detection_hourly_counts.count + 1;
+
+        insert into person_detection_daily_counts (camera_id, bucket, target_direction, normalized_direction, count)
+        values (new.camera_id, new.detection_date::date, new.target_direction, new.normalized_direction, 1)
+        on conflict (camera_id, bucket, target_direction, normalized_direction) do update set count = person_detection_daily_counts.count + 1;
+    end if;
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+drop trigger person_detection_rollups on person_detections;
+
+create trigger person_detection_rollups
+    after insert or update of camera_id, detection_date, target_direction, excluded or delete
+    on person_detections
+    for each row
+execute function maintain_person_detection_rollups();
+
+-- +goose Down
+drop trigger person_detection_rollups on person_detections;
+
+create trigger person_detection_rollups
+    after insert or update of camera_id, detection_date, target_direction or delete
+    on person_detections
+    for each row
+execute function maintain_person_detection_rollups();
+
+-- +goose StatementBegin
+create or replace function maintain_person_detection_rollups() returns trigger as
+$$
+begin
+    if current_setting('camera_service.skip_rollups', true) = 'on' then
+        return null;
+    end if;
+
+    if tg_op in ('UPDATE', 'DELETE') then
+        update person_detection_hourly_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = date_trunc('hour', old.detection_date)
+          and target_direction = old.target_direction
+          and normalized_direction = old.normalized_direction;
+
+        update person_detection_daily_counts
+        set count = count - 1
+        where camera_id = old.camera_id
+          and bucket = old.detection_date::date
+          and target_direction = old.target_direction
+          and normalized_direction = old.normalized_direction;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') then
+        insert into person_detection_hourly_counts (camera_id, bucket, target_direction, normalized_direction, count)
+        values (new.camera_id, date_trunc('hour', new.detection_date), new.target_direction, new.normalized_direction, 1)
+        on conflict (camera_id, bucket, target_direction, normalized_direction) do update set count = person_detection_hourly_counts.count + 1;
+
+        insert into person_detection_daily_counts (camera_id, bucket, target_direction, normalized_direction, count)
+        values (new.camera_id, new.detection_date::date, new.target_direction, new.normalized_direction, 1)
+        on conflict (camera_id, bucket, target_direction, normalized_direction) do update set count = person_detection_daily_counts.count + 1;
+    end if;
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+drop index person_detections_track_id;
+
+alter table person_detections
+    drop constraint person_detections_confidence_check,
+    drop constraint person_detections_bbox_check,
+    drop constraint person_detections_bbox_size_check,
+    drop column track_id,
+    drop column confidence,
+    drop column bbox_x,
+    drop column bbox_y,
+    drop column bbox_width,
+    drop column bbox_height,
+    drop column frame_date,
+    drop column model_version,
+    drop column excluded;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- the Idempotency-Key a detection was created with, so that a detector resending it gets the original detection
+-- back instead of a duplicate. Keys are unique per camera and kept here because person_detections is partitioned
+-- and can only hold unique constraints that include detection_date. There is no foreign key to person_detections
+-- either, as it would keep its partitions from being dropped, keys of detections that are gone are taken over.
+create table person_detection_idempotency_keys
+(
+    camera_id           bigint                   not null references cameras on delete cascade,
+    key                 text                     not null,
+    person_detection_id bigint                   not null,
+    detection_date      timestamp with time zone not null,
+    created_at          timestamp with time zone not null default now(),
+    primary key (camera_id, key)
+);
+
+create index person_detection_idempotency_keys_created_at on person_detection_idempotency_keys (camera_id, created_at);
+
+-- +goose Down
+drop table person_detection_idempotency_keys;
@@ -0,0 +1,56 @@
+-- +goose Up
+-- a partition can't be created while the default partition holds detections of its range. Those detections are moved
+-- to a new table which is then attached as the partition, attaching it does not run the triggers, so the detections
+-- keep their normalized direction. The rollups are skipped while deleting them from the default partition, as they
+-- are still stored.
+-- +goose StatementBegin
+create or replace function create_person_detections_partition(month date) returns void as
+$$
+declare
+    partition_name text        := 'person_detections_' || to_char(month, 'YYYY_MM');
+    range_start    timestamptz := date_trunc('month', month);
+    range_end      timestamptz := date_trunc('month', month) + interval '1 month';
+    skip_rollups   text        := coalesce(current_setting('camera_service.skip_rollups', true), '');
+begin
+    if exists(select 1 from person_detection_partitions where name = partition_name) then
+        return;
+    end if;
+
+    execute format('create table %I (like person_detections including defaults including constraints)',
+                   partition_name);
+
+    perform set_config('camera_service.skip_rollups', 'on', true);
+    execute format('with moved as (delete from person_detections_default where detection_date >= %L and detection_date < %L returning *)
+                    insert into %I select * from moved', range_start, range_end, partition_name);
+    perform set_config('camera_service.skip_rollups', skip_rollups, true);
+
+    execute format('alter table person_detections attach partition %I for values from (%L) to (%L)',
+                   partition_name, range_start, range_end);
+
+    insert into person_detection_partitions (name, range_start, range_end)
+    values (partition_name, range_start, range_end);
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+create or replace function create_person_detections_partition(month date) returns void as
+$$
+declare
+    partition_name text        := 'person_detections_' || to_char(month, 'YYYY_MM');
+    range_start    timestamptz := date_trunc('month', month);
+    range_end      timestamptz := date_trunc('month', month) + interval '1 month';
+begin
+    if exists(select 1 from person_detection_partitions where name = partition_name) then
+        return;
+    end if;
+
+    execute format('create table %I partition of person_detections for values from (%L) to (%L)',
+                   partition_name, range_start, range_end);
+
+    insert into person_detection_partitions (name, range_start, range_end)
+    values (partition_name, range_start, range_end);
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- the highest detection id in each archive. Ids come from a sequence, so detections stored in a day after it was
+-- archived have higher ids than its archives, they are archived to another file of the day and retention only prunes
+-- detections an archive holds. Archives written before are taken to hold every detection their day has now.
+alter table detection_archives
+    add column max_id bigint;
+
+update detection_archives
+set max_id = coalesce((select max(id)
+                       from person_detections
+                       where detection_date >= detection_archives.range_start
+                         and detection_date < detection_archives.range_end), 0);
+
+alter table detection_archives
+    alter column max_id set not null;
+
+create index detection_archives_range_start on detection_archives (range_start, max_id);
+
+-- +goose Down
+drop index detection_archives_range_start;
+
+alter table detection_archives
+    drop column max_id;
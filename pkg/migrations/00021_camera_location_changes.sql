@@ -0,0 +1,68 @@
+-- +goose Up
+-- moving a camera twice in one transaction used to close the assignment opened by the first move at the time it
+-- started, which the range check rejects. That assignment was never in effect, so it is replaced instead, and moving
+-- the camera back to where it was before the transaction reopens the previous assignment.
+-- +goose StatementBegin
+create or replace function record_camera_location() returns trigger as
+$$
+begin
+    if tg_op = 'UPDATE' then
+        if new.location_id = old.location_id then
+            return null;
+        end if;
+
+        delete
+        from camera_location_assignments
+        where camera_id = new.id
+          and valid_to is null
+          and valid_from = now();
+
+        if found then
+            update camera_location_assignments
+            set valid_to = null
+            where camera_id = new.id
+              and valid_to = now()
+              and location_id = new.location_id;
+
+            if found then
+                return null;
+            end if;
+        else
+            update camera_location_assignments
+            set valid_to = now()
+            where camera_id = new.id
+              and valid_to is null;
+        end if;
+    end if;
+
+    insert into camera_location_assignments (camera_id, location_id, valid_from)
+    values (new.id, new.location_id, case when tg_op = 'INSERT' then '-infinity'::timestamptz else now() end);
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+create or replace function record_camera_location() returns trigger as
+$$
+begin
+    if tg_op = 'UPDATE' then
+        if new.location_id = old.location_id then
+            return null;
+        end if;
+
+        update camera_location_assignments
+        set valid_to = now()
+        where camera_id = new.id
+          and valid_to is null;
+    end if;
+
+    insert into camera_location_assignments (camera_id, location_id, valid_from)
+    values (new.id, new.location_id, case when tg_op = 'INSERT' then '-infinity'::timestamptz else now() end);
+
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- keys are claimed before their detection is created, so that concurrent requests with the same key wait for the
+-- one that claimed it instead of both creating a detection. A claimed key has no detection until the request that
+-- claimed it commits.
+alter table person_detection_idempotency_keys
+    alter column person_detection_id drop not null,
+    alter column detection_date drop not null;
+
+-- +goose Down
+delete
+from person_detection_idempotency_keys
+where person_detection_id is null;
+
+alter table person_detection_idempotency_keys
+    alter column person_detection_id set not null,
+    alter column detection_date set not null;
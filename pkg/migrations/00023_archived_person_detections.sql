@@ -0,0 +1,54 @@
+-- +goose Up
+-- the archive holding each detection, null until it is archived. Only detections an archive holds are pruned while
+-- archiving is enabled. Any other change to an archived detection clears it, as the archived copy no longer matches,
+-- so the detection is archived again with the day it has now. The ids of the archives written before can't tell which
+-- detections they hold, those detections are archived again to another file of their day.
+alter table person_detections
+    add column archive_id bigint references detection_archives;
+
+create index person_detections_unarchived on person_detections (detection_date) where archive_id is null;
+
+-- +goose StatementBegin
+create function clear_person_detection_archive() returns trigger as
+$$
+begin
+    if new.archive_id is not distinct from old.archive_id and row (new.*) is distinct from row (old.*) then
+        new.archive_id := null;
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger person_detection_archive
+    before update
+    on person_detections
+    for each row
+execute function clear_person_detection_archive();
+
+drop index detection_archives_range_start;
+
+alter table detection_archives
+    drop column max_id;
+
+create index detection_archives_range_start on detection_archives (range_start);
+
+-- +goose Down
+drop index detection_archives_range_start;
+
+alter table detection_archives
+    add column max_id bigint not null default 0;
+
+update detection_archives
+set max_id = coalesce((select max(id) from person_detections where archive_id = detection_archives.id), 0);
+
+create index detection_archives_range_start on detection_archives (range_start, max_id);
+
+drop trigger person_detection_archive on person_detections;
+
+drop function clear_person_detection_archive();
+
+drop index person_detections_unarchived;
+
+alter table person_detections
+    drop column archive_id;
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// Slack posts notifications to a Slack incoming webhook, or to any chat accepting the same {"text": ...} payload
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s Slack) Notify(ctx context.Context, notification Notification) error {
+	payload := struct {
+		Text string `json:"text"`
+	}{
+		Text: "*" + notification.Title + "*\n" + notification.Message,
+	}
+	return sendJson(ctx, s.Client, http.MethodPost, s.URL, nil, payload)
+}
+
+// Teams posts notifications as message cards to a Microsoft Teams incoming webhook
+type Teams struct {
+	URL    string
+	Client *http.Client
+}
+
+// teamsColors are the accent colors of the cards by severity, resolved alerts are always green
+var teamsColors = map[string]string{
+	"warning":  "FFA500",
+	"critical": "D70000",
+	"resolved": "2EB886",
+}
+
+func (t Teams) Notify(ctx context.Context, notification Notification) error {
+	color := teamsColors[notification.Severity]
+	if notification.State == "resolved" {
+		color = teamsColors["resolved"]
+	}
+
+	payload := struct {
+		Type       string `json:"@type"`
+		Context    string `json:"@context"`
+		Summary    string `json:"summary"`
+		Title      string `json:"title"`
+		Text       string `json:"text"`
+		ThemeColor string `json:"themeColor,omitempty"`
+	}{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    notification.Title,
+		Title:      notification.Title,
+		Text:       notification.Message,
+		ThemeColor: color,
+	}
+	return sendJson(ctx, t.Client, http.MethodPost, t.URL, nil, payload)
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSuppressed is returned by Limited for the notifications it drops
+var ErrSuppressed = errors.New("notification suppressed")
+
+// Limited drops repeated notifications and the ones sent too often, so a flapping alert can not flood a channel
+type Limited struct {
+	notifier Notifier
+	// dedupWindow is how long a change of an alert is not sent again, zero disables deduplication
+	dedupWindow time.Duration
+	// rateLimit is how many notifications can be sent every ratePeriod, zero disables rate limiting
+	rateLimit  int
+	ratePeriod time.Duration
+	now        func() time.Time
+
+	mutex sync.Mutex
+	// sent is when every change of an alert was last sent, by dedupKey
+	sent map[string]time.Time
+	// recent are the times of the notifications sent in the last rate period
+	recent []time.Time
+}
+
+func NewLimited(notifier Notifier, dedupWindow time.Duration, rateLimit int, ratePeriod time.Duration) *Limited {
+	return &Limited{
+		notifier:    notifier,
+		dedupWindow: dedupWindow,
+		rateLimit:   rateLimit,
+		ratePeriod:  ratePeriod,
+		now:         time.Now,
+		sent:        make(map[string]time.Time),
+	}
+}
+
+// dedupKey identifies a change of an alert, notifications with the same key are duplicates
+func dedupKey(notification Notification) string {
+	return fmt.Sprintf("%s/%d/%s/%s", notification.Kind, notification.AlertID, notification.State, notification.Severity)
+}
+
+func (l *Limited) Notify(ctx context.Context, notification Notification) error {
+	if err := l.reserve(notification); err != nil {
+		return err
+	}
+	if err := l.notifier.Notify(ctx, notification); err != nil {
+		// a failed delivery is not a duplicate of a later retry
+		l.mutex.Lock()
+		delete(l.sent, dedupKey(notification))
+		l.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// reserve records notification as sent, or returns why it must be dropped
+func (l *Limited) reserve(notification Notification) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+
+	key := dedupKey(notification)
+	if l.dedupWindow > 0 {
+		for k, sentAt := range l.sent {
+			if now.Sub(sentAt) >= l.dedupWindow {
+				delete(l.sent, k)
+			}
+		}
+		if sentAt, ok := l.sent[key]; ok {
+			return fmt.Errorf("%w: the same notification was sent %s ago", ErrSuppressed, now.Sub(sentAt).Round(time.Second))
+		}
+	}
+
+	if l.rateLimit > 0 {
+		kept := l.recent[:0]
+		for _, sentAt := range l.recent {
+			if now.Sub(sentAt) < l.ratePeriod {
+				kept = append(kept, sentAt)
+			}
+		}
+		l.recent = kept
+		if len(l.recent) >= l.rateLimit {
+			return fmt.Errorf("%w: the limit of %d notifications every %s was reached", ErrSuppressed, l.rateLimit, l.ratePeriod)
+		}
+		l.recent = append(l.recent, now)
+	}
+
+	if l.dedupWindow > 0 {
+		l.sent[key] = now
+	}
+	return nil
+}
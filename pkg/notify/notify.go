@@ -0,0 +1,110 @@
+// Package notify delivers notifications about alerts opened and resolved by the service. Notifications are sent
+// through channels, like email or a chat webhook, chosen by the routes of a Router.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Notification describes a change of an alert
+type Notification struct {
+	// Kind is the kind of the alert, like camera_silent
+	Kind string `json:"kind"`
+	// State is the state the alert changed to, open or resolved
+	State string `json:"state"`
+	// Severity is warning or critical
+	Severity       string    `json:"severity"`
+	Title          string    `json:"title"`
+	Message        string    `json:"message"`
+	AlertID        int64     `json:"alert_id"`
+	CameraID       int64     `json:"camera_id,omitempty"`
+	LocationID     int64     `json:"location_id,omitempty"`
+	ActivityRuleID int64     `json:"activity_rule_id,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Multi delivers every notification to all of its notifiers, returning every error found
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, notification Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Log writes notifications to a logger
+type Log struct {
+	Logger *zap.SugaredLogger
+}
+
+func (l Log) Notify(ctx context.Context, notification Notification) error {
+	l.Logger.Infow(notification.Title, "kind", notification.Kind, "state", notification.State,
+		"alert", notification.AlertID, "message", notification.Message)
+	return nil
+}
+
+// Webhook sends notifications as json to an url, it is the generic http channel
+type Webhook struct {
+	URL string
+	// Method is POST when empty
+	Method string
+	// Headers are added to every request, like an Authorization header
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (h Webhook) Notify(ctx context.Context, notification Notification) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return sendJson(ctx, h.Client, method, h.URL, h.Headers, notification)
+}
+
+// sendJson sends payload as json to url, any status but 2xx is an error
+func sendJson(ctx context.Context, client *http.Client, method string, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshaling notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling webhook: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("webhook answered with status %d: %s", res.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// request is what a test server got
+type request struct {
+	method string
+	header http.Header
+	body   string
+}
+
+// testServer records every request it gets and answers them with status
+func testServer(t *testing.T, status int) (*httptest.Server, <-chan request) {
+	t.Helper()
+
+	requests := make(chan request, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{method: r.Method, header: r.Header, body: string(body)}
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var testNotification = Notification{
+	Kind:     "camera_silent",
+	State:    "open",
+	Severity: "critical",
+	Title:    "Camera entrance is silent",
+	Message:  "no detections for 1h",
+	AlertID:  7,
+	CameraID: 3,
+	Time:     time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC),
+}
+
+func TestWebhook(t *testing.T) {
+	server, requests := testServer(t, http.StatusNoContent)
+
+	webhook := Webhook{URL: server.URL, Method: http.MethodPut, Headers: map[string]string{"Authorization": "Bearer token"}}
+	if err := webhook.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("error notifying: %s", err)
+	}
+
+	got := <-requests
+	if got.method != http.MethodPut {
+		t.Fatalf("expected method PUT, got %s", got.method)
+	}
+	if got.header.Get("Authorization") != "Bearer token" || got.header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers %v", got.header)
+	}
+	var notification Notification
+	if err := json.Unmarshal([]byte(got.body), &notification); err != nil {
+		t.Fatalf("error decoding body: %s", err)
+	}
+	if notification != testNotification {
+		t.Fatalf("expected %+v, got %+v", testNotification, notification)
+	}
+}
+
+func TestWebhookDefaultsToPost(t *testing.T) {
+	server, requests := testServer(t, http.StatusOK)
+
+	if err := (Webhook{URL: server.URL}).Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("error notifying: %s", err)
+	}
+	if got := <-requests; got.method != http.MethodPost {
+		t.Fatalf("expected method POST, got %s", got.method)
+	}
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	server, _ := testServer(t, http.StatusBadGateway)
+
+	err := (Webhook{URL: server.URL}).Notify(context.Background(), testNotification)
+	if err == nil || !strings.Contains(err.Error(), "status 502") {
+		t.Fatalf("expected an error with the status, got %v", err)
+	}
+}
+
+func TestChatPayloads(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier func(url string) Notifier
+		expected map[string]any
+	}{
+		{
+			name:     "slack",
+			notifier: func(url string) Notifier { return Slack{URL: url} },
+			expected: map[string]any{"text": "*Camera entrance is silent*\nno detections for 1h"},
+		},
+		{
+			name:     "teams",
+			notifier: func(url string) Notifier { return Teams{URL: url} },
+			expected: map[string]any{
+				"@type":      "MessageCard",
+				"@context":   "https://schema.org/extensions",
+				"summary":    "Camera entrance is silent",
+				"title":      "Camera entrance is silent",
+				"text":       "no detections for 1h",
+				"themeColor": "D70000",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := testServer(t, http.StatusOK)
+			if err := test.notifier(server.URL).Notify(context.Background(), testNotification); err != nil {
+				t.Fatalf("error notifying: %s", err)
+			}
+
+			var payload map[string]any
+			if err := json.Unmarshal([]byte((<-requests).body), &payload); err != nil {
+				t.Fatalf("error decoding body: %s", err)
+			}
+			for key, value := range test.expected {
+				if payload[key] != value {
+					t.Errorf("expected %s to be %v, got %v", key, value, payload[key])
+				}
+			}
+		})
+	}
+}
+
+// recorder keeps every notification it is sent
+type recorder struct {
+	notifications []Notification
+	err           error
+}
+
+func (r *recorder) Notify(ctx context.Context, notification Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return r.err
+}
+
+func TestTemplated(t *testing.T) {
+	var sent recorder
+	templated := Templated{
+		Notifier: &sent,
+		Title:    template.Must(template.New("title").Parse("[{{.Severity}}] {{.Title}}")),
+	}
+
+	if err := templated.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("error notifying: %s", err)
+	}
+
+	if len(sent.notifications) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(sent.notifications))
+	}
+	if title := sent.notifications[0].Title; title != "[critical] Camera entrance is silent" {
+		t.Fatalf("unexpected title %q", title)
+	}
+	if message := sent.notifications[0].Message; message != testNotification.Message {
+		t.Fatalf("expected the message to be kept, got %q", message)
+	}
+}
@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// the types of channels
+const (
+	ChannelSMTP  = "smtp"
+	ChannelSlack = "slack"
+	ChannelTeams = "teams"
+	ChannelHTTP  = "http"
+)
+
+type (
+	SMTPConfig struct {
+		Host     string   `mapstructure:"host"`
+		Port     int      `mapstructure:"port"`
+		Username string   `mapstructure:"username"`
+		Password string   `mapstructure:"password"`
+		From     string   `mapstructure:"from"`
+		To       []string `mapstructure:"to"`
+	}
+	// ChannelConfig configures a channel notifications can be routed to
+	ChannelConfig struct {
+		Name string `mapstructure:"name"`
+		// Type is smtp, slack, teams or http
+		Type string `mapstructure:"type"`
+		// URL is the webhook of slack, teams and http channels
+		URL string `mapstructure:"url"`
+		// Method and Headers are only used by http channels
+		Method  string            `mapstructure:"method"`
+		Headers map[string]string `mapstructure:"headers"`
+		SMTP    SMTPConfig        `mapstructure:"smtp"`
+		// Title and Message are text/template templates replacing the title and message of the notifications, like
+		// "[{{.Severity}}] {{.Title}}". Every field of Notification can be used.
+		Title   string `mapstructure:"title"`
+		Message string `mapstructure:"message"`
+		// DedupWindow is how long the same change of an alert is not sent again through the channel
+		DedupWindow time.Duration `mapstructure:"dedup_window"`
+		// RateLimit is how many notifications the channel sends every RatePeriod, one minute by default
+		RateLimit  int           `mapstructure:"rate_limit"`
+		RatePeriod time.Duration `mapstructure:"rate_period"`
+	}
+	// RouteConfig sends the notifications matching all of its filters to its channels, empty filters match anything
+	RouteConfig struct {
+		Channels        []string `mapstructure:"channels"`
+		Kinds           []string `mapstructure:"kinds"`
+		States          []string `mapstructure:"states"`
+		Severities      []string `mapstructure:"severities"`
+		CameraIDs       []int64  `mapstructure:"camera_ids"`
+		LocationIDs     []int64  `mapstructure:"location_ids"`
+		ActivityRuleIDs []int64  `mapstructure:"activity_rule_ids"`
+	}
+	// Config configures the channels of a Router and how notifications are routed to them. Without routes every
+	// notification is sent to every channel.
+	Config struct {
+		Channels []ChannelConfig `mapstructure:"channels"`
+		Routes   []RouteConfig   `mapstructure:"routes"`
+		// Timeout limits every delivery, 10 seconds when zero
+		Timeout time.Duration `mapstructure:"timeout"`
+	}
+)
+
+// ErrUnknownChannel is returned by Router.Test when there is no channel with the given name
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Channel describes a configured channel without its secrets
+type Channel struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type routerChannel struct {
+	Channel
+	// templated delivers through the channel, limited also applies deduplication and rate limiting
+	templated Notifier
+	limited   Notifier
+}
+
+// Router sends every notification to the channels of the routes it matches
+type Router struct {
+	channels []routerChannel
+	routes   []RouteConfig
+	logger   *zap.SugaredLogger
+}
+
+func New(config Config, logger *zap.SugaredLogger) (*Router, error) {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	router := &Router{routes: config.Routes, logger: logger}
+	names := make(map[string]bool, len(config.Channels))
+	for _, channelConfig := range config.Channels {
+		if channelConfig.Name == "" {
+			return nil, errors.New("notification channels must have a name")
+		}
+		if names[channelConfig.Name] {
+			return nil, fmt.Errorf("duplicate notification channel %q", channelConfig.Name)
+		}
+		names[channelConfig.Name] = true
+
+		channel, err := newChannel(channelConfig, client, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification channel %q: %w", channelConfig.Name, err)
+		}
+		router.channels = append(router.channels, channel)
+	}
+
+	for i, route := range config.Routes {
+		if len(route.Channels) == 0 {
+			return nil, fmt.Errorf("notification route %d has no channels", i+1)
+		}
+		for _, name := range route.Channels {
+			if !names[name] {
+				return nil, fmt.Errorf("notification route %d uses unknown channel %q", i+1, name)
+			}
+		}
+	}
+	return router, nil
+}
+
+func newChannel(config ChannelConfig, client *http.Client, timeout time.Duration) (routerChannel, error) {
+	var notifier Notifier
+	switch config.Type {
+	case ChannelSMTP:
+		if config.SMTP.Host == "" || config.SMTP.From == "" || len(config.SMTP.To) == 0 {
+			return routerChannel{}, errors.New("smtp channels need a host, a sender and at least one recipient")
+		}
+		notifier = SMTP{
+			Host:     config.SMTP.Host,
+			Port:     config.SMTP.Port,
+			Username: config.SMTP.Username,
+			Password: config.SMTP.Password,
+			From:     config.SMTP.From,
+			To:       config.SMTP.To,
+			Timeout:  timeout,
+		}
+	case ChannelSlack, ChannelTeams, ChannelHTTP:
+		if config.URL == "" {
+			return routerChannel{}, fmt.Errorf("%s channels need an url", config.Type)
+		}
+		switch config.Type {
+		case ChannelSlack:
+			notifier = Slack{URL: config.URL, Client: client}
+		case ChannelTeams:
+			notifier = Teams{URL: config.URL, Client: client}
+		default:
+			notifier = Webhook{URL: config.URL, Method: config.Method, Headers: config.Headers, Client: client}
+		}
+	default:
+		return routerChannel{}, fmt.Errorf("unknown channel type %q, expected smtp, slack, teams or http", config.Type)
+	}
+
+	templated := Templated{Notifier: notifier}
+	var err error
+	if config.Title != "" {
+		if templated.Title, err = template.New("title").Option("missingkey=error").Parse(config.Title); err != nil {
+			return routerChannel{}, fmt.Errorf("invalid title template: %w", err)
+		}
+	}
+	if config.Message != "" {
+		if templated.Message, err = template.New("message").Option("missingkey=error").Parse(config.Message); err != nil {
+			return routerChannel{}, fmt.Errorf("invalid message template: %w", err)
+		}
+	}
+
+	if config.RateLimit < 0 || config.DedupWindow < 0 || config.RatePeriod < 0 {
+		return routerChannel{}, errors.New("rate limits and dedup windows can not be negative")
+	}
+	ratePeriod := config.RatePeriod
+	if ratePeriod == 0 {
+		ratePeriod = time.Minute
+	}
+
+	return routerChannel{
+		Channel:   Channel{Name: config.Name, Type: config.Type},
+		templated: templated,
+		limited:   NewLimited(templated, config.DedupWindow, config.RateLimit, ratePeriod),
+	}, nil
+}
+
+// matches returns whether notification passes every filter of route
+func (route RouteConfig) matches(notification Notification) bool {
+	return matchesAny(route.Kinds, notification.Kind) &&
+		matchesAny(route.States, notification.State) &&
+		matchesAny(route.Severities, notification.Severity) &&
+		matchesAny(route.CameraIDs, notification.CameraID) &&
+		matchesAny(route.LocationIDs, notification.LocationID) &&
+		matchesAny(route.ActivityRuleIDs, notification.ActivityRuleID)
+}
+
+// matchesAny returns whether value is one of values, or true when there are no values
+func matchesAny[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return len(values) == 0
+}
+
+// Notify sends notification to the channels of the routes it matches, a channel matched by several routes is only
+// sent one notification. Notifications dropped by deduplication or rate limiting are logged instead of failing.
+func (r *Router) Notify(ctx context.Context, notification Notification) error {
+	selected := make(map[string]bool)
+	for _, route := range r.routes {
+		if route.matches(notification) {
+			for _, name := range route.Channels {
+				selected[name] = true
+			}
+		}
+	}
+
+	var errs []error
+	for _, channel := range r.channels {
+		if len(r.routes) > 0 && !selected[channel.Name] {
+			continue
+		}
+		err := channel.limited.Notify(ctx, notification)
+		if errors.Is(err, ErrSuppressed) {
+			r.logger.Infow("notification not sent", "channel", channel.Name, "alert", notification.AlertID, "reason", err)
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("error notifying channel %s: %w", channel.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Channels returns the configured channels, in configuration order
+func (r *Router) Channels() []Channel {
+	channels := make([]Channel, 0, len(r.channels))
+	for _, channel := range r.channels {
+		channels = append(channels, channel.Channel)
+	}
+	return channels
+}
+
+// Test sends notification through the named channel, skipping routing, deduplication and rate limiting
+func (r *Router) Test(ctx context.Context, name string, notification Notification) error {
+	for _, channel := range r.channels {
+		if channel.Name == name {
+			return channel.templated.Notify(ctx, notification)
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnknownChannel, name)
+}
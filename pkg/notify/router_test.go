@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRouterRoutes(t *testing.T) {
+	ops, opsRequests := testServer(t, http.StatusOK)
+	oncall, oncallRequests := testServer(t, http.StatusOK)
+
+	router, err := New(Config{
+		Channels: []ChannelConfig{
+			{Name: "ops", Type: ChannelHTTP, URL: ops.URL},
+			{Name: "oncall", Type: ChannelHTTP, URL: oncall.URL, Title: "[{{.Severity}}] {{.Title}}"},
+		},
+		Routes: []RouteConfig{
+			{Channels: []string{"ops"}},
+			{Channels: []string{"oncall", "ops"}, Severities: []string{"critical"}, CameraIDs: []int64{3}},
+		},
+	}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("error creating router: %s", err)
+	}
+
+	tests := []struct {
+		name         string
+		notification Notification
+		ops, oncall  int
+	}{
+		{"matching every route", testNotification, 1, 1},
+		{"other severity", Notification{Kind: "camera_silent", AlertID: 8, Severity: "warning", CameraID: 3}, 1, 0},
+		{"other camera", Notification{Kind: "camera_silent", AlertID: 9, Severity: "critical", CameraID: 4}, 1, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := router.Notify(context.Background(), test.notification); err != nil {
+				t.Fatalf("error notifying: %s", err)
+			}
+			if len(opsRequests) != test.ops || len(oncallRequests) != test.oncall {
+				t.Fatalf("expected %d and %d notifications, got %d and %d", test.ops, test.oncall, len(opsRequests),
+					len(oncallRequests))
+			}
+			for i := 0; i < test.ops; i++ {
+				<-opsRequests
+			}
+			for i := 0; i < test.oncall; i++ {
+				var notification Notification
+				if err := json.Unmarshal([]byte((<-oncallRequests).body), &notification); err != nil {
+					t.Fatalf("error decoding body: %s", err)
+				}
+				if notification.Title != "[critical] Camera entrance is silent" {
+					t.Fatalf("expected the title template to be used, got %q", notification.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterWithoutRoutesNotifiesEveryChannel(t *testing.T) {
+	first, firstRequests := testServer(t, http.StatusOK)
+	second, secondRequests := testServer(t, http.StatusInternalServerError)
+
+	router, err := New(Config{Channels: []ChannelConfig{
+		{Name: "first", Type: ChannelHTTP, URL: first.URL},
+		{Name: "second", Type: ChannelHTTP, URL: second.URL},
+	}}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("error creating router: %s", err)
+	}
+
+	if err := router.Notify(context.Background(), testNotification); err == nil {
+		t.Fatal("expected the failing channel to be reported")
+	}
+	if len(firstRequests) != 1 || len(secondRequests) != 1 {
+		t.Fatalf("expected every channel to be notified, got %d and %d", len(firstRequests), len(secondRequests))
+	}
+}
+
+func TestRouterTestSkipsLimits(t *testing.T) {
+	server, requests := testServer(t, http.StatusOK)
+
+	router, err := New(Config{Channels: []ChannelConfig{
+		{Name: "ops", Type: ChannelHTTP, URL: server.URL, DedupWindow: time.Hour},
+	}}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("error creating router: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := router.Test(context.Background(), "ops", testNotification); err != nil {
+			t.Fatalf("error sending test notification: %s", err)
+		}
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 test notifications, got %d", len(requests))
+	}
+
+	if err := router.Test(context.Background(), "missing", testNotification); !errors.Is(err, ErrUnknownChannel) {
+		t.Fatalf("expected ErrUnknownChannel, got %v", err)
+	}
+}
+
+func TestNewRejectsInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"channel without name", Config{Channels: []ChannelConfig{{Type: ChannelHTTP, URL: "http://localhost"}}}},
+		{"duplicate channel", Config{Channels: []ChannelConfig{
+			{Name: "ops", Type: ChannelHTTP, URL: "http://localhost"},
+			{Name: "ops", Type: ChannelSlack, URL: "http://localhost"},
+		}}},
+		{"unknown type", Config{Channels: []ChannelConfig{{Name: "ops", Type: "pager"}}}},
+		{"http channel without url", Config{Channels: []ChannelConfig{{Name: "ops", Type: ChannelHTTP}}}},
+		{"smtp channel without recipients", Config{Channels: []ChannelConfig{
+			{Name: "ops", Type: ChannelSMTP, SMTP: SMTPConfig{Host: "localhost", From: "alerts@example.com"}},
+		}}},
+		{"invalid template", Config{Channels: []ChannelConfig{
+			{Name: "ops", Type: ChannelHTTP, URL: "http://localhost", Title: "{{.Title"},
+		}}},
+		{"negative rate limit", Config{Channels: []ChannelConfig{
+			{Name: "ops", Type: ChannelHTTP, URL: "http://localhost", RateLimit: -1},
+		}}},
+		{"route without channels", Config{Routes: []RouteConfig{{}}}},
+		{"route with unknown channel", Config{Routes: []RouteConfig{{Channels: []string{"ops"}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := New(test.config, zap.NewNop().Sugar()); err == nil {
+				t.Fatal("expected the config to be rejected")
+			}
+		})
+	}
+}
+
+func TestLimitedDeduplicates(t *testing.T) {
+	var sent recorder
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	limited := NewLimited(&sent, time.Hour, 0, 0)
+	limited.now = func() time.Time { return now }
+
+	resolved := testNotification
+	resolved.State = "resolved"
+
+	steps := []struct {
+		notification Notification
+		after        time.Duration
+		suppressed   bool
+	}{
+		{testNotification, 0, false},
+		{testNotification, time.Minute, true},
+		{resolved, 0, false},
+		{testNotification, time.Hour, false},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.after)
+		err := limited.Notify(context.Background(), step.notification)
+		if suppressed := errors.Is(err, ErrSuppressed); suppressed != step.suppressed {
+			t.Fatalf("step %d: expected suppressed %t, got %v", i, step.suppressed, err)
+		}
+	}
+	if len(sent.notifications) != 3 {
+		t.Fatalf("expected 3 notifications to be sent, got %d", len(sent.notifications))
+	}
+}
+
+func TestLimitedRetriesFailedDeliveries(t *testing.T) {
+	sent := recorder{err: errors.New("unreachable")}
+	limited := NewLimited(&sent, time.Hour, 0, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := limited.Notify(context.Background(), testNotification); err == nil || errors.Is(err, ErrSuppressed) {
+			t.Fatalf("attempt %d: expected the delivery error, got %v", i, err)
+		}
+	}
+	if len(sent.notifications) != 2 {
+		t.Fatalf("expected the failed notification to be sent again, got %d deliveries", len(sent.notifications))
+	}
+}
+
+func TestLimitedRateLimits(t *testing.T) {
+	var sent recorder
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	limited := NewLimited(&sent, 0, 2, time.Minute)
+	limited.now = func() time.Time { return now }
+
+	notify := func(alertId int64) error {
+		notification := testNotification
+		notification.AlertID = alertId
+		return limited.Notify(context.Background(), notification)
+	}
+
+	for alertId := int64(1); alertId <= 2; alertId++ {
+		if err := notify(alertId); err != nil {
+			t.Fatalf("error notifying alert %d: %s", alertId, err)
+		}
+	}
+	if err := notify(3); !errors.Is(err, ErrSuppressed) {
+		t.Fatalf("expected the third notification to be suppressed, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := notify(4); err != nil {
+		t.Fatalf("expected the limit to reset after the rate period, got %s", err)
+	}
+	if len(sent.notifications) != 3 {
+		t.Fatalf("expected 3 notifications to be sent, got %d", len(sent.notifications))
+	}
+}
+
+func TestRouterAppliesLimits(t *testing.T) {
+	deduplicated, deduplicatedRequests := testServer(t, http.StatusOK)
+	limited, limitedRequests := testServer(t, http.StatusOK)
+
+	router, err := New(Config{Channels: []ChannelConfig{
+		{Name: "deduplicated", Type: ChannelHTTP, URL: deduplicated.URL, DedupWindow: time.Hour},
+		{Name: "limited", Type: ChannelHTTP, URL: limited.URL, RateLimit: 2},
+	}}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("error creating router: %s", err)
+	}
+
+	for _, alertId := range []int64{1, 1, 2} {
+		notification := testNotification
+		notification.AlertID = alertId
+		// suppressed notifications are not errors
+		if err := router.Notify(context.Background(), notification); err != nil {
+			t.Fatalf("error notifying alert %d: %s", alertId, err)
+		}
+	}
+
+	if len(deduplicatedRequests) != 2 {
+		t.Fatalf("expected the repeated notification to be dropped, got %d notifications", len(deduplicatedRequests))
+	}
+	if len(limitedRequests) != 2 {
+		t.Fatalf("expected the rate limit to drop the third notification, got %d notifications", len(limitedRequests))
+	}
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP sends notifications by email, using the title as subject
+type SMTP struct {
+	Host string
+	// Port is 25 when zero
+	Port int
+	// Username and Password authenticate with PLAIN auth when set, which requires TLS unless the server is local
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Timeout limits the whole delivery, 10 seconds when zero
+	Timeout time.Duration
+}
+
+func (s SMTP) Notify(ctx context.Context, notification Notification) error {
+	if len(s.To) == 0 {
+		return errors.New("no email recipients configured")
+	}
+
+	port := s.Port
+	if port == 0 {
+		port = 25
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("error connecting to smtp server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("error setting smtp deadline: %w", err)
+		}
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return fmt.Errorf("error starting smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return fmt.Errorf("error starting tls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("error authenticating with smtp server: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.From); err != nil {
+		return fmt.Errorf("error setting email sender: %w", err)
+	}
+	for _, to := range s.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("error adding email recipient %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error starting email body: %w", err)
+	}
+	if _, err := w.Write(s.message(notification)); err != nil {
+		return fmt.Errorf("error writing email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return client.Quit()
+}
+
+// message formats notification as a plain text email
+func (s SMTP) message(notification Notification) []byte {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	fmt.Fprintf(&message, "Date: %s\r\n", notification.Time.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	// lines must end with crlf, and the data writer already escapes lines starting with a dot
+	message.WriteString(strings.ReplaceAll(strings.ReplaceAll(notification.Message, "\r\n", "\n"), "\n", "\r\n"))
+	message.WriteString("\r\n")
+	return message.Bytes()
+}
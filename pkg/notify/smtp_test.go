@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// email is what the fake smtp server got
+type email struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer accepts a single session and sends the email it gets on the returned channel. Any command but the
+// ones needed to send an email is rejected
+func fakeSMTPServer(t *testing.T) (int, <-chan email) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	emails := make(chan email, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+		var received email
+		reply("220 localhost ready")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimSpace(line)
+			verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0])
+			switch {
+			case verb == "EHLO" || verb == "HELO":
+				reply("250 localhost")
+			case strings.HasPrefix(strings.ToUpper(command), "MAIL FROM:"):
+				received.from = strings.Trim(command[len("MAIL FROM:"):], "<>")
+				reply("250 ok")
+			case strings.HasPrefix(strings.ToUpper(command), "RCPT TO:"):
+				received.to = append(received.to, strings.Trim(command[len("RCPT TO:"):], "<>"))
+				reply("250 ok")
+			case verb == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received.data = data.String()
+				reply("250 queued")
+			case verb == "QUIT":
+				reply("221 bye")
+				emails <- received
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, emails
+}
+
+func TestSMTP(t *testing.T) {
+	port, emails := fakeSMTPServer(t)
+
+	notifier := SMTP{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "alerts@example.com",
+		To:   []string{"ops@example.com", "oncall@example.com"},
+	}
+	notification := testNotification
+	notification.Message = "first line\nsecond line"
+	if err := notifier.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("error sending email: %s", err)
+	}
+
+	received := <-emails
+	if received.from != "alerts@example.com" {
+		t.Fatalf("unexpected sender %q", received.from)
+	}
+	if strings.Join(received.to, ",") != "ops@example.com,oncall@example.com" {
+		t.Fatalf("unexpected recipients %v", received.to)
+	}
+	for _, expected := range []string{
+		"Subject: Camera entrance is silent\r\n",
+		"To: ops@example.com, oncall@example.com\r\n",
+		"\r\n\r\nfirst line\r\nsecond line\r\n",
+	} {
+		if !strings.Contains(received.data, expected) {
+			t.Errorf("expected the email to contain %q, got %q", expected, received.data)
+		}
+	}
+}
+
+func TestSMTPWithoutRecipients(t *testing.T) {
+	if err := (SMTP{Host: "127.0.0.1", From: "alerts@example.com"}).Notify(context.Background(), testNotification); err == nil {
+		t.Fatal("expected an error without recipients")
+	}
+}
+
+func TestSMTPUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %s", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	notifier := SMTP{Host: "127.0.0.1", Port: port, From: "alerts@example.com", To: []string{"ops@example.com"}}
+	if err := notifier.Notify(context.Background(), testNotification); err == nil {
+		t.Fatal("expected an error connecting to a closed port")
+	}
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+)
+
+// Templated renders the title and message of notifications with templates before handing them to Notifier. The
+// templates are executed with the original Notification, so they can include its title and message.
+type Templated struct {
+	Notifier Notifier
+	// Title and Message keep the original text when nil
+	Title   *template.Template
+	Message *template.Template
+}
+
+func (t Templated) Notify(ctx context.Context, notification Notification) error {
+	rendered := notification
+	for _, field := range []struct {
+		template *template.Template
+		text     *string
+	}{{t.Title, &rendered.Title}, {t.Message, &rendered.Message}} {
+		if field.template == nil {
+			continue
+		}
+		var text bytes.Buffer
+		if err := field.template.Execute(&text, notification); err != nil {
+			return fmt.Errorf("error rendering template %s: %w", field.template.Name(), err)
+		}
+		*field.text = text.String()
+	}
+	return t.Notifier.Notify(ctx, rendered)
+}
@@ -0,0 +1,39 @@
+-- name: GetActivityRule :one
+select *
+from activity_rules
+where id = $1;
+
+-- name: GetActivityRules :many
+select *
+from activity_rules
+order by id;
+
+-- name: GetActivityRulesForCamera :many
+select *
+from activity_rules
+where camera_id = $1
+order by id;
+
+-- name: CreateActivityRule :one
+insert into activity_rules(camera_id, name, min_detections, window_minutes, weekdays, active_from, active_to, timezone,
+                           enabled)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+returning *;
+
+-- name: UpdateActivityRule :one
+update activity_rules
+set name           = coalesce(sqlc.narg('name'), name),
+    min_detections = coalesce(sqlc.narg('min_detections'), min_detections),
+    window_minutes = coalesce(sqlc.narg('window_minutes'), window_minutes),
+    weekdays       = coalesce(sqlc.narg('weekdays'), weekdays),
+    active_from    = coalesce(sqlc.narg('active_from'), active_from),
+    active_to      = coalesce(sqlc.narg('active_to'), active_to),
+    timezone       = coalesce(sqlc.narg('timezone'), timezone),
+    enabled        = coalesce(sqlc.narg('enabled'), enabled)
+where id = $1
+returning *;
+
+-- name: DeleteActivityRule :exec
+delete
+from activity_rules
+where id = $1;
@@ -0,0 +1,62 @@
+-- name: GetAlert :one
+select *
+from alerts
+where id = $1;
+
+-- name: GetAlerts :many
+select *
+from alerts
+where (sqlc.narg('state')::text is null or state = sqlc.narg('state'))
+  and (sqlc.narg('kind')::text is null or kind = sqlc.narg('kind'))
+  and (sqlc.narg('severity')::text is null or severity = sqlc.narg('severity'))
+  and (sqlc.narg('camera_id')::bigint is null or camera_id = sqlc.narg('camera_id'))
+  and (sqlc.narg('location_id')::bigint is null or location_id = sqlc.narg('location_id'))
+order by opened_at desc, id desc
+offset @alert_offset::int limit @count::int;
+
+-- name: GetUnresolvedActivityRuleAlert :one
+select *
+from alerts
+where activity_rule_id = $1
+  and state <> 'resolved';
+
+-- name: GetUnresolvedLocationAlert :one
+select *
+from alerts
+where location_id = $1
+  and kind = $2
+  and state <> 'resolved';
+
+-- name: CreateAlert :one
+insert into alerts(kind, severity, camera_id, location_id, activity_rule_id, message)
+values ($1, $2, $3, $4, $5, $6)
+returning *;
+
+-- name: AcknowledgeAlert :one
+update alerts
+set state           = 'acknowledged',
+    acknowledged_at = now(),
+    acknowledged_by = $2
+where id = $1
+  and state = 'open'
+returning *;
+
+-- name: UpdateAlertSeverity :one
+-- alerts escalated to critical are open again, so they must be acknowledged again
+update alerts
+set severity        = $2,
+    message         = $3,
+    state           = case when $2 = 'critical' then 'open' else state end,
+    acknowledged_at = case when $2 = 'critical' then null else acknowledged_at end,
+    acknowledged_by = case when $2 = 'critical' then null else acknowledged_by end
+where id = $1
+  and state <> 'resolved'
+returning *;
+
+-- name: ResolveAlert :one
+update alerts
+set state       = 'resolved',
+    resolved_at = now()
+where id = $1
+  and state <> 'resolved'
+returning *;
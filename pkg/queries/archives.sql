@@ -0,0 +1,54 @@
+-- name: CreateDetectionArchive :one
+insert into detection_archives (key, format, range_start, range_end, row_count, sha256)
+values ($1, $2, $3, $4, $5, $6)
+returning *;
+
+-- name: GetDaysWithUnarchivedDetections :many
+-- the utc days before sqlc.arg('before') with detections that no archive holds, with how many archives they have
+select days.day::timestamptz                                                         as day,
+       (select count(*) from detection_archives where range_start = days.day)::bigint as archives
+from (select distinct date_trunc('day', detection_date at time zone 'UTC') at time zone 'UTC' as day
+      from person_detections
+      where archive_id is null
+        and detection_date < sqlc.arg('before')) as days
+order by days.day;
+
+-- name: GetPersonDetectionsToArchive :many
+-- the detections are locked until they are marked as archived, so they can't change in the meantime
+select *
+from person_detections
+where (detection_date, id) > (sqlc.arg('after_date')::timestamptz, sqlc.arg('after_id')::bigint)
+  and detection_date < sqlc.arg('to_date')
+  and archive_id is null
+order by detection_date, id
+limit sqlc.arg('batch_size')::int
+for update;
+
+-- name: MarkPersonDetectionsArchived :execrows
+update person_detections
+set archive_id = sqlc.arg('archive_id')::bigint
+where id = any (sqlc.arg('ids')::bigint[])
+  and detection_date >= sqlc.arg('from_date')
+  and detection_date < sqlc.arg('to_date');
+
+-- name: ImportPersonDetection :execrows
+-- ids only come from person_detections_id_seq, but the primary key includes detection_date so it does not keep an
+-- imported detection from taking the id of another one. Detections whose id is stored already are skipped
+insert into person_detections (id, camera_id, detection_date, target_direction, flagged, track_id, confidence, bbox_x,
+                               bbox_y, bbox_width, bbox_height, frame_date, model_version, excluded)
+select sqlc.arg('id')::bigint, sqlc.arg('camera_id')::bigint, sqlc.arg('detection_date')::timestamptz,
+       sqlc.arg('target_direction')::direction, sqlc.arg('flagged')::boolean, sqlc.narg('track_id')::text,
+       sqlc.narg('confidence')::double precision, sqlc.narg('bbox_x')::double precision,
+       sqlc.narg('bbox_y')::double precision, sqlc.narg('bbox_width')::double precision,
+       sqlc.narg('bbox_height')::double precision, sqlc.narg('frame_date')::timestamptz,
+       sqlc.narg('model_version')::text, sqlc.arg('excluded')::boolean
+where not exists(select 1 from person_detections where id = sqlc.arg('id')::bigint)
+on conflict do nothing;
+
+-- name: RestorePersonDetectionNormalizedDirection :exec
+-- the normalized direction is derived from the current camera when a detection is stored, imported detections get
+-- back the one they had when they were archived. Updating it alone does not touch the rollups
+update person_detections
+set normalized_direction = $3
+where id = $1
+  and detection_date = $2;
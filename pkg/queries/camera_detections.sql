@@ -0,0 +1,9 @@
+-- name: DeleteCameraDetectionsForCamera :execrows
+delete
+from camera_detections
+where camera_id = $1;
+
+-- name: ReassignCameraDetections :execrows
+update camera_detections
+set camera_id = sqlc.arg('to_camera_id')
+where camera_id = sqlc.arg('from_camera_id');
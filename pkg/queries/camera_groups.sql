@@ -0,0 +1,53 @@
+-- name: GetCameraGroup :one
+select *
+from camera_groups
+where id = $1;
+
+-- name: GetCameraGroupByName :one
+select *
+from camera_groups
+where name = $1;
+
+-- name: GetCameraGroups :many
+select *
+from camera_groups
+order by id;
+
+-- name: CreateCameraGroup :one
+insert into camera_groups(name, description)
+values ($1, $2)
+returning *;
+
+-- name: UpdateCameraGroup :one
+update camera_groups
+set name        = coalesce(sqlc.narg('name'), name),
+    description = coalesce(sqlc.narg('description'), description)
+where id = $1
+returning *;
+
+-- name: DeleteCameraGroup :exec
+delete
+from camera_groups
+where id = $1;
+
+-- name: GetCameraGroupMembers :many
+select *
+from camera_group_members
+order by group_id, camera_id;
+
+-- name: GetCameraGroupCameraIds :many
+select camera_id
+from camera_group_members
+where group_id = $1
+order by camera_id;
+
+-- name: AddCameraGroupMember :exec
+insert into camera_group_members(group_id, camera_id)
+values ($1, $2)
+on conflict do nothing;
+
+-- name: RemoveCameraGroupMember :exec
+delete
+from camera_group_members
+where group_id = $1
+  and camera_id = $2;
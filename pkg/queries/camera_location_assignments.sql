@@ -0,0 +1,14 @@
+-- name: GetCameraLocationAssignments :many
+-- returns the locations the camera has been in, the first one starting at -infinity and the current one without an
+-- end
+select *
+from camera_location_assignments
+where camera_id = $1
+order by valid_from, id;
+
+-- name: DeleteCameraLocationAssignmentsForLocation :execrows
+-- forgets that cameras were ever in the location, their detections of that time are no longer counted towards any
+-- location
+delete
+from camera_location_assignments
+where location_id = $1;
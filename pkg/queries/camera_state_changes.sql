@@ -0,0 +1,10 @@
+-- name: GetCameraStateChanges :many
+select *
+from camera_state_changes
+where camera_id = $1
+order by changed_at, id;
+
+-- name: CreateCameraStateChange :one
+insert into camera_state_changes(camera_id, from_state, to_state, reason, changed_at)
+values ($1, $2, $3, $4, $5)
+returning *;
@@ -0,0 +1,26 @@
+-- name: GetCameraStatus :one
+select *
+from camera_statuses
+where camera_id = $1;
+
+-- name: GetCameraStatuses :many
+select *
+from camera_statuses
+order by camera_id;
+
+-- name: UpsertCameraStatus :one
+insert into camera_statuses (camera_id, status, latency_ms, checked_at, last_seen_at, error, consecutive_failures)
+values ($1, $2, $3, $4,
+        case when $2 = 'online' then $4::timestamptz end,
+        $5,
+        case when $2 = 'online' then 0 else 1 end)
+on conflict (camera_id) do update
+    set status               = excluded.status,
+        latency_ms           = excluded.latency_ms,
+        checked_at           = excluded.checked_at,
+        last_seen_at         = coalesce(excluded.last_seen_at, camera_statuses.last_seen_at),
+        error                = excluded.error,
+        consecutive_failures = case
+                                   when excluded.status = 'online' then 0
+                                   else camera_statuses.consecutive_failures + 1 end
+returning *;
@@ -6,24 +6,54 @@ where id = $1;
 -- name: GetCameras :many
 select *
 from cameras
+where sqlc.arg('include_deleted')::bool
+   or deleted_at is null
 order by id;
 
 -- name: CreateCamera :one
-insert into cameras(name, connection_string, location_text, location_id, orientation)
-values ($1, $2, $3, $4, $5)
+insert into cameras(name, connection_string, location_id, orientation, mount_description, floor_x, floor_y, heading,
+                    mounting_height, field_of_view, tags, entry_direction)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, coalesce(sqlc.narg('entry_direction')::direction, 'none'))
 returning *;
 
 -- name: UpdateCamera :one
 update cameras
 set name              = coalesce(sqlc.narg('name'), name),
     connection_string = coalesce(sqlc.narg('connection_string'), connection_string),
-    location_text     = coalesce(sqlc.narg('location_text'), location_text),
     location_id       = coalesce(sqlc.narg('location_id'), location_id),
-    orientation       = coalesce(sqlc.narg('orientation'), orientation)
+    orientation       = coalesce(sqlc.narg('orientation'), orientation),
+    entry_direction   = coalesce(sqlc.narg('entry_direction'), entry_direction),
+    mount_description = coalesce(sqlc.narg('mount_description'), mount_description),
+    floor_x           = coalesce(sqlc.narg('floor_x'), floor_x),
+    floor_y           = coalesce(sqlc.narg('floor_y'), floor_y),
+    heading           = coalesce(sqlc.narg('heading'), heading),
+    mounting_height   = coalesce(sqlc.narg('mounting_height'), mounting_height),
+    field_of_view     = coalesce(sqlc.narg('field_of_view'), field_of_view),
+    tags              = coalesce(sqlc.narg('tags'), tags)
 where id = $1
 returning *;
 
 -- name: DeleteCamera :exec
 delete
 from cameras
-where id = $1;
\ No newline at end of file
+where id = $1;
+
+-- name: SoftDeleteCamera :one
+-- hides the camera, deleting it again keeps the date it was first deleted at
+update cameras
+set deleted_at = coalesce(deleted_at, now())
+where id = $1
+returning *;
+
+-- name: SetCameraState :one
+update cameras
+set state            = $2,
+    state_changed_at = now()
+where id = $1
+returning *;
+
+-- name: RestoreCamera :one
+update cameras
+set deleted_at = null
+where id = $1
+returning *;
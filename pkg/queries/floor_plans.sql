@@ -0,0 +1,32 @@
+-- name: GetFloorPlan :one
+select *
+from floor_plans
+where location_id = $1;
+
+-- name: SetFloorPlan :one
+-- stores the floor plan of a location, replacing the previous one
+insert into floor_plans (location_id, image_key, content_type, image_width, image_height, width, height, unit)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+on conflict (location_id) do update set image_key    = excluded.image_key,
+                                        content_type = excluded.content_type,
+                                        image_width  = excluded.image_width,
+                                        image_height = excluded.image_height,
+                                        width        = excluded.width,
+                                        height       = excluded.height,
+                                        unit         = excluded.unit,
+                                        updated_at   = now()
+returning *;
+
+-- name: UpdateFloorPlan :one
+update floor_plans
+set width      = coalesce(sqlc.narg('width'), width),
+    height     = coalesce(sqlc.narg('height'), height),
+    unit       = coalesce(sqlc.narg('unit'), unit),
+    updated_at = now()
+where location_id = $1
+returning *;
+
+-- name: DeleteFloorPlan :exec
+delete
+from floor_plans
+where location_id = $1;
@@ -0,0 +1,37 @@
+-- name: GetIdempotentPersonDetection :one
+-- returns the detection created for the camera with the key, unless the key expired or the detection is gone
+select person_detections.*
+from person_detection_idempotency_keys
+         join person_detections on person_detections.id = person_detection_idempotency_keys.person_detection_id and
+                                   person_detections.detection_date = person_detection_idempotency_keys.detection_date
+where person_detection_idempotency_keys.camera_id = $1
+  and person_detection_idempotency_keys.key = $2
+  and person_detection_idempotency_keys.created_at >= sqlc.arg('cutoff');
+
+-- name: ClaimPersonDetectionIdempotencyKey :execrows
+-- no rows are affected when the key is stored already, after waiting for the transaction that claimed it to finish
+insert into person_detection_idempotency_keys (camera_id, key)
+values ($1, $2)
+on conflict (camera_id, key) do nothing;
+
+-- name: LockPersonDetectionIdempotencyKey :execrows
+-- keeps other transactions from taking over the key until this one finishes, no rows are affected when it is gone
+select 1
+from person_detection_idempotency_keys
+where camera_id = $1
+  and key = $2
+for update;
+
+-- name: SetPersonDetectionIdempotencyKeyDetection :exec
+update person_detection_idempotency_keys
+set person_detection_id = $3,
+    detection_date      = $4,
+    created_at          = now()
+where camera_id = $1
+  and key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+delete
+from person_detection_idempotency_keys
+where camera_id = $1
+  and created_at < sqlc.arg('cutoff');
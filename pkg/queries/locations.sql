@@ -6,21 +6,100 @@ where id = $1;
 -- name: GetLocations :many
 select *
 from locations
+where sqlc.arg('include_deleted')::bool
+   or deleted_at is null
 order by id;
 
+-- name: GetLocationChildren :many
+select *
+from locations
+where parent_id = sqlc.arg('parent_id')::bigint
+order by id;
+
+-- name: GetLocationSubtreeIds :many
+-- returns the id of the location followed by the ids of all of its descendants, nothing if the location does not
+-- exist
+with recursive subtree(id, depth) as (select locations.id, 0
+                                      from locations
+                                      where locations.id = $1
+                                      union all
+                                      select locations.id, subtree.depth + 1
+                                      from locations
+                                               join subtree on locations.parent_id = subtree.id)
+select id
+from subtree
+order by depth, id;
+
 -- name: CreateLocation :one
-insert into locations (name, description)
-values ($1, $2)
+insert into locations (name, description, capacity, warning_threshold, parent_id)
+values ($1, $2, $3, $4, $5)
 returning *;
 
 -- name: UpdateLocation :one
+-- a parent_id of 0 moves the location to the top of the hierarchy
 update locations
-set name       = coalesce(sqlc.narg('name'), name),
-    description= coalesce(sqlc.narg('description'), name)
+set name              = coalesce(sqlc.narg('name'), name),
+    description       = coalesce(sqlc.narg('description'), description),
+    capacity          = coalesce(sqlc.narg('capacity'), capacity),
+    warning_threshold = coalesce(sqlc.narg('warning_threshold'), warning_threshold),
+    parent_id         = case
+                            when sqlc.narg('parent_id')::bigint = 0 then null
+                            else coalesce(sqlc.narg('parent_id'), parent_id) end
 where id = $1
 returning *;
 
 -- name: DeleteLocation :exec
 delete
 from locations
-where id = $1;
\ No newline at end of file
+where id = $1;
+
+-- name: SoftDeleteLocation :one
+-- hides the location, deleting it again keeps the date it was first deleted at
+update locations
+set deleted_at = coalesce(deleted_at, now())
+where id = $1
+returning *;
+
+-- name: RestoreLocation :one
+update locations
+set deleted_at = null
+where id = $1
+returning *;
+
+-- name: GetLocationOccupancies :many
+-- counts the people who entered and left every location since the given date, through the cameras with an entry
+-- direction when the detections were made, from their normalized direction. Detections count towards the location
+-- their camera was in when they were made
+select camera_location_at(camera_id, detection_date)::int as location_id,
+       count(*) filter (where normalized_direction = 'in')  as entries,
+       count(*) filter (where normalized_direction = 'out') as exits
+from person_detections
+where normalized_direction <> 'none'
+  and not excluded
+  and detection_date >= sqlc.arg('since')
+  and (sqlc.narg('location_id')::int is null or camera_location_at(camera_id, detection_date) = sqlc.narg('location_id'))
+group by 1
+order by 1;
+
+-- name: GetLocationCameraCounts :many
+-- counts the detections of every camera of a location since the given date, and for the cameras with an entry
+-- direction the people who entered and left the location through them. Cameras that were moved out of the location
+-- since the given date are included with the detections they made while in it
+select cameras.id                                                                                as camera_id,
+       count(person_detections.id)                                                               as detections,
+       count(person_detections.id) filter (where person_detections.normalized_direction = 'in')  as entries,
+       count(person_detections.id) filter (where person_detections.normalized_direction = 'out') as exits
+from cameras
+         left join person_detections on person_detections.camera_id = cameras.id and
+                                        person_detections.detection_date >= sqlc.arg('since') and
+                                        not person_detections.excluded and
+                                        camera_location_at(cameras.id, person_detections.detection_date) =
+                                        sqlc.arg('location_id')
+where exists(select
+             from camera_location_assignments
+             where camera_location_assignments.camera_id = cameras.id
+               and camera_location_assignments.location_id = sqlc.arg('location_id')
+               and (camera_location_assignments.valid_to is null or
+                    camera_location_assignments.valid_to > sqlc.arg('since')))
+group by cameras.id
+order by cameras.id;
@@ -0,0 +1,47 @@
+-- name: GetMaintenanceWindow :one
+select *
+from maintenance_windows
+where id = $1;
+
+-- name: GetMaintenanceWindowsForCamera :many
+select *
+from maintenance_windows
+where camera_id = $1
+order by starts_at, id;
+
+-- name: GetMaintenanceWindowAt :one
+-- the window of a camera in progress at the given date, windows rejecting detections take precedence
+select *
+from maintenance_windows
+where camera_id = $1
+  and starts_at <= sqlc.arg('at')
+  and ends_at > sqlc.arg('at')
+order by ingest = 'reject' desc, starts_at
+limit 1;
+
+-- name: GetCamerasInMaintenance :many
+-- the cameras with a maintenance window overlapping the given range
+select distinct camera_id
+from maintenance_windows
+where starts_at < sqlc.arg('to_date')
+  and ends_at > sqlc.arg('from_date')
+order by camera_id;
+
+-- name: CreateMaintenanceWindow :one
+insert into maintenance_windows(camera_id, starts_at, ends_at, ingest, description)
+values ($1, $2, $3, $4, $5)
+returning *;
+
+-- name: UpdateMaintenanceWindow :one
+update maintenance_windows
+set starts_at   = coalesce(sqlc.narg('starts_at'), starts_at),
+    ends_at     = coalesce(sqlc.narg('ends_at'), ends_at),
+    ingest      = coalesce(sqlc.narg('ingest'), ingest),
+    description = coalesce(sqlc.narg('description'), description)
+where id = $1
+returning *;
+
+-- name: DeleteMaintenanceWindow :exec
+delete
+from maintenance_windows
+where id = $1;
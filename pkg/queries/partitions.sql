@@ -0,0 +1,10 @@
+-- name: CreatePersonDetectionsPartition :exec
+select create_person_detections_partition(sqlc.arg('month')::date);
+
+-- name: GetPersonDetectionsPartitions :many
+select *
+from person_detection_partitions
+order by range_start;
+
+-- name: DropPersonDetectionsPartition :exec
+select drop_person_detections_partition(sqlc.arg('name')::text);
@@ -3,29 +3,52 @@ select *
 from person_detections
 where id = $1;
 
+-- name: GetLatestPersonDetection :one
+select *
+from person_detections
+order by id desc
+limit 1;
+
 -- name: GetPersonDetections :many
 select *
 from person_detections
-order by detection_date desc
+where (sqlc.narg('camera_ids')::bigint[] is null or camera_id = any (sqlc.narg('camera_ids')))
+  and (sqlc.narg('track_id')::text is null or track_id = sqlc.narg('track_id'))
+  and (sqlc.narg('min_confidence')::float8 is null or confidence >= sqlc.narg('min_confidence'))
+  and (sqlc.narg('model_version')::text is null or model_version = sqlc.narg('model_version'))
+  and (sqlc.narg('excluded')::boolean is null or excluded = sqlc.narg('excluded'))
+  and (sqlc.narg('after_id')::bigint is null or id > sqlc.narg('after_id'))
+order by case when sqlc.narg('after_id')::bigint is null then detection_date end desc,
+         case when sqlc.narg('after_id')::bigint is not null then id end
 offset @detection_offset::int limit @count::int;
 
 -- name: GetPersonDetectionsForCamera :many
 select *
 from person_detections
 where camera_id = $1
-order by detection_date desc
+  and (sqlc.narg('track_id')::text is null or track_id = sqlc.narg('track_id'))
+  and (sqlc.narg('min_confidence')::float8 is null or confidence >= sqlc.narg('min_confidence'))
+  and (sqlc.narg('model_version')::text is null or model_version = sqlc.narg('model_version'))
+  and (sqlc.narg('excluded')::boolean is null or excluded = sqlc.narg('excluded'))
+  and (sqlc.narg('after_id')::bigint is null or id > sqlc.narg('after_id'))
+order by case when sqlc.narg('after_id')::bigint is null then detection_date end desc,
+         case when sqlc.narg('after_id')::bigint is not null then id end
 offset @detection_offset::int limit @count::int;
 
 -- name: CreatePersonDetection :one
-insert into person_detections(camera_id, detection_date, target_direction)
-values ($1, $2, $3)
+insert into person_detections(camera_id, detection_date, target_direction, flagged, track_id, confidence, bbox_x, bbox_y,
+                              bbox_width, bbox_height, frame_date, model_version, excluded)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 returning *;
 
 -- name: UpdatePersonDetection :one
+-- the minimum confidence is applied again, detections are never included back as they may have been excluded when
+-- they were reported
 update person_detections
 set camera_id        = coalesce(sqlc.narg('camera_id'), camera_id),
     detection_date   = coalesce(sqlc.narg('detection_date'), detection_date),
-    target_direction = coalesce(sqlc.narg('target_direction'), target_direction)
+    target_direction = coalesce(sqlc.narg('target_direction'), target_direction),
+    excluded         = excluded or coalesce(confidence < sqlc.arg('min_confidence')::float8, false)
 where id = $1
 returning *;
 
@@ -34,16 +57,132 @@ delete
 from person_detections
 where id = $1;
 
+-- name: DeletePersonDetectionsForCamera :execrows
+delete
+from person_detections
+where camera_id = $1;
+
+-- name: ReassignPersonDetections :execrows
+-- moves the detections of a camera to another one, the rollups follow through their trigger
+update person_detections
+set camera_id = sqlc.arg('to_camera_id')
+where camera_id = sqlc.arg('from_camera_id');
+
 -- name: GetDailyPersonDetectionsCount :many
-with detection_dates as (select detection_date::date
-                         from person_detections
-                         where camera_id = $1)
-select date_series.date::date as date,
-       count(detection_dates.detection_date) as count
+with daily_counts as (select bucket,
+                             sum(count)                                             as count,
+                             sum(count) filter (where normalized_direction = 'in')  as entries,
+                             sum(count) filter (where normalized_direction = 'out') as exits
+                      from person_detection_daily_counts
+                      where camera_id = $1
+                      group by bucket)
+select date_series.date::date                   as date,
+       coalesce(daily_counts.count, 0)::bigint   as count,
+       coalesce(daily_counts.entries, 0)::bigint as entries,
+       coalesce(daily_counts.exits, 0)::bigint   as exits
 from (select(current_date - b.offs) as date
       from (select generate_series(0, current_date - (current_date - sqlc.arg('interval')::interval)::date,
                                    1) as offs) as b) as date_series
-         left outer join detection_dates
-                         on (date_series.date::date = detection_date)
-group by date_series.date
-order by date_series.date;
\ No newline at end of file
+         left outer join daily_counts
+                         on (date_series.date::date = daily_counts.bucket)
+order by date_series.date;
+
+-- name: GetLocationDailyPersonDetectionsCount :many
+-- counts the detections made in the given locations per day, like GetDailyPersonDetectionsCount. A camera counts
+-- towards the location it was in at the start of each day, except in the days it was moved in, which are counted
+-- from the detections that are still stored
+with moves as (select distinct camera_id, valid_from::date as bucket
+               from camera_location_assignments
+               where valid_from > '-infinity'
+                 and valid_from <> valid_from::date::timestamptz
+                 and valid_from::date >= (current_date - sqlc.arg('interval')::interval)::date),
+     daily_counts as (select counts.bucket,
+                             sum(counts.count)                                                    as count,
+                             sum(counts.count) filter (where counts.normalized_direction = 'in')  as entries,
+                             sum(counts.count) filter (where counts.normalized_direction = 'out') as exits
+                      from (select daily.bucket, daily.normalized_direction, daily.count
+                            from person_detection_daily_counts as daily
+                            where camera_location_at(daily.camera_id, daily.bucket::timestamptz) =
+                                  any (sqlc.arg('location_ids')::bigint[])
+                              and not exists(select 1
+                                             from moves
+                                             where moves.camera_id = daily.camera_id
+                                               and moves.bucket = daily.bucket)
+                            union all
+                            select moves.bucket, person_detections.normalized_direction, 1
+                            from moves
+                                     join person_detections
+                                          on person_detections.camera_id = moves.camera_id and
+                                             person_detections.detection_date >= moves.bucket::timestamptz and
+                                             person_detections.detection_date < (moves.bucket + 1)::timestamptz
+                            where camera_location_at(person_detections.camera_id, person_detections.detection_date) =
+                                  any (sqlc.arg('location_ids')::bigint[])
+                              and not person_detections.excluded) as counts
+                      group by counts.bucket)
+select date_series.date::date                   as date,
+       coalesce(daily_counts.count, 0)::bigint   as count,
+       coalesce(daily_counts.entries, 0)::bigint as entries,
+       coalesce(daily_counts.exits, 0)::bigint   as exits
+from (select(current_date - b.offs) as date
+      from (select generate_series(0, current_date - (current_date - sqlc.arg('interval')::interval)::date,
+                                   1) as offs) as b) as date_series
+         left outer join daily_counts
+                         on (date_series.date::date = daily_counts.bucket)
+order by date_series.date;
+
+-- name: GetCamerasDailyPersonDetectionsCount :many
+-- counts the detections of the given cameras per day, like GetDailyPersonDetectionsCount
+with daily_counts as (select bucket,
+                             sum(count)                                             as count,
+                             sum(count) filter (where normalized_direction = 'in')  as entries,
+                             sum(count) filter (where normalized_direction = 'out') as exits
+                      from person_detection_daily_counts
+                      where camera_id = any (sqlc.arg('camera_ids')::bigint[])
+                      group by bucket)
+select date_series.date::date                   as date,
+       coalesce(daily_counts.count, 0)::bigint   as count,
+       coalesce(daily_counts.entries, 0)::bigint as entries,
+       coalesce(daily_counts.exits, 0)::bigint   as exits
+from (select(current_date - b.offs) as date
+      from (select generate_series(0, current_date - (current_date - sqlc.arg('interval')::interval)::date,
+                                   1) as offs) as b) as date_series
+         left outer join daily_counts
+                         on (date_series.date::date = daily_counts.bucket)
+order by date_series.date;
+
+-- name: ExportPersonDetections :many
+select person_detections.id,
+       person_detections.camera_id,
+       cameras.name   as camera_name,
+       locations.id   as location_id,
+       locations.name as location_name,
+       person_detections.detection_date,
+       person_detections.target_direction,
+       person_detections.normalized_direction,
+       person_detections.flagged,
+       person_detections.excluded,
+       person_detections.track_id,
+       person_detections.confidence,
+       person_detections.bbox_x,
+       person_detections.bbox_y,
+       person_detections.bbox_width,
+       person_detections.bbox_height,
+       person_detections.frame_date,
+       person_detections.model_version
+from person_detections
+         join cameras on cameras.id = person_detections.camera_id
+         left join locations on locations.id = camera_location_at(cameras.id, person_detections.detection_date)
+where (sqlc.narg('from_date')::timestamptz is null or person_detections.detection_date >= sqlc.narg('from_date'))
+  and (sqlc.narg('to_date')::timestamptz is null or person_detections.detection_date < sqlc.narg('to_date'))
+  and (sqlc.narg('camera_id')::bigint is null or person_detections.camera_id = sqlc.narg('camera_id'))
+  and (sqlc.narg('location_ids')::bigint[] is null or locations.id = any (sqlc.narg('location_ids')))
+  and (sqlc.narg('camera_ids')::bigint[] is null or person_detections.camera_id = any (sqlc.narg('camera_ids')))
+order by person_detections.detection_date, person_detections.id;
+
+-- name: CountPersonDetectionsForCamera :one
+select count(*)
+from person_detections
+where camera_id = $1
+  and detection_date >= sqlc.arg('from_date')
+  and detection_date < sqlc.arg('to_date')
+  and not excluded;
@@ -0,0 +1,54 @@
+-- name: CountExpiredPersonDetections :one
+select count(*)
+from person_detections
+where camera_id = $1
+  and detection_date < sqlc.arg('cutoff');
+
+-- name: DeleteExpiredPersonDetections :execrows
+delete
+from person_detections
+where id in (select expired.id
+             from person_detections as expired
+             where expired.camera_id = $1
+               and expired.detection_date < sqlc.arg('cutoff')
+             limit sqlc.arg('batch_size')::int);
+
+-- name: CountExpiredArchivedPersonDetections :one
+select count(*)
+from person_detections
+where camera_id = $1
+  and detection_date < sqlc.arg('cutoff')
+  and archive_id is not null;
+
+-- name: DeleteExpiredArchivedPersonDetections :execrows
+-- like DeleteExpiredPersonDetections, but only deletes the detections that an archive holds
+delete
+from person_detections
+where id in (select expired.id
+             from person_detections as expired
+             where expired.camera_id = $1
+               and expired.detection_date < sqlc.arg('cutoff')
+               and expired.archive_id is not null
+             limit sqlc.arg('batch_size')::int);
+
+-- name: CountUnarchivedPersonDetections :one
+select count(*)
+from person_detections
+where detection_date >= sqlc.arg('from_date')
+  and detection_date < sqlc.arg('to_date')
+  and archive_id is null;
+
+-- name: CountExpiredCameraDetections :one
+select count(*)
+from camera_detections
+where camera_id = $1
+  and detection_date < sqlc.arg('cutoff');
+
+-- name: DeleteExpiredCameraDetections :execrows
+delete
+from camera_detections
+where id in (select expired.id
+             from camera_detections as expired
+             where expired.camera_id = $1
+               and expired.detection_date < sqlc.arg('cutoff')
+             limit sqlc.arg('batch_size')::int);
@@ -0,0 +1,119 @@
+-- name: GetHourlyPersonDetectionsCount :many
+select bucket, target_direction, normalized_direction, sum(count)::bigint as count
+from person_detection_hourly_counts
+where camera_id = $1
+  and bucket >= sqlc.arg('from_date')
+  and bucket < sqlc.arg('to_date')
+group by bucket, target_direction, normalized_direction
+having sum(count) > 0
+order by bucket, target_direction, normalized_direction;
+
+-- name: GetHourlyPersonDetectionsCountRaw :many
+select date_trunc('hour', detection_date)::timestamptz as bucket, target_direction, normalized_direction, count(*) as count
+from person_detections
+where camera_id = $1
+  and detection_date >= sqlc.arg('from_date')
+  and detection_date < sqlc.arg('to_date')
+  and not excluded
+group by 1, 2, 3
+order by 1, 2, 3;
+
+-- name: GetLocationHourlyPersonDetectionsCount :many
+-- counts the detections made in the given locations per hour, like GetHourlyPersonDetectionsCount. A camera counts
+-- towards the location it was in at the start of each hour, except in the hours it was moved in, which are counted
+-- from the detections that are still stored
+with moves as (select distinct camera_id, date_trunc('hour', valid_from) as bucket
+               from camera_location_assignments
+               where valid_from <> date_trunc('hour', valid_from)
+                 and date_trunc('hour', valid_from) >= sqlc.arg('from_date')
+                 and date_trunc('hour', valid_from) < sqlc.arg('to_date'))
+select counts.bucket, counts.target_direction, counts.normalized_direction, sum(counts.count)::bigint as count
+from (select hourly.bucket, hourly.target_direction, hourly.normalized_direction, hourly.count
+      from person_detection_hourly_counts as hourly
+      where camera_location_at(hourly.camera_id, hourly.bucket) = any (sqlc.arg('location_ids')::bigint[])
+        and hourly.bucket >= sqlc.arg('from_date')
+        and hourly.bucket < sqlc.arg('to_date')
+        and not exists(select 1 from moves where moves.camera_id = hourly.camera_id and moves.bucket = hourly.bucket)
+      union all
+      select moves.bucket, person_detections.target_direction, person_detections.normalized_direction, 1
+      from moves
+               join person_detections on person_detections.camera_id = moves.camera_id and
+                                         person_detections.detection_date >= moves.bucket and
+                                         person_detections.detection_date < moves.bucket + interval '1 hour'
+      where camera_location_at(person_detections.camera_id, person_detections.detection_date) =
+            any (sqlc.arg('location_ids')::bigint[])
+        and not person_detections.excluded) as counts
+group by counts.bucket, counts.target_direction, counts.normalized_direction
+having sum(counts.count) > 0
+order by counts.bucket, counts.target_direction, counts.normalized_direction;
+
+-- name: GetLocationHourlyPersonDetectionsCountRaw :many
+select date_trunc('hour', detection_date)::timestamptz as bucket, target_direction, normalized_direction, count(*) as count
+from person_detections
+where camera_location_at(camera_id, detection_date) = any (sqlc.arg('location_ids')::bigint[])
+  and detection_date >= sqlc.arg('from_date')
+  and detection_date < sqlc.arg('to_date')
+  and not excluded
+group by 1, 2, 3
+order by 1, 2, 3;
+
+-- name: GetCamerasHourlyPersonDetectionsCount :many
+-- counts the detections of the given cameras per hour, like GetHourlyPersonDetectionsCount
+select bucket, target_direction, normalized_direction, sum(count)::bigint as count
+from person_detection_hourly_counts
+where camera_id = any (sqlc.arg('camera_ids')::bigint[])
+  and bucket >= sqlc.arg('from_date')
+  and bucket < sqlc.arg('to_date')
+group by bucket, target_direction, normalized_direction
+having sum(count) > 0
+order by bucket, target_direction, normalized_direction;
+
+-- name: GetCamerasHourlyPersonDetectionsCountRaw :many
+select date_trunc('hour', detection_date)::timestamptz as bucket, target_direction, normalized_direction, count(*) as count
+from person_detections
+where camera_id = any (sqlc.arg('camera_ids')::bigint[])
+  and detection_date >= sqlc.arg('from_date')
+  and detection_date < sqlc.arg('to_date')
+  and not excluded
+group by 1, 2, 3
+order by 1, 2, 3;
+
+-- name: SkipRollupMaintenance :exec
+select set_config('camera_service.skip_rollups', 'on', true);
+
+-- name: GetRollupBackfillDays :one
+-- returns the day of the oldest detection, the first day the given retention can't have pruned detections of yet and
+-- tomorrow. Days are taken in the session time zone like the rollup triggers and BackfillDailyRollups do
+select (select min(detection_date) from person_detections)::date as oldest_day,
+       ((now() - sqlc.arg('retention')::interval)::date + 1)::date as first_retained_day,
+       (current_date + 1)::date as tomorrow;
+
+-- name: DeleteHourlyRollups :exec
+delete
+from person_detection_hourly_counts
+where bucket >= sqlc.arg('from_date')::date
+  and bucket < sqlc.arg('to_date')::date;
+
+-- name: DeleteDailyRollups :exec
+delete
+from person_detection_daily_counts
+where bucket >= sqlc.arg('from_date')::date
+  and bucket < sqlc.arg('to_date')::date;
+
+-- name: BackfillHourlyRollups :execrows
+insert into person_detection_hourly_counts (camera_id, bucket, target_direction, normalized_direction, count)
+select camera_id, date_trunc('hour', detection_date), target_direction, normalized_direction, count(*)
+from person_detections
+where detection_date >= sqlc.arg('from_date')::date
+  and detection_date < sqlc.arg('to_date')::date
+  and not excluded
+group by 1, 2, 3, 4;
+
+-- name: BackfillDailyRollups :execrows
+insert into person_detection_daily_counts (camera_id, bucket, target_direction, normalized_direction, count)
+select camera_id, detection_date::date, target_direction, normalized_direction, count(*)
+from person_detections
+where detection_date >= sqlc.arg('from_date')::date
+  and detection_date < sqlc.arg('to_date')::date
+  and not excluded
+group by 1, 2, 3, 4;
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// the functions below build the errors postgres reports for the constraints of the schema, so that code handling
+// *pgconn.PgError behaves the same with every Store
+
+func notNullViolation(table string, column string) *pgconn.PgError {
+	return &pgconn.PgError{
+		Severity:   "ERROR",
+		Code:       "23502",
+		Message:    fmt.Sprintf("null value in column \"%s\" of relation \"%s\" violates not-null constraint", column, table),
+		TableName:  table,
+		ColumnName: column,
+	}
+}
+
+func foreignKeyViolation(table string, column string, value any, referencedTable string) *pgconn.PgError {
+	constraint := fmt.Sprintf("%s_%s_fkey", table, column)
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23503",
+		Message:        fmt.Sprintf("insert or update on table \"%s\" violates foreign key constraint \"%s\"", table, constraint),
+		Detail:         fmt.Sprintf("Key (%s)=(%v) is not present in table \"%s\".", column, value, referencedTable),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func stillReferencedViolation(table string, id int64, referencingTable string, referencingColumn string) *pgconn.PgError {
+	constraint := fmt.Sprintf("%s_%s_fkey", referencingTable, referencingColumn)
+	return &pgconn.PgError{
+		Severity: "ERROR",
+		Code:     "23503",
+		Message: fmt.Sprintf("update or delete on table \"%s\" violates foreign key constraint \"%s\" on table \"%s\"",
+			table, constraint, referencingTable),
+		Detail:         fmt.Sprintf("Key (id)=(%d) is still referenced from table \"%s\".", id, referencingTable),
+		TableName:      referencingTable,
+		ConstraintName: constraint,
+	}
+}
+
+func checkViolation(table string, constraint string) *pgconn.PgError {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23514",
+		Message:        fmt.Sprintf("new row for relation \"%s\" violates check constraint \"%s\"", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func invalidEnumValue(enum string, value string) *pgconn.PgError {
+	return &pgconn.PgError{
+		Severity: "ERROR",
+		Code:     "22P02",
+		Message:  fmt.Sprintf("invalid input value for enum %s: \"%s\"", enum, value),
+	}
+}
+
+func negativeLimit() *pgconn.PgError {
+	return &pgconn.PgError{Severity: "ERROR", Code: "2201W", Message: "LIMIT must not be negative"}
+}
+
+func negativeOffset() *pgconn.PgError {
+	return &pgconn.PgError{Severity: "ERROR", Code: "2201X", Message: "OFFSET must not be negative"}
+}
+
+func uniqueViolation(table string, constraint string, column string, value any) *pgconn.PgError {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint \"%s\"", constraint),
+		Detail:         fmt.Sprintf("Key (%s)=(%v) already exists.", column, value),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
@@ -0,0 +1,1353 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a Store that keeps everything in memory, following the same semantics as the postgres schema. It is
+// meant for tests and for running the service without a database.
+type Memory struct {
+	mutex sync.RWMutex
+	// txMutex serializes transactions, as they are implemented by restoring a snapshot
+	txMutex sync.Mutex
+
+	locations        map[int64]dbschema.Location
+	cameras          map[int64]dbschema.Camera
+	personDetections map[int64]dbschema.PersonDetection
+	cameraStatuses   map[int64]dbschema.CameraStatus
+	activityRules    map[int64]dbschema.ActivityRule
+	alerts           map[int64]dbschema.Alert
+	floorPlans       map[int64]dbschema.FloorPlan
+	cameraGroups     map[int64]dbschema.CameraGroup
+	// cameraGroupMembers is a set, like the primary key of the table
+	cameraGroupMembers map[dbschema.CameraGroupMember]struct{}
+	cameraStateChanges map[int64]dbschema.CameraStateChange
+	maintenanceWindows map[int64]dbschema.MaintenanceWindow
+	// locationAssignments is filled by CreateCamera and UpdateCamera, like the trigger on the cameras table
+	locationAssignments map[int64]dbschema.CameraLocationAssignment
+	// idempotencyKeys is keyed by camera and key, like the primary key of the table
+	idempotencyKeys map[idempotencyKey]dbschema.PersonDetectionIdempotencyKey
+
+	lastLocationId        int64
+	lastCameraId          int64
+	lastPersonDetectionId int64
+	lastActivityRuleId    int64
+	lastAlertId           int64
+	lastCameraGroupId     int64
+	lastStateChangeId     int64
+	lastMaintenanceId     int64
+	lastAssignmentId      int64
+
+	// now returns the current time, it replaces clock_timestamp() and current_date
+	now func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		locations:           map[int64]dbschema.Location{},
+		cameras:             map[int64]dbschema.Camera{},
+		personDetections:    map[int64]dbschema.PersonDetection{},
+		cameraStatuses:      map[int64]dbschema.CameraStatus{},
+		activityRules:       map[int64]dbschema.ActivityRule{},
+		alerts:              map[int64]dbschema.Alert{},
+		floorPlans:          map[int64]dbschema.FloorPlan{},
+		cameraGroups:        map[int64]dbschema.CameraGroup{},
+		cameraGroupMembers:  map[dbschema.CameraGroupMember]struct{}{},
+		cameraStateChanges:  map[int64]dbschema.CameraStateChange{},
+		maintenanceWindows:  map[int64]dbschema.MaintenanceWindow{},
+		locationAssignments: map[int64]dbschema.CameraLocationAssignment{},
+		idempotencyKeys:     map[idempotencyKey]dbschema.PersonDetectionIdempotencyKey{},
+		now:                 time.Now,
+	}
+}
+
+func validOrientation(orientation dbenums.Orientation) bool {
+	switch orientation {
+	case dbenums.CameraOrientationVertical, dbenums.CameraOrientationHorizontal,
+		dbenums.CameraOrientationInvertedVertical, dbenums.CameraOrientationInvertedHorizontal:
+		return true
+	}
+	return false
+}
+
+// normalizeDirection returns in, out or none for a detection of the camera going in the given direction, like the
+// normalize_direction function. The entry direction is given as seen by an upright camera
+func normalizeDirection(camera dbschema.Camera, direction dbenums.Direction) string {
+	if direction == dbenums.DirectionNone || camera.EntryDirection == dbenums.DirectionNone {
+		return "none"
+	}
+	inverted := camera.Orientation == dbenums.CameraOrientationInvertedVertical ||
+		camera.Orientation == dbenums.CameraOrientationInvertedHorizontal
+	if (direction == camera.EntryDirection) != inverted {
+		return "in"
+	}
+	return "out"
+}
+
+func validDirection(direction dbenums.Direction) bool {
+	switch direction {
+	case dbenums.DirectionLeft, dbenums.DirectionRight, dbenums.DirectionNone:
+		return true
+	}
+	return false
+}
+
+// page applies offset and limit the way postgres does
+func page[T any](items []T, offset int32, count int32) ([]T, error) {
+	if count < 0 {
+		return nil, negativeLimit()
+	}
+	if offset < 0 {
+		return nil, negativeOffset()
+	}
+
+	if int(offset) >= len(items) {
+		return []T{}, nil
+	}
+	items = items[offset:]
+	if int(count) < len(items) {
+		items = items[:count]
+	}
+	return items, nil
+}
+
+func (m *Memory) GetCamera(ctx context.Context, id int64) (dbschema.Camera, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	camera, ok := m.cameras[id]
+	if !ok {
+		return dbschema.Camera{}, pgx.ErrNoRows
+	}
+	return camera, nil
+}
+
+func (m *Memory) GetCameras(ctx context.Context, includeDeleted bool) ([]dbschema.Camera, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	cameras := make([]dbschema.Camera, 0, len(m.cameras))
+	for _, camera := range m.cameras {
+		if includeDeleted || !camera.DeletedAt.Valid {
+			cameras = append(cameras, camera)
+		}
+	}
+	sort.Slice(cameras, func(i, j int) bool {
+		return cameras[i].ID < cameras[j].ID
+	})
+	return cameras, nil
+}
+
+// checkCamera validates a camera as the table constraints would, the caller must hold the lock
+func (m *Memory) checkCamera(camera dbschema.Camera) error {
+	if !validOrientation(camera.Orientation) {
+		return invalidEnumValue("orientation", string(camera.Orientation))
+	}
+	if !validDirection(camera.EntryDirection) {
+		return invalidEnumValue("direction", string(camera.EntryDirection))
+	}
+	if _, ok := m.locations[int64(camera.LocationID)]; !ok {
+		return foreignKeyViolation("cameras", "location_id", camera.LocationID, "locations")
+	}
+	if (camera.FloorX == nil) != (camera.FloorY == nil) {
+		return checkViolation("cameras", "cameras_floor_position_check")
+	}
+	if camera.Heading != nil && (*camera.Heading < 0 || *camera.Heading >= 360) {
+		return checkViolation("cameras", "cameras_heading_check")
+	}
+	if camera.MountingHeight != nil && *camera.MountingHeight <= 0 {
+		return checkViolation("cameras", "cameras_mounting_height_check")
+	}
+	if camera.FieldOfView != nil && len(camera.FieldOfView) < 3 {
+		return checkViolation("cameras", "cameras_field_of_view_check")
+	}
+	if camera.Tags == nil {
+		return notNullViolation("cameras", "tags")
+	}
+	if !validCameraState(camera.State) {
+		return checkViolation("cameras", "cameras_state_check")
+	}
+	return nil
+}
+
+func (m *Memory) CreateCamera(ctx context.Context, arg dbschema.CreateCameraParams) (dbschema.Camera, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	camera := dbschema.Camera{
+		Name:             arg.Name,
+		ConnectionString: arg.ConnectionString,
+		LocationID:       arg.LocationID,
+		Orientation:      arg.Orientation,
+		EntryDirection:   dbenums.DirectionNone,
+		MountDescription: arg.MountDescription,
+		FloorX:           arg.FloorX,
+		FloorY:           arg.FloorY,
+		Heading:          arg.Heading,
+		MountingHeight:   arg.MountingHeight,
+		FieldOfView:      arg.FieldOfView,
+		Tags:             arg.Tags,
+		State:            "active",
+		StateChangedAt:   pgtype.Timestamptz{Time: m.now(), Valid: true},
+	}
+	if arg.EntryDirection.Valid {
+		camera.EntryDirection = arg.EntryDirection.Direction
+	}
+	if err := m.checkCamera(camera); err != nil {
+		return dbschema.Camera{}, err
+	}
+
+	m.lastCameraId++
+	camera.ID = m.lastCameraId
+	m.cameras[camera.ID] = camera
+	m.recordCameraLocation(camera.ID, camera.LocationID, pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true})
+	return camera, nil
+}
+
+func (m *Memory) UpdateCamera(ctx context.Context, arg dbschema.UpdateCameraParams) (dbschema.Camera, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	camera, ok := m.cameras[arg.ID]
+	if !ok {
+		return dbschema.Camera{}, pgx.ErrNoRows
+	}
+
+	if arg.Name.Valid {
+		camera.Name = arg.Name.String
+	}
+	if arg.ConnectionString.Valid {
+		camera.ConnectionString = arg.ConnectionString.String
+	}
+	if arg.LocationID.Valid {
+		camera.LocationID = arg.LocationID.Int32
+	}
+	if arg.Orientation.Valid {
+		camera.Orientation = arg.Orientation.Orientation
+	}
+	if arg.EntryDirection.Valid {
+		camera.EntryDirection = arg.EntryDirection.Direction
+	}
+	if arg.MountDescription.Valid {
+		camera.MountDescription = arg.MountDescription.String
+	}
+	if arg.FloorX != nil {
+		camera.FloorX = arg.FloorX
+	}
+	if arg.FloorY != nil {
+		camera.FloorY = arg.FloorY
+	}
+	if arg.Heading != nil {
+		camera.Heading = arg.Heading
+	}
+	if arg.MountingHeight != nil {
+		camera.MountingHeight = arg.MountingHeight
+	}
+	if arg.FieldOfView != nil {
+		camera.FieldOfView = arg.FieldOfView
+	}
+	if arg.Tags != nil {
+		camera.Tags = arg.Tags
+	}
+
+	if err := m.checkCamera(camera); err != nil {
+		return dbschema.Camera{}, err
+	}
+
+	if camera.LocationID != m.cameras[camera.ID].LocationID {
+		m.recordCameraLocation(camera.ID, camera.LocationID, pgtype.Timestamptz{Time: m.now(), Valid: true})
+	}
+	m.cameras[camera.ID] = camera
+	return camera, nil
+}
+
+func (m *Memory) DeleteCamera(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cameras[id]; !ok {
+		return nil
+	}
+
+	for _, personDetection := range m.personDetections {
+		if personDetection.CameraID == id {
+			return stillReferencedViolation("cameras", id, "person_detections", "camera_id")
+		}
+	}
+
+	delete(m.cameras, id)
+	delete(m.cameraStatuses, id)
+	for member := range m.cameraGroupMembers {
+		if member.CameraID == id {
+			delete(m.cameraGroupMembers, member)
+		}
+	}
+	for ruleId, rule := range m.activityRules {
+		if rule.CameraID == id {
+			m.deleteActivityRule(ruleId)
+		}
+	}
+	for alertId, alert := range m.alerts {
+		if alert.CameraID.Valid && alert.CameraID.Int64 == id {
+			delete(m.alerts, alertId)
+		}
+	}
+	for changeId, change := range m.cameraStateChanges {
+		if change.CameraID == id {
+			delete(m.cameraStateChanges, changeId)
+		}
+	}
+	for windowId, window := range m.maintenanceWindows {
+		if window.CameraID == id {
+			delete(m.maintenanceWindows, windowId)
+		}
+	}
+	for assignmentId, assignment := range m.locationAssignments {
+		if assignment.CameraID == id {
+			delete(m.locationAssignments, assignmentId)
+		}
+	}
+	for key := range m.idempotencyKeys {
+		if key.cameraId == id {
+			delete(m.idempotencyKeys, key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) SoftDeleteCamera(ctx context.Context, id int64) (dbschema.Camera, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	camera, ok := m.cameras[id]
+	if !ok {
+		return dbschema.Camera{}, pgx.ErrNoRows
+	}
+	if !camera.DeletedAt.Valid {
+		camera.DeletedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+	}
+
+	m.cameras[id] = camera
+	return camera, nil
+}
+
+func (m *Memory) RestoreCamera(ctx context.Context, id int64) (dbschema.Camera, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	camera, ok := m.cameras[id]
+	if !ok {
+		return dbschema.Camera{}, pgx.ErrNoRows
+	}
+	camera.DeletedAt = pgtype.Timestamptz{}
+
+	m.cameras[id] = camera
+	return camera, nil
+}
+
+func (m *Memory) GetCameraStatus(ctx context.Context, cameraID int64) (dbschema.CameraStatus, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status, ok := m.cameraStatuses[cameraID]
+	if !ok {
+		return dbschema.CameraStatus{}, pgx.ErrNoRows
+	}
+	return status, nil
+}
+
+func (m *Memory) GetCameraStatuses(ctx context.Context) ([]dbschema.CameraStatus, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	statuses := make([]dbschema.CameraStatus, 0, len(m.cameraStatuses))
+	for _, status := range m.cameraStatuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].CameraID < statuses[j].CameraID
+	})
+	return statuses, nil
+}
+
+func (m *Memory) UpsertCameraStatus(ctx context.Context, arg dbschema.UpsertCameraStatusParams) (dbschema.CameraStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cameras[arg.CameraID]; !ok {
+		return dbschema.CameraStatus{}, foreignKeyViolation("camera_statuses", "camera_id", arg.CameraID, "cameras")
+	}
+	if arg.Status != "online" && arg.Status != "offline" {
+		return dbschema.CameraStatus{}, checkViolation("camera_statuses", "camera_statuses_status_check")
+	}
+
+	status := m.cameraStatuses[arg.CameraID]
+	status.CameraID = arg.CameraID
+	status.Status = arg.Status
+	status.LatencyMs = arg.LatencyMs
+	status.CheckedAt = arg.CheckedAt
+	status.Error = arg.Error
+	if arg.Status == "online" {
+		status.LastSeenAt = arg.CheckedAt
+		status.ConsecutiveFailures = 0
+	} else {
+		status.ConsecutiveFailures++
+	}
+
+	m.cameraStatuses[arg.CameraID] = status
+	return status, nil
+}
+
+func (m *Memory) GetLocation(ctx context.Context, id int64) (dbschema.Location, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	location, ok := m.locations[id]
+	if !ok {
+		return dbschema.Location{}, pgx.ErrNoRows
+	}
+	return location, nil
+}
+
+func (m *Memory) GetLocations(ctx context.Context, includeDeleted bool) ([]dbschema.Location, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	locations := make([]dbschema.Location, 0, len(m.locations))
+	for _, location := range m.locations {
+		if includeDeleted || !location.DeletedAt.Valid {
+			locations = append(locations, location)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].ID < locations[j].ID
+	})
+	return locations, nil
+}
+
+// checkLocation validates a location as the table constraints and the cycle trigger would
+func (m *Memory) checkLocation(location dbschema.Location) error {
+	if location.Capacity.Valid && location.Capacity.Int32 <= 0 {
+		return checkViolation("locations", "locations_capacity_check")
+	}
+	if location.WarningThreshold.Valid && location.WarningThreshold.Int32 <= 0 {
+		return checkViolation("locations", "locations_warning_threshold_check")
+	}
+	if location.Capacity.Valid && location.WarningThreshold.Valid && location.WarningThreshold.Int32 > location.Capacity.Int32 {
+		return checkViolation("locations", "locations_warning_threshold_capacity_check")
+	}
+	if location.ParentID.Valid {
+		if location.ParentID.Int64 == location.ID {
+			return checkViolation("locations", "locations_parent_id_check")
+		}
+		if _, ok := m.locations[location.ParentID.Int64]; !ok {
+			return foreignKeyViolation("locations", "parent_id", location.ParentID.Int64, "locations")
+		}
+		for ancestor := m.locations[location.ParentID.Int64]; ancestor.ParentID.Valid; ancestor = m.locations[ancestor.ParentID.Int64] {
+			if ancestor.ParentID.Int64 == location.ID {
+				return checkViolation("locations", "locations_parent_id_cycle")
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateLocation(ctx context.Context, arg dbschema.CreateLocationParams) (dbschema.Location, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	location := dbschema.Location{
+		Name:             arg.Name,
+		Description:      arg.Description,
+		Capacity:         arg.Capacity,
+		WarningThreshold: arg.WarningThreshold,
+		ParentID:         arg.ParentID,
+	}
+	if err := m.checkLocation(location); err != nil {
+		return dbschema.Location{}, err
+	}
+
+	m.lastLocationId++
+	location.ID = m.lastLocationId
+	m.locations[location.ID] = location
+	return location, nil
+}
+
+func (m *Memory) UpdateLocation(ctx context.Context, arg dbschema.UpdateLocationParams) (dbschema.Location, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	location, ok := m.locations[arg.ID]
+	if !ok {
+		return dbschema.Location{}, pgx.ErrNoRows
+	}
+
+	if arg.Name.Valid {
+		location.Name = arg.Name.String
+	}
+	if arg.Description.Valid {
+		location.Description = arg.Description.String
+	}
+	if arg.Capacity.Valid {
+		location.Capacity = arg.Capacity
+	}
+	if arg.WarningThreshold.Valid {
+		location.WarningThreshold = arg.WarningThreshold
+	}
+	if arg.ParentID.Valid {
+		location.ParentID = arg.ParentID
+		if arg.ParentID.Int64 == 0 {
+			location.ParentID = pgtype.Int8{}
+		}
+	}
+
+	if err := m.checkLocation(location); err != nil {
+		return dbschema.Location{}, err
+	}
+
+	m.locations[location.ID] = location
+	return location, nil
+}
+
+func (m *Memory) DeleteLocation(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.locations[id]; !ok {
+		return nil
+	}
+
+	for _, camera := range m.cameras {
+		if int64(camera.LocationID) == id {
+			return stillReferencedViolation("locations", id, "cameras", "location_id")
+		}
+	}
+	for _, location := range m.locations {
+		if location.ParentID.Valid && location.ParentID.Int64 == id {
+			return stillReferencedViolation("locations", id, "locations", "parent_id")
+		}
+	}
+
+	for _, assignment := range m.locationAssignments {
+		if int64(assignment.LocationID) == id {
+			return stillReferencedViolation("locations", id, "camera_location_assignments", "location_id")
+		}
+	}
+
+	delete(m.locations, id)
+	delete(m.floorPlans, id)
+	for alertId, alert := range m.alerts {
+		if alert.LocationID.Valid && alert.LocationID.Int64 == id {
+			delete(m.alerts, alertId)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) SoftDeleteLocation(ctx context.Context, id int64) (dbschema.Location, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	location, ok := m.locations[id]
+	if !ok {
+		return dbschema.Location{}, pgx.ErrNoRows
+	}
+	if !location.DeletedAt.Valid {
+		location.DeletedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+	}
+
+	m.locations[id] = location
+	return location, nil
+}
+
+func (m *Memory) RestoreLocation(ctx context.Context, id int64) (dbschema.Location, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	location, ok := m.locations[id]
+	if !ok {
+		return dbschema.Location{}, pgx.ErrNoRows
+	}
+	location.DeletedAt = pgtype.Timestamptz{}
+
+	m.locations[id] = location
+	return location, nil
+}
+
+func (m *Memory) GetLocationChildren(ctx context.Context, parentID int64) ([]dbschema.Location, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	children := []dbschema.Location{}
+	for _, location := range m.locations {
+		if location.ParentID.Valid && location.ParentID.Int64 == parentID {
+			children = append(children, location)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].ID < children[j].ID
+	})
+	return children, nil
+}
+
+func (m *Memory) GetLocationSubtreeIds(ctx context.Context, id int64) ([]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ids := []int64{}
+	if _, ok := m.locations[id]; !ok {
+		return ids, nil
+	}
+
+	// breadth first, so ids are ordered by depth like in the query
+	level := []int64{id}
+	for len(level) > 0 {
+		ids = append(ids, level...)
+		var next []int64
+		for _, location := range m.locations {
+			for _, parentId := range level {
+				if location.ParentID.Valid && location.ParentID.Int64 == parentId {
+					next = append(next, location.ID)
+				}
+			}
+		}
+		sort.Slice(next, func(i, j int) bool {
+			return next[i] < next[j]
+		})
+		level = next
+	}
+	return ids, nil
+}
+
+func (m *Memory) GetLocationOccupancies(ctx context.Context, arg dbschema.GetLocationOccupanciesParams) ([]dbschema.GetLocationOccupanciesRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	counts := map[int32]*dbschema.GetLocationOccupanciesRow{}
+	for _, personDetection := range m.personDetections {
+		if personDetection.NormalizedDirection == "none" || personDetection.Excluded ||
+			personDetection.DetectionDate.Time.Before(arg.Since.Time) {
+			continue
+		}
+		locationId, ok := m.locationAt(personDetection.CameraID, personDetection.DetectionDate.Time)
+		if !ok || arg.LocationID.Valid && locationId != arg.LocationID.Int32 {
+			continue
+		}
+
+		row, ok := counts[locationId]
+		if !ok {
+			row = &dbschema.GetLocationOccupanciesRow{LocationID: locationId}
+			counts[locationId] = row
+		}
+		if personDetection.NormalizedDirection == "in" {
+			row.Entries++
+		} else {
+			row.Exits++
+		}
+	}
+
+	rows := make([]dbschema.GetLocationOccupanciesRow, 0, len(counts))
+	for _, row := range counts {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].LocationID < rows[j].LocationID
+	})
+	return rows, nil
+}
+
+func (m *Memory) GetLocationCameraCounts(ctx context.Context, arg dbschema.GetLocationCameraCountsParams) ([]dbschema.GetLocationCameraCountsRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	counts := map[int64]*dbschema.GetLocationCameraCountsRow{}
+	for _, assignment := range m.locationAssignments {
+		if int64(assignment.LocationID) == arg.LocationID && (!assignment.ValidTo.Valid || assignment.ValidTo.Time.After(arg.Since.Time)) {
+			counts[assignment.CameraID] = &dbschema.GetLocationCameraCountsRow{CameraID: assignment.CameraID}
+		}
+	}
+	for _, personDetection := range m.personDetections {
+		row, ok := counts[personDetection.CameraID]
+		if !ok || personDetection.Excluded || personDetection.DetectionDate.Time.Before(arg.Since.Time) {
+			continue
+		}
+		if locationId, ok := m.locationAt(personDetection.CameraID, personDetection.DetectionDate.Time); !ok || int64(locationId) != arg.LocationID {
+			continue
+		}
+
+		row.Detections++
+		switch personDetection.NormalizedDirection {
+		case "in":
+			row.Entries++
+		case "out":
+			row.Exits++
+		}
+	}
+
+	rows := make([]dbschema.GetLocationCameraCountsRow, 0, len(counts))
+	for _, row := range counts {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].CameraID < rows[j].CameraID
+	})
+	return rows, nil
+}
+
+func (m *Memory) GetFloorPlan(ctx context.Context, locationID int64) (dbschema.FloorPlan, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	floorPlan, ok := m.floorPlans[locationID]
+	if !ok {
+		return dbschema.FloorPlan{}, pgx.ErrNoRows
+	}
+	return floorPlan, nil
+}
+
+// checkFloorPlan validates a floor plan as the table constraints would, the caller must hold the lock
+func (m *Memory) checkFloorPlan(floorPlan dbschema.FloorPlan) error {
+	if _, ok := m.locations[floorPlan.LocationID]; !ok {
+		return foreignKeyViolation("floor_plans", "location_id", floorPlan.LocationID, "locations")
+	}
+	if floorPlan.Width <= 0 {
+		return checkViolation("floor_plans", "floor_plans_width_check")
+	}
+	if floorPlan.Height <= 0 {
+		return checkViolation("floor_plans", "floor_plans_height_check")
+	}
+	return nil
+}
+
+func (m *Memory) SetFloorPlan(ctx context.Context, arg dbschema.SetFloorPlanParams) (dbschema.FloorPlan, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	floorPlan := dbschema.FloorPlan{
+		LocationID:  arg.LocationID,
+		ImageKey:    arg.ImageKey,
+		ContentType: arg.ContentType,
+		ImageWidth:  arg.ImageWidth,
+		ImageHeight: arg.ImageHeight,
+		Width:       arg.Width,
+		Height:      arg.Height,
+		Unit:        arg.Unit,
+		UpdatedAt:   pgtype.Timestamptz{Time: m.now(), Valid: true},
+	}
+	if err := m.checkFloorPlan(floorPlan); err != nil {
+		return dbschema.FloorPlan{}, err
+	}
+
+	m.floorPlans[floorPlan.LocationID] = floorPlan
+	return floorPlan, nil
+}
+
+func (m *Memory) UpdateFloorPlan(ctx context.Context, arg dbschema.UpdateFloorPlanParams) (dbschema.FloorPlan, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	floorPlan, ok := m.floorPlans[arg.LocationID]
+	if !ok {
+		return dbschema.FloorPlan{}, pgx.ErrNoRows
+	}
+
+	if arg.Width != nil {
+		floorPlan.Width = *arg.Width
+	}
+	if arg.Height != nil {
+		floorPlan.Height = *arg.Height
+	}
+	if arg.Unit.Valid {
+		floorPlan.Unit = arg.Unit.String
+	}
+	floorPlan.UpdatedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+
+	if err := m.checkFloorPlan(floorPlan); err != nil {
+		return dbschema.FloorPlan{}, err
+	}
+
+	m.floorPlans[floorPlan.LocationID] = floorPlan
+	return floorPlan, nil
+}
+
+func (m *Memory) DeleteFloorPlan(ctx context.Context, locationID int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.floorPlans, locationID)
+	return nil
+}
+
+func (m *Memory) GetPersonDetection(ctx context.Context, id int64) (dbschema.PersonDetection, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	personDetection, ok := m.personDetections[id]
+	if !ok {
+		return dbschema.PersonDetection{}, pgx.ErrNoRows
+	}
+	return personDetection, nil
+}
+
+func (m *Memory) GetLatestPersonDetection(ctx context.Context) (dbschema.PersonDetection, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var latest dbschema.PersonDetection
+	for _, personDetection := range m.personDetections {
+		if personDetection.ID > latest.ID {
+			latest = personDetection
+		}
+	}
+	if latest.ID == 0 {
+		return dbschema.PersonDetection{}, pgx.ErrNoRows
+	}
+	return latest, nil
+}
+
+// sortedPersonDetections returns the detections matching filter, newest first, or by id when afterId is set like
+// the listings sort them. The caller must hold the lock.
+func (m *Memory) sortedPersonDetections(afterId pgtype.Int8, filter func(personDetection dbschema.PersonDetection) bool) []dbschema.PersonDetection {
+	var personDetections []dbschema.PersonDetection
+	for _, personDetection := range m.personDetections {
+		if filter(personDetection) {
+			personDetections = append(personDetections, personDetection)
+		}
+	}
+	sort.Slice(personDetections, func(i, j int) bool {
+		a, b := personDetections[i], personDetections[j]
+		if afterId.Valid {
+			return a.ID < b.ID
+		}
+		if !a.DetectionDate.Time.Equal(b.DetectionDate.Time) {
+			return a.DetectionDate.Time.After(b.DetectionDate.Time)
+		}
+		return a.ID > b.ID
+	})
+	return personDetections
+}
+
+func (m *Memory) GetPersonDetections(ctx context.Context, arg dbschema.GetPersonDetectionsParams) ([]dbschema.PersonDetection, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	personDetections := m.sortedPersonDetections(arg.AfterID, func(personDetection dbschema.PersonDetection) bool {
+		return (arg.CameraIds == nil || inCameras(arg.CameraIds)(personDetection.CameraID, personDetection.DetectionDate.Time)) &&
+			matchesDetectionFilters(personDetection, arg.TrackID, arg.MinConfidence, arg.ModelVersion, arg.Excluded, arg.AfterID)
+	})
+	return page(personDetections, arg.DetectionOffset, arg.Count)
+}
+
+func (m *Memory) GetPersonDetectionsForCamera(ctx context.Context, arg dbschema.GetPersonDetectionsForCameraParams) ([]dbschema.PersonDetection, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	personDetections := m.sortedPersonDetections(arg.AfterID, func(personDetection dbschema.PersonDetection) bool {
+		return personDetection.CameraID == arg.CameraID &&
+			matchesDetectionFilters(personDetection, arg.TrackID, arg.MinConfidence, arg.ModelVersion, arg.Excluded, arg.AfterID)
+	})
+	return page(personDetections, arg.DetectionOffset, arg.Count)
+}
+
+// checkPersonDetection validates a detection as the table constraints would, the caller must hold the lock
+func (m *Memory) checkPersonDetection(personDetection dbschema.PersonDetection) error {
+	if !personDetection.DetectionDate.Valid {
+		return notNullViolation("person_detections", "detection_date")
+	}
+	if !validDirection(personDetection.TargetDirection) {
+		return invalidEnumValue("direction", string(personDetection.TargetDirection))
+	}
+	if personDetection.Confidence != nil && (*personDetection.Confidence < 0 || *personDetection.Confidence > 1) {
+		return checkViolation("person_detections", "person_detections_confidence_check")
+	}
+	bbox := []*float64{personDetection.BboxX, personDetection.BboxY, personDetection.BboxWidth, personDetection.BboxHeight}
+	for _, value := range bbox {
+		if (value == nil) != (bbox[0] == nil) {
+			return checkViolation("person_detections", "person_detections_bbox_check")
+		}
+	}
+	if personDetection.BboxWidth != nil && *personDetection.BboxWidth <= 0 ||
+		personDetection.BboxHeight != nil && *personDetection.BboxHeight <= 0 {
+		return checkViolation("person_detections", "person_detections_bbox_size_check")
+	}
+	if _, ok := m.cameras[personDetection.CameraID]; !ok {
+		return foreignKeyViolation("person_detections", "camera_id", personDetection.CameraID, "cameras")
+	}
+	return nil
+}
+
+// matchesDetectionFilters tells whether the detection passes the optional filters of the detection listings
+func matchesDetectionFilters(personDetection dbschema.PersonDetection, trackId pgtype.Text, minConfidence pgtype.Float8, modelVersion pgtype.Text, excluded pgtype.Bool, afterId pgtype.Int8) bool {
+	if trackId.Valid && (!personDetection.TrackID.Valid || personDetection.TrackID.String != trackId.String) {
+		return false
+	}
+	if minConfidence.Valid && (personDetection.Confidence == nil || *personDetection.Confidence < minConfidence.Float64) {
+		return false
+	}
+	if modelVersion.Valid && (!personDetection.ModelVersion.Valid || personDetection.ModelVersion.String != modelVersion.String) {
+		return false
+	}
+	if afterId.Valid && personDetection.ID <= afterId.Int64 {
+		return false
+	}
+	return !excluded.Valid || personDetection.Excluded == excluded.Bool
+}
+
+func (m *Memory) CreatePersonDetection(ctx context.Context, arg dbschema.CreatePersonDetectionParams) (dbschema.PersonDetection, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	personDetection := dbschema.PersonDetection{
+		CameraID:        arg.CameraID,
+		DetectionDate:   arg.DetectionDate,
+		TargetDirection: arg.TargetDirection,
+		Flagged:         arg.Flagged,
+		TrackID:         arg.TrackID,
+		Confidence:      arg.Confidence,
+		BboxX:           arg.BboxX,
+		BboxY:           arg.BboxY,
+		BboxWidth:       arg.BboxWidth,
+		BboxHeight:      arg.BboxHeight,
+		FrameDate:       arg.FrameDate,
+		ModelVersion:    arg.ModelVersion,
+		Excluded:        arg.Excluded,
+	}
+	if err := m.checkPersonDetection(personDetection); err != nil {
+		return dbschema.PersonDetection{}, err
+	}
+	personDetection.NormalizedDirection = normalizeDirection(m.cameras[personDetection.CameraID], personDetection.TargetDirection)
+
+	m.lastPersonDetectionId++
+	personDetection.ID = m.lastPersonDetectionId
+	m.personDetections[personDetection.ID] = personDetection
+	return personDetection, nil
+}
+
+func (m *Memory) UpdatePersonDetection(ctx context.Context, arg dbschema.UpdatePersonDetectionParams) (dbschema.PersonDetection, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	personDetection, ok := m.personDetections[arg.ID]
+	if !ok {
+		return dbschema.PersonDetection{}, pgx.ErrNoRows
+	}
+	previous := personDetection
+
+	if arg.CameraID.Valid {
+		personDetection.CameraID = arg.CameraID.Int64
+	}
+	if arg.DetectionDate.Valid {
+		personDetection.DetectionDate = arg.DetectionDate
+	}
+	if arg.TargetDirection.Valid {
+		personDetection.TargetDirection = arg.TargetDirection.Direction
+	}
+	personDetection.Excluded = personDetection.Excluded ||
+		personDetection.Confidence != nil && *personDetection.Confidence < arg.MinConfidence
+
+	if err := m.checkPersonDetection(personDetection); err != nil {
+		return dbschema.PersonDetection{}, err
+	}
+	if personDetection.CameraID != previous.CameraID || personDetection.TargetDirection != previous.TargetDirection {
+		personDetection.NormalizedDirection = normalizeDirection(m.cameras[personDetection.CameraID], personDetection.TargetDirection)
+	}
+
+	m.personDetections[personDetection.ID] = personDetection
+	return personDetection, nil
+}
+
+func (m *Memory) DeletePersonDetection(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.personDetections, id)
+	return nil
+}
+
+func (m *Memory) DeletePersonDetectionsForCamera(ctx context.Context, cameraID int64) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var deleted int64
+	for id, personDetection := range m.personDetections {
+		if personDetection.CameraID == cameraID {
+			delete(m.personDetections, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *Memory) ReassignPersonDetections(ctx context.Context, arg dbschema.ReassignPersonDetectionsParams) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cameras[arg.ToCameraID]; !ok {
+		for _, personDetection := range m.personDetections {
+			if personDetection.CameraID == arg.FromCameraID {
+				return 0, foreignKeyViolation("person_detections", "camera_id", arg.ToCameraID, "cameras")
+			}
+		}
+	}
+
+	var reassigned int64
+	for id, personDetection := range m.personDetections {
+		if personDetection.CameraID == arg.FromCameraID {
+			personDetection.CameraID = arg.ToCameraID
+			m.personDetections[id] = personDetection
+			reassigned++
+		}
+	}
+	return reassigned, nil
+}
+
+// DeleteCameraDetectionsForCamera does nothing, camera detections are not kept in memory
+func (m *Memory) DeleteCameraDetectionsForCamera(ctx context.Context, cameraID int64) (int64, error) {
+	return 0, nil
+}
+
+// ReassignCameraDetections does nothing, camera detections are not kept in memory
+func (m *Memory) ReassignCameraDetections(ctx context.Context, arg dbschema.ReassignCameraDetectionsParams) (int64, error) {
+	return 0, nil
+}
+
+// truncateToDate returns midnight of the day t falls on, in the local time zone
+func truncateToDate(t time.Time) time.Time {
+	year, month, day := t.In(time.Local).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// dailyCounts counts the detections include accepts per day, along with the ones normalized to in and out, for the
+// days of the interval up to today. Excluded detections are left out
+func (m *Memory) dailyCounts(include func(cameraId int64, date time.Time) bool, interval pgtype.Interval) []dbschema.GetDailyPersonDetectionsCountRow {
+	today := truncateToDate(m.now())
+	first := truncateToDate(today.AddDate(0, -int(interval.Months), -int(interval.Days)).
+		Add(-time.Duration(interval.Microseconds) * time.Microsecond))
+
+	counts := map[time.Time]*dbschema.GetDailyPersonDetectionsCountRow{}
+	for date := first; !date.After(today); date = date.AddDate(0, 0, 1) {
+		counts[date] = &dbschema.GetDailyPersonDetectionsCountRow{Date: pgtype.Date{Time: date, Valid: true}}
+	}
+	for _, personDetection := range m.personDetections {
+		if personDetection.Excluded || !include(personDetection.CameraID, personDetection.DetectionDate.Time) {
+			continue
+		}
+		count, ok := counts[truncateToDate(personDetection.DetectionDate.Time)]
+		if !ok {
+			continue
+		}
+		count.Count++
+		switch personDetection.NormalizedDirection {
+		case "in":
+			count.Entries++
+		case "out":
+			count.Exits++
+		}
+	}
+
+	rows := []dbschema.GetDailyPersonDetectionsCountRow{}
+	for date := first; !date.After(today); date = date.AddDate(0, 0, 1) {
+		rows = append(rows, *counts[date])
+	}
+	return rows
+}
+
+func (m *Memory) GetDailyPersonDetectionsCount(ctx context.Context, arg dbschema.GetDailyPersonDetectionsCountParams) ([]dbschema.GetDailyPersonDetectionsCountRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.dailyCounts(isCamera(arg.CameraID), arg.Interval), nil
+}
+
+// hourlyCounts counts the detections include accepts per hour and direction, in the range [from, to). Excluded
+// detections are left out
+func (m *Memory) hourlyCounts(include func(cameraId int64, date time.Time) bool, from time.Time, to time.Time) []dbschema.GetHourlyPersonDetectionsCountRow {
+	type key struct {
+		bucket              time.Time
+		direction           dbenums.Direction
+		normalizedDirection string
+	}
+
+	counts := map[key]int64{}
+	for _, personDetection := range m.personDetections {
+		date := personDetection.DetectionDate.Time
+		if !personDetection.Excluded && include(personDetection.CameraID, date) && !date.Before(from) && date.Before(to) {
+			counts[key{date.Truncate(time.Hour), personDetection.TargetDirection, personDetection.NormalizedDirection}]++
+		}
+	}
+
+	rows := []dbschema.GetHourlyPersonDetectionsCountRow{}
+	for k, count := range counts {
+		rows = append(rows, dbschema.GetHourlyPersonDetectionsCountRow{
+			Bucket:              pgtype.Timestamptz{Time: k.bucket, Valid: true},
+			TargetDirection:     k.direction,
+			NormalizedDirection: k.normalizedDirection,
+			Count:               count,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].Bucket.Time.Equal(rows[j].Bucket.Time) {
+			return rows[i].Bucket.Time.Before(rows[j].Bucket.Time)
+		}
+		if rows[i].TargetDirection != rows[j].TargetDirection {
+			return rows[i].TargetDirection < rows[j].TargetDirection
+		}
+		return rows[i].NormalizedDirection < rows[j].NormalizedDirection
+	})
+	return rows
+}
+
+// isCamera returns a filter for hourlyCounts and dailyCounts accepting only the given camera
+func isCamera(id int64) func(cameraId int64, date time.Time) bool {
+	return func(cameraId int64, date time.Time) bool {
+		return cameraId == id
+	}
+}
+
+// inCameras returns a filter for hourlyCounts and dailyCounts accepting only the given cameras
+func inCameras(ids []int64) func(cameraId int64, date time.Time) bool {
+	return func(cameraId int64, date time.Time) bool {
+		for _, id := range ids {
+			if cameraId == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// inLocations returns a filter for hourlyCounts and dailyCounts accepting the detections made while their camera was
+// in one of the given locations, the caller must hold the lock while using it
+func (m *Memory) inLocations(locationIds []int64) func(cameraId int64, date time.Time) bool {
+	return func(cameraId int64, date time.Time) bool {
+		cameraLocationId, ok := m.locationAt(cameraId, date)
+		if !ok {
+			return false
+		}
+		for _, locationId := range locationIds {
+			if int64(cameraLocationId) == locationId {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GetHourlyPersonDetectionsCount computes the counts from the detections, as there are no rollups in memory
+func (m *Memory) GetHourlyPersonDetectionsCount(ctx context.Context, arg dbschema.GetHourlyPersonDetectionsCountParams) ([]dbschema.GetHourlyPersonDetectionsCountRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.hourlyCounts(isCamera(arg.CameraID), arg.FromDate.Time, arg.ToDate.Time), nil
+}
+
+func (m *Memory) GetHourlyPersonDetectionsCountRaw(ctx context.Context, arg dbschema.GetHourlyPersonDetectionsCountRawParams) ([]dbschema.GetHourlyPersonDetectionsCountRawRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetHourlyPersonDetectionsCountRawRow{}
+	for _, row := range m.hourlyCounts(isCamera(arg.CameraID), arg.FromDate.Time, arg.ToDate.Time) {
+		rows = append(rows, dbschema.GetHourlyPersonDetectionsCountRawRow(row))
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetLocationDailyPersonDetectionsCount(ctx context.Context, arg dbschema.GetLocationDailyPersonDetectionsCountParams) ([]dbschema.GetLocationDailyPersonDetectionsCountRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetLocationDailyPersonDetectionsCountRow{}
+	for _, row := range m.dailyCounts(m.inLocations(arg.LocationIds), arg.Interval) {
+		rows = append(rows, dbschema.GetLocationDailyPersonDetectionsCountRow(row))
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetLocationHourlyPersonDetectionsCount(ctx context.Context, arg dbschema.GetLocationHourlyPersonDetectionsCountParams) ([]dbschema.GetLocationHourlyPersonDetectionsCountRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetLocationHourlyPersonDetectionsCountRow{}
+	for _, row := range m.hourlyCounts(m.inLocations(arg.LocationIds), arg.FromDate.Time, arg.ToDate.Time) {
+		rows = append(rows, dbschema.GetLocationHourlyPersonDetectionsCountRow(row))
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetLocationHourlyPersonDetectionsCountRaw(ctx context.Context, arg dbschema.GetLocationHourlyPersonDetectionsCountRawParams) ([]dbschema.GetLocationHourlyPersonDetectionsCountRawRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetLocationHourlyPersonDetectionsCountRawRow{}
+	for _, row := range m.hourlyCounts(m.inLocations(arg.LocationIds), arg.FromDate.Time, arg.ToDate.Time) {
+		rows = append(rows, dbschema.GetLocationHourlyPersonDetectionsCountRawRow(row))
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetCamerasDailyPersonDetectionsCount(ctx context.Context, arg dbschema.GetCamerasDailyPersonDetectionsCountParams) ([]dbschema.GetCamerasDailyPersonDetectionsCountRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetCamerasDailyPersonDetectionsCountRow{}
+	for _, row := range m.dailyCounts(inCameras(arg.CameraIds), arg.Interval) {
+		rows = append(rows, dbschema.GetCamerasDailyPersonDetectionsCountRow(row))
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetCamerasHourlyPersonDetectionsCount(ctx context.Context, arg dbschema.GetCamerasHourlyPersonDetectionsCountParams) ([]dbschema.GetCamerasHourlyPersonDetectionsCountRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetCamerasHourlyPersonDetectionsCountRow{}
+	for _, row := range m.hourlyCounts(inCameras(arg.CameraIds), arg.FromDate.Time, arg.ToDate.Time) {
+		rows = append(rows, dbschema.GetCamerasHourlyPersonDetectionsCountRow(row))
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetCamerasHourlyPersonDetectionsCountRaw(ctx context.Context, arg dbschema.GetCamerasHourlyPersonDetectionsCountRawParams) ([]dbschema.GetCamerasHourlyPersonDetectionsCountRawRow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rows := []dbschema.GetCamerasHourlyPersonDetectionsCountRawRow{}
+	for _, row := range m.hourlyCounts(inCameras(arg.CameraIds), arg.FromDate.Time, arg.ToDate.Time) {
+		rows = append(rows, dbschema.GetCamerasHourlyPersonDetectionsCountRawRow(row))
+	}
+	return rows, nil
+}
+
+// StreamPersonDetectionsExport collects the matching rows before calling fn, so the lock is not held while fn runs
+func (m *Memory) StreamPersonDetectionsExport(ctx context.Context, arg dbschema.ExportPersonDetectionsParams, fn func(row dbschema.ExportPersonDetectionsRow) error) error {
+	m.mutex.RLock()
+	personDetections := m.sortedPersonDetections(pgtype.Int8{}, func(personDetection dbschema.PersonDetection) bool {
+		date := personDetection.DetectionDate.Time
+		return (!arg.FromDate.Valid || !date.Before(arg.FromDate.Time)) &&
+			(!arg.ToDate.Valid || date.Before(arg.ToDate.Time)) &&
+			(!arg.CameraID.Valid || personDetection.CameraID == arg.CameraID.Int64) &&
+			(arg.LocationIds == nil || m.inLocations(arg.LocationIds)(personDetection.CameraID, date)) &&
+			(arg.CameraIds == nil || inCameras(arg.CameraIds)(personDetection.CameraID, date))
+	})
+
+	rows := make([]dbschema.ExportPersonDetectionsRow, 0, len(personDetections))
+	// sortedPersonDetections returns the newest first, exports go from the oldest
+	for i := len(personDetections) - 1; i >= 0; i-- {
+		personDetection := personDetections[i]
+		row := dbschema.ExportPersonDetectionsRow{
+			ID:                  personDetection.ID,
+			CameraID:            personDetection.CameraID,
+			CameraName:          m.cameras[personDetection.CameraID].Name,
+			DetectionDate:       personDetection.DetectionDate,
+			TargetDirection:     personDetection.TargetDirection,
+			NormalizedDirection: personDetection.NormalizedDirection,
+			Flagged:             personDetection.Flagged,
+			Excluded:            personDetection.Excluded,
+			TrackID:             personDetection.TrackID,
+			Confidence:          personDetection.Confidence,
+			BboxX:               personDetection.BboxX,
+			BboxY:               personDetection.BboxY,
+			BboxWidth:           personDetection.BboxWidth,
+			BboxHeight:          personDetection.BboxHeight,
+			FrameDate:           personDetection.FrameDate,
+			ModelVersion:        personDetection.ModelVersion,
+		}
+		if locationId, ok := m.locationAt(personDetection.CameraID, personDetection.DetectionDate.Time); ok {
+			row.LocationID = pgtype.Int8{Int64: int64(locationId), Valid: true}
+			row.LocationName = pgtype.Text{String: m.locations[int64(locationId)].Name, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	m.mutex.RUnlock()
+
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clone[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// InTx snapshots the stored records and restores them if fn fails. Transactions are not isolated: writes made
+// outside of the transaction while it runs are visible to it, and are undone as well when it fails. Calling InTx on
+// the Store given to fn nests a transaction that only undoes its own writes when it fails, like a savepoint does.
+func (m *Memory) InTx(ctx context.Context, fn func(s Store) error) error {
+	m.txMutex.Lock()
+	defer m.txMutex.Unlock()
+
+	return m.savepoint(fn)
+}
+
+// memoryTx is the Store given to the function run by Memory.InTx, its transactions are nested in the running one
+// instead of waiting for it to finish
+type memoryTx struct {
+	*Memory
+}
+
+func (t memoryTx) InTx(ctx context.Context, fn func(s Store) error) error {
+	return t.savepoint(fn)
+}
+
+// savepoint runs fn, restoring the records to how they were before it if it fails. The caller holds txMutex.
+func (m *Memory) savepoint(fn func(s Store) error) error {
+	m.mutex.RLock()
+	snapshot := Memory{
+		locations:             clone(m.locations),
+		cameras:               clone(m.cameras),
+		personDetections:      clone(m.personDetections),
+		cameraStatuses:        clone(m.cameraStatuses),
+		activityRules:         clone(m.activityRules),
+		alerts:                clone(m.alerts),
+		floorPlans:            clone(m.floorPlans),
+		cameraGroups:          clone(m.cameraGroups),
+		cameraGroupMembers:    clone(m.cameraGroupMembers),
+		cameraStateChanges:    clone(m.cameraStateChanges),
+		maintenanceWindows:    clone(m.maintenanceWindows),
+		locationAssignments:   clone(m.locationAssignments),
+		idempotencyKeys:       clone(m.idempotencyKeys),
+		lastLocationId:        m.lastLocationId,
+		lastCameraId:          m.lastCameraId,
+		lastPersonDetectionId: m.lastPersonDetectionId,
+		lastActivityRuleId:    m.lastActivityRuleId,
+		lastAlertId:           m.lastAlertId,
+		lastCameraGroupId:     m.lastCameraGroupId,
+		lastStateChangeId:     m.lastStateChangeId,
+		lastMaintenanceId:     m.lastMaintenanceId,
+		lastAssignmentId:      m.lastAssignmentId,
+	}
+	m.mutex.RUnlock()
+
+	err := fn(memoryTx{m})
+	if err != nil {
+		m.mutex.Lock()
+		m.locations, m.cameras, m.personDetections = snapshot.locations, snapshot.cameras, snapshot.personDetections
+		m.cameraStatuses, m.activityRules, m.alerts = snapshot.cameraStatuses, snapshot.activityRules, snapshot.alerts
+		m.floorPlans, m.cameraGroups, m.cameraGroupMembers = snapshot.floorPlans, snapshot.cameraGroups, snapshot.cameraGroupMembers
+		m.lastLocationId, m.lastCameraId, m.lastPersonDetectionId = snapshot.lastLocationId, snapshot.lastCameraId, snapshot.lastPersonDetectionId
+		m.lastActivityRuleId, m.lastAlertId, m.lastCameraGroupId = snapshot.lastActivityRuleId, snapshot.lastAlertId, snapshot.lastCameraGroupId
+		m.cameraStateChanges, m.maintenanceWindows = snapshot.cameraStateChanges, snapshot.maintenanceWindows
+		m.lastStateChangeId, m.lastMaintenanceId = snapshot.lastStateChangeId, snapshot.lastMaintenanceId
+		m.locationAssignments, m.lastAssignmentId = snapshot.locationAssignments, snapshot.lastAssignmentId
+		m.idempotencyKeys = snapshot.idempotencyKeys
+		m.mutex.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"regexp"
+	"sort"
+)
+
+var (
+	activeFromPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+	activeToPattern   = regexp.MustCompile(`^(([01][0-9]|2[0-3]):[0-5][0-9]|24:00)$`)
+)
+
+func (m *Memory) GetActivityRule(ctx context.Context, id int64) (dbschema.ActivityRule, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rule, ok := m.activityRules[id]
+	if !ok {
+		return dbschema.ActivityRule{}, pgx.ErrNoRows
+	}
+	return rule, nil
+}
+
+// sortedActivityRules returns the rules matching filter by id. The caller must hold the lock.
+func (m *Memory) sortedActivityRules(filter func(rule dbschema.ActivityRule) bool) []dbschema.ActivityRule {
+	rules := []dbschema.ActivityRule{}
+	for _, rule := range m.activityRules {
+		if filter(rule) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+func (m *Memory) GetActivityRules(ctx context.Context) ([]dbschema.ActivityRule, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.sortedActivityRules(func(rule dbschema.ActivityRule) bool {
+		return true
+	}), nil
+}
+
+func (m *Memory) GetActivityRulesForCamera(ctx context.Context, cameraID int64) ([]dbschema.ActivityRule, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.sortedActivityRules(func(rule dbschema.ActivityRule) bool {
+		return rule.CameraID == cameraID
+	}), nil
+}
+
+// checkActivityRule validates a rule as the table constraints would, the caller must hold the lock
+func (m *Memory) checkActivityRule(rule dbschema.ActivityRule) error {
+	if rule.Weekdays == nil {
+		return notNullViolation("activity_rules", "weekdays")
+	}
+	if rule.MinDetections <= 0 {
+		return checkViolation("activity_rules", "activity_rules_min_detections_check")
+	}
+	if rule.WindowMinutes <= 0 {
+		return checkViolation("activity_rules", "activity_rules_window_minutes_check")
+	}
+	for _, weekday := range rule.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return checkViolation("activity_rules", "activity_rules_weekdays_check")
+		}
+	}
+	if !activeFromPattern.MatchString(rule.ActiveFrom) {
+		return checkViolation("activity_rules", "activity_rules_active_from_check")
+	}
+	if !activeToPattern.MatchString(rule.ActiveTo) {
+		return checkViolation("activity_rules", "activity_rules_active_to_check")
+	}
+	if rule.ActiveFrom == rule.ActiveTo {
+		return checkViolation("activity_rules", "activity_rules_check")
+	}
+	if _, ok := m.cameras[rule.CameraID]; !ok {
+		return foreignKeyViolation("activity_rules", "camera_id", rule.CameraID, "cameras")
+	}
+	return nil
+}
+
+func (m *Memory) CreateActivityRule(ctx context.Context, arg dbschema.CreateActivityRuleParams) (dbschema.ActivityRule, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	rule := dbschema.ActivityRule{
+		CameraID:      arg.CameraID,
+		Name:          arg.Name,
+		MinDetections: arg.MinDetections,
+		WindowMinutes: arg.WindowMinutes,
+		Weekdays:      arg.Weekdays,
+		ActiveFrom:    arg.ActiveFrom,
+		ActiveTo:      arg.ActiveTo,
+		Timezone:      arg.Timezone,
+		Enabled:       arg.Enabled,
+	}
+	if err := m.checkActivityRule(rule); err != nil {
+		return dbschema.ActivityRule{}, err
+	}
+
+	m.lastActivityRuleId++
+	rule.ID = m.lastActivityRuleId
+	m.activityRules[rule.ID] = rule
+	return rule, nil
+}
+
+func (m *Memory) UpdateActivityRule(ctx context.Context, arg dbschema.UpdateActivityRuleParams) (dbschema.ActivityRule, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	rule, ok := m.activityRules[arg.ID]
+	if !ok {
+		return dbschema.ActivityRule{}, pgx.ErrNoRows
+	}
+
+	if arg.Name.Valid {
+		rule.Name = arg.Name.String
+	}
+	if arg.MinDetections.Valid {
+		rule.MinDetections = arg.MinDetections.Int32
+	}
+	if arg.WindowMinutes.Valid {
+		rule.WindowMinutes = arg.WindowMinutes.Int32
+	}
+	if arg.Weekdays != nil {
+		rule.Weekdays = arg.Weekdays
+	}
+	if arg.ActiveFrom.Valid {
+		rule.ActiveFrom = arg.ActiveFrom.String
+	}
+	if arg.ActiveTo.Valid {
+		rule.ActiveTo = arg.ActiveTo.String
+	}
+	if arg.Timezone.Valid {
+		rule.Timezone = arg.Timezone.String
+	}
+	if arg.Enabled.Valid {
+		rule.Enabled = arg.Enabled.Bool
+	}
+
+	if err := m.checkActivityRule(rule); err != nil {
+		return dbschema.ActivityRule{}, err
+	}
+
+	m.activityRules[rule.ID] = rule
+	return rule, nil
+}
+
+// deleteActivityRule deletes a rule along with its alerts, the caller must hold the lock
+func (m *Memory) deleteActivityRule(id int64) {
+	delete(m.activityRules, id)
+	for alertId, alert := range m.alerts {
+		if alert.ActivityRuleID.Valid && alert.ActivityRuleID.Int64 == id {
+			delete(m.alerts, alertId)
+		}
+	}
+}
+
+func (m *Memory) DeleteActivityRule(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.deleteActivityRule(id)
+	return nil
+}
+
+func (m *Memory) CountPersonDetectionsForCamera(ctx context.Context, arg dbschema.CountPersonDetectionsForCameraParams) (int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var count int64
+	for _, personDetection := range m.personDetections {
+		date := personDetection.DetectionDate.Time
+		if personDetection.CameraID == arg.CameraID && !personDetection.Excluded &&
+			!date.Before(arg.FromDate.Time) && date.Before(arg.ToDate.Time) {
+			count++
+		}
+	}
+	return count, nil
+}
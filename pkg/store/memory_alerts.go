@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"sort"
+)
+
+func (m *Memory) GetAlert(ctx context.Context, id int64) (dbschema.Alert, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	alert, ok := m.alerts[id]
+	if !ok {
+		return dbschema.Alert{}, pgx.ErrNoRows
+	}
+	return alert, nil
+}
+
+func (m *Memory) GetAlerts(ctx context.Context, arg dbschema.GetAlertsParams) ([]dbschema.Alert, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	alerts := []dbschema.Alert{}
+	for _, alert := range m.alerts {
+		if (!arg.State.Valid || alert.State == arg.State.String) &&
+			(!arg.Kind.Valid || alert.Kind == arg.Kind.String) &&
+			(!arg.Severity.Valid || alert.Severity == arg.Severity.String) &&
+			(!arg.CameraID.Valid || alert.CameraID == arg.CameraID) &&
+			(!arg.LocationID.Valid || alert.LocationID == arg.LocationID) {
+			alerts = append(alerts, alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if !a.OpenedAt.Time.Equal(b.OpenedAt.Time) {
+			return a.OpenedAt.Time.After(b.OpenedAt.Time)
+		}
+		return a.ID > b.ID
+	})
+	return page(alerts, arg.AlertOffset, arg.Count)
+}
+
+// unresolvedAlert returns the first alert matching filter that is not resolved yet, the caller must hold the lock
+func (m *Memory) unresolvedAlert(filter func(alert dbschema.Alert) bool) (dbschema.Alert, bool) {
+	for _, alert := range m.alerts {
+		if alert.State != "resolved" && filter(alert) {
+			return alert, true
+		}
+	}
+	return dbschema.Alert{}, false
+}
+
+func (m *Memory) GetUnresolvedActivityRuleAlert(ctx context.Context, activityRuleID pgtype.Int8) (dbschema.Alert, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	alert, ok := m.unresolvedAlert(func(alert dbschema.Alert) bool {
+		return activityRuleID.Valid && alert.ActivityRuleID == activityRuleID
+	})
+	if !ok {
+		return dbschema.Alert{}, pgx.ErrNoRows
+	}
+	return alert, nil
+}
+
+func (m *Memory) GetUnresolvedLocationAlert(ctx context.Context, arg dbschema.GetUnresolvedLocationAlertParams) (dbschema.Alert, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	alert, ok := m.unresolvedAlert(func(alert dbschema.Alert) bool {
+		return arg.LocationID.Valid && alert.LocationID == arg.LocationID && alert.Kind == arg.Kind
+	})
+	if !ok {
+		return dbschema.Alert{}, pgx.ErrNoRows
+	}
+	return alert, nil
+}
+
+func validSeverity(severity string) bool {
+	return severity == "warning" || severity == "critical"
+}
+
+func (m *Memory) CreateAlert(ctx context.Context, arg dbschema.CreateAlertParams) (dbschema.Alert, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !validSeverity(arg.Severity) {
+		return dbschema.Alert{}, checkViolation("alerts", "alerts_severity_check")
+	}
+	if arg.CameraID.Valid {
+		if _, ok := m.cameras[arg.CameraID.Int64]; !ok {
+			return dbschema.Alert{}, foreignKeyViolation("alerts", "camera_id", arg.CameraID.Int64, "cameras")
+		}
+	}
+	if arg.LocationID.Valid {
+		if _, ok := m.locations[arg.LocationID.Int64]; !ok {
+			return dbschema.Alert{}, foreignKeyViolation("alerts", "location_id", arg.LocationID.Int64, "locations")
+		}
+		if arg.Kind == "location_occupancy" {
+			if _, ok := m.unresolvedAlert(func(alert dbschema.Alert) bool {
+				return alert.LocationID == arg.LocationID && alert.Kind == arg.Kind
+			}); ok {
+				return dbschema.Alert{}, uniqueViolation("alerts", "alerts_location_id_unresolved", "location_id", arg.LocationID.Int64)
+			}
+		}
+	}
+	if arg.ActivityRuleID.Valid {
+		if _, ok := m.activityRules[arg.ActivityRuleID.Int64]; !ok {
+			return dbschema.Alert{}, foreignKeyViolation("alerts", "activity_rule_id", arg.ActivityRuleID.Int64, "activity_rules")
+		}
+		if _, ok := m.unresolvedAlert(func(alert dbschema.Alert) bool {
+			return alert.ActivityRuleID == arg.ActivityRuleID
+		}); ok {
+			return dbschema.Alert{}, uniqueViolation("alerts", "alerts_activity_rule_id_unresolved", "activity_rule_id", arg.ActivityRuleID.Int64)
+		}
+	}
+
+	m.lastAlertId++
+	alert := dbschema.Alert{
+		ID:             m.lastAlertId,
+		Kind:           arg.Kind,
+		State:          "open",
+		Severity:       arg.Severity,
+		CameraID:       arg.CameraID,
+		LocationID:     arg.LocationID,
+		ActivityRuleID: arg.ActivityRuleID,
+		Message:        arg.Message,
+		OpenedAt:       pgtype.Timestamptz{Time: m.now(), Valid: true},
+	}
+	m.alerts[alert.ID] = alert
+	return alert, nil
+}
+
+func (m *Memory) AcknowledgeAlert(ctx context.Context, arg dbschema.AcknowledgeAlertParams) (dbschema.Alert, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	alert, ok := m.alerts[arg.ID]
+	if !ok || alert.State != "open" {
+		return dbschema.Alert{}, pgx.ErrNoRows
+	}
+
+	alert.State = "acknowledged"
+	alert.AcknowledgedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+	alert.AcknowledgedBy = arg.AcknowledgedBy
+	m.alerts[alert.ID] = alert
+	return alert, nil
+}
+
+func (m *Memory) UpdateAlertSeverity(ctx context.Context, arg dbschema.UpdateAlertSeverityParams) (dbschema.Alert, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	alert, ok := m.alerts[arg.ID]
+	if !ok || alert.State == "resolved" {
+		return dbschema.Alert{}, pgx.ErrNoRows
+	}
+	if !validSeverity(arg.Severity) {
+		return dbschema.Alert{}, checkViolation("alerts", "alerts_severity_check")
+	}
+
+	alert.Severity = arg.Severity
+	alert.Message = arg.Message
+	if arg.Severity == "critical" {
+		alert.State = "open"
+		alert.AcknowledgedAt = pgtype.Timestamptz{}
+		alert.AcknowledgedBy = pgtype.Text{}
+	}
+	m.alerts[alert.ID] = alert
+	return alert, nil
+}
+
+func (m *Memory) ResolveAlert(ctx context.Context, id int64) (dbschema.Alert, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	alert, ok := m.alerts[id]
+	if !ok || alert.State == "resolved" {
+		return dbschema.Alert{}, pgx.ErrNoRows
+	}
+
+	alert.State = "resolved"
+	alert.ResolvedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+	m.alerts[id] = alert
+	return alert, nil
+}
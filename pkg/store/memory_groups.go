@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"sort"
+)
+
+func (m *Memory) GetCameraGroup(ctx context.Context, id int64) (dbschema.CameraGroup, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	group, ok := m.cameraGroups[id]
+	if !ok {
+		return dbschema.CameraGroup{}, pgx.ErrNoRows
+	}
+	return group, nil
+}
+
+func (m *Memory) GetCameraGroupByName(ctx context.Context, name string) (dbschema.CameraGroup, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, group := range m.cameraGroups {
+		if group.Name == name {
+			return group, nil
+		}
+	}
+	return dbschema.CameraGroup{}, pgx.ErrNoRows
+}
+
+func (m *Memory) GetCameraGroups(ctx context.Context) ([]dbschema.CameraGroup, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	groups := make([]dbschema.CameraGroup, 0, len(m.cameraGroups))
+	for _, group := range m.cameraGroups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return groups, nil
+}
+
+// checkCameraGroup validates a group as the table constraints would, the caller must hold the lock
+func (m *Memory) checkCameraGroup(group dbschema.CameraGroup) error {
+	for _, other := range m.cameraGroups {
+		if other.ID != group.ID && other.Name == group.Name {
+			return uniqueViolation("camera_groups", "camera_groups_name_key", "name", group.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateCameraGroup(ctx context.Context, arg dbschema.CreateCameraGroupParams) (dbschema.CameraGroup, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	group := dbschema.CameraGroup{
+		Name:        arg.Name,
+		Description: arg.Description,
+	}
+	if err := m.checkCameraGroup(group); err != nil {
+		return dbschema.CameraGroup{}, err
+	}
+
+	m.lastCameraGroupId++
+	group.ID = m.lastCameraGroupId
+	m.cameraGroups[group.ID] = group
+	return group, nil
+}
+
+func (m *Memory) UpdateCameraGroup(ctx context.Context, arg dbschema.UpdateCameraGroupParams) (dbschema.CameraGroup, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	group, ok := m.cameraGroups[arg.ID]
+	if !ok {
+		return dbschema.CameraGroup{}, pgx.ErrNoRows
+	}
+
+	if arg.Name.Valid {
+		group.Name = arg.Name.String
+	}
+	if arg.Description.Valid {
+		group.Description = arg.Description.String
+	}
+
+	if err := m.checkCameraGroup(group); err != nil {
+		return dbschema.CameraGroup{}, err
+	}
+
+	m.cameraGroups[group.ID] = group
+	return group, nil
+}
+
+func (m *Memory) DeleteCameraGroup(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.cameraGroups, id)
+	for member := range m.cameraGroupMembers {
+		if member.GroupID == id {
+			delete(m.cameraGroupMembers, member)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetCameraGroupMembers(ctx context.Context) ([]dbschema.CameraGroupMember, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	members := make([]dbschema.CameraGroupMember, 0, len(m.cameraGroupMembers))
+	for member := range m.cameraGroupMembers {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].GroupID != members[j].GroupID {
+			return members[i].GroupID < members[j].GroupID
+		}
+		return members[i].CameraID < members[j].CameraID
+	})
+	return members, nil
+}
+
+func (m *Memory) GetCameraGroupCameraIds(ctx context.Context, groupID int64) ([]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ids := []int64{}
+	for member := range m.cameraGroupMembers {
+		if member.GroupID == groupID {
+			ids = append(ids, member.CameraID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids, nil
+}
+
+func (m *Memory) AddCameraGroupMember(ctx context.Context, arg dbschema.AddCameraGroupMemberParams) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cameraGroups[arg.GroupID]; !ok {
+		return foreignKeyViolation("camera_group_members", "group_id", arg.GroupID, "camera_groups")
+	}
+	if _, ok := m.cameras[arg.CameraID]; !ok {
+		return foreignKeyViolation("camera_group_members", "camera_id", arg.CameraID, "cameras")
+	}
+
+	m.cameraGroupMembers[dbschema.CameraGroupMember(arg)] = struct{}{}
+	return nil
+}
+
+func (m *Memory) RemoveCameraGroupMember(ctx context.Context, arg dbschema.RemoveCameraGroupMemberParams) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.cameraGroupMembers, dbschema.CameraGroupMember(arg))
+	return nil
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type idempotencyKey struct {
+	cameraId int64
+	key      string
+}
+
+// idempotentDetection returns the detection the key was stored with, if it still exists. The caller must hold the
+// lock
+func (m *Memory) idempotentDetection(key dbschema.PersonDetectionIdempotencyKey) (dbschema.PersonDetection, bool) {
+	personDetection, ok := m.personDetections[key.PersonDetectionID.Int64]
+	if !key.PersonDetectionID.Valid || !ok || !personDetection.DetectionDate.Time.Equal(key.DetectionDate.Time) {
+		return dbschema.PersonDetection{}, false
+	}
+	return personDetection, true
+}
+
+func (m *Memory) GetIdempotentPersonDetection(ctx context.Context, arg dbschema.GetIdempotentPersonDetectionParams) (dbschema.PersonDetection, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	key, ok := m.idempotencyKeys[idempotencyKey{cameraId: arg.CameraID, key: arg.Key}]
+	if !ok || key.CreatedAt.Time.Before(arg.Cutoff.Time) {
+		return dbschema.PersonDetection{}, pgx.ErrNoRows
+	}
+	personDetection, ok := m.idempotentDetection(key)
+	if !ok {
+		return dbschema.PersonDetection{}, pgx.ErrNoRows
+	}
+	return personDetection, nil
+}
+
+func (m *Memory) ClaimPersonDetectionIdempotencyKey(ctx context.Context, arg dbschema.ClaimPersonDetectionIdempotencyKeyParams) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cameras[arg.CameraID]; !ok {
+		return 0, foreignKeyViolation("person_detection_idempotency_keys", "camera_id", arg.CameraID, "cameras")
+	}
+
+	id := idempotencyKey{cameraId: arg.CameraID, key: arg.Key}
+	if _, ok := m.idempotencyKeys[id]; ok {
+		return 0, nil
+	}
+	m.idempotencyKeys[id] = dbschema.PersonDetectionIdempotencyKey{
+		CameraID:  arg.CameraID,
+		Key:       arg.Key,
+		CreatedAt: pgtype.Timestamptz{Time: m.now(), Valid: true},
+	}
+	return 1, nil
+}
+
+// LockPersonDetectionIdempotencyKey only tells whether the key exists, transactions of the memory store already run
+// one at a time
+func (m *Memory) LockPersonDetectionIdempotencyKey(ctx context.Context, arg dbschema.LockPersonDetectionIdempotencyKeyParams) (int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if _, ok := m.idempotencyKeys[idempotencyKey{cameraId: arg.CameraID, key: arg.Key}]; !ok {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (m *Memory) SetPersonDetectionIdempotencyKeyDetection(ctx context.Context, arg dbschema.SetPersonDetectionIdempotencyKeyDetectionParams) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	id := idempotencyKey{cameraId: arg.CameraID, key: arg.Key}
+	key, ok := m.idempotencyKeys[id]
+	if !ok {
+		return nil
+	}
+	key.PersonDetectionID = arg.PersonDetectionID
+	key.DetectionDate = arg.DetectionDate
+	key.CreatedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+	m.idempotencyKeys[id] = key
+	return nil
+}
+
+func (m *Memory) DeleteExpiredIdempotencyKeys(ctx context.Context, arg dbschema.DeleteExpiredIdempotencyKeysParams) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var deleted int64
+	for id, key := range m.idempotencyKeys {
+		if id.cameraId == arg.CameraID && key.CreatedAt.Time.Before(arg.Cutoff.Time) {
+			delete(m.idempotencyKeys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"sort"
+	"time"
+)
+
+// recordCameraLocation closes the current location assignment of the camera and opens one for the given location,
+// like the record_camera_location trigger. An assignment that starts at from was never in effect, it is replaced, and
+// moving back to the location the camera was in just before reopens that assignment. The caller must hold the write
+// lock
+func (m *Memory) recordCameraLocation(cameraId int64, locationId int32, from pgtype.Timestamptz) {
+	replaced := false
+	for assignmentId, assignment := range m.locationAssignments {
+		if assignment.CameraID != cameraId || assignment.ValidTo.Valid {
+			continue
+		}
+		if assignment.ValidFrom.InfinityModifier == pgtype.Finite && assignment.ValidFrom.Time.Equal(from.Time) {
+			delete(m.locationAssignments, assignmentId)
+			replaced = true
+		} else {
+			assignment.ValidTo = from
+			m.locationAssignments[assignmentId] = assignment
+		}
+	}
+
+	if replaced {
+		for assignmentId, assignment := range m.locationAssignments {
+			if assignment.CameraID == cameraId && assignment.LocationID == locationId && assignment.ValidTo.Valid &&
+				assignment.ValidTo.Time.Equal(from.Time) {
+				assignment.ValidTo = pgtype.Timestamptz{}
+				m.locationAssignments[assignmentId] = assignment
+				return
+			}
+		}
+	}
+
+	m.lastAssignmentId++
+	m.locationAssignments[m.lastAssignmentId] = dbschema.CameraLocationAssignment{
+		ID:         m.lastAssignmentId,
+		CameraID:   cameraId,
+		LocationID: locationId,
+		ValidFrom:  from,
+	}
+}
+
+// locationAt returns the location the camera was in at the given date, like camera_location_at, and false if it was
+// in none. The caller must hold the lock
+func (m *Memory) locationAt(cameraId int64, date time.Time) (int32, bool) {
+	for _, assignment := range m.locationAssignments {
+		if assignment.CameraID != cameraId {
+			continue
+		}
+		started := assignment.ValidFrom.InfinityModifier == pgtype.NegativeInfinity || !assignment.ValidFrom.Time.After(date)
+		ended := assignment.ValidTo.Valid && !assignment.ValidTo.Time.After(date)
+		if started && !ended {
+			return assignment.LocationID, true
+		}
+	}
+	return 0, false
+}
+
+func (m *Memory) GetCameraLocationAssignments(ctx context.Context, cameraID int64) ([]dbschema.CameraLocationAssignment, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	assignments := []dbschema.CameraLocationAssignment{}
+	for _, assignment := range m.locationAssignments {
+		if assignment.CameraID == cameraID {
+			assignments = append(assignments, assignment)
+		}
+	}
+	// ids grow with valid_from, as assignments are only ever opened at the current time
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].ID < assignments[j].ID
+	})
+	return assignments, nil
+}
+
+func (m *Memory) DeleteCameraLocationAssignmentsForLocation(ctx context.Context, locationID int32) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var deleted int64
+	for id, assignment := range m.locationAssignments {
+		if assignment.LocationID == locationID {
+			delete(m.locationAssignments, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
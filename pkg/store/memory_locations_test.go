@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+	"testing"
+	"time"
+)
+
+func TestCameraMovedTwiceAtOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		// moves are the locations the camera is moved to, all at the same time, by index in the created locations
+		moves []int
+		// expected are the locations of the assignments left, by index in the created locations
+		expected []int
+	}{
+		{"moved once", []int{1}, []int{0, 1}},
+		{"moved twice", []int{1, 2}, []int{0, 2}},
+		{"moved back", []int{1, 0}, []int{0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewMemory()
+			now := time.Date(2026, 1, 5, 10, 30, 0, 0, time.UTC)
+			s.now = func() time.Time { return now }
+
+			locations := []dbschema.Location{createTestLocation(t, s), createTestLocation(t, s), createTestLocation(t, s)}
+			camera := createTestCamera(t, s, locations[0].ID)
+
+			for _, move := range test.moves {
+				if _, err := s.UpdateCamera(ctx, dbschema.UpdateCameraParams{
+					ID:         camera.ID,
+					LocationID: pgtype.Int4{Int32: int32(locations[move].ID), Valid: true},
+				}); err != nil {
+					t.Fatalf("error moving camera: %s", err)
+				}
+			}
+
+			assignments, err := s.GetCameraLocationAssignments(ctx, camera.ID)
+			if err != nil {
+				t.Fatalf("error getting assignments: %s", err)
+			}
+			if len(assignments) != len(test.expected) {
+				t.Fatalf("expected %d assignments, got %d: %v", len(test.expected), len(assignments), assignments)
+			}
+			for i, assignment := range assignments {
+				if int64(assignment.LocationID) != locations[test.expected[i]].ID {
+					t.Errorf("assignment %d: expected location %d, got %d", i, locations[test.expected[i]].ID, assignment.LocationID)
+				}
+				if assignment.ValidTo.Valid && !assignment.ValidTo.Time.After(assignment.ValidFrom.Time) &&
+					assignment.ValidFrom.InfinityModifier == pgtype.Finite {
+					t.Errorf("assignment %d is empty: %v", i, assignment)
+				}
+			}
+			if last := assignments[len(assignments)-1]; last.ValidTo.Valid {
+				t.Errorf("the last assignment should be open, got %v", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"sort"
+)
+
+func validCameraState(state string) bool {
+	switch state {
+	case "active", "maintenance", "disabled", "retired":
+		return true
+	}
+	return false
+}
+
+func (m *Memory) SetCameraState(ctx context.Context, arg dbschema.SetCameraStateParams) (dbschema.Camera, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	camera, ok := m.cameras[arg.ID]
+	if !ok {
+		return dbschema.Camera{}, pgx.ErrNoRows
+	}
+	if !validCameraState(arg.State) {
+		return dbschema.Camera{}, checkViolation("cameras", "cameras_state_check")
+	}
+
+	camera.State = arg.State
+	camera.StateChangedAt = pgtype.Timestamptz{Time: m.now(), Valid: true}
+	m.cameras[camera.ID] = camera
+	return camera, nil
+}
+
+func (m *Memory) GetCameraStateChanges(ctx context.Context, cameraID int64) ([]dbschema.CameraStateChange, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	changes := []dbschema.CameraStateChange{}
+	for _, change := range m.cameraStateChanges {
+		if change.CameraID == cameraID {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].ChangedAt.Time.Equal(changes[j].ChangedAt.Time) {
+			return changes[i].ChangedAt.Time.Before(changes[j].ChangedAt.Time)
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	return changes, nil
+}
+
+func (m *Memory) CreateCameraStateChange(ctx context.Context, arg dbschema.CreateCameraStateChangeParams) (dbschema.CameraStateChange, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cameras[arg.CameraID]; !ok {
+		return dbschema.CameraStateChange{}, foreignKeyViolation("camera_state_changes", "camera_id", arg.CameraID, "cameras")
+	}
+
+	m.lastStateChangeId++
+	change := dbschema.CameraStateChange{
+		ID:        m.lastStateChangeId,
+		CameraID:  arg.CameraID,
+		FromState: arg.FromState,
+		ToState:   arg.ToState,
+		Reason:    arg.Reason,
+		ChangedAt: arg.ChangedAt,
+	}
+	m.cameraStateChanges[change.ID] = change
+	return change, nil
+}
+
+func (m *Memory) GetMaintenanceWindow(ctx context.Context, id int64) (dbschema.MaintenanceWindow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	window, ok := m.maintenanceWindows[id]
+	if !ok {
+		return dbschema.MaintenanceWindow{}, pgx.ErrNoRows
+	}
+	return window, nil
+}
+
+// sortMaintenanceWindows orders windows by start, then by id
+func sortMaintenanceWindows(windows []dbschema.MaintenanceWindow) {
+	sort.Slice(windows, func(i, j int) bool {
+		if !windows[i].StartsAt.Time.Equal(windows[j].StartsAt.Time) {
+			return windows[i].StartsAt.Time.Before(windows[j].StartsAt.Time)
+		}
+		return windows[i].ID < windows[j].ID
+	})
+}
+
+func (m *Memory) GetMaintenanceWindowsForCamera(ctx context.Context, cameraID int64) ([]dbschema.MaintenanceWindow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	windows := []dbschema.MaintenanceWindow{}
+	for _, window := range m.maintenanceWindows {
+		if window.CameraID == cameraID {
+			windows = append(windows, window)
+		}
+	}
+	sortMaintenanceWindows(windows)
+	return windows, nil
+}
+
+func (m *Memory) GetMaintenanceWindowAt(ctx context.Context, arg dbschema.GetMaintenanceWindowAtParams) (dbschema.MaintenanceWindow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	windows := []dbschema.MaintenanceWindow{}
+	for _, window := range m.maintenanceWindows {
+		if window.CameraID == arg.CameraID && !window.StartsAt.Time.After(arg.At.Time) && window.EndsAt.Time.After(arg.At.Time) {
+			windows = append(windows, window)
+		}
+	}
+	if len(windows) == 0 {
+		return dbschema.MaintenanceWindow{}, pgx.ErrNoRows
+	}
+
+	sortMaintenanceWindows(windows)
+	for _, window := range windows {
+		if window.Ingest == "reject" {
+			return window, nil
+		}
+	}
+	return windows[0], nil
+}
+
+func (m *Memory) GetCamerasInMaintenance(ctx context.Context, arg dbschema.GetCamerasInMaintenanceParams) ([]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	seen := map[int64]bool{}
+	ids := []int64{}
+	for _, window := range m.maintenanceWindows {
+		if window.StartsAt.Time.Before(arg.ToDate.Time) && window.EndsAt.Time.After(arg.FromDate.Time) && !seen[window.CameraID] {
+			seen[window.CameraID] = true
+			ids = append(ids, window.CameraID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids, nil
+}
+
+// checkMaintenanceWindow validates a window as the table constraints would, the caller must hold the lock
+func (m *Memory) checkMaintenanceWindow(window dbschema.MaintenanceWindow) error {
+	if _, ok := m.cameras[window.CameraID]; !ok {
+		return foreignKeyViolation("maintenance_windows", "camera_id", window.CameraID, "cameras")
+	}
+	if !window.StartsAt.Valid {
+		return notNullViolation("maintenance_windows", "starts_at")
+	}
+	if !window.EndsAt.Valid {
+		return notNullViolation("maintenance_windows", "ends_at")
+	}
+	if window.Ingest != "reject" && window.Ingest != "flag" {
+		return checkViolation("maintenance_windows", "maintenance_windows_ingest_check")
+	}
+	if !window.EndsAt.Time.After(window.StartsAt.Time) {
+		return checkViolation("maintenance_windows", "maintenance_windows_range_check")
+	}
+	return nil
+}
+
+func (m *Memory) CreateMaintenanceWindow(ctx context.Context, arg dbschema.CreateMaintenanceWindowParams) (dbschema.MaintenanceWindow, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	window := dbschema.MaintenanceWindow{
+		CameraID:    arg.CameraID,
+		StartsAt:    arg.StartsAt,
+		EndsAt:      arg.EndsAt,
+		Ingest:      arg.Ingest,
+		Description: arg.Description,
+	}
+	if err := m.checkMaintenanceWindow(window); err != nil {
+		return dbschema.MaintenanceWindow{}, err
+	}
+
+	m.lastMaintenanceId++
+	window.ID = m.lastMaintenanceId
+	m.maintenanceWindows[window.ID] = window
+	return window, nil
+}
+
+func (m *Memory) UpdateMaintenanceWindow(ctx context.Context, arg dbschema.UpdateMaintenanceWindowParams) (dbschema.MaintenanceWindow, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	window, ok := m.maintenanceWindows[arg.ID]
+	if !ok {
+		return dbschema.MaintenanceWindow{}, pgx.ErrNoRows
+	}
+
+	if arg.StartsAt.Valid {
+		window.StartsAt = arg.StartsAt
+	}
+	if arg.EndsAt.Valid {
+		window.EndsAt = arg.EndsAt
+	}
+	if arg.Ingest.Valid {
+		window.Ingest = arg.Ingest.String
+	}
+	if arg.Description.Valid {
+		window.Description = arg.Description.String
+	}
+
+	if err := m.checkMaintenanceWindow(window); err != nil {
+		return dbschema.MaintenanceWindow{}, err
+	}
+
+	m.maintenanceWindows[window.ID] = window
+	return window, nil
+}
+
+func (m *Memory) DeleteMaintenanceWindow(ctx context.Context, id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.maintenanceWindows, id)
+	return nil
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// conn is implemented by *pgxpool.Pool and by pgx.Tx, where Begin creates a savepoint
+type conn interface {
+	dbschema.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Postgres is the Store backed by the sqlc generated queries, adding transactions and streamed queries on top of them
+type Postgres struct {
+	*dbschema.Queries
+	db conn
+}
+
+func NewPostgres(db *pgxpool.Pool) *Postgres {
+	return &Postgres{Queries: dbschema.New(db), db: db}
+}
+
+func (p *Postgres) InTx(ctx context.Context, fn func(s Store) error) error {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&Postgres{Queries: p.Queries.WithTx(tx), db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// StreamPersonDetectionsExport runs the generated ExportPersonDetections query, but hands each row to fn while it is
+// read from the connection instead of collecting every row first. Returning an error from fn stops the query and
+// returns that error.
+func (p *Postgres) StreamPersonDetectionsExport(ctx context.Context, arg dbschema.ExportPersonDetectionsParams, fn func(row dbschema.ExportPersonDetectionsRow) error) error {
+	rows, err := p.db.Query(ctx, dbschema.ExportPersonDetections,
+		arg.FromDate,
+		arg.ToDate,
+		arg.CameraID,
+		arg.LocationIds,
+		arg.CameraIds,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i dbschema.ExportPersonDetectionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CameraID,
+			&i.CameraName,
+			&i.LocationID,
+			&i.LocationName,
+			&i.DetectionDate,
+			&i.TargetDirection,
+			&i.NormalizedDirection,
+			&i.Flagged,
+			&i.Excluded,
+			&i.TrackID,
+			&i.Confidence,
+			&i.BboxX,
+			&i.BboxY,
+			&i.BboxWidth,
+			&i.BboxHeight,
+			&i.FrameDate,
+			&i.ModelVersion,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,117 @@
+// Package store defines the storage operations used by the service, implemented by the sqlc generated
+// *dbschema.Queries for postgres and by Memory for running without a database.
+package store
+
+import (
+	"context"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Store is the storage used by the api. Implementations report errors like postgres does: pgx.ErrNoRows for a
+// missing record and *pgconn.PgError with the postgres error code for constraint violations.
+type Store interface {
+	GetCamera(ctx context.Context, id int64) (dbschema.Camera, error)
+	GetCameras(ctx context.Context, includeDeleted bool) ([]dbschema.Camera, error)
+	CreateCamera(ctx context.Context, arg dbschema.CreateCameraParams) (dbschema.Camera, error)
+	UpdateCamera(ctx context.Context, arg dbschema.UpdateCameraParams) (dbschema.Camera, error)
+	DeleteCamera(ctx context.Context, id int64) error
+	SoftDeleteCamera(ctx context.Context, id int64) (dbschema.Camera, error)
+	RestoreCamera(ctx context.Context, id int64) (dbschema.Camera, error)
+	GetCameraStatus(ctx context.Context, cameraID int64) (dbschema.CameraStatus, error)
+	GetCameraStatuses(ctx context.Context) ([]dbschema.CameraStatus, error)
+	UpsertCameraStatus(ctx context.Context, arg dbschema.UpsertCameraStatusParams) (dbschema.CameraStatus, error)
+	SetCameraState(ctx context.Context, arg dbschema.SetCameraStateParams) (dbschema.Camera, error)
+	GetCameraStateChanges(ctx context.Context, cameraID int64) ([]dbschema.CameraStateChange, error)
+	CreateCameraStateChange(ctx context.Context, arg dbschema.CreateCameraStateChangeParams) (dbschema.CameraStateChange, error)
+	GetCameraLocationAssignments(ctx context.Context, cameraID int64) ([]dbschema.CameraLocationAssignment, error)
+	DeleteCameraLocationAssignmentsForLocation(ctx context.Context, locationID int32) (int64, error)
+
+	GetMaintenanceWindow(ctx context.Context, id int64) (dbschema.MaintenanceWindow, error)
+	GetMaintenanceWindowsForCamera(ctx context.Context, cameraID int64) ([]dbschema.MaintenanceWindow, error)
+	GetMaintenanceWindowAt(ctx context.Context, arg dbschema.GetMaintenanceWindowAtParams) (dbschema.MaintenanceWindow, error)
+	GetCamerasInMaintenance(ctx context.Context, arg dbschema.GetCamerasInMaintenanceParams) ([]int64, error)
+	CreateMaintenanceWindow(ctx context.Context, arg dbschema.CreateMaintenanceWindowParams) (dbschema.MaintenanceWindow, error)
+	UpdateMaintenanceWindow(ctx context.Context, arg dbschema.UpdateMaintenanceWindowParams) (dbschema.MaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, id int64) error
+
+	GetCameraGroup(ctx context.Context, id int64) (dbschema.CameraGroup, error)
+	GetCameraGroupByName(ctx context.Context, name string) (dbschema.CameraGroup, error)
+	GetCameraGroups(ctx context.Context) ([]dbschema.CameraGroup, error)
+	CreateCameraGroup(ctx context.Context, arg dbschema.CreateCameraGroupParams) (dbschema.CameraGroup, error)
+	UpdateCameraGroup(ctx context.Context, arg dbschema.UpdateCameraGroupParams) (dbschema.CameraGroup, error)
+	DeleteCameraGroup(ctx context.Context, id int64) error
+	GetCameraGroupMembers(ctx context.Context) ([]dbschema.CameraGroupMember, error)
+	GetCameraGroupCameraIds(ctx context.Context, groupID int64) ([]int64, error)
+	AddCameraGroupMember(ctx context.Context, arg dbschema.AddCameraGroupMemberParams) error
+	RemoveCameraGroupMember(ctx context.Context, arg dbschema.RemoveCameraGroupMemberParams) error
+
+	GetActivityRule(ctx context.Context, id int64) (dbschema.ActivityRule, error)
+	GetActivityRules(ctx context.Context) ([]dbschema.ActivityRule, error)
+	GetActivityRulesForCamera(ctx context.Context, cameraID int64) ([]dbschema.ActivityRule, error)
+	CreateActivityRule(ctx context.Context, arg dbschema.CreateActivityRuleParams) (dbschema.ActivityRule, error)
+	UpdateActivityRule(ctx context.Context, arg dbschema.UpdateActivityRuleParams) (dbschema.ActivityRule, error)
+	DeleteActivityRule(ctx context.Context, id int64) error
+
+	GetAlert(ctx context.Context, id int64) (dbschema.Alert, error)
+	GetAlerts(ctx context.Context, arg dbschema.GetAlertsParams) ([]dbschema.Alert, error)
+	GetUnresolvedActivityRuleAlert(ctx context.Context, activityRuleID pgtype.Int8) (dbschema.Alert, error)
+	GetUnresolvedLocationAlert(ctx context.Context, arg dbschema.GetUnresolvedLocationAlertParams) (dbschema.Alert, error)
+	CreateAlert(ctx context.Context, arg dbschema.CreateAlertParams) (dbschema.Alert, error)
+	AcknowledgeAlert(ctx context.Context, arg dbschema.AcknowledgeAlertParams) (dbschema.Alert, error)
+	UpdateAlertSeverity(ctx context.Context, arg dbschema.UpdateAlertSeverityParams) (dbschema.Alert, error)
+	ResolveAlert(ctx context.Context, id int64) (dbschema.Alert, error)
+
+	GetLocation(ctx context.Context, id int64) (dbschema.Location, error)
+	GetLocations(ctx context.Context, includeDeleted bool) ([]dbschema.Location, error)
+	GetLocationChildren(ctx context.Context, parentID int64) ([]dbschema.Location, error)
+	GetLocationSubtreeIds(ctx context.Context, id int64) ([]int64, error)
+	CreateLocation(ctx context.Context, arg dbschema.CreateLocationParams) (dbschema.Location, error)
+	UpdateLocation(ctx context.Context, arg dbschema.UpdateLocationParams) (dbschema.Location, error)
+	DeleteLocation(ctx context.Context, id int64) error
+	SoftDeleteLocation(ctx context.Context, id int64) (dbschema.Location, error)
+	RestoreLocation(ctx context.Context, id int64) (dbschema.Location, error)
+	GetLocationOccupancies(ctx context.Context, arg dbschema.GetLocationOccupanciesParams) ([]dbschema.GetLocationOccupanciesRow, error)
+	GetLocationCameraCounts(ctx context.Context, arg dbschema.GetLocationCameraCountsParams) ([]dbschema.GetLocationCameraCountsRow, error)
+
+	GetFloorPlan(ctx context.Context, locationID int64) (dbschema.FloorPlan, error)
+	SetFloorPlan(ctx context.Context, arg dbschema.SetFloorPlanParams) (dbschema.FloorPlan, error)
+	UpdateFloorPlan(ctx context.Context, arg dbschema.UpdateFloorPlanParams) (dbschema.FloorPlan, error)
+	DeleteFloorPlan(ctx context.Context, locationID int64) error
+
+	GetPersonDetection(ctx context.Context, id int64) (dbschema.PersonDetection, error)
+	GetLatestPersonDetection(ctx context.Context) (dbschema.PersonDetection, error)
+	GetPersonDetections(ctx context.Context, arg dbschema.GetPersonDetectionsParams) ([]dbschema.PersonDetection, error)
+	GetPersonDetectionsForCamera(ctx context.Context, arg dbschema.GetPersonDetectionsForCameraParams) ([]dbschema.PersonDetection, error)
+	CreatePersonDetection(ctx context.Context, arg dbschema.CreatePersonDetectionParams) (dbschema.PersonDetection, error)
+	UpdatePersonDetection(ctx context.Context, arg dbschema.UpdatePersonDetectionParams) (dbschema.PersonDetection, error)
+	DeletePersonDetection(ctx context.Context, id int64) error
+	DeletePersonDetectionsForCamera(ctx context.Context, cameraID int64) (int64, error)
+	ReassignPersonDetections(ctx context.Context, arg dbschema.ReassignPersonDetectionsParams) (int64, error)
+	GetIdempotentPersonDetection(ctx context.Context, arg dbschema.GetIdempotentPersonDetectionParams) (dbschema.PersonDetection, error)
+	ClaimPersonDetectionIdempotencyKey(ctx context.Context, arg dbschema.ClaimPersonDetectionIdempotencyKeyParams) (int64, error)
+	LockPersonDetectionIdempotencyKey(ctx context.Context, arg dbschema.LockPersonDetectionIdempotencyKeyParams) (int64, error)
+	SetPersonDetectionIdempotencyKeyDetection(ctx context.Context, arg dbschema.SetPersonDetectionIdempotencyKeyDetectionParams) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, arg dbschema.DeleteExpiredIdempotencyKeysParams) (int64, error)
+	DeleteCameraDetectionsForCamera(ctx context.Context, cameraID int64) (int64, error)
+	ReassignCameraDetections(ctx context.Context, arg dbschema.ReassignCameraDetectionsParams) (int64, error)
+	GetDailyPersonDetectionsCount(ctx context.Context, arg dbschema.GetDailyPersonDetectionsCountParams) ([]dbschema.GetDailyPersonDetectionsCountRow, error)
+	GetHourlyPersonDetectionsCount(ctx context.Context, arg dbschema.GetHourlyPersonDetectionsCountParams) ([]dbschema.GetHourlyPersonDetectionsCountRow, error)
+	CountPersonDetectionsForCamera(ctx context.Context, arg dbschema.CountPersonDetectionsForCameraParams) (int64, error)
+	GetHourlyPersonDetectionsCountRaw(ctx context.Context, arg dbschema.GetHourlyPersonDetectionsCountRawParams) ([]dbschema.GetHourlyPersonDetectionsCountRawRow, error)
+	GetLocationDailyPersonDetectionsCount(ctx context.Context, arg dbschema.GetLocationDailyPersonDetectionsCountParams) ([]dbschema.GetLocationDailyPersonDetectionsCountRow, error)
+	GetLocationHourlyPersonDetectionsCount(ctx context.Context, arg dbschema.GetLocationHourlyPersonDetectionsCountParams) ([]dbschema.GetLocationHourlyPersonDetectionsCountRow, error)
+	GetLocationHourlyPersonDetectionsCountRaw(ctx context.Context, arg dbschema.GetLocationHourlyPersonDetectionsCountRawParams) ([]dbschema.GetLocationHourlyPersonDetectionsCountRawRow, error)
+	GetCamerasDailyPersonDetectionsCount(ctx context.Context, arg dbschema.GetCamerasDailyPersonDetectionsCountParams) ([]dbschema.GetCamerasDailyPersonDetectionsCountRow, error)
+	GetCamerasHourlyPersonDetectionsCount(ctx context.Context, arg dbschema.GetCamerasHourlyPersonDetectionsCountParams) ([]dbschema.GetCamerasHourlyPersonDetectionsCountRow, error)
+	GetCamerasHourlyPersonDetectionsCountRaw(ctx context.Context, arg dbschema.GetCamerasHourlyPersonDetectionsCountRawParams) ([]dbschema.GetCamerasHourlyPersonDetectionsCountRawRow, error)
+	// StreamPersonDetectionsExport calls fn for every exported detection, oldest first, stopping at the first error
+	StreamPersonDetectionsExport(ctx context.Context, arg dbschema.ExportPersonDetectionsParams, fn func(row dbschema.ExportPersonDetectionsRow) error) error
+
+	// InTx runs fn with a Store whose changes are only kept if fn returns nil. Calling InTx on that Store nests a
+	// transaction that only undoes its own changes when it fails, like a savepoint
+	InTx(ctx context.Context, fn func(s Store) error) error
+}
+
+var _ Store = (*Postgres)(nil)
+var _ Store = (*Memory)(nil)
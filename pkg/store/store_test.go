@@ -0,0 +1,560 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbenums"
+	"github.com/SmartFactory-Tec/camera_service/pkg/dbschema"
+	"github.com/SmartFactory-Tec/camera_service/pkg/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testDatabaseUrlVariable names the database the postgres store is tested against, the tests that need it are
+// skipped when it is not set. The database is migrated to the latest schema and every test leaves it as it found it
+const testDatabaseUrlVariable = "CAMERA_SERVICE_TEST_DATABASE_URL"
+
+// testStores calls fn with every Store implementation available, each one empty as far as fn can tell
+func testStores(t *testing.T, fn func(t *testing.T, s Store)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemory())
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		pool := testPostgresPool(t)
+
+		ctx := context.Background()
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("error starting transaction: %s", err)
+		}
+		defer tx.Rollback(ctx)
+
+		fn(t, &Postgres{Queries: dbschema.New(tx), db: tx})
+	})
+}
+
+func testPostgresPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseUrlVariable)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseUrlVariable)
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("error connecting to database for migrations: %s", err)
+	}
+	defer db.Close()
+
+	goose.SetBaseFS(migrations.Migrations)
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("error initializing goose: %s", err)
+	}
+	if err := goose.Up(db, "."); err != nil {
+		t.Fatalf("error migrating database: %s", err)
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("error connecting to database: %s", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func createTestLocation(t *testing.T, s Store) dbschema.Location {
+	t.Helper()
+	location, err := s.CreateLocation(context.Background(), dbschema.CreateLocationParams{Name: "hall"})
+	if err != nil {
+		t.Fatalf("error creating location: %s", err)
+	}
+	return location
+}
+
+func createTestCamera(t *testing.T, s Store, locationId int64) dbschema.Camera {
+	t.Helper()
+	camera, err := s.CreateCamera(context.Background(), dbschema.CreateCameraParams{
+		Name:             "entrance",
+		ConnectionString: "rtsp://entrance",
+		LocationID:       int32(locationId),
+		Orientation:      dbenums.CameraOrientationHorizontal,
+		Tags:             map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("error creating camera: %s", err)
+	}
+	return camera
+}
+
+func TestStoreErrorParity(t *testing.T) {
+	const missingId = 1 << 40
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, t *testing.T, s Store) error
+		// code is the postgres error code expected, empty when pgx.ErrNoRows is expected
+		code       string
+		constraint string
+	}{
+		{
+			name: "get missing camera",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.GetCamera(ctx, missingId)
+				return err
+			},
+		},
+		{
+			name: "update missing camera",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.UpdateCamera(ctx, dbschema.UpdateCameraParams{ID: missingId})
+				return err
+			},
+		},
+		{
+			name: "get missing location",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.GetLocation(ctx, missingId)
+				return err
+			},
+		},
+		{
+			name: "get missing person detection",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.GetPersonDetection(ctx, missingId)
+				return err
+			},
+		},
+		{
+			name: "camera in missing location",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.CreateCamera(ctx, dbschema.CreateCameraParams{
+					Name:        "entrance",
+					LocationID:  1 << 30,
+					Orientation: dbenums.CameraOrientationHorizontal,
+					Tags:        map[string]string{},
+				})
+				return err
+			},
+			code:       "23503",
+			constraint: "cameras_location_id_fkey",
+		},
+		{
+			name: "location with missing parent",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{
+					Name:     "hall",
+					ParentID: pgtype.Int8{Int64: missingId, Valid: true},
+				})
+				return err
+			},
+			code:       "23503",
+			constraint: "locations_parent_id_fkey",
+		},
+		{
+			name: "location its own parent",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				location := createTestLocation(t, s)
+				_, err := s.UpdateLocation(ctx, dbschema.UpdateLocationParams{
+					ID:       location.ID,
+					ParentID: pgtype.Int8{Int64: location.ID, Valid: true},
+				})
+				return err
+			},
+			code:       "23514",
+			constraint: "locations_parent_id_check",
+		},
+		{
+			name: "location its own ancestor",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				site := createTestLocation(t, s)
+				building, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{
+					Name:     "building",
+					ParentID: pgtype.Int8{Int64: site.ID, Valid: true},
+				})
+				if err != nil {
+					t.Fatalf("error creating location: %s", err)
+				}
+				floor, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{
+					Name:     "floor",
+					ParentID: pgtype.Int8{Int64: building.ID, Valid: true},
+				})
+				if err != nil {
+					t.Fatalf("error creating location: %s", err)
+				}
+				_, err = s.UpdateLocation(ctx, dbschema.UpdateLocationParams{
+					ID:       site.ID,
+					ParentID: pgtype.Int8{Int64: floor.ID, Valid: true},
+				})
+				return err
+			},
+			code:       "23514",
+			constraint: "locations_parent_id_cycle",
+		},
+		{
+			name: "location with zero capacity",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{
+					Name:     "hall",
+					Capacity: pgtype.Int4{Int32: 0, Valid: true},
+				})
+				return err
+			},
+			code:       "23514",
+			constraint: "locations_capacity_check",
+		},
+		{
+			name: "delete location with cameras",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				location := createTestLocation(t, s)
+				createTestCamera(t, s, location.ID)
+				return s.DeleteLocation(ctx, location.ID)
+			},
+			code:       "23503",
+			constraint: "cameras_location_id_fkey",
+		},
+		{
+			name: "delete location in the history of a camera",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				location := createTestLocation(t, s)
+				camera := createTestCamera(t, s, location.ID)
+				if _, err := s.UpdateCamera(ctx, dbschema.UpdateCameraParams{
+					ID:         camera.ID,
+					LocationID: pgtype.Int4{Int32: int32(createTestLocation(t, s).ID), Valid: true},
+				}); err != nil {
+					t.Fatalf("error moving camera: %s", err)
+				}
+				return s.DeleteLocation(ctx, location.ID)
+			},
+			code:       "23503",
+			constraint: "camera_location_assignments_location_id_fkey",
+		},
+		{
+			name: "person detection of missing camera",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				_, err := s.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+					CameraID:        missingId,
+					DetectionDate:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+					TargetDirection: dbenums.DirectionLeft,
+				})
+				return err
+			},
+			code:       "23503",
+			constraint: "person_detections_camera_id_fkey",
+		},
+		{
+			name: "delete camera with person detections",
+			run: func(ctx context.Context, t *testing.T, s Store) error {
+				camera := createTestCamera(t, s, createTestLocation(t, s).ID)
+				if _, err := s.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+					CameraID:        camera.ID,
+					DetectionDate:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+					TargetDirection: dbenums.DirectionLeft,
+				}); err != nil {
+					t.Fatalf("error creating person detection: %s", err)
+				}
+				return s.DeleteCamera(ctx, camera.ID)
+			},
+			code:       "23503",
+			constraint: "person_detections_camera_id_fkey",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testStores(t, func(t *testing.T, s Store) {
+				err := test.run(context.Background(), t, s)
+
+				if test.code == "" {
+					if !errors.Is(err, pgx.ErrNoRows) {
+						t.Fatalf("expected pgx.ErrNoRows, got %v", err)
+					}
+					return
+				}
+
+				var pgErr *pgconn.PgError
+				if !errors.As(err, &pgErr) {
+					t.Fatalf("expected a postgres error with code %s, got %v", test.code, err)
+				}
+				if pgErr.Code != test.code || pgErr.ConstraintName != test.constraint {
+					t.Fatalf("expected code %s on constraint %s, got code %s on constraint %s",
+						test.code, test.constraint, pgErr.Code, pgErr.ConstraintName)
+				}
+			})
+		})
+	}
+}
+
+func TestUpdatePersonDetectionAppliesMinConfidence(t *testing.T) {
+	testStores(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		location := createTestLocation(t, s)
+		camera := createTestCamera(t, s, location.ID)
+		other := createTestCamera(t, s, location.ID)
+
+		confidence := 0.4
+		personDetection, err := s.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+			CameraID:        camera.ID,
+			DetectionDate:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			TargetDirection: dbenums.DirectionLeft,
+			Confidence:      &confidence,
+		})
+		if err != nil {
+			t.Fatalf("error creating person detection: %s", err)
+		}
+
+		moved, err := s.UpdatePersonDetection(ctx, dbschema.UpdatePersonDetectionParams{
+			ID:            personDetection.ID,
+			CameraID:      pgtype.Int8{Int64: other.ID, Valid: true},
+			MinConfidence: 0.5,
+		})
+		if err != nil {
+			t.Fatalf("error updating person detection: %s", err)
+		}
+		if !moved.Excluded {
+			t.Fatal("expected the moved detection to be excluded")
+		}
+
+		// lowering the minimum confidence does not include it back
+		updated, err := s.UpdatePersonDetection(ctx, dbschema.UpdatePersonDetectionParams{ID: personDetection.ID})
+		if err != nil {
+			t.Fatalf("error updating person detection: %s", err)
+		}
+		if !updated.Excluded {
+			t.Fatal("expected the detection to stay excluded")
+		}
+	})
+}
+
+func TestPersonDetectionsAfterIdSortedById(t *testing.T) {
+	testStores(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		location := createTestLocation(t, s)
+		camera := createTestCamera(t, s, location.ID)
+
+		// every detection is created with a newer date than the previous one, so newest first is the reverse of by id
+		var ids []int64
+		for i := 0; i < 3; i++ {
+			personDetection, err := s.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+				CameraID:        camera.ID,
+				DetectionDate:   pgtype.Timestamptz{Time: time.Now().Add(-time.Duration(3-i) * time.Hour), Valid: true},
+				TargetDirection: dbenums.DirectionLeft,
+			})
+			if err != nil {
+				t.Fatalf("error creating person detection: %s", err)
+			}
+			ids = append(ids, personDetection.ID)
+		}
+
+		latest, err := s.GetLatestPersonDetection(ctx)
+		if err != nil {
+			t.Fatalf("error getting latest person detection: %s", err)
+		}
+		if latest.ID != ids[2] {
+			t.Errorf("latest detection is %d, expected %d", latest.ID, ids[2])
+		}
+
+		newestFirst, err := s.GetPersonDetectionsForCamera(ctx, dbschema.GetPersonDetectionsForCameraParams{
+			CameraID: camera.ID,
+			Count:    10,
+		})
+		if err != nil {
+			t.Fatalf("error listing person detections: %s", err)
+		}
+		afterFirst, err := s.GetPersonDetections(ctx, dbschema.GetPersonDetectionsParams{
+			CameraIds: []int64{camera.ID},
+			AfterID:   pgtype.Int8{Int64: ids[0], Valid: true},
+			Count:     10,
+		})
+		if err != nil {
+			t.Fatalf("error listing person detections: %s", err)
+		}
+
+		listed := func(personDetections []dbschema.PersonDetection) []int64 {
+			var ids []int64
+			for _, personDetection := range personDetections {
+				ids = append(ids, personDetection.ID)
+			}
+			return ids
+		}
+		if got := listed(newestFirst); len(got) != 3 || got[0] != ids[2] || got[1] != ids[1] || got[2] != ids[0] {
+			t.Errorf("listing without after_id returned %v, expected the newest first", got)
+		}
+		if got := listed(afterFirst); len(got) != 2 || got[0] != ids[1] || got[1] != ids[2] {
+			t.Errorf("listing after %d returned %v, expected %v by id", ids[0], got, ids[1:])
+		}
+	})
+}
+
+func TestStreamPersonDetectionsExportInOrder(t *testing.T) {
+	testStores(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		location := createTestLocation(t, s)
+		camera := createTestCamera(t, s, location.ID)
+
+		// detections are created out of order, with two of them at the same date so the id breaks the tie
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		var ids []int64
+		for _, minutes := range []int{30, 10, 20, 10, 0} {
+			personDetection, err := s.CreatePersonDetection(ctx, dbschema.CreatePersonDetectionParams{
+				CameraID:        camera.ID,
+				DetectionDate:   pgtype.Timestamptz{Time: base.Add(time.Duration(minutes) * time.Minute), Valid: true},
+				TargetDirection: dbenums.DirectionLeft,
+			})
+			if err != nil {
+				t.Fatalf("error creating person detection: %s", err)
+			}
+			ids = append(ids, personDetection.ID)
+		}
+		expected := []int64{ids[4], ids[1], ids[3], ids[2], ids[0]}
+
+		params := dbschema.ExportPersonDetectionsParams{CameraID: pgtype.Int8{Int64: camera.ID, Valid: true}}
+		var streamed []int64
+		if err := s.StreamPersonDetectionsExport(ctx, params, func(row dbschema.ExportPersonDetectionsRow) error {
+			streamed = append(streamed, row.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("error streaming person detections: %s", err)
+		}
+		if !reflect.DeepEqual(streamed, expected) {
+			t.Errorf("streamed %v, expected %v", streamed, expected)
+		}
+
+		errStop := errors.New("stop")
+		calls := 0
+		if err := s.StreamPersonDetectionsExport(ctx, params, func(row dbschema.ExportPersonDetectionsRow) error {
+			calls++
+			return errStop
+		}); !errors.Is(err, errStop) || calls != 1 {
+			t.Errorf("expected streaming to stop with %v after 1 row, got %v after %d", errStop, err, calls)
+		}
+	})
+}
+
+func TestNestedTransactions(t *testing.T) {
+	testStores(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		errRollback := errors.New("rollback")
+		create := func(s Store, name string) {
+			t.Helper()
+			if _, err := s.CreateLocation(ctx, dbschema.CreateLocationParams{Name: name}); err != nil {
+				t.Fatalf("error creating location: %s", err)
+			}
+		}
+		// locations are looked up by name, the memory store hands out the ids of undone records again
+		stored := func(s Store) map[string]bool {
+			t.Helper()
+			locations, err := s.GetLocations(ctx, true)
+			if err != nil {
+				t.Fatalf("error listing locations: %s", err)
+			}
+			names := map[string]bool{}
+			for _, location := range locations {
+				names[location.Name] = true
+			}
+			return names
+		}
+
+		err := s.InTx(ctx, func(s Store) error {
+			create(s, "outer")
+
+			// a failing nested transaction only undoes its own writes
+			if err := s.InTx(ctx, func(s Store) error {
+				create(s, "undone")
+				return errRollback
+			}); !errors.Is(err, errRollback) {
+				t.Errorf("expected %v from the failed nested transaction, got %v", errRollback, err)
+			}
+			if err := s.InTx(ctx, func(s Store) error {
+				create(s, "kept")
+				return nil
+			}); err != nil {
+				t.Errorf("error in nested transaction: %s", err)
+			}
+
+			if names := stored(s); !names["outer"] || names["undone"] || !names["kept"] {
+				t.Errorf("expected outer and kept locations only, got %v", names)
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("expected %v from the failed transaction, got %v", errRollback, err)
+		}
+
+		// the failed outer transaction undoes the writes of the nested transactions that succeeded as well
+		if names := stored(s); names["outer"] || names["undone"] || names["kept"] {
+			t.Errorf("expected every location to be undone, got %v", names)
+		}
+	})
+}
+
+func TestGetLatestPersonDetectionWithoutDetections(t *testing.T) {
+	if _, err := NewMemory().GetLatestPersonDetection(context.Background()); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows, got %v", err)
+	}
+}
+
+func TestConcurrentLocationCycleRejected(t *testing.T) {
+	pool := testPostgresPool(t)
+	ctx := context.Background()
+
+	// the changes must be committed for the other transaction to see them, so they are deleted afterwards instead
+	// of rolled back
+	queries := dbschema.New(pool)
+	var ids []int64
+	for _, name := range []string{"cycle a", "cycle b"} {
+		location, err := queries.CreateLocation(ctx, dbschema.CreateLocationParams{Name: name})
+		if err != nil {
+			t.Fatalf("error creating location: %s", err)
+		}
+		ids = append(ids, location.ID)
+	}
+	t.Cleanup(func() {
+		if _, err := pool.Exec(ctx, "update locations set parent_id = null where id = any($1)", ids); err != nil {
+			t.Errorf("error detaching locations: %s", err)
+		}
+		if _, err := pool.Exec(ctx, "delete from locations where id = any($1)", ids); err != nil {
+			t.Errorf("error deleting locations: %s", err)
+		}
+	})
+
+	first, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("error starting transaction: %s", err)
+	}
+	defer first.Rollback(ctx)
+	if _, err := dbschema.New(first).UpdateLocation(ctx, dbschema.UpdateLocationParams{
+		ID:       ids[0],
+		ParentID: pgtype.Int8{Int64: ids[1], Valid: true},
+	}); err != nil {
+		t.Fatalf("error updating location: %s", err)
+	}
+
+	// the second change waits for the first one to commit, and then sees it
+	second := make(chan error, 1)
+	go func() {
+		_, err := dbschema.New(pool).UpdateLocation(ctx, dbschema.UpdateLocationParams{
+			ID:       ids[1],
+			ParentID: pgtype.Int8{Int64: ids[0], Valid: true},
+		})
+		second <- err
+	}()
+	if err := first.Commit(ctx); err != nil {
+		t.Fatalf("error committing transaction: %s", err)
+	}
+
+	var pgErr *pgconn.PgError
+	if err := <-second; !errors.As(err, &pgErr) || pgErr.ConstraintName != "locations_parent_id_cycle" {
+		t.Fatalf("expected a violation of locations_parent_id_cycle, got %v", err)
+	}
+}